@@ -7,6 +7,7 @@ import (
 	"net"
 	"os"
 	"sort"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/stringid"
@@ -38,6 +39,8 @@ type agent struct {
 	bindAddr          string
 	advertiseAddr     string
 	epTblCancel       func()
+	policyTblCancel   func()
+	dnsTblCancel      func()
 	driverCancelFuncs map[string][]func()
 }
 
@@ -252,11 +255,23 @@ func (c *controller) agentInit(listenAddr, bindAddrOrInterface, advertiseAddr st
 	nodeName := hostname + "-" + stringid.TruncateID(stringid.GenerateRandomID())
 	logrus.Info("Gossip cluster hostname ", nodeName)
 
+	gossip := c.cfg.Cluster.Gossip
 	nDB, err := networkdb.New(&networkdb.Config{
-		BindAddr:      listenAddr,
-		AdvertiseAddr: advertiseAddr,
-		NodeName:      nodeName,
-		Keys:          keys,
+		BindAddr:            listenAddr,
+		AdvertiseAddr:       advertiseAddr,
+		NodeName:            nodeName,
+		Keys:                keys,
+		DiagnosticAddr:      c.cfg.Daemon.NetworkDBDiagnostic,
+		DiagnosticWrite:     c.cfg.Daemon.NetworkDBDiagnosticWrite,
+		Store:               c.getStore(datastore.LocalScope),
+		Profile:             gossip.Profile,
+		ReapEntryInterval:   gossip.ReapEntryInterval,
+		ReapNodeInterval:    gossip.ReapNodeInterval,
+		GossipInterval:      gossip.GossipInterval,
+		BulkSyncInterval:    gossip.BulkSyncInterval,
+		RetransmitMult:      gossip.RetransmitMult,
+		BulkSyncChunkSize:   gossip.BulkSyncChunkSize,
+		BulkSyncCompression: gossip.BulkSyncCompression,
 	})
 
 	if err != nil {
@@ -264,16 +279,22 @@ func (c *controller) agentInit(listenAddr, bindAddrOrInterface, advertiseAddr st
 	}
 
 	ch, cancel := nDB.Watch("endpoint_table", "", "")
+	policyCh, policyCancel := nDB.Watch(networkPolicyTable, "", "")
+	dnsCh, dnsCancel := nDB.Watch(dnsRecordTable, "", "")
 
 	c.agent = &agent{
 		networkDB:         nDB,
 		bindAddr:          bindAddr,
 		advertiseAddr:     advertiseAddr,
 		epTblCancel:       cancel,
+		policyTblCancel:   policyCancel,
+		dnsTblCancel:      dnsCancel,
 		driverCancelFuncs: make(map[string][]func()),
 	}
 
 	go c.handleTableEvents(ch, c.handleEpTableEvent)
+	go c.handleTableEvents(policyCh, c.handleNetworkPolicyTableEvent)
+	go c.handleTableEvents(dnsCh, c.handleDNSRecordTableEvent)
 
 	drvEnc := discoverapi.DriverEncryptionConfig{}
 	keys, tags = c.getKeys(subsysIPSec)
@@ -346,6 +367,8 @@ func (c *controller) agentClose() {
 	}
 
 	agent.epTblCancel()
+	agent.policyTblCancel()
+	agent.dnsTblCancel()
 
 	agent.networkDB.Close()
 }
@@ -396,7 +419,7 @@ func (ep *endpoint) addToCluster() error {
 				ingressPorts = ep.ingressPorts
 			}
 
-			if err := c.addServiceBinding(ep.svcName, ep.svcID, n.ID(), ep.ID(), ep.virtualIP, ingressPorts, ep.svcAliases, ep.Iface().Address().IP); err != nil {
+			if err := c.addServiceBinding(ep.svcName, ep.svcID, n.ID(), ep.ID(), ep.virtualIP, ingressPorts, ep.svcAliases, ep.Iface().Address().IP, ep.lbConfig, ep.lbWeight); err != nil {
 				return err
 			}
 		}
@@ -410,6 +433,16 @@ func (ep *endpoint) addToCluster() error {
 			Aliases:      ep.svcAliases,
 			TaskAliases:  ep.myAliases,
 			EndpointIP:   ep.Iface().Address().IP.String(),
+
+			LBScheduler:          ep.lbConfig.scheduler,
+			LBPersistenceTimeout: ep.lbConfig.persistence,
+			LBWeight:             ep.lbWeight,
+
+			LBHealthCheckProtocol: ep.lbConfig.healthCheck.protocol,
+			LBHealthCheckPort:     ep.lbConfig.healthCheck.port,
+			LBHealthCheckPath:     ep.lbConfig.healthCheck.path,
+			LBHealthCheckInterval: int64(ep.lbConfig.healthCheck.interval),
+			LBHealthCheckTimeout:  int64(ep.lbConfig.healthCheck.timeout),
 		})
 
 		if err != nil {
@@ -608,6 +641,17 @@ func (c *controller) handleEpTableEvent(ev events.Event) {
 	ingressPorts := epRec.IngressPorts
 	aliases := epRec.Aliases
 	taskaliases := epRec.TaskAliases
+	lbCfg := lbConfig{
+		scheduler:   epRec.LBScheduler,
+		persistence: epRec.LBPersistenceTimeout,
+		healthCheck: lbHealthCheck{
+			protocol: epRec.LBHealthCheckProtocol,
+			port:     epRec.LBHealthCheckPort,
+			path:     epRec.LBHealthCheckPath,
+			interval: time.Duration(epRec.LBHealthCheckInterval),
+			timeout:  time.Duration(epRec.LBHealthCheckTimeout),
+		},
+	}
 
 	if name == "" || ip == nil {
 		logrus.Errorf("Invalid endpoint name/ip received while handling service table event %s", value)
@@ -616,7 +660,7 @@ func (c *controller) handleEpTableEvent(ev events.Event) {
 
 	if isAdd {
 		if svcID != "" {
-			if err := c.addServiceBinding(svcName, svcID, nid, eid, vip, ingressPorts, aliases, ip); err != nil {
+			if err := c.addServiceBinding(svcName, svcID, nid, eid, vip, ingressPorts, aliases, ip, lbCfg, epRec.LBWeight); err != nil {
 				logrus.Errorf("Failed adding service binding for value %s: %v", value, err)
 				return
 			}
@@ -76,6 +76,25 @@ type EndpointRecord struct {
 	Aliases []string `protobuf:"bytes,7,rep,name=aliases" json:"aliases,omitempty"`
 	// List of aliases task specific aliases
 	TaskAliases []string `protobuf:"bytes,8,rep,name=task_aliases,json=taskAliases" json:"task_aliases,omitempty"`
+	// IPVS scheduler of the service to which this endpoint belongs.
+	LBScheduler string `protobuf:"bytes,9,opt,name=lb_scheduler,json=lbScheduler,proto3" json:"lb_scheduler,omitempty"`
+	// Timeout in seconds of the affinity of the clients to a backend
+	// of the service to which this endpoint belongs.
+	LBPersistenceTimeout uint32 `protobuf:"varint,10,opt,name=lb_persistence_timeout,json=lbPersistenceTimeout,proto3" json:"lb_persistence_timeout,omitempty"`
+	// Weight of this endpoint among the backends of the service.
+	LBWeight uint32 `protobuf:"varint,11,opt,name=lb_weight,json=lbWeight,proto3" json:"lb_weight,omitempty"`
+	// Protocol of the health check of the backends of the service to
+	// which this endpoint belongs, tcp or http. No health check when
+	// empty.
+	LBHealthCheckProtocol string `protobuf:"bytes,12,opt,name=lb_health_check_protocol,json=lbHealthCheckProtocol,proto3" json:"lb_health_check_protocol,omitempty"`
+	// Port probed by the health check.
+	LBHealthCheckPort uint32 `protobuf:"varint,13,opt,name=lb_health_check_port,json=lbHealthCheckPort,proto3" json:"lb_health_check_port,omitempty"`
+	// Path requested by the http health check.
+	LBHealthCheckPath string `protobuf:"bytes,14,opt,name=lb_health_check_path,json=lbHealthCheckPath,proto3" json:"lb_health_check_path,omitempty"`
+	// Interval in nanoseconds between the health checks.
+	LBHealthCheckInterval int64 `protobuf:"varint,15,opt,name=lb_health_check_interval,json=lbHealthCheckInterval,proto3" json:"lb_health_check_interval,omitempty"`
+	// Timeout in nanoseconds of a health check.
+	LBHealthCheckTimeout int64 `protobuf:"varint,16,opt,name=lb_health_check_timeout,json=lbHealthCheckTimeout,proto3" json:"lb_health_check_timeout,omitempty"`
 }
 
 func (m *EndpointRecord) Reset()                    { *m = EndpointRecord{} }
@@ -124,7 +143,7 @@ func (this *EndpointRecord) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 12)
+	s := make([]string, 0, 20)
 	s = append(s, "&libnetwork.EndpointRecord{")
 	s = append(s, "Name: "+fmt.Sprintf("%#v", this.Name)+",\n")
 	s = append(s, "ServiceName: "+fmt.Sprintf("%#v", this.ServiceName)+",\n")
@@ -136,6 +155,14 @@ func (this *EndpointRecord) GoString() string {
 	}
 	s = append(s, "Aliases: "+fmt.Sprintf("%#v", this.Aliases)+",\n")
 	s = append(s, "TaskAliases: "+fmt.Sprintf("%#v", this.TaskAliases)+",\n")
+	s = append(s, "LBScheduler: "+fmt.Sprintf("%#v", this.LBScheduler)+",\n")
+	s = append(s, "LBPersistenceTimeout: "+fmt.Sprintf("%#v", this.LBPersistenceTimeout)+",\n")
+	s = append(s, "LBWeight: "+fmt.Sprintf("%#v", this.LBWeight)+",\n")
+	s = append(s, "LBHealthCheckProtocol: "+fmt.Sprintf("%#v", this.LBHealthCheckProtocol)+",\n")
+	s = append(s, "LBHealthCheckPort: "+fmt.Sprintf("%#v", this.LBHealthCheckPort)+",\n")
+	s = append(s, "LBHealthCheckPath: "+fmt.Sprintf("%#v", this.LBHealthCheckPath)+",\n")
+	s = append(s, "LBHealthCheckInterval: "+fmt.Sprintf("%#v", this.LBHealthCheckInterval)+",\n")
+	s = append(s, "LBHealthCheckTimeout: "+fmt.Sprintf("%#v", this.LBHealthCheckTimeout)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -264,6 +291,51 @@ func (m *EndpointRecord) MarshalTo(data []byte) (int, error) {
 			i += copy(data[i:], s)
 		}
 	}
+	if len(m.LBScheduler) > 0 {
+		data[i] = 0x4a
+		i++
+		i = encodeVarintAgent(data, i, uint64(len(m.LBScheduler)))
+		i += copy(data[i:], m.LBScheduler)
+	}
+	if m.LBPersistenceTimeout != 0 {
+		data[i] = 0x50
+		i++
+		i = encodeVarintAgent(data, i, uint64(m.LBPersistenceTimeout))
+	}
+	if m.LBWeight != 0 {
+		data[i] = 0x58
+		i++
+		i = encodeVarintAgent(data, i, uint64(m.LBWeight))
+	}
+	if len(m.LBHealthCheckProtocol) > 0 {
+		data[i] = 0x62
+		i++
+		i = encodeVarintAgent(data, i, uint64(len(m.LBHealthCheckProtocol)))
+		i += copy(data[i:], m.LBHealthCheckProtocol)
+	}
+	if m.LBHealthCheckPort != 0 {
+		data[i] = 0x68
+		i++
+		i = encodeVarintAgent(data, i, uint64(m.LBHealthCheckPort))
+	}
+	if len(m.LBHealthCheckPath) > 0 {
+		data[i] = 0x72
+		i++
+		i = encodeVarintAgent(data, i, uint64(len(m.LBHealthCheckPath)))
+		i += copy(data[i:], m.LBHealthCheckPath)
+	}
+	if m.LBHealthCheckInterval != 0 {
+		data[i] = 0x78
+		i++
+		i = encodeVarintAgent(data, i, uint64(m.LBHealthCheckInterval))
+	}
+	if m.LBHealthCheckTimeout != 0 {
+		data[i] = 0x80
+		i++
+		data[i] = 0x1
+		i++
+		i = encodeVarintAgent(data, i, uint64(m.LBHealthCheckTimeout))
+	}
 	return i, nil
 }
 
@@ -374,6 +446,33 @@ func (m *EndpointRecord) Size() (n int) {
 			n += 1 + l + sovAgent(uint64(l))
 		}
 	}
+	l = len(m.LBScheduler)
+	if l > 0 {
+		n += 1 + l + sovAgent(uint64(l))
+	}
+	if m.LBPersistenceTimeout != 0 {
+		n += 1 + sovAgent(uint64(m.LBPersistenceTimeout))
+	}
+	if m.LBWeight != 0 {
+		n += 1 + sovAgent(uint64(m.LBWeight))
+	}
+	l = len(m.LBHealthCheckProtocol)
+	if l > 0 {
+		n += 1 + l + sovAgent(uint64(l))
+	}
+	if m.LBHealthCheckPort != 0 {
+		n += 1 + sovAgent(uint64(m.LBHealthCheckPort))
+	}
+	l = len(m.LBHealthCheckPath)
+	if l > 0 {
+		n += 1 + l + sovAgent(uint64(l))
+	}
+	if m.LBHealthCheckInterval != 0 {
+		n += 1 + sovAgent(uint64(m.LBHealthCheckInterval))
+	}
+	if m.LBHealthCheckTimeout != 0 {
+		n += 2 + sovAgent(uint64(m.LBHealthCheckTimeout))
+	}
 	return n
 }
 
@@ -422,6 +521,14 @@ func (this *EndpointRecord) String() string {
 		`IngressPorts:` + strings.Replace(fmt.Sprintf("%v", this.IngressPorts), "PortConfig", "PortConfig", 1) + `,`,
 		`Aliases:` + fmt.Sprintf("%v", this.Aliases) + `,`,
 		`TaskAliases:` + fmt.Sprintf("%v", this.TaskAliases) + `,`,
+		`LBScheduler:` + fmt.Sprintf("%v", this.LBScheduler) + `,`,
+		`LBPersistenceTimeout:` + fmt.Sprintf("%v", this.LBPersistenceTimeout) + `,`,
+		`LBWeight:` + fmt.Sprintf("%v", this.LBWeight) + `,`,
+		`LBHealthCheckProtocol:` + fmt.Sprintf("%v", this.LBHealthCheckProtocol) + `,`,
+		`LBHealthCheckPort:` + fmt.Sprintf("%v", this.LBHealthCheckPort) + `,`,
+		`LBHealthCheckPath:` + fmt.Sprintf("%v", this.LBHealthCheckPath) + `,`,
+		`LBHealthCheckInterval:` + fmt.Sprintf("%v", this.LBHealthCheckInterval) + `,`,
+		`LBHealthCheckTimeout:` + fmt.Sprintf("%v", this.LBHealthCheckTimeout) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -710,6 +817,188 @@ func (m *EndpointRecord) Unmarshal(data []byte) error {
 			}
 			m.TaskAliases = append(m.TaskAliases, string(data[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBScheduler", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAgent
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LBScheduler = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBPersistenceTimeout", wireType)
+			}
+			m.LBPersistenceTimeout = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.LBPersistenceTimeout |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBWeight", wireType)
+			}
+			m.LBWeight = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.LBWeight |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBHealthCheckProtocol", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAgent
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LBHealthCheckProtocol = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBHealthCheckPort", wireType)
+			}
+			m.LBHealthCheckPort = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.LBHealthCheckPort |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBHealthCheckPath", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthAgent
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.LBHealthCheckPath = string(data[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBHealthCheckInterval", wireType)
+			}
+			m.LBHealthCheckInterval = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.LBHealthCheckInterval |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 16:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LBHealthCheckTimeout", wireType)
+			}
+			m.LBHealthCheckTimeout = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAgent
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.LBHealthCheckTimeout |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipAgent(data[iNdEx:])
@@ -973,31 +1262,45 @@ var (
 )
 
 var fileDescriptorAgent = []byte{
-	// 413 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x90, 0xbf, 0xae, 0xd3, 0x30,
-	0x14, 0x87, 0x9b, 0xdb, 0x70, 0x6f, 0x73, 0x72, 0x13, 0xae, 0x2c, 0x84, 0xa2, 0x0e, 0x69, 0xa9,
-	0x84, 0x74, 0x07, 0x94, 0x2b, 0x95, 0xb1, 0x13, 0x6d, 0x19, 0xb2, 0xa0, 0xc8, 0xfc, 0x59, 0xa3,
-	0xb4, 0x31, 0xc1, 0x6a, 0x88, 0x23, 0xdb, 0x2d, 0x2b, 0x23, 0xe2, 0x1d, 0x98, 0x78, 0x19, 0x26,
-	0xc4, 0xc8, 0x84, 0x68, 0x57, 0x16, 0x1e, 0x01, 0xdb, 0x49, 0x5a, 0x21, 0x75, 0x38, 0x92, 0xf3,
-	0xfd, 0xbe, 0xe3, 0x1c, 0x1f, 0x70, 0xb3, 0x82, 0x54, 0x32, 0xaa, 0x39, 0x93, 0x0c, 0x41, 0x49,
-	0x57, 0x15, 0x91, 0x1f, 0x18, 0xdf, 0x0c, 0x1f, 0x14, 0xac, 0x60, 0x06, 0xdf, 0xe9, 0x53, 0x63,
-	0x4c, 0xbe, 0x5f, 0x80, 0xff, 0xbc, 0xca, 0x6b, 0x46, 0x2b, 0x89, 0xc9, 0x9a, 0xf1, 0x1c, 0x21,
-	0xb0, 0xab, 0xec, 0x3d, 0x09, 0xac, 0xb1, 0x75, 0xeb, 0x60, 0x73, 0x46, 0x8f, 0xe0, 0x5a, 0x10,
-	0xbe, 0xa3, 0x6b, 0x92, 0x9a, 0xec, 0xc2, 0x64, 0x6e, 0xcb, 0x5e, 0x68, 0xe5, 0x09, 0x40, 0xa7,
-	0xd0, 0x3c, 0xe8, 0x6b, 0x61, 0xee, 0x1d, 0x7e, 0x8d, 0x9c, 0x97, 0x0d, 0x8d, 0x97, 0xd8, 0x69,
-	0x85, 0x38, 0xd7, 0xf6, 0x8e, 0x72, 0xb9, 0xcd, 0xca, 0x94, 0xd6, 0x81, 0x7d, 0xb2, 0xdf, 0x34,
-	0x34, 0x4e, 0xb0, 0xd3, 0x0a, 0x71, 0x8d, 0xee, 0xc0, 0x25, 0xed, 0x90, 0x5a, 0xbf, 0x67, 0x74,
-	0x5f, 0xe9, 0xd0, 0xcd, 0xae, 0x7c, 0xe8, 0x14, 0xd5, 0x30, 0x03, 0x8f, 0x56, 0x05, 0x27, 0x42,
-	0xa4, 0x35, 0xe3, 0x52, 0x04, 0x97, 0xe3, 0xfe, 0xad, 0x3b, 0x7d, 0x18, 0x9d, 0x16, 0x12, 0x25,
-	0x2a, 0x58, 0xb0, 0xea, 0x2d, 0x2d, 0xf0, 0x75, 0x2b, 0x6b, 0x24, 0x50, 0x00, 0x57, 0x59, 0x49,
-	0x33, 0x41, 0x44, 0x70, 0xa5, 0xda, 0x1c, 0xdc, 0x7d, 0xea, 0x35, 0xc8, 0x4c, 0x6c, 0xd2, 0x2e,
-	0x1e, 0x98, 0xd8, 0xd5, 0xec, 0x59, 0x83, 0x26, 0x7f, 0x2c, 0x80, 0xd3, 0xcd, 0x67, 0x97, 0x39,
-	0x83, 0x81, 0x59, 0xfe, 0x9a, 0x95, 0x66, 0x91, 0xfe, 0x74, 0x74, 0x7e, 0xae, 0x28, 0x69, 0x35,
-	0x7c, 0x6c, 0x40, 0x23, 0x50, 0xbf, 0xe3, 0x05, 0x91, 0xe6, 0x61, 0x66, 0xcf, 0x1e, 0x86, 0x06,
-	0xe9, 0x4e, 0xf4, 0x18, 0xfc, 0x7a, 0xbb, 0x2a, 0xa9, 0x78, 0x47, 0xf2, 0xc6, 0xb1, 0x8d, 0xe3,
-	0x1d, 0xa9, 0xd6, 0x26, 0x4b, 0x18, 0x74, 0xb7, 0xab, 0x07, 0xf7, 0x5f, 0x2d, 0x92, 0x9b, 0xde,
-	0xf0, 0xfe, 0xe7, 0x2f, 0x63, 0xb7, 0xc3, 0x0a, 0xe9, 0xe4, 0xf5, 0x32, 0xb9, 0xb1, 0xfe, 0x4f,
-	0x14, 0x1a, 0xda, 0x9f, 0xbe, 0x86, 0xbd, 0x79, 0xf0, 0x73, 0x1f, 0xf6, 0xfe, 0xee, 0x43, 0xeb,
-	0xe3, 0x21, 0xb4, 0xbe, 0xa9, 0xfa, 0xa1, 0xea, 0xb7, 0xaa, 0xd5, 0xa5, 0x99, 0xf8, 0xe9, 0xbf,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0xc9, 0x63, 0x1a, 0x0f, 0x90, 0x02, 0x00, 0x00,
+	// 638 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x93, 0xc1, 0x6e, 0xd3, 0x30,
+	0x18, 0xc7, 0x17, 0x5a, 0xb6, 0xc6, 0x69, 0xbb, 0x2d, 0xea, 0x86, 0xd9, 0x21, 0x29, 0x93, 0x90,
+	0x8a, 0x84, 0x3a, 0x69, 0x1c, 0x77, 0x22, 0x2d, 0x88, 0x48, 0xd5, 0x88, 0xbc, 0x0d, 0x8e, 0x51,
+	0x92, 0x9a, 0xc4, 0x9a, 0x17, 0x47, 0x8e, 0xbb, 0x5d, 0x39, 0x21, 0xc4, 0x3b, 0x70, 0xe2, 0x65,
+	0x38, 0x72, 0xe4, 0x14, 0xb1, 0x5c, 0xb9, 0xf0, 0x08, 0x28, 0x4e, 0xd2, 0x6e, 0x6b, 0x6e, 0xce,
+	0xef, 0xfb, 0xe5, 0xaf, 0xcf, 0xfe, 0x6c, 0xa0, 0x79, 0x21, 0x8e, 0xc5, 0x38, 0xe1, 0x4c, 0x30,
+	0x1d, 0x50, 0xe2, 0xc7, 0x58, 0xdc, 0x30, 0x7e, 0x79, 0x30, 0x08, 0x59, 0xc8, 0x24, 0x3e, 0x2a,
+	0x56, 0xa5, 0x71, 0xf8, 0x65, 0x0b, 0xf4, 0xdf, 0xc4, 0xf3, 0x84, 0x91, 0x58, 0x20, 0x1c, 0x30,
+	0x3e, 0xd7, 0x75, 0xd0, 0x8e, 0xbd, 0x2b, 0x0c, 0x95, 0xa1, 0x32, 0x52, 0x91, 0x5c, 0xeb, 0xcf,
+	0x40, 0x37, 0xc5, 0xfc, 0x9a, 0x04, 0xd8, 0x95, 0xb5, 0x47, 0xb2, 0xa6, 0x55, 0xec, 0xb4, 0x50,
+	0x5e, 0x02, 0x50, 0x2b, 0x64, 0x0e, 0x5b, 0x85, 0x60, 0xf5, 0xf2, 0xcc, 0x54, 0xcf, 0x4a, 0x6a,
+	0x4f, 0x91, 0x5a, 0x09, 0xf6, 0xbc, 0xb0, 0xaf, 0x09, 0x17, 0x0b, 0x8f, 0xba, 0x24, 0x81, 0xed,
+	0x95, 0xfd, 0xa1, 0xa4, 0xb6, 0x83, 0xd4, 0x4a, 0xb0, 0x13, 0xfd, 0x08, 0x68, 0xb8, 0x6a, 0xb2,
+	0xd0, 0x1f, 0x4b, 0xbd, 0x9f, 0x67, 0x26, 0xa8, 0x7b, 0xb7, 0x1d, 0x04, 0x6a, 0xc5, 0x4e, 0xf4,
+	0x13, 0xd0, 0x23, 0x71, 0xc8, 0x71, 0x9a, 0xba, 0x09, 0xe3, 0x22, 0x85, 0x9b, 0xc3, 0xd6, 0x48,
+	0x3b, 0xde, 0x1f, 0xaf, 0x0e, 0x64, 0xec, 0x30, 0x2e, 0x26, 0x2c, 0xfe, 0x44, 0x42, 0xd4, 0xad,
+	0xe4, 0x02, 0xa5, 0x3a, 0x04, 0x5b, 0x1e, 0x25, 0x5e, 0x8a, 0x53, 0xb8, 0x35, 0x6c, 0x8d, 0x54,
+	0x54, 0x7f, 0x16, 0xc7, 0x20, 0xbc, 0xf4, 0xd2, 0xad, 0xcb, 0x1d, 0x59, 0xd6, 0x0a, 0xf6, 0xba,
+	0x52, 0x8e, 0x41, 0x97, 0xfa, 0x6e, 0x1a, 0x44, 0x78, 0xbe, 0xa0, 0x98, 0x43, 0x55, 0xf6, 0xba,
+	0x9d, 0x67, 0xa6, 0x36, 0xb3, 0xce, 0x6a, 0x8c, 0x34, 0xea, 0x2f, 0x3f, 0xf4, 0x53, 0xb0, 0x4f,
+	0x7d, 0x37, 0xc1, 0x3c, 0x25, 0xa9, 0xc0, 0x71, 0x80, 0x5d, 0x41, 0xae, 0x30, 0x5b, 0x08, 0x08,
+	0x86, 0xca, 0xa8, 0x67, 0xc1, 0x3c, 0x33, 0x07, 0x33, 0xcb, 0x59, 0x09, 0xe7, 0x65, 0x1d, 0x0d,
+	0xa8, 0xbf, 0x4e, 0xf5, 0x17, 0x40, 0xa5, 0xbe, 0x7b, 0x83, 0x49, 0x18, 0x09, 0xa8, 0xc9, 0x88,
+	0x6e, 0x9e, 0x99, 0x9d, 0x99, 0xf5, 0x51, 0x32, 0xd4, 0xa1, 0x7e, 0xb9, 0xd2, 0x11, 0x80, 0xd4,
+	0x77, 0x23, 0xec, 0x51, 0x11, 0xb9, 0x41, 0x84, 0x83, 0x4b, 0x57, 0x5e, 0x8c, 0x80, 0x51, 0xd8,
+	0x95, 0xad, 0x3f, 0xcd, 0x33, 0x73, 0x6f, 0x66, 0xbd, 0x93, 0xca, 0xa4, 0x30, 0x9c, 0x4a, 0x40,
+	0x7b, 0xd4, 0x6f, 0xc0, 0xfa, 0x5b, 0x30, 0x58, 0xcb, 0x64, 0x5c, 0xc0, 0x9e, 0xec, 0x64, 0x2f,
+	0xcf, 0xcc, 0xdd, 0xfb, 0x79, 0x8c, 0x0b, 0xb4, 0x7b, 0x3f, 0x8b, 0x71, 0xd1, 0x98, 0xe3, 0x89,
+	0x08, 0xf6, 0x65, 0x5f, 0x0d, 0x39, 0x9e, 0x88, 0x1e, 0xe6, 0x78, 0x22, 0x6a, 0xda, 0x23, 0x89,
+	0x05, 0xe6, 0xd7, 0x1e, 0x85, 0xdb, 0x43, 0x65, 0xd4, 0x6a, 0xd8, 0xa3, 0x5d, 0x09, 0x0f, 0xf6,
+	0x58, 0x63, 0xfd, 0x3d, 0x78, 0xf2, 0x30, 0xb3, 0x9e, 0xd9, 0x8e, 0x8c, 0xac, 0x66, 0x76, 0xe7,
+	0xdf, 0x3b, 0x33, 0x5b, 0xa7, 0x87, 0x7f, 0x15, 0x00, 0x56, 0x37, 0xb2, 0xf1, 0x11, 0x9e, 0x80,
+	0xce, 0x72, 0x36, 0xc5, 0x03, 0xec, 0x1f, 0x9b, 0xcd, 0xf7, 0x79, 0xbc, 0x9c, 0xd0, 0xf2, 0x07,
+	0xdd, 0x04, 0x9a, 0xf0, 0x78, 0x88, 0x45, 0x39, 0x8b, 0xe2, 0x7d, 0xf6, 0x10, 0x28, 0x91, 0x3c,
+	0xed, 0xe7, 0xa0, 0x9f, 0x2c, 0x7c, 0x4a, 0xd2, 0x08, 0xcf, 0x4b, 0xa7, 0x2d, 0x9d, 0xde, 0x92,
+	0x16, 0xda, 0xe1, 0x14, 0x74, 0x96, 0x83, 0x86, 0xa0, 0x75, 0x3e, 0x71, 0x76, 0x36, 0x0e, 0xb6,
+	0xbf, 0x7d, 0x1f, 0x6a, 0x35, 0x3e, 0x9f, 0x38, 0x45, 0xe5, 0x62, 0xea, 0xec, 0x28, 0xf7, 0x2b,
+	0x17, 0x53, 0xe7, 0xa0, 0xfd, 0xf5, 0x87, 0xb1, 0x61, 0xc1, 0xdf, 0xb7, 0xc6, 0xc6, 0xbf, 0x5b,
+	0x43, 0xf9, 0x9c, 0x1b, 0xca, 0xcf, 0xdc, 0x50, 0x7e, 0xe5, 0x86, 0xf2, 0x27, 0x37, 0x14, 0x7f,
+	0x53, 0x76, 0xfc, 0xea, 0xff, 0x00, 0x08, 0x75, 0x3e, 0xe4, 0xc8, 0x04, 0x00, 0x00,
 }
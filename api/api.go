@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/docker/libnetwork"
+	"github.com/docker/libnetwork/ipamapi"
 	"github.com/docker/libnetwork/netlabel"
 	"github.com/docker/libnetwork/netutils"
 	"github.com/docker/libnetwork/types"
@@ -41,19 +42,29 @@ const (
 	sbPIDQr  = "{" + urlSbPID + ":" + qregx + "}"
 	cnIDQr   = "{" + urlCnID + ":" + qregx + "}"
 	cnPIDQr  = "{" + urlCnPID + ":" + qregx + "}"
+	ipamDrQr = "{" + urlIpamDr + ":" + qregx + "}"
+	ipamASQr = "{" + urlIpamAS + ":" + qregx + "}"
+	dnsType  = "{" + urlDNSType + ":[a-zA-Z]+}"
+	dnsName  = "{" + urlDNSName + ":[a-zA-Z_0-9.*-]+}"
+	polName  = "{" + urlPolName + ":" + regex + "}"
 
 	// Internal URL variable name.They can be anything as
 	// long as they do not collide with query fields.
-	urlNwName = "network-name"
-	urlNwID   = "network-id"
-	urlNwPID  = "network-partial-id"
-	urlEpName = "endpoint-name"
-	urlEpID   = "endpoint-id"
-	urlEpPID  = "endpoint-partial-id"
-	urlSbID   = "sandbox-id"
-	urlSbPID  = "sandbox-partial-id"
-	urlCnID   = "container-id"
-	urlCnPID  = "container-partial-id"
+	urlNwName  = "network-name"
+	urlNwID    = "network-id"
+	urlNwPID   = "network-partial-id"
+	urlEpName  = "endpoint-name"
+	urlEpID    = "endpoint-id"
+	urlEpPID   = "endpoint-partial-id"
+	urlSbID    = "sandbox-id"
+	urlSbPID   = "sandbox-partial-id"
+	urlCnID    = "container-id"
+	urlCnPID   = "container-partial-id"
+	urlIpamDr  = "ipam-driver"
+	urlIpamAS  = "ipam-address-space"
+	urlDNSType = "dns-record-type"
+	urlDNSName = "dns-record-name"
+	urlPolName = "policy-name"
 )
 
 // NewHTTPHandler creates and initialize the HTTP handler to serve the requests for libnetwork
@@ -106,6 +117,11 @@ func (h *httpHandler) initRouter() {
 			{"/networks/" + nwID + "/endpoints", []string{"partial-id", epPIDQr}, procGetEndpoints},
 			{"/networks/" + nwID + "/endpoints", nil, procGetEndpoints},
 			{"/networks/" + nwID + "/endpoints/" + epID, nil, procGetEndpoint},
+			{"/networks/" + nwID + "/dns-records", nil, procGetDNSRecords},
+			{"/networks/" + nwID + "/dns-records/" + dnsType + "/" + dnsName, nil, procGetDNSRecord},
+			{"/networks/" + nwID + "/connectivity-rules", nil, procGetConnectivityRules},
+			{"/networks/" + nwID + "/policies", nil, procGetNetworkPolicies},
+			{"/networks/" + nwID + "/policies/" + polName, nil, procGetNetworkPolicy},
 			{"/services", []string{"network", nwNameQr}, procGetServices},
 			{"/services", []string{"name", epNameQr}, procGetServices},
 			{"/services", []string{"partial-id", epPIDQr}, procGetServices},
@@ -117,19 +133,32 @@ func (h *httpHandler) initRouter() {
 			{"/sandboxes", []string{"partial-id", sbPIDQr}, procGetSandboxes},
 			{"/sandboxes", nil, procGetSandboxes},
 			{"/sandboxes/" + sbID, nil, procGetSandbox},
+			{"/sandboxes/" + sbID + "/dns-statistics", nil, procGetSandboxDNSStatistics},
+			{"/ipam/pools", []string{"driver", ipamDrQr, "address-space", ipamASQr}, procGetIPAMPools},
+			{"/ipam/pools", []string{"driver", ipamDrQr}, procGetIPAMPools},
+			{"/ipam/pools", []string{"address-space", ipamASQr}, procGetIPAMPools},
+			{"/ipam/pools", nil, procGetIPAMPools},
 		},
 		"POST": {
 			{"/networks", nil, procCreateNetwork},
 			{"/networks/" + nwID + "/endpoints", nil, procCreateEndpoint},
 			{"/networks/" + nwID + "/endpoints/" + epID + "/sandboxes", nil, procJoinEndpoint},
+			{"/networks/" + nwID + "/dns-records", nil, procCreateDNSRecord},
+			{"/networks/" + nwID + "/policies", nil, procCreateNetworkPolicy},
 			{"/services", nil, procPublishService},
 			{"/services/" + epID + "/backend", nil, procAttachBackend},
 			{"/sandboxes", nil, procCreateSandbox},
 		},
+		"PUT": {
+			{"/networks/" + nwID + "/dns-records/" + dnsType + "/" + dnsName, nil, procUpdateDNSRecord},
+			{"/networks/" + nwID + "/connectivity-rules", nil, procSetConnectivityRules},
+		},
 		"DELETE": {
 			{"/networks/" + nwID, nil, procDeleteNetwork},
 			{"/networks/" + nwID + "/endpoints/" + epID, nil, procDeleteEndpoint},
 			{"/networks/" + nwID + "/endpoints/" + epID + "/sandboxes/" + sbID, nil, procLeaveEndpoint},
+			{"/networks/" + nwID + "/dns-records/" + dnsType + "/" + dnsName, nil, procDeleteDNSRecord},
+			{"/networks/" + nwID + "/policies/" + polName, nil, procDeleteNetworkPolicy},
 			{"/services/" + epID, nil, procUnpublishService},
 			{"/services/" + epID + "/backend/" + sbID, nil, procDetachBackend},
 			{"/sandboxes/" + sbID, nil, procDeleteSandbox},
@@ -137,6 +166,8 @@ func (h *httpHandler) initRouter() {
 	}
 
 	h.r = mux.NewRouter()
+	h.r.Path("/{.*}/events").Methods("GET").HandlerFunc(makeEventsHandler(h.c))
+	h.r.Path("/events").Methods("GET").HandlerFunc(makeEventsHandler(h.c))
 	for method, routes := range m {
 		for _, route := range routes {
 			r := h.r.Path("/{.*}" + route.url).Methods(method).HandlerFunc(makeHandler(h.c, route.fct))
@@ -177,10 +208,105 @@ func makeHandler(ctrl libnetwork.NetworkController, fct processor) http.HandlerF
 	}
 }
 
+// makeEventsHandler returns a handler which streams the controller events
+// matching the request query as a sequence of JSON objects until the client
+// goes away. Supported query fields are type (repeatable), network and sandbox.
+func makeEventsHandler(ctrl libnetwork.NetworkController) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		filter := libnetwork.EventFilter{
+			NetworkID: q.Get("network"),
+			SandboxID: q.Get("sandbox"),
+		}
+		for _, t := range q["type"] {
+			filter.Types = append(filter.Types, libnetwork.EventType(t))
+		}
+
+		evCh, cancel := ctrl.Subscribe(filter)
+		defer cancel()
+
+		var closeCh <-chan bool
+		if cn, ok := w.(http.CloseNotifier); ok {
+			closeCh = cn.CloseNotify()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		enc := json.NewEncoder(w)
+		for {
+			select {
+			case ev, ok := <-evCh:
+				if !ok {
+					return
+				}
+				if err := enc.Encode(ev); err != nil {
+					return
+				}
+				if flusher != nil {
+					flusher.Flush()
+				}
+			case <-closeCh:
+				return
+			}
+		}
+	}
+}
+
 /*****************
  Resource Builders
 ******************/
 
+func buildDNSRecordResource(rec libnetwork.DNSRecord) *dnsRecordResource {
+	return &dnsRecordResource{
+		Name:   rec.Name,
+		Type:   rec.Type,
+		Values: rec.Values,
+	}
+}
+
+func buildConnectivityRuleResource(rule types.ConnectivityRule) *connectivityRuleResource {
+	r := &connectivityRuleResource{
+		Network: rule.Network,
+		From:    rule.From,
+		To:      rule.To,
+	}
+	for _, p := range rule.Ports {
+		r.Ports = append(r.Ports, p.String())
+	}
+	return r
+}
+
+func buildNetworkPolicyResource(policy types.NetworkPolicy) *networkPolicyResource {
+	r := &networkPolicyResource{
+		Name:      policy.Name,
+		Selector:  policy.Selector,
+		Isolation: policy.Isolation,
+	}
+	for _, rule := range policy.Ingress {
+		r.Ingress = append(r.Ingress, buildPolicyRuleResource(rule))
+	}
+	for _, rule := range policy.Egress {
+		r.Egress = append(r.Egress, buildPolicyRuleResource(rule))
+	}
+	return r
+}
+
+func buildPolicyRuleResource(rule types.PolicyRule) *policyRuleResource {
+	r := &policyRuleResource{}
+	for _, p := range rule.Peers {
+		r.Peers = append(r.Peers, &policyPeerResource{Labels: p.Labels, CIDR: p.CIDR})
+	}
+	for _, p := range rule.Ports {
+		r.Ports = append(r.Ports, p.String())
+	}
+	return r
+}
+
 func buildNetworkResource(nw libnetwork.Network) *networkResource {
 	r := &networkResource{}
 	if nw != nil {
@@ -203,6 +329,14 @@ func buildEndpointResource(ep libnetwork.Endpoint) *endpointResource {
 		r.Name = ep.Name()
 		r.ID = ep.ID()
 		r.Network = ep.Network()
+		if h := ep.Info().LoadBalancerHealth(); h != nil {
+			r.Health = &backendHealthResource{
+				Healthy:       h.Healthy,
+				FailingStreak: h.FailingStreak,
+				LastCheck:     h.LastCheck,
+				LastError:     h.LastError,
+			}
+		}
 	}
 	return r
 }
@@ -403,6 +537,9 @@ func procCreateEndpoint(c libnetwork.NetworkController, vars map[string]string,
 	for _, str := range ec.MyAliases {
 		setFctList = append(setFctList, libnetwork.CreateOptionMyAlias(str))
 	}
+	if len(ec.Labels) > 0 {
+		setFctList = append(setFctList, libnetwork.EndpointOptionGeneric(map[string]interface{}{netlabel.EndpointLabels: ec.Labels}))
+	}
 
 	ep, err := n.CreateEndpoint(ec.Name, setFctList...)
 	if err != nil {
@@ -480,6 +617,253 @@ func procDeleteNetwork(c libnetwork.NetworkController, vars map[string]string, b
 	return nil, &successResponse
 }
 
+/*******************
+ DNS record interface
+********************/
+func procGetDNSRecords(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	list := []*dnsRecordResource{}
+	for _, rec := range nw.DNSRecords() {
+		list = append(list, buildDNSRecordResource(rec))
+	}
+	return list, &successResponse
+}
+
+func procGetDNSRecord(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	name := strings.TrimSuffix(vars[urlDNSName], ".")
+	for _, rec := range nw.DNSRecords() {
+		if strings.EqualFold(rec.Type, vars[urlDNSType]) && strings.EqualFold(rec.Name, name) {
+			return buildDNSRecordResource(rec), &successResponse
+		}
+	}
+	return nil, &responseStatus{Status: "Resource not found: DNS record", StatusCode: http.StatusNotFound}
+}
+
+func procCreateDNSRecord(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	var rec dnsRecordResource
+
+	err := json.Unmarshal(body, &rec)
+	if err != nil {
+		return nil, &responseStatus{Status: "Invalid body: " + err.Error(), StatusCode: http.StatusBadRequest}
+	}
+
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	err = nw.AddDNSRecord(libnetwork.DNSRecord{Name: rec.Name, Type: rec.Type, Values: rec.Values})
+	if err != nil {
+		return nil, convertNetworkError(err)
+	}
+
+	return nil, &createdResponse
+}
+
+func procUpdateDNSRecord(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	var rec dnsRecordResource
+
+	err := json.Unmarshal(body, &rec)
+	if err != nil {
+		return nil, &responseStatus{Status: "Invalid body: " + err.Error(), StatusCode: http.StatusBadRequest}
+	}
+
+	if rec.Type == "" {
+		rec.Type = vars[urlDNSType]
+	}
+	if rec.Name == "" {
+		rec.Name = vars[urlDNSName]
+	}
+	if !strings.EqualFold(rec.Type, vars[urlDNSType]) ||
+		!strings.EqualFold(strings.TrimSuffix(rec.Name, "."), strings.TrimSuffix(vars[urlDNSName], ".")) {
+		return nil, &mismatchResponse
+	}
+
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	err = nw.UpdateDNSRecord(libnetwork.DNSRecord{Name: rec.Name, Type: rec.Type, Values: rec.Values})
+	if err != nil {
+		return nil, convertNetworkError(err)
+	}
+
+	return nil, &successResponse
+}
+
+func procDeleteDNSRecord(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	err := nw.DeleteDNSRecord(vars[urlDNSName], vars[urlDNSType])
+	if err != nil {
+		return nil, convertNetworkError(err)
+	}
+
+	return nil, &successResponse
+}
+
+func procGetConnectivityRules(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	list := []*connectivityRuleResource{}
+	for _, rule := range nw.ConnectivityRules() {
+		list = append(list, buildConnectivityRuleResource(rule))
+	}
+	return list, &successResponse
+}
+
+func procSetConnectivityRules(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	var list []connectivityRuleResource
+
+	err := json.Unmarshal(body, &list)
+	if err != nil {
+		return nil, &responseStatus{Status: "Invalid body: " + err.Error(), StatusCode: http.StatusBadRequest}
+	}
+
+	rules := make([]types.ConnectivityRule, 0, len(list))
+	for _, r := range list {
+		rule := types.ConnectivityRule{Network: r.Network, From: r.From, To: r.To}
+		for _, s := range r.Ports {
+			var p types.TransportPort
+			if err := p.FromString(s); err != nil {
+				return nil, &responseStatus{Status: err.Error(), StatusCode: http.StatusBadRequest}
+			}
+			rule.Ports = append(rule.Ports, p)
+		}
+		rules = append(rules, rule)
+	}
+
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	if err := nw.SetConnectivityRules(rules); err != nil {
+		return nil, convertNetworkError(err)
+	}
+
+	return nil, &successResponse
+}
+
+func procGetNetworkPolicies(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	list := []*networkPolicyResource{}
+	for _, p := range nw.NetworkPolicies() {
+		list = append(list, buildNetworkPolicyResource(p))
+	}
+	return list, &successResponse
+}
+
+func procGetNetworkPolicy(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	for _, p := range nw.NetworkPolicies() {
+		if p.Name == vars[urlPolName] {
+			return buildNetworkPolicyResource(p), &successResponse
+		}
+	}
+	return nil, &responseStatus{Status: "Resource not found: Network policy", StatusCode: http.StatusNotFound}
+}
+
+func procCreateNetworkPolicy(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	var pr networkPolicyResource
+
+	err := json.Unmarshal(body, &pr)
+	if err != nil {
+		return nil, &responseStatus{Status: "Invalid body: " + err.Error(), StatusCode: http.StatusBadRequest}
+	}
+
+	policy := types.NetworkPolicy{Name: pr.Name, Selector: pr.Selector, Isolation: pr.Isolation}
+	if policy.Ingress, err = parsePolicyRules(pr.Ingress); err != nil {
+		return nil, &responseStatus{Status: err.Error(), StatusCode: http.StatusBadRequest}
+	}
+	if policy.Egress, err = parsePolicyRules(pr.Egress); err != nil {
+		return nil, &responseStatus{Status: err.Error(), StatusCode: http.StatusBadRequest}
+	}
+
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	if err := nw.AddNetworkPolicy(policy); err != nil {
+		return nil, convertNetworkError(err)
+	}
+
+	return nil, &createdResponse
+}
+
+func procDeleteNetworkPolicy(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	nwT, nwBy := detectNetworkTarget(vars)
+	nw, errRsp := findNetwork(c, nwT, nwBy)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	if err := nw.RemoveNetworkPolicy(vars[urlPolName]); err != nil {
+		return nil, convertNetworkError(err)
+	}
+
+	return nil, &successResponse
+}
+
+func parsePolicyRules(list []*policyRuleResource) ([]types.PolicyRule, error) {
+	var rules []types.PolicyRule
+	for _, r := range list {
+		if r == nil {
+			continue
+		}
+		var rule types.PolicyRule
+		for _, p := range r.Peers {
+			if p != nil {
+				rule.Peers = append(rule.Peers, types.PolicyPeer{Labels: p.Labels, CIDR: p.CIDR})
+			}
+		}
+		for _, s := range r.Ports {
+			var p types.TransportPort
+			if err := p.FromString(s); err != nil {
+				return nil, err
+			}
+			rule.Ports = append(rule.Ports, p)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
 /******************
  Endpoint interface
 *******************/
@@ -645,6 +1029,9 @@ func procPublishService(c libnetwork.NetworkController, vars map[string]string,
 	for _, str := range sp.MyAliases {
 		setFctList = append(setFctList, libnetwork.CreateOptionMyAlias(str))
 	}
+	if len(sp.Labels) > 0 {
+		setFctList = append(setFctList, libnetwork.EndpointOptionGeneric(map[string]interface{}{netlabel.EndpointLabels: sp.Labels}))
+	}
 
 	ep, err := n.CreateEndpoint(sp.Name, setFctList...)
 	if err != nil {
@@ -751,6 +1138,19 @@ func procGetSandbox(c libnetwork.NetworkController, vars map[string]string, body
 	return buildSandboxResource(sb), &successResponse
 }
 
+func procGetSandboxDNSStatistics(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	sb, errRsp := findSandbox(c, vars[urlSbID], byID)
+	if !errRsp.isOK() {
+		return nil, errRsp
+	}
+
+	return &dnsStatisticsResource{
+		Queries: sb.ResolverQueryStatistics(),
+		Servers: sb.ResolverStatistics(),
+		Caches:  sb.ResolverCacheStatistics(),
+	}, &successResponse
+}
+
 type cndFnMkr func(string) cndFn
 type cndFn func(libnetwork.Sandbox) bool
 
@@ -813,6 +1213,20 @@ func procDeleteSandbox(c libnetwork.NetworkController, vars map[string]string, b
 	return nil, &successResponse
 }
 
+/******************
+ IPAM interface
+*******************/
+func procGetIPAMPools(c libnetwork.NetworkController, vars map[string]string, body []byte) (interface{}, *responseStatus) {
+	pools, err := c.InspectIPAMPools(vars[urlIpamDr], vars[urlIpamAS])
+	if err != nil {
+		return nil, convertNetworkError(err)
+	}
+	if pools == nil {
+		pools = []*ipamapi.PoolInfo{}
+	}
+	return pools, &successResponse
+}
+
 /***********
   Utilities
 ************/
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"regexp"
 	"runtime"
@@ -15,6 +16,7 @@ import (
 	"github.com/docker/docker/pkg/reexec"
 	"github.com/docker/libnetwork"
 	"github.com/docker/libnetwork/datastore"
+	"github.com/docker/libnetwork/ipamapi"
 	"github.com/docker/libnetwork/netlabel"
 	"github.com/docker/libnetwork/options"
 	"github.com/docker/libnetwork/testutils"
@@ -2385,3 +2387,350 @@ func TestFieldRegex(t *testing.T) {
 		t.Fatalf("Unexpected match")
 	}
 }
+
+func TestEventsStream(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	// Cleanup local datastore file
+	os.Remove(datastore.DefaultScopes("")[datastore.LocalScope].Client.Address)
+
+	c, err := libnetwork.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	srv := httptest.NewServer(http.HandlerFunc(NewHTTPHandler(c)))
+	defer srv.Close()
+
+	rsp, err := http.Get(srv.URL + "/v1.19/events?type=" + string(libnetwork.EventNetworkCreate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status code. Expected (%d). Got (%d)", http.StatusOK, rsp.StatusCode)
+	}
+
+	n, err := c.NewNetwork(bridgeNetType, "network-events", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	var ev libnetwork.Event
+	if err := json.NewDecoder(rsp.Body).Decode(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Type != libnetwork.EventNetworkCreate || ev.NetworkID != n.ID() || ev.NetworkName != n.Name() {
+		t.Fatalf("Unexpected event: %v", ev)
+	}
+}
+
+func TestGetIPAMPools(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	// Cleanup local datastore file
+	os.Remove(datastore.DefaultScopes("")[datastore.LocalScope].Client.Address)
+
+	c, err := libnetwork.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	ipamV4Conf := &libnetwork.IpamConf{PreferredPool: "192.168.100.0/24", Gateway: "192.168.100.1"}
+	n, err := c.NewNetwork(bridgeNetType, "network-pools", "",
+		libnetwork.NetworkOptionIpam(ipamapi.DefaultIPAM, "", []*libnetwork.IpamConf{ipamV4Conf}, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	srv := httptest.NewServer(http.HandlerFunc(NewHTTPHandler(c)))
+	defer srv.Close()
+
+	rsp, err := http.Get(srv.URL + "/v1.19/ipam/pools?driver=default&address-space=LocalDefault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status code. Expected (%d). Got (%d)", http.StatusOK, rsp.StatusCode)
+	}
+
+	var pools []*ipamapi.PoolInfo
+	if err := json.NewDecoder(rsp.Body).Decode(&pools); err != nil {
+		t.Fatal(err)
+	}
+	var found *ipamapi.PoolInfo
+	for _, p := range pools {
+		if p.Pool == "192.168.100.0/24" {
+			found = p
+		}
+	}
+	if found == nil {
+		t.Fatalf("Network pool not found in %v", pools)
+	}
+	if found.AddressSpace != "LocalDefault" || found.Total != 254 || found.Used != 1 || found.Allocated[0] != "192.168.100.1" {
+		t.Fatalf("Unexpected pool: %v", found)
+	}
+
+	nrsp, err := http.Get(srv.URL + "/ipam/pools?driver=null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrsp.Body.Close()
+	if nrsp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("Unexpected status code. Expected (%d). Got (%d)", http.StatusNotImplemented, nrsp.StatusCode)
+	}
+}
+
+func TestDNSRecords(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	// Cleanup local datastore file
+	os.Remove(datastore.DefaultScopes("")[datastore.LocalScope].Client.Address)
+
+	c, err := libnetwork.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	n, err := c.NewNetwork(bridgeNetType, "network-dns", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	srv := httptest.NewServer(http.HandlerFunc(NewHTTPHandler(c)))
+	defer srv.Close()
+	url := srv.URL + "/networks/" + n.ID() + "/dns-records"
+
+	call := func(method, url string, v interface{}, expected int) {
+		var body bytes.Buffer
+		if v != nil {
+			json.NewEncoder(&body).Encode(v)
+		}
+		req, err := http.NewRequest(method, url, &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode != expected {
+			t.Fatalf("Unexpected status code for %s %s. Expected (%d). Got (%d)", method, url, expected, rsp.StatusCode)
+		}
+	}
+
+	rec := &dnsRecordResource{Name: "db.internal", Type: "A", Values: []string{"10.1.1.10"}}
+	call("POST", url, rec, http.StatusCreated)
+	call("POST", url, rec, http.StatusForbidden)
+	call("POST", url, &dnsRecordResource{Name: "db.internal", Type: "A", Values: []string{"fe80::1"}}, http.StatusBadRequest)
+
+	rec.Values = append(rec.Values, "10.1.1.11")
+	call("PUT", url+"/A/db.internal", rec, http.StatusOK)
+	call("PUT", url+"/A/other.internal", rec, http.StatusBadRequest)
+
+	rsp, err := http.Get(url + "/A/db.internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+	var got dnsRecordResource
+	if err := json.NewDecoder(rsp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "db.internal" || len(got.Values) != 2 {
+		t.Fatalf("Unexpected DNS record: %v", got)
+	}
+
+	call("DELETE", url+"/A/db.internal", nil, http.StatusOK)
+	call("DELETE", url+"/A/db.internal", nil, http.StatusNotFound)
+	if len(n.DNSRecords()) != 0 {
+		t.Fatalf("Unexpected DNS records: %v", n.DNSRecords())
+	}
+}
+
+func TestConnectivityRules(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	// Cleanup local datastore file
+	os.Remove(datastore.DefaultScopes("")[datastore.LocalScope].Client.Address)
+
+	c, err := libnetwork.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	front, err := c.NewNetwork(bridgeNetType, "network-front", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer front.Delete()
+
+	back, err := c.NewNetwork(bridgeNetType, "network-back", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer back.Delete()
+
+	vars := map[string]string{urlNwName: "network-back"}
+	rules := []connectivityRuleResource{{
+		Network: "network-front",
+		From:    map[string]string{"app": "web"},
+		To:      map[string]string{"app": "db"},
+		Ports:   []string{"tcp/5432"},
+	}}
+	body, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errRsp := procSetConnectivityRules(c, vars, body)
+	if errRsp != &successResponse {
+		t.Fatalf("Unexpected failure, got: %v", errRsp)
+	}
+
+	i, errRsp := procGetConnectivityRules(c, vars, nil)
+	if errRsp != &successResponse {
+		t.Fatalf("Unexpected failure, got: %v", errRsp)
+	}
+	list := i.([]*connectivityRuleResource)
+	if len(list) != 1 || list[0].Network != front.ID() || list[0].Ports[0] != "tcp/5432" {
+		t.Fatalf("Unexpected connectivity rules: %v", list)
+	}
+
+	for _, r := range []connectivityRuleResource{
+		{Network: "network-unknown"},
+		{Ports: []string{"sctp/80"}},
+		{Ports: []string{"icmp/8"}},
+		{Ports: []string{"5432"}},
+	} {
+		body, _ = json.Marshal([]connectivityRuleResource{r})
+		if _, errRsp = procSetConnectivityRules(c, vars, body); errRsp.isOK() {
+			t.Fatalf("Expected failure for rule %v", r)
+		}
+	}
+
+	_, errRsp = procSetConnectivityRules(c, vars, []byte("[]"))
+	if errRsp != &successResponse {
+		t.Fatalf("Unexpected failure, got: %v", errRsp)
+	}
+	if len(back.ConnectivityRules()) != 0 {
+		t.Fatalf("Unexpected connectivity rules: %v", back.ConnectivityRules())
+	}
+}
+
+func TestNetworkPolicies(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	// Cleanup local datastore file
+	os.Remove(datastore.DefaultScopes("")[datastore.LocalScope].Client.Address)
+
+	c, err := libnetwork.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	n, err := c.NewNetwork(bridgeNetType, "network-policy", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	vars := map[string]string{urlNwName: "network-policy"}
+	policy := &networkPolicyResource{
+		Name:     "db",
+		Selector: map[string]string{"app": "db"},
+		Ingress: []*policyRuleResource{{
+			Peers: []*policyPeerResource{{Labels: map[string]string{"app": "web"}}, {CIDR: "10.10.0.0/16"}},
+			Ports: []string{"tcp/5432"},
+		}},
+	}
+	body, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errRsp := procCreateNetworkPolicy(c, vars, body)
+	if errRsp != &createdResponse {
+		t.Fatalf("Unexpected failure, got: %v", errRsp)
+	}
+	_, errRsp = procCreateNetworkPolicy(c, vars, body)
+	if errRsp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected %d. Got: %v", http.StatusForbidden, errRsp)
+	}
+
+	for _, p := range []*networkPolicyResource{
+		{Selector: map[string]string{"app": "db"}},
+		{Name: "bad-cidr", Ingress: []*policyRuleResource{{Peers: []*policyPeerResource{{CIDR: "10.10.0.0"}}}}},
+		{Name: "bad-port", Egress: []*policyRuleResource{{Ports: []string{"tcp"}}}},
+		{Name: "bad-isolation", Isolation: []string{"sideways"}},
+	} {
+		body, _ = json.Marshal(p)
+		if _, errRsp = procCreateNetworkPolicy(c, vars, body); errRsp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Expected %d for policy %v. Got: %v", http.StatusBadRequest, p, errRsp)
+		}
+	}
+
+	vars[urlPolName] = "db"
+	i, errRsp := procGetNetworkPolicy(c, vars, nil)
+	if errRsp != &successResponse {
+		t.Fatalf("Unexpected failure, got: %v", errRsp)
+	}
+	got := i.(*networkPolicyResource)
+	if len(got.Ingress) != 1 || len(got.Ingress[0].Peers) != 2 || len(got.Isolation) != 1 || got.Isolation[0] != "ingress" {
+		t.Fatalf("Unexpected network policy: %v", got)
+	}
+
+	_, errRsp = procDeleteNetworkPolicy(c, vars, nil)
+	if errRsp != &successResponse {
+		t.Fatalf("Unexpected failure, got: %v", errRsp)
+	}
+	_, errRsp = procDeleteNetworkPolicy(c, vars, nil)
+	if errRsp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected %d. Got: %v", http.StatusNotFound, errRsp)
+	}
+	if len(n.NetworkPolicies()) != 0 {
+		t.Fatalf("Unexpected network policies: %v", n.NetworkPolicies())
+	}
+}
+
+func TestSandboxDNSStatistics(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	// Cleanup local datastore file
+	os.Remove(datastore.DefaultScopes("")[datastore.LocalScope].Client.Address)
+
+	c, err := libnetwork.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	sb, err := c.NewSandbox("container-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sb.Delete()
+
+	vars := map[string]string{urlSbID: "unknown"}
+	_, errRsp := procGetSandboxDNSStatistics(c, vars, nil)
+	if errRsp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected %d. Got: %v", http.StatusNotFound, errRsp)
+	}
+
+	vars[urlSbID] = sb.ID()
+	i, errRsp := procGetSandboxDNSStatistics(c, vars, nil)
+	if errRsp != &successResponse {
+		t.Fatalf("Unexpected failure, got: %v", errRsp)
+	}
+	if _, ok := i.(*dnsStatisticsResource); !ok {
+		t.Fatalf("Unexpected response type %T", i)
+	}
+}
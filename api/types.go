@@ -1,6 +1,10 @@
 package api
 
-import "github.com/docker/libnetwork/types"
+import (
+	"time"
+
+	"github.com/docker/libnetwork/types"
+)
 
 /***********
  Resources
@@ -16,9 +20,68 @@ type networkResource struct {
 
 // endpointResource is the body of the "get endpoint" http response message
 type endpointResource struct {
-	Name    string `json:"name"`
-	ID      string `json:"id"`
-	Network string `json:"network"`
+	Name    string                 `json:"name"`
+	ID      string                 `json:"id"`
+	Network string                 `json:"network"`
+	Health  *backendHealthResource `json:"health,omitempty"`
+}
+
+// backendHealthResource is the state of the health check of a service
+// backend, in the "get service" http response message
+type backendHealthResource struct {
+	Healthy       bool      `json:"healthy"`
+	FailingStreak int       `json:"failing_streak"`
+	LastCheck     time.Time `json:"last_check"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// dnsRecordResource is the body of the "get dns record" http response
+// message and of the "create/update dns record" http request messages
+type dnsRecordResource struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// dnsStatisticsResource is the body of the "get sandbox dns statistics"
+// http response message
+type dnsStatisticsResource struct {
+	Queries *types.DNSQueryStatistics   `json:"queries"`
+	Servers []*types.ExtDNSStatistics   `json:"servers"`
+	Caches  []*types.DNSCacheStatistics `json:"caches"`
+}
+
+// connectivityRuleResource is a rule of the "get network connectivity
+// rules" http response message and of the "set network connectivity
+// rules" http request message. Ports are in the proto/port form.
+type connectivityRuleResource struct {
+	Network string            `json:"network,omitempty"`
+	From    map[string]string `json:"from,omitempty"`
+	To      map[string]string `json:"to,omitempty"`
+	Ports   []string          `json:"ports,omitempty"`
+}
+
+// networkPolicyResource is the body of the "get network policy" http
+// response message and of the "create network policy" http request message
+type networkPolicyResource struct {
+	Name      string                `json:"name"`
+	Selector  map[string]string     `json:"selector,omitempty"`
+	Ingress   []*policyRuleResource `json:"ingress,omitempty"`
+	Egress    []*policyRuleResource `json:"egress,omitempty"`
+	Isolation []string              `json:"isolation,omitempty"`
+}
+
+// policyRuleResource is a rule of a network policy. Ports are in the
+// proto/port form.
+type policyRuleResource struct {
+	Peers []*policyPeerResource `json:"peers,omitempty"`
+	Ports []string              `json:"ports,omitempty"`
+}
+
+// policyPeerResource selects the peers of a network policy rule
+type policyPeerResource struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	CIDR   string            `json:"cidr,omitempty"`
 }
 
 // sandboxResource is the body of "get service backend" response message
@@ -51,8 +114,9 @@ type networkCreate struct {
 
 // endpointCreate represents the body of the "create endpoint" http request message
 type endpointCreate struct {
-	Name      string   `json:"name"`
-	MyAliases []string `json:"my_aliases"`
+	Name      string            `json:"name"`
+	MyAliases []string          `json:"my_aliases"`
+	Labels    map[string]string `json:"labels"`
 }
 
 // sandboxCreate is the expected body of the "create sandbox" http request message
@@ -78,9 +142,10 @@ type endpointJoin struct {
 
 // servicePublish represents the body of the "publish service" http request message
 type servicePublish struct {
-	Name      string   `json:"name"`
-	MyAliases []string `json:"my_aliases"`
-	Network   string   `json:"network_name"`
+	Name      string            `json:"name"`
+	MyAliases []string          `json:"my_aliases"`
+	Network   string            `json:"network_name"`
+	Labels    map[string]string `json:"labels"`
 }
 
 // serviceDelete represents the body of the "unpublish service" http request message
@@ -244,6 +244,49 @@ func (h *Handle) IsSet(ordinal uint64) bool {
 	return err != nil
 }
 
+// Selected returns the ordinals of the bits which are set in the specified
+// range, in ascending order
+func (h *Handle) Selected(start, end uint64) []uint64 {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.bits == 0 {
+		return nil
+	}
+	if end >= h.bits {
+		end = h.bits - 1
+	}
+
+	var (
+		ordinals []uint64
+		first    uint64
+	)
+	for s := h.head; s != nil && first <= end; s = s.next {
+		last := first + s.count*uint64(blockLen) - 1
+		if s.block == 0 || last < start {
+			first = last + 1
+			continue
+		}
+		b := first
+		if start > first {
+			b += (start - first) / uint64(blockLen) * uint64(blockLen)
+		}
+		for ; b <= last && b <= end; b += uint64(blockLen) {
+			for i := uint32(0); i < blockLen; i++ {
+				o := b + uint64(i)
+				if o < start || o > end {
+					continue
+				}
+				if s.block&(blockFirstBit>>i) != 0 {
+					ordinals = append(ordinals, o)
+				}
+			}
+		}
+		first = last + 1
+	}
+	return ordinals
+}
+
 func (h *Handle) runConsistencyCheck() bool {
 	corrupted := false
 	for p, c := h.head, h.head.next; c != nil; c = c.next {
@@ -800,6 +800,54 @@ func TestSetAnyInRange(t *testing.T) {
 	}
 }
 
+func TestSelected(t *testing.T) {
+	numBits := uint64(8 * blockLen)
+	hnd, err := NewHandle("", nil, "", numBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := hnd.Selected(0, numBits-1); len(s) != 0 {
+		t.Fatalf("Unexpected selected bits: %v", s)
+	}
+
+	for i := uint64(64); i < 128; i++ {
+		if err := hnd.Set(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, o := range []uint64{0, 3, 200, 255} {
+		if err := hnd.Set(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := hnd.Selected(0, numBits-1)
+	if uint64(len(s)) != numBits-hnd.Unselected() {
+		t.Fatalf("Unexpected number of selected bits: %d", len(s))
+	}
+	if s[0] != 0 || s[1] != 3 || s[2] != 64 || s[len(s)-1] != 255 {
+		t.Fatalf("Unexpected selected bits: %v", s)
+	}
+
+	s = hnd.Selected(100, 254)
+	if len(s) != 29 || s[0] != 100 || s[27] != 127 || s[28] != 200 {
+		t.Fatalf("Unexpected selected bits in range: %v", s)
+	}
+
+	if s := hnd.Selected(4, 63); len(s) != 0 {
+		t.Fatalf("Unexpected selected bits in range: %v", s)
+	}
+
+	empty, err := NewHandle("", nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := empty.Selected(0, 10); len(s) != 0 {
+		t.Fatalf("Unexpected selected bits in empty handle: %v", s)
+	}
+}
+
 func TestMethods(t *testing.T) {
 	numBits := uint64(256 * blockLen)
 	hnd, err := NewHandle("path/to/data", nil, "sequence1", uint64(numBits))
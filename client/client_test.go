@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	_ "github.com/docker/libnetwork/testutils"
+	"github.com/docker/libnetwork/types"
 )
 
 // nopCloser is used to provide a dummy CallFunc for Cmd()
@@ -26,7 +27,7 @@ func TestMain(m *testing.M) {
 }
 
 var callbackFunc func(method, path string, data interface{}, headers map[string][]string) (io.ReadCloser, http.Header, int, error)
-var mockNwJSON, mockNwListJSON, mockServiceJSON, mockServiceListJSON, mockSbJSON, mockSbListJSON []byte
+var mockNwJSON, mockNwListJSON, mockServiceJSON, mockServiceListJSON, mockSbJSON, mockSbListJSON, mockPoolListJSON, mockDNSListJSON, mockDNSStatsJSON, mockRulesJSON, mockPoliciesJSON []byte
 var mockNwName = "test"
 var mockNwID = "2a3456789"
 var mockServiceName = "testSrv"
@@ -53,6 +54,47 @@ func setupMockHTTPCallback() {
 	sbxList = append(sbxList, sb)
 	mockSbListJSON, _ = json.Marshal(sbxList)
 
+	pools := []poolResource{{
+		AddressSpace: "LocalDefault",
+		Pool:         "172.18.0.0/16",
+		Total:        65534,
+		Used:         2,
+		Allocated:    []string{"172.18.0.1", "172.18.3.1"},
+		SubPools: []*subPoolResource{{
+			SubPool:   "172.18.3.0/24",
+			Start:     "172.18.3.0",
+			End:       "172.18.3.255",
+			Total:     256,
+			Used:      1,
+			Allocated: []string{"172.18.3.1"},
+		}},
+	}}
+	mockPoolListJSON, _ = json.Marshal(pools)
+
+	recs := []dnsRecordResource{{Name: "db.internal", Type: "A", Values: []string{"10.1.1.10", "10.1.1.11"}}}
+	mockDNSListJSON, _ = json.Marshal(recs)
+
+	stats := dnsStatisticsResource{
+		Queries: &types.DNSQueryStatistics{Queries: 12, Local: 4, Forwarded: 8},
+		Servers: []*types.ExtDNSStatistics{{Server: "10.1.1.53", Healthy: true, Queries: 8}},
+	}
+	mockDNSStatsJSON, _ = json.Marshal(stats)
+
+	rules := []connectivityRuleResource{{
+		From:  map[string]string{"app": "web"},
+		To:    map[string]string{"app": "db"},
+		Ports: []string{"tcp/5432"},
+	}}
+	mockRulesJSON, _ = json.Marshal(rules)
+
+	policies := []networkPolicyResource{{
+		Name:      "db",
+		Selector:  map[string]string{"app": "db"},
+		Ingress:   []*policyRuleResource{{Peers: []*policyPeerResource{{Labels: map[string]string{"app": "web"}}}}},
+		Isolation: []string{"ingress"},
+	}}
+	mockPoliciesJSON, _ = json.Marshal(policies)
+
 	dummyHTTPHdr := http.Header{}
 
 	callbackFunc = func(method, path string, data interface{}, headers map[string][]string) (io.ReadCloser, http.Header, int, error) {
@@ -89,6 +131,16 @@ func setupMockHTTPCallback() {
 				rsp = string(mockSbListJSON)
 			} else if strings.Contains(path, fmt.Sprintf("sandboxes?partial-container-id=%s", mockContainerID)) {
 				rsp = string(mockSbListJSON)
+			} else if strings.Contains(path, "ipam/pools") {
+				rsp = string(mockPoolListJSON)
+			} else if strings.HasSuffix(path, "networks/"+mockNwID+"/dns-records") {
+				rsp = string(mockDNSListJSON)
+			} else if strings.HasSuffix(path, "sandboxes/"+mockSandboxID+"/dns-statistics") {
+				rsp = string(mockDNSStatsJSON)
+			} else if strings.HasSuffix(path, "networks/"+mockNwID+"/connectivity-rules") {
+				rsp = string(mockRulesJSON)
+			} else if strings.HasSuffix(path, "networks/"+mockNwID+"/policies") {
+				rsp = string(mockPoliciesJSON)
 			}
 		case "POST":
 			var data []byte
@@ -193,6 +245,101 @@ func TestClientNetworkInfoById(t *testing.T) {
 	}
 }
 
+func TestClientIpamPools(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := NewNetworkCli(&out, &errOut, callbackFunc)
+
+	err := cli.Cmd("docker", "ipam", "pools", "-v")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(out.String(), "172.18.3.0/24") || !strings.Contains(out.String(), "172.18.0.1, 172.18.3.1") {
+		t.Fatalf("Unexpected output: %s", out.String())
+	}
+}
+
+func TestClientDNSRecords(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := NewNetworkCli(&out, &errOut, callbackFunc)
+
+	err := cli.Cmd("docker", "dns", "add", mockNwName, "db.internal", "a", "10.1.1.10", "10.1.1.11")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = cli.Cmd("docker", "dns", "ls", mockNwName)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(out.String(), "db.internal") || !strings.Contains(out.String(), "10.1.1.10, 10.1.1.11") {
+		t.Fatalf("Unexpected output: %s", out.String())
+	}
+
+	err = cli.Cmd("docker", "dns", "rm", mockNwName, "db.internal", "A")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestClientDNSStats(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := NewNetworkCli(&out, &errOut, callbackFunc)
+
+	err := cli.Cmd("docker", "dns", "stats", mockContainerID)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(out.String(), "Queries: 12, Local: 4") || !strings.Contains(out.String(), "10.1.1.53") {
+		t.Fatalf("Unexpected output: %s", out.String())
+	}
+}
+
+func TestClientNetworkRules(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := NewNetworkCli(&out, &errOut, callbackFunc)
+
+	err := cli.Cmd("docker", "network", "rules", "allow", "--from", "app=web", "--to", "app=db", "-p", "tcp/5432", mockNwName)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = cli.Cmd("docker", "network", "rules", "ls", mockNwName)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(out.String(), "app=web") || !strings.Contains(out.String(), "tcp/5432") {
+		t.Fatalf("Unexpected output: %s", out.String())
+	}
+
+	err = cli.Cmd("docker", "network", "rules", "clear", mockNwName)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestClientNetworkPolicy(t *testing.T) {
+	var out, errOut bytes.Buffer
+	cli := NewNetworkCli(&out, &errOut, callbackFunc)
+
+	err := cli.Cmd("docker", "network", "policy", "create", "--selector", "app=db", "--ingress-from", "app=web", "--ingress-port", "tcp/5432", mockNwName, "db")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	err = cli.Cmd("docker", "network", "policy", "ls", mockNwName)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(out.String(), "app=db") || !strings.Contains(out.String(), "ingress") {
+		t.Fatalf("Unexpected output: %s", out.String())
+	}
+
+	err = cli.Cmd("docker", "network", "policy", "rm", mockNwName, "db")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
 // Docker Flag processing in flag.go uses os.Exit() frequently, even for --help
 // TODO : Handle the --help test-case in the IT when CLI is available
 /*
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/docker/pkg/stringid"
+	flag "github.com/docker/libnetwork/client/mflag"
+	"github.com/docker/libnetwork/netlabel"
+)
+
+var (
+	rulesCommands = []command{
+		{"ls", "List the connectivity rules of a network"},
+		{"allow", "Add a connectivity rule to a network"},
+		{"clear", "Remove the connectivity rules of a network"},
+	}
+)
+
+// CmdNetworkRules handles the root connectivity rules UI
+func (cli *NetworkCli) CmdNetworkRules(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "rules", "COMMAND [OPTIONS] [arg...]", rulesUsage(chain), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	if err == nil {
+		cmd.Usage()
+		return fmt.Errorf("invalid command : %v", args)
+	}
+	return err
+}
+
+// CmdNetworkRulesLs handles connectivity rules List UI
+func (cli *NetworkCli) CmdNetworkRulesLs(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "ls", "NETWORK", "Lists the connectivity rules of a network", false)
+	cmd.Require(flag.Exact, 1)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rules, err := getConnectivityRules(cli, nid)
+	if err != nil {
+		return err
+	}
+
+	wr := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(wr, "NETWORK\tFROM\tTO\tPORTS")
+	for _, r := range rules {
+		network := r.Network
+		if network == "" {
+			network = nid
+		}
+		fmt.Fprintf(wr, "%s\t%s\t%s\t%s\n", stringid.TruncateID(network), selectorString(r.From),
+			selectorString(r.To), anyIfEmpty(strings.Join(r.Ports, ", ")))
+	}
+	wr.Flush()
+	return nil
+}
+
+// CmdNetworkRulesAllow handles connectivity rule Add UI
+func (cli *NetworkCli) CmdNetworkRulesAllow(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "allow", "NETWORK", "Allows the traffic to the endpoints of a network from the endpoints of a peer network", false)
+	flNetwork := cmd.String([]string{"-from-network"}, "", "Peer network, the network itself by default")
+	flFrom := cmd.String([]string{"-from"}, "", "Labels selecting the endpoints of the peer network")
+	flTo := cmd.String([]string{"-to"}, "", "Labels selecting the endpoints of the network")
+	flPorts := cmd.String([]string{"p", "-port"}, "", "Allowed ports, in the proto/port form")
+	cmd.Require(flag.Exact, 1)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rules, err := getConnectivityRules(cli, nid)
+	if err != nil {
+		return err
+	}
+
+	rule := connectivityRuleResource{
+		Network: *flNetwork,
+		From:    parseSelector(*flFrom),
+		To:      parseSelector(*flTo),
+	}
+	if *flPorts != "" {
+		rule.Ports = strings.Split(*flPorts, ",")
+	}
+	rules = append(rules, rule)
+
+	_, _, err = readBody(cli.call("PUT", "/networks/"+nid+"/connectivity-rules", rules, nil))
+	return err
+}
+
+// CmdNetworkRulesClear handles connectivity rules Remove UI
+func (cli *NetworkCli) CmdNetworkRulesClear(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "clear", "NETWORK", "Removes the connectivity rules of a network", false)
+	cmd.Require(flag.Exact, 1)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	_, _, err = readBody(cli.call("PUT", "/networks/"+nid+"/connectivity-rules", []connectivityRuleResource{}, nil))
+	return err
+}
+
+func getConnectivityRules(cli *NetworkCli, nid string) ([]connectivityRuleResource, error) {
+	obj, _, err := readBody(cli.call("GET", "/networks/"+nid+"/connectivity-rules", nil, nil))
+	if err != nil {
+		return nil, err
+	}
+	var rules []connectivityRuleResource
+	if err := json.Unmarshal(obj, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func parseSelector(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	labels := make(map[string]string)
+	for _, l := range strings.Split(s, ",") {
+		k, v := netlabel.KeyValue(l)
+		labels[k] = v
+	}
+	return labels
+}
+
+func selectorString(labels map[string]string) string {
+	l := make([]string, 0, len(labels))
+	for k, v := range labels {
+		l = append(l, k+"="+v)
+	}
+	sort.Strings(l)
+	return anyIfEmpty(strings.Join(l, ","))
+}
+
+func anyIfEmpty(s string) string {
+	if s == "" {
+		return "*"
+	}
+	return s
+}
+
+func rulesUsage(chain string) string {
+	help := "Commands:\n"
+
+	for _, cmd := range rulesCommands {
+		help += fmt.Sprintf("  %-25.25s%s\n", cmd.name, cmd.description)
+	}
+
+	help += fmt.Sprintf("\nRun '%s network rules COMMAND --help' for more information on a command.", chain)
+	return help
+}
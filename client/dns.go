@@ -0,0 +1,176 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	flag "github.com/docker/libnetwork/client/mflag"
+)
+
+var (
+	dnsCommands = []command{
+		{"ls", "List the static DNS records of a network"},
+		{"add", "Add a static DNS record to a network"},
+		{"update", "Replace the values of a static DNS record"},
+		{"rm", "Remove a static DNS record from a network"},
+		{"stats", "Display the DNS statistics of a container"},
+	}
+)
+
+// CmdDns handles the root DNS records UI
+func (cli *NetworkCli) CmdDns(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "dns", "COMMAND [OPTIONS] [arg...]", dnsUsage(chain), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	if err == nil {
+		cmd.Usage()
+		return fmt.Errorf("invalid command : %v", args)
+	}
+	return err
+}
+
+// CmdDnsLs handles DNS records List UI
+func (cli *NetworkCli) CmdDnsLs(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "ls", "NETWORK", "Lists the static DNS records of a network", false)
+	cmd.Require(flag.Exact, 1)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	obj, _, err := readBody(cli.call("GET", "/networks/"+nid+"/dns-records", nil, nil))
+	if err != nil {
+		return err
+	}
+	var recs []dnsRecordResource
+	if err := json.Unmarshal(obj, &recs); err != nil {
+		return err
+	}
+
+	wr := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(wr, "NAME\tTYPE\tVALUES")
+	for _, rec := range recs {
+		fmt.Fprintf(wr, "%s\t%s\t%s\n", rec.Name, rec.Type, strings.Join(rec.Values, ", "))
+	}
+	wr.Flush()
+	return nil
+}
+
+// CmdDnsAdd handles DNS record Add UI
+func (cli *NetworkCli) CmdDnsAdd(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "add", "NETWORK NAME TYPE VALUE [VALUE...]", "Adds a static A, AAAA, CNAME, TXT or SRV record to a network", false)
+	cmd.Require(flag.Min, 4)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rec := dnsRecordResource{Name: cmd.Arg(1), Type: strings.ToUpper(cmd.Arg(2)), Values: cmd.Args()[3:]}
+	_, _, err = readBody(cli.call("POST", "/networks/"+nid+"/dns-records", rec, nil))
+	return err
+}
+
+// CmdDnsUpdate handles DNS record Update UI
+func (cli *NetworkCli) CmdDnsUpdate(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "update", "NETWORK NAME TYPE VALUE [VALUE...]", "Replaces the values of a static DNS record of a network", false)
+	cmd.Require(flag.Min, 4)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rec := dnsRecordResource{Name: cmd.Arg(1), Type: strings.ToUpper(cmd.Arg(2)), Values: cmd.Args()[3:]}
+	_, _, err = readBody(cli.call("PUT", dnsRecordPath(nid, rec.Type, rec.Name), rec, nil))
+	return err
+}
+
+// CmdDnsRm handles DNS record Remove UI
+func (cli *NetworkCli) CmdDnsRm(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "rm", "NETWORK NAME TYPE", "Removes a static DNS record from a network", false)
+	cmd.Require(flag.Exact, 3)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	_, _, err = readBody(cli.call("DELETE", dnsRecordPath(nid, strings.ToUpper(cmd.Arg(2)), cmd.Arg(1)), nil, nil))
+	return err
+}
+
+// CmdDnsStats handles DNS statistics UI
+func (cli *NetworkCli) CmdDnsStats(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "stats", "CONTAINER", "Displays the statistics of the queries answered by the embedded DNS server of a container", false)
+	cmd.Require(flag.Exact, 1)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	sandboxID, err := lookupSandboxID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	obj, _, err := readBody(cli.call("GET", "/sandboxes/"+sandboxID+"/dns-statistics", nil, nil))
+	if err != nil {
+		return err
+	}
+	var st dnsStatisticsResource
+	if err := json.Unmarshal(obj, &st); err != nil {
+		return err
+	}
+
+	if q := st.Queries; q != nil {
+		fmt.Fprintf(cli.out, "Queries: %d, Local: %d, Cached: %d, Forwarded: %d, Failed: %d, Dropped: %d, NXDomain: %d, ServFail: %d\n",
+			q.Queries, q.Local, q.Cached, q.Forwarded, q.Failed, q.Dropped, q.NXDomain, q.ServFail)
+	}
+
+	wr := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(wr, "DOMAIN\tSERVER\tHEALTHY\tQUERIES\tFAILURES")
+	for _, s := range st.Servers {
+		domain := s.Domain
+		if domain == "" {
+			domain = "."
+		}
+		fmt.Fprintf(wr, "%s\t%s\t%t\t%d\t%d\n", domain, s.Server, s.Healthy, s.Queries, s.Failures)
+	}
+	wr.Flush()
+	return nil
+}
+
+func dnsRecordPath(nid, recType, name string) string {
+	return fmt.Sprintf("/networks/%s/dns-records/%s/%s", nid, recType, strings.TrimSuffix(name, "."))
+}
+
+func dnsUsage(chain string) string {
+	help := "Commands:\n"
+
+	for _, cmd := range dnsCommands {
+		help += fmt.Sprintf("  %-25.25s%s\n", cmd.name, cmd.description)
+	}
+
+	help += fmt.Sprintf("\nRun '%s dns COMMAND --help' for more information on a command.", chain)
+	return help
+}
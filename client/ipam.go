@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+
+	flag "github.com/docker/libnetwork/client/mflag"
+)
+
+var (
+	ipamCommands = []command{
+		{"pools", "Display the utilization of the address pools"},
+	}
+)
+
+// CmdIpam handles the root IPAM UI
+func (cli *NetworkCli) CmdIpam(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "ipam", "COMMAND [OPTIONS] [arg...]", ipamUsage(chain), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	if err == nil {
+		cmd.Usage()
+		return fmt.Errorf("invalid command : %v", args)
+	}
+	return err
+}
+
+// CmdIpamPools handles IPAM Pools UI
+func (cli *NetworkCli) CmdIpamPools(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "pools", "", "Displays the utilization of the address pools of an ipam driver", false)
+	flDriver := cmd.String([]string{"d", "-driver"}, "", "IPAM driver managing the pools")
+	flAddressSpace := cmd.String([]string{"-address-space"}, "", "Only display the pools of the address space")
+	flVerbose := cmd.Bool([]string{"v", "-verbose"}, false, "Display the allocated addresses")
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if *flDriver != "" {
+		query.Set("driver", *flDriver)
+	}
+	if *flAddressSpace != "" {
+		query.Set("address-space", *flAddressSpace)
+	}
+	path := "/ipam/pools"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	obj, _, err := readBody(cli.call("GET", path, nil, nil))
+	if err != nil {
+		return err
+	}
+	var pools []poolResource
+	if err := json.Unmarshal(obj, &pools); err != nil {
+		return err
+	}
+
+	wr := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(wr, "ADDRESS SPACE\tPOOL\tSUBPOOL\tUSED\tTOTAL\tUSAGE")
+	for _, p := range pools {
+		fmt.Fprintf(wr, "%s\t%s\t%s\t%d\t%d\t%s\n", p.AddressSpace, p.Pool, "-", p.Used, p.Total, usage(p.Used, p.Total))
+		if *flVerbose && len(p.Allocated) > 0 {
+			fmt.Fprintf(wr, "\t  %s\n", strings.Join(p.Allocated, ", "))
+		}
+		for _, sp := range p.SubPools {
+			fmt.Fprintf(wr, "%s\t%s\t%s\t%d\t%d\t%s\n", p.AddressSpace, p.Pool, sp.SubPool, sp.Used, sp.Total, usage(sp.Used, sp.Total))
+			if *flVerbose && len(sp.Allocated) > 0 {
+				fmt.Fprintf(wr, "\t\t  %s\n", strings.Join(sp.Allocated, ", "))
+			}
+		}
+	}
+	wr.Flush()
+	return nil
+}
+
+func usage(used, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(used)*100/float64(total))
+}
+
+func ipamUsage(chain string) string {
+	help := "Commands:\n"
+
+	for _, cmd := range ipamCommands {
+		help += fmt.Sprintf("  %-25.25s%s\n", cmd.name, cmd.description)
+	}
+
+	help += fmt.Sprintf("\nRun '%s ipam COMMAND --help' for more information on a command.", chain)
+	return help
+}
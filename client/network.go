@@ -24,6 +24,8 @@ var (
 		{"rm", "Remove a network"},
 		{"ls", "List all networks"},
 		{"info", "Display information of a network"},
+		{"rules", "Manage the connectivity rules of a network"},
+		{"policy", "Manage the network policies of a network"},
 	}
 )
 
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	flag "github.com/docker/libnetwork/client/mflag"
+)
+
+var (
+	policyCommands = []command{
+		{"ls", "List the network policies of a network"},
+		{"create", "Create a network policy"},
+		{"rm", "Remove a network policy"},
+	}
+)
+
+// CmdNetworkPolicy handles the root network policy UI
+func (cli *NetworkCli) CmdNetworkPolicy(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "policy", "COMMAND [OPTIONS] [arg...]", policyUsage(chain), false)
+	cmd.Require(flag.Min, 1)
+	err := cmd.ParseFlags(args, true)
+	if err == nil {
+		cmd.Usage()
+		return fmt.Errorf("invalid command : %v", args)
+	}
+	return err
+}
+
+// CmdNetworkPolicyLs handles network policy List UI
+func (cli *NetworkCli) CmdNetworkPolicyLs(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "ls", "NETWORK", "Lists the network policies of a network", false)
+	cmd.Require(flag.Exact, 1)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	obj, _, err := readBody(cli.call("GET", "/networks/"+nid+"/policies", nil, nil))
+	if err != nil {
+		return err
+	}
+	var policies []networkPolicyResource
+	if err := json.Unmarshal(obj, &policies); err != nil {
+		return err
+	}
+
+	wr := tabwriter.NewWriter(cli.out, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(wr, "NAME\tSELECTOR\tISOLATION\tINGRESS RULES\tEGRESS RULES")
+	for _, p := range policies {
+		fmt.Fprintf(wr, "%s\t%s\t%s\t%d\t%d\n", p.Name, selectorString(p.Selector),
+			strings.Join(p.Isolation, ","), len(p.Ingress), len(p.Egress))
+	}
+	wr.Flush()
+	return nil
+}
+
+// CmdNetworkPolicyCreate handles network policy Create UI
+func (cli *NetworkCli) CmdNetworkPolicyCreate(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "create", "NETWORK NAME", "Creates a network policy restricting the traffic of the endpoints it selects", false)
+	flSelector := cmd.String([]string{"-selector"}, "", "Labels selecting the endpoints the policy applies to")
+	flIsolation := cmd.String([]string{"-isolation"}, "", "Directions the endpoints are isolated in, ingress and/or egress")
+	flInFrom := cmd.String([]string{"-ingress-from"}, "", "Labels selecting the endpoints allowed to reach the selected endpoints")
+	flInCIDR := cmd.String([]string{"-ingress-cidr"}, "", "Address range allowed to reach the selected endpoints")
+	flInPorts := cmd.String([]string{"-ingress-port"}, "", "Ports the selected endpoints can be reached on, in the proto/port form")
+	flOutTo := cmd.String([]string{"-egress-to"}, "", "Labels selecting the endpoints the selected endpoints can reach")
+	flOutCIDR := cmd.String([]string{"-egress-cidr"}, "", "Address range the selected endpoints can reach")
+	flOutPorts := cmd.String([]string{"-egress-port"}, "", "Ports the selected endpoints can reach, in the proto/port form")
+	cmd.Require(flag.Exact, 2)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	policy := networkPolicyResource{
+		Name:     cmd.Arg(1),
+		Selector: parseSelector(*flSelector),
+		Ingress:  policyRules(*flInFrom, *flInCIDR, *flInPorts),
+		Egress:   policyRules(*flOutTo, *flOutCIDR, *flOutPorts),
+	}
+	if *flIsolation != "" {
+		policy.Isolation = strings.Split(*flIsolation, ",")
+	}
+
+	_, _, err = readBody(cli.call("POST", "/networks/"+nid+"/policies", policy, nil))
+	return err
+}
+
+// CmdNetworkPolicyRm handles network policy Remove UI
+func (cli *NetworkCli) CmdNetworkPolicyRm(chain string, args ...string) error {
+	cmd := cli.Subcmd(chain, "rm", "NETWORK NAME", "Removes a network policy", false)
+	cmd.Require(flag.Exact, 2)
+	err := cmd.ParseFlags(args, true)
+	if err != nil {
+		return err
+	}
+
+	nid, err := lookupNetworkID(cli, cmd.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	_, _, err = readBody(cli.call("DELETE", "/networks/"+nid+"/policies/"+cmd.Arg(1), nil, nil))
+	return err
+}
+
+// policyRules returns the rule the flags of a direction describe, if any
+func policyRules(labels, cidr, ports string) []*policyRuleResource {
+	if labels == "" && cidr == "" && ports == "" {
+		return nil
+	}
+
+	rule := &policyRuleResource{}
+	if labels != "" {
+		rule.Peers = append(rule.Peers, &policyPeerResource{Labels: parseSelector(labels)})
+	}
+	if cidr != "" {
+		rule.Peers = append(rule.Peers, &policyPeerResource{CIDR: cidr})
+	}
+	if ports != "" {
+		rule.Ports = strings.Split(ports, ",")
+	}
+	return []*policyRuleResource{rule}
+}
+
+func policyUsage(chain string) string {
+	help := "Commands:\n"
+
+	for _, cmd := range policyCommands {
+		help += fmt.Sprintf("  %-25.25s%s\n", cmd.name, cmd.description)
+	}
+
+	help += fmt.Sprintf("\nRun '%s network policy COMMAND --help' for more information on a command.", chain)
+	return help
+}
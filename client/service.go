@@ -11,6 +11,7 @@ import (
 	"github.com/docker/docker/opts"
 	"github.com/docker/docker/pkg/stringid"
 	flag "github.com/docker/libnetwork/client/mflag"
+	"github.com/docker/libnetwork/netlabel"
 	"github.com/docker/libnetwork/netutils"
 )
 
@@ -165,6 +166,8 @@ func (cli *NetworkCli) CmdServicePublish(chain string, args ...string) error {
 	cmd := cli.Subcmd(chain, "publish", "SERVICE[.NETWORK]", "Publish a new service on a network", false)
 	flAlias := opts.NewListOpts(netutils.ValidateAlias)
 	cmd.Var(&flAlias, []string{"-alias"}, "Add alias to self")
+	flLabels := opts.NewListOpts(opts.ValidateLabel)
+	cmd.Var(&flLabels, []string{"l", "-label"}, "Set a label on the service endpoint")
 	cmd.Require(flag.Exact, 1)
 	err := cmd.ParseFlags(args, true)
 	if err != nil {
@@ -173,6 +176,13 @@ func (cli *NetworkCli) CmdServicePublish(chain string, args ...string) error {
 
 	sn, nn := parseServiceName(cmd.Arg(0))
 	sc := serviceCreate{Name: sn, Network: nn, MyAliases: flAlias.GetAll()}
+	if labels := flLabels.GetAll(); len(labels) > 0 {
+		sc.Labels = make(map[string]string, len(labels))
+		for _, l := range labels {
+			k, v := netlabel.KeyValue(l)
+			sc.Labels[k] = v
+		}
+	}
 	obj, _, err := readBody(cli.call("POST", "/services", sc, nil))
 	if err != nil {
 		return err
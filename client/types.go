@@ -28,6 +28,75 @@ type SandboxResource struct {
 	ContainerID string `json:"container_id"`
 }
 
+// dnsRecordResource is the body of the "get dns record" http response
+// message and of the "create/update dns record" http request messages
+type dnsRecordResource struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// dnsStatisticsResource is the body of the "get sandbox dns statistics"
+// http response message
+type dnsStatisticsResource struct {
+	Queries *types.DNSQueryStatistics   `json:"queries"`
+	Servers []*types.ExtDNSStatistics   `json:"servers"`
+	Caches  []*types.DNSCacheStatistics `json:"caches"`
+}
+
+// connectivityRuleResource is a rule of the "get network connectivity
+// rules" http response message and of the "set network connectivity
+// rules" http request message. Ports are in the proto/port form.
+type connectivityRuleResource struct {
+	Network string            `json:"network,omitempty"`
+	From    map[string]string `json:"from,omitempty"`
+	To      map[string]string `json:"to,omitempty"`
+	Ports   []string          `json:"ports,omitempty"`
+}
+
+// networkPolicyResource is the body of the "get network policy" http
+// response message and of the "create network policy" http request message
+type networkPolicyResource struct {
+	Name      string                `json:"name"`
+	Selector  map[string]string     `json:"selector,omitempty"`
+	Ingress   []*policyRuleResource `json:"ingress,omitempty"`
+	Egress    []*policyRuleResource `json:"egress,omitempty"`
+	Isolation []string              `json:"isolation,omitempty"`
+}
+
+// policyRuleResource is a rule of a network policy. Ports are in the
+// proto/port form.
+type policyRuleResource struct {
+	Peers []*policyPeerResource `json:"peers,omitempty"`
+	Ports []string              `json:"ports,omitempty"`
+}
+
+// policyPeerResource selects the peers of a network policy rule
+type policyPeerResource struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	CIDR   string            `json:"cidr,omitempty"`
+}
+
+// poolResource is the body of the "get ipam pools" http response message
+type poolResource struct {
+	AddressSpace string
+	Pool         string
+	Total        uint64
+	Used         uint64
+	Allocated    []string
+	SubPools     []*subPoolResource
+}
+
+// subPoolResource is the utilization of an address range of a pool
+type subPoolResource struct {
+	SubPool   string
+	Start     string
+	End       string
+	Total     uint64
+	Used      uint64
+	Allocated []string
+}
+
 /***********
   Body types
   ************/
@@ -50,9 +119,10 @@ type networkCreate struct {
 
 // serviceCreate represents the body of the "publish service" http request message
 type serviceCreate struct {
-	Name      string   `json:"name"`
-	MyAliases []string `json:"my_aliases"`
-	Network   string   `json:"network_name"`
+	Name      string            `json:"name"`
+	MyAliases []string          `json:"my_aliases"`
+	Network   string            `json:"network_name"`
+	Labels    map[string]string `json:"labels"`
 }
 
 // serviceDelete represents the body of the "unpublish service" http request message
@@ -34,6 +34,8 @@ var (
 	dnetCommands = []cli.Command{
 		createDockerCommand("network"),
 		createDockerCommand("service"),
+		createDockerCommand("ipam"),
+		createDockerCommand("dns"),
 		{
 			Name:        "container",
 			Usage:       "Container management commands",
@@ -103,6 +103,10 @@ func (ep *endpoint) AddTableEntry(tableName string, key string, value []byte) er
 
 func (ep *endpoint) DisableGatewayService() {}
 
+func (ep *endpoint) SetQosPolicy(qos *types.QosPolicy) error {
+	return nil
+}
+
 func main() {
 	if reexec.Init() {
 		return
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	log "github.com/Sirupsen/logrus"
@@ -42,6 +43,14 @@ type DaemonCfg struct {
 	DriverCfg       map[string]interface{}
 	ClusterProvider cluster.Provider
 	DisableProvider chan struct{}
+	FirewallBackend string
+	DNSQueryLog     bool
+	// NetworkDBDiagnostic is the loopback address of the networkdb
+	// diagnostic server, disabled if empty
+	NetworkDBDiagnostic string
+	// NetworkDBDiagnosticWrite enables the networkdb diagnostic
+	// endpoints modifying the state
+	NetworkDBDiagnosticWrite bool
 }
 
 // ClusterCfg represents cluster configuration
@@ -50,6 +59,20 @@ type ClusterCfg struct {
 	Address   string
 	Discovery string
 	Heartbeat uint64
+	Gossip    GossipCfg
+}
+
+// GossipCfg represents the configuration of the networkdb gossip
+// cluster. The zero values default to the settings of the profile.
+type GossipCfg struct {
+	Profile             string
+	ReapEntryInterval   time.Duration
+	ReapNodeInterval    time.Duration
+	GossipInterval      time.Duration
+	BulkSyncInterval    time.Duration
+	RetransmitMult      int
+	BulkSyncChunkSize   int
+	BulkSyncCompression bool
 }
 
 // LoadDefaultScopes loads default scope configs for scopes which
@@ -201,6 +224,23 @@ func OptionDiscoveryAddress(address string) Option {
 	}
 }
 
+// OptionGossipProfile function returns an option setter for the preset
+// of the networkdb timing settings
+func OptionGossipProfile(profile string) Option {
+	return func(c *Config) {
+		log.Debugf("Option GossipProfile: %s", profile)
+		c.Cluster.Gossip.Profile = strings.TrimSpace(profile)
+	}
+}
+
+// OptionGossipConfig function returns an option setter for the networkdb
+// gossip settings
+func OptionGossipConfig(cfg GossipCfg) Option {
+	return func(c *Config) {
+		c.Cluster.Gossip = cfg
+	}
+}
+
 // OptionDataDir function returns an option setter for data folder
 func OptionDataDir(dataDir string) Option {
 	return func(c *Config) {
@@ -208,6 +248,40 @@ func OptionDataDir(dataDir string) Option {
 	}
 }
 
+// OptionFirewallBackend function returns an option setter for the firewall
+// backend programming the iptables rules
+func OptionFirewallBackend(backend string) Option {
+	return func(c *Config) {
+		log.Debugf("Option FirewallBackend: %s", backend)
+		c.Daemon.FirewallBackend = strings.TrimSpace(backend)
+	}
+}
+
+// OptionDNSQueryLog function returns an option setter for logging the
+// queries answered by the embedded DNS servers of the containers
+func OptionDNSQueryLog(enable bool) Option {
+	return func(c *Config) {
+		c.Daemon.DNSQueryLog = enable
+	}
+}
+
+// OptionNetworkDBDiagnostic function returns an option setter for the
+// address of the networkdb diagnostic server
+func OptionNetworkDBDiagnostic(addr string) Option {
+	return func(c *Config) {
+		log.Debugf("Option NetworkDBDiagnostic: %s", addr)
+		c.Daemon.NetworkDBDiagnostic = strings.TrimSpace(addr)
+	}
+}
+
+// OptionNetworkDBDiagnosticWrite function returns an option setter to
+// enable the networkdb diagnostic endpoints modifying the state
+func OptionNetworkDBDiagnosticWrite(enable bool) Option {
+	return func(c *Config) {
+		c.Daemon.NetworkDBDiagnosticWrite = enable
+	}
+}
+
 // OptionExecRoot function returns an option setter for exec root folder
 func OptionExecRoot(execRoot string) Option {
 	return func(c *Config) {
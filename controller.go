@@ -126,6 +126,14 @@ type NetworkController interface {
 
 	// SetKeys configures the encryption key for gossip and overlay data path
 	SetKeys(keys []*types.EncryptionKey) error
+
+	// Subscribe returns a channel delivering the lifecycle events matching the filter,
+	// and a function to cancel the subscription
+	Subscribe(filter EventFilter) (<-chan Event, func())
+
+	// InspectIPAMPools returns the utilization of the pools of the address space
+	// managed by the ipam driver, or of all its address spaces when none is specified
+	InspectIPAMPools(ipamDriver, addressSpace string) ([]*ipamapi.PoolInfo, error)
 }
 
 // NetworkWalker is a client provided function which will be used to walk the Networks.
@@ -149,6 +157,7 @@ type controller struct {
 	watchCh                chan *endpoint
 	unWatchCh              chan *endpoint
 	svcRecords             map[string]svcInfo
+	dnsRecords             map[string]map[string]*DNSRecord
 	nmap                   map[string]*netWatch
 	serviceBindings        map[serviceKey]*service
 	defOsSbox              osl.Sandbox
@@ -159,6 +168,7 @@ type controller struct {
 	agentInitDone          chan struct{}
 	keys                   []*types.EncryptionKey
 	clusterConfigAvailable bool
+	events                 *eventBroker
 	sync.Mutex
 }
 
@@ -174,15 +184,21 @@ func New(cfgOptions ...config.Option) (NetworkController, error) {
 		cfg:             config.ParseConfigOptions(cfgOptions...),
 		sandboxes:       sandboxTable{},
 		svcRecords:      make(map[string]svcInfo),
+		dnsRecords:      make(map[string]map[string]*DNSRecord),
 		serviceBindings: make(map[serviceKey]*service),
 		agentInitDone:   make(chan struct{}),
 		networkLocker:   locker.New(),
+		events:          newEventBroker(),
 	}
 
 	if err := c.initStores(); err != nil {
 		return nil, err
 	}
 
+	if err := setFirewallBackend(c.cfg.Daemon.FirewallBackend); err != nil {
+		return nil, err
+	}
+
 	drvRegistry, err := drvregistry.New(c.getStore(datastore.LocalScope), c.getStore(datastore.GlobalScope), c.RegisterDriver, nil, c.cfg.PluginGetter)
 	if err != nil {
 		return nil, err
@@ -227,6 +243,8 @@ func New(cfgOptions ...config.Option) (NetworkController, error) {
 	c.sandboxCleanup(c.cfg.ActiveSandboxes)
 	c.cleanupLocalEndpoints()
 	c.networkCleanup()
+	c.WalkNetworks(restoreConnectivityRules)
+	c.WalkNetworks(restoreNetworkPolicies)
 
 	if err := c.startExternalKeyListener(); err != nil {
 		return nil, err
@@ -650,6 +668,20 @@ func (c *controller) NewNetwork(networkType, name string, id string, options ...
 
 	network.processOptions(options...)
 
+	if label, ok := network.labels[netlabel.DNSForward]; ok {
+		if _, err := parseExtDNSRules(label); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, rec := range network.dnsRecords {
+		r, err := rec.normalize()
+		if err != nil {
+			return nil, err
+		}
+		network.dnsRecords[k] = &r
+	}
+
 	_, cap, err := network.resolveDriver(networkType, true)
 	if err != nil {
 		return nil, err
@@ -717,6 +749,8 @@ func (c *controller) NewNetwork(networkType, name string, id string, options ...
 		arrangeIngressFilterRule()
 	}
 
+	c.publishNetworkEvent(EventNetworkCreate, network)
+
 	return network, nil
 }
 
@@ -979,6 +1013,8 @@ func (c *controller) NewSandbox(containerID string, options ...SandboxOption) (s
 		return nil, fmt.Errorf("updating the store state of sandbox failed: %v", err)
 	}
 
+	c.publishSandboxEvent(EventSandboxCreate, sb)
+
 	return sb, nil
 }
 
@@ -1118,6 +1154,21 @@ func (c *controller) getIPAMDriver(name string) (ipamapi.Ipam, *ipamapi.Capabili
 	return id, cap, nil
 }
 
+func (c *controller) InspectIPAMPools(ipamDriver, addressSpace string) ([]*ipamapi.PoolInfo, error) {
+	if ipamDriver == "" {
+		ipamDriver = ipamapi.DefaultIPAM
+	}
+	id, _, err := c.getIPAMDriver(ipamDriver)
+	if err != nil {
+		return nil, err
+	}
+	pi, ok := id.(ipamapi.PoolInspector)
+	if !ok {
+		return nil, types.NotImplementedErrorf("ipam driver %q does not support pool inspection", ipamDriver)
+	}
+	return pi.InspectPools(addressSpace)
+}
+
 func (c *controller) Stop() {
 	c.clearIngress(false)
 	c.closeStores()
@@ -1,5 +1,7 @@
 package discoverapi
 
+import "github.com/docker/libnetwork/types"
+
 // Discover is an interface to be implemented by the component interested in receiving discover events
 // like new node joining the cluster or datastore updates
 type Discover interface {
@@ -22,6 +24,10 @@ const (
 	EncryptionKeysConfig
 	// EncryptionKeysUpdate represents an update to the datapath encryption key(s)
 	EncryptionKeysUpdate
+	// ConnectivityConfig represents the connectivity rules set on a network
+	ConnectivityConfig
+	// NetworkPolicyConfig represents the network policies set on a network
+	NetworkPolicyConfig
 )
 
 // NodeDiscoveryData represents the structure backing the node discovery data json string
@@ -58,3 +64,17 @@ type DriverEncryptionUpdate struct {
 	Prune      []byte
 	PruneTag   uint64
 }
+
+// ConnectivityConfigData carries the connectivity rules of a network, they
+// replace the rules previously set on the network
+type ConnectivityConfigData struct {
+	NetworkID string
+	Rules     []types.ConnectivityRule
+}
+
+// NetworkPolicyConfigData carries the network policies of a network, they
+// replace the policies previously set on the network
+type NetworkPolicyConfigData struct {
+	NetworkID string
+	Policies  []types.NetworkPolicy
+}
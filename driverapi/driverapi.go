@@ -5,6 +5,7 @@ import (
 
 	"github.com/docker/docker/pkg/plugingetter"
 	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/types"
 )
 
 // NetworkPluginEndpointType represents the Endpoint Type used by Plugin system
@@ -82,6 +83,10 @@ type NetworkInfo interface {
 	// TableEventRegister registers driver interest in a given
 	// table name.
 	TableEventRegister(tableName string) error
+
+	// UpdateTableEntry updates the value of a table entry added by
+	// the driver when an endpoint of the network joined.
+	UpdateTableEntry(tableName string, key string, value []byte) error
 }
 
 // InterfaceInfo provides a go interface for drivers to retrive
@@ -133,6 +138,9 @@ type JoinInfo interface {
 	// DisableGatewayService tells libnetwork not to provide Default GW for the container
 	DisableGatewayService()
 
+	// SetQosPolicy sets the bandwidth limits to be programmed on the container interface.
+	SetQosPolicy(qos *types.QosPolicy) error
+
 	// AddTableEntry adds a table entry to the gossip layer
 	// passing the table name, key and an opaque value.
 	AddTableEntry(tableName string, key string, value []byte) error
@@ -149,6 +157,16 @@ type DriverCallback interface {
 // Capability represents the high level capabilities of the drivers which libnetwork can make use of
 type Capability struct {
 	DataScope string
+	// ConnectivityRules is set by the drivers programming the
+	// connectivity rules between their networks
+	ConnectivityRules bool
+	// NetworkPolicies is set by the drivers enforcing the network
+	// policies on the endpoints of their networks
+	NetworkPolicies bool
+	// AllocatesIPv4 is set by the drivers choosing the IPv4 address of
+	// the endpoints created without a preferred address, the address is
+	// reserved in the IPAM driver once the endpoint is created
+	AllocatesIPv4 bool
 }
 
 // IPAMData represents the per-network ip related
@@ -87,6 +87,8 @@ const (
 // endpointConfiguration represents the user specified configuration for the sandbox endpoint
 type endpointConfiguration struct {
 	MacAddress net.HardwareAddr
+	QosPolicy  *types.QosPolicy
+	Labels     map[string]string
 }
 
 // containerConfiguration represents the user specified configuration for a container
@@ -117,13 +119,17 @@ type bridgeEndpoint struct {
 }
 
 type bridgeNetwork struct {
-	id            string
-	bridge        *bridgeInterface // The bridge's L3 interface
-	config        *networkConfiguration
-	endpoints     map[string]*bridgeEndpoint // key: endpoint id
-	portMapper    *portmapper.PortMapper
-	driver        *driver // The network's driver
-	iptCleanFuncs iptablesCleanFuncs
+	id             string
+	bridge         *bridgeInterface // The bridge's L3 interface
+	config         *networkConfiguration
+	endpoints      map[string]*bridgeEndpoint // key: endpoint id
+	portMapper     *portmapper.PortMapper
+	driver         *driver // The network's driver
+	iptCleanFuncs  iptablesCleanFuncs
+	connRules      []types.ConnectivityRule
+	connIptRules   []iptRule // The programmed connectivity rules
+	policies       []types.NetworkPolicy
+	policyIptRules []iptRule // The programmed network policy rules
 	sync.Mutex
 }
 
@@ -152,7 +158,9 @@ func Init(dc driverapi.DriverCallback, config map[string]interface{}) error {
 	}
 
 	c := driverapi.Capability{
-		DataScope: datastore.LocalScope,
+		DataScope:         datastore.LocalScope,
+		ConnectivityRules: true,
+		NetworkPolicies:   true,
 	}
 	return dc.RegisterDriver(networkType, d, c)
 }
@@ -299,6 +307,7 @@ func (n *bridgeNetwork) isolateNetwork(others []*bridgeNetwork, enable bool) err
 	}
 
 	// Install the rules to isolate this networks against each of the other networks
+	t := iptables.NewTransaction()
 	for _, o := range others {
 		o.Lock()
 		otherConfig := o.config
@@ -309,10 +318,15 @@ func (n *bridgeNetwork) isolateNetwork(others []*bridgeNetwork, enable bool) err
 		}
 
 		if thisConfig.BridgeName != otherConfig.BridgeName {
-			if err := setINC(thisConfig.BridgeName, otherConfig.BridgeName, enable); err != nil {
-				return err
-			}
+			setINC(t, thisConfig.BridgeName, otherConfig.BridgeName, enable)
+		}
+	}
+
+	if err := t.Commit(); err != nil {
+		if enable {
+			return fmt.Errorf("unable to add inter-network communication rules: %v", err)
 		}
+		return fmt.Errorf("unable to remove inter-network communication rules: %v", err)
 	}
 
 	return nil
@@ -679,10 +693,9 @@ func (d *driver) createNetwork(config *networkConfiguration) error {
 	}
 
 	setupNetworkIsolationRules := func(config *networkConfiguration, i *bridgeInterface) error {
+		// The rules are programmed as a transaction, nothing is left
+		// behind on failure
 		if err := network.isolateNetwork(networkList, true); err != nil {
-			if err := network.isolateNetwork(networkList, false); err != nil {
-				logrus.Warnf("Failed on removing the inter-network iptables rules on cleanup: %v", err)
-			}
 			return err
 		}
 		network.registerIptCleanFunc(func() error {
@@ -834,6 +847,15 @@ func (d *driver) DeleteNetwork(nid string) error {
 			logrus.Warnf("Failed to clean iptables rules for bridge network: %v", errClean)
 		}
 	}
+	if err := n.clearConnectivityRules(); err != nil {
+		logrus.Warn(err)
+	}
+	if err := n.clearNetworkPolicies(); err != nil {
+		logrus.Warn(err)
+	}
+	// The rules of the other networks allowing this one are stale
+	d.updateConnectivityRules(nid)
+
 	return d.storeDelete(config)
 }
 
@@ -1069,6 +1091,15 @@ func (d *driver) CreateEndpoint(nid, eid string, ifInfo driverapi.InterfaceInfo,
 		return fmt.Errorf("failed to save bridge endpoint %s to store: %v", endpoint.id[0:7], err)
 	}
 
+	if err = n.programNetworkPolicies(); err != nil {
+		if e := d.storeDelete(endpoint); e != nil {
+			logrus.Warnf("Failed to remove bridge endpoint %s from store: %v", endpoint.id[0:7], e)
+		}
+		return err
+	}
+
+	d.updateConnectivityRules(nid)
+
 	return nil
 }
 
@@ -1133,6 +1164,12 @@ func (d *driver) DeleteEndpoint(nid, eid string) error {
 		logrus.Warnf("Failed to remove bridge endpoint %s from store: %v", ep.id[0:7], err)
 	}
 
+	if err := n.programNetworkPolicies(); err != nil {
+		logrus.Warn(err)
+	}
+
+	d.updateConnectivityRules(nid)
+
 	return nil
 }
 
@@ -1189,6 +1226,10 @@ func (d *driver) EndpointOperInfo(nid, eid string) (map[string]interface{}, erro
 		m[netlabel.MacAddress] = ep.macAddress
 	}
 
+	if ep.config != nil && ep.config.QosPolicy != nil {
+		m[netlabel.QosPolicy] = *ep.config.QosPolicy
+	}
+
 	return m, nil
 }
 
@@ -1231,6 +1272,12 @@ func (d *driver) Join(nid, eid string, sboxKey string, jinfo driverapi.JoinInfo,
 		return err
 	}
 
+	if endpoint.config != nil && endpoint.config.QosPolicy != nil {
+		if err = jinfo.SetQosPolicy(endpoint.config.QosPolicy); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -1425,6 +1472,28 @@ func (d *driver) Type() string {
 
 // DiscoverNew is a notification for a new discovery event, such as a new node joining a cluster
 func (d *driver) DiscoverNew(dType discoverapi.DiscoveryType, data interface{}) error {
+	switch dType {
+	case discoverapi.ConnectivityConfig:
+		cfg, ok := data.(discoverapi.ConnectivityConfigData)
+		if !ok {
+			return types.BadRequestErrorf("invalid connectivity configuration: %v", data)
+		}
+		n, err := d.getNetwork(cfg.NetworkID)
+		if err != nil {
+			return err
+		}
+		return n.setConnectivityRules(cfg.Rules)
+	case discoverapi.NetworkPolicyConfig:
+		cfg, ok := data.(discoverapi.NetworkPolicyConfigData)
+		if !ok {
+			return types.BadRequestErrorf("invalid network policy configuration: %v", data)
+		}
+		n, err := d.getNetwork(cfg.NetworkID)
+		if err != nil {
+			return err
+		}
+		return n.setNetworkPolicies(cfg.Policies)
+	}
 	return nil
 }
 
@@ -1448,6 +1517,20 @@ func parseEndpointOptions(epOptions map[string]interface{}) (*endpointConfigurat
 		}
 	}
 
+	if opt, ok := epOptions[netlabel.QosPolicy]; ok {
+		if qos, ok := opt.(types.QosPolicy); ok {
+			ec.QosPolicy = &qos
+		} else {
+			return nil, &ErrInvalidEndpointConfig{}
+		}
+	}
+
+	labels, err := netlabel.GetEndpointLabels(epOptions)
+	if err != nil {
+		return nil, &ErrInvalidEndpointConfig{}
+	}
+	ec.Labels = labels
+
 	return ec, nil
 }
 
@@ -503,6 +503,7 @@ type testEndpoint struct {
 	hostsPath      string
 	resolvConfPath string
 	routes         []types.StaticRoute
+	qos            *types.QosPolicy
 }
 
 func newTestEndpoint(nw *net.IPNet, ordinal byte) *testEndpoint {
@@ -595,6 +596,11 @@ func (te *testEndpoint) AddTableEntry(tableName string, key string, value []byte
 
 func (te *testEndpoint) DisableGatewayService() {}
 
+func (te *testEndpoint) SetQosPolicy(qos *types.QosPolicy) error {
+	te.qos = qos
+	return nil
+}
+
 func TestQueryEndpointInfo(t *testing.T) {
 	testQueryEndpointInfo(t, true)
 }
@@ -993,6 +999,9 @@ func TestCleanupIptableRules(t *testing.T) {
 		{Name: DockerChain, Table: iptables.Nat},
 		{Name: DockerChain, Table: iptables.Filter},
 		{Name: IsolationChain, Table: iptables.Filter},
+		{Name: ConnectivityChain, Table: iptables.Filter},
+		{Name: PolicyIngressChain, Table: iptables.Filter},
+		{Name: PolicyEgressChain, Table: iptables.Filter},
 	}
 	if _, _, _, err := setupIPChains(&configuration{EnableIPTables: true}); err != nil {
 		t.Fatalf("Error setting up ip chains: %v", err)
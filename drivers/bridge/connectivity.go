@@ -0,0 +1,205 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/iptables"
+	"github.com/docker/libnetwork/netpolicy"
+	"github.com/docker/libnetwork/types"
+)
+
+// setConnectivityRules replaces the connectivity rules of the network and
+// programs them
+func (n *bridgeNetwork) setConnectivityRules(rules []types.ConnectivityRule) error {
+	d := n.driver
+	d.Lock()
+	driverConfig := d.config
+	d.Unlock()
+
+	if !driverConfig.EnableIPTables {
+		return types.ForbiddenErrorf("connectivity rules cannot be programmed on network %s, iptables is disabled", n.id)
+	}
+
+	cr := make([]types.ConnectivityRule, 0, len(rules))
+	for _, r := range rules {
+		cr = append(cr, r.GetCopy())
+	}
+
+	n.Lock()
+	old := n.connRules
+	n.connRules = cr
+	n.Unlock()
+
+	if err := n.programConnectivityRules(); err != nil {
+		n.Lock()
+		n.connRules = old
+		n.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// programConnectivityRules replaces the iptables rules programmed for the
+// connectivity rules of the network with the ones matching the current
+// endpoints of the network and of its peers
+func (n *bridgeNetwork) programConnectivityRules() error {
+	rules := n.connectivityIptRules()
+
+	n.Lock()
+	defer n.Unlock()
+
+	t := iptables.NewTransaction()
+	for _, r := range n.connIptRules {
+		r.program(t, false)
+	}
+	for _, r := range rules {
+		r.program(t, true)
+	}
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("unable to program the connectivity rules of network %s: %v", n.id, err)
+	}
+	n.connIptRules = rules
+
+	return nil
+}
+
+// clearConnectivityRules removes the iptables rules programmed for the
+// connectivity rules of the network
+func (n *bridgeNetwork) clearConnectivityRules() error {
+	n.Lock()
+	defer n.Unlock()
+
+	t := iptables.NewTransaction()
+	for _, r := range n.connIptRules {
+		r.program(t, false)
+	}
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("unable to remove the connectivity rules of network %s: %v", n.id, err)
+	}
+	n.connIptRules = nil
+
+	return nil
+}
+
+// updateConnectivityRules reprograms the connectivity rules of the networks
+// selecting the endpoints of the network nid, after its endpoints changed
+func (d *driver) updateConnectivityRules(nid string) {
+	for _, n := range d.getNetworks() {
+		n.Lock()
+		affected := false
+		for _, r := range n.connRules {
+			if n.id == nid || r.Network == nid {
+				affected = true
+				break
+			}
+		}
+		n.Unlock()
+
+		if !affected {
+			continue
+		}
+		if err := n.programConnectivityRules(); err != nil {
+			logrus.Warnf("Failed to update the connectivity rules of network %s: %v", n.id, err)
+		}
+	}
+}
+
+// connectivityIptRules returns the iptables rules accepting the traffic the
+// connectivity rules of the network allow, and the traffic of the
+// connections already established the other way around
+func (n *bridgeNetwork) connectivityIptRules() []iptRule {
+	n.Lock()
+	rules := n.connRules
+	bridgeName := n.config.BridgeName
+	n.Unlock()
+
+	var (
+		iptRules []iptRule
+		seen     = make(map[string]bool)
+	)
+	add := func(args []string) {
+		k := fmt.Sprint(args)
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		iptRules = append(iptRules, iptRule{table: iptables.Filter, chain: ConnectivityChain, args: args})
+	}
+
+	for _, r := range rules {
+		peer := n
+		if r.Network != "" && r.Network != n.id {
+			p, err := n.driver.getNetwork(r.Network)
+			if err != nil {
+				// The peer network is gone, or not restored yet
+				continue
+			}
+			peer = p
+		}
+		peerBridgeName := peer.getNetworkBridgeName()
+
+		srcs := peer.selectEndpoints(r.From)
+		dsts := n.selectEndpoints(r.To)
+		ports := r.Ports
+		if len(ports) == 0 {
+			ports = []types.TransportPort{{}}
+		}
+
+		for _, src := range srcs {
+			for _, dst := range dsts {
+				for _, p := range ports {
+					fwd := []string{"-i", peerBridgeName, "-o", bridgeName}
+					rev := []string{"-i", bridgeName, "-o", peerBridgeName}
+					if src != "" {
+						fwd = append(fwd, "-s", src)
+						rev = append(rev, "-d", src)
+					}
+					if dst != "" {
+						fwd = append(fwd, "-d", dst)
+						rev = append(rev, "-s", dst)
+					}
+					if p.Proto != 0 {
+						fwd = append(fwd, "-p", p.Proto.String())
+						rev = append(rev, "-p", p.Proto.String())
+						if p.Port != 0 {
+							fwd = append(fwd, "--dport", strconv.Itoa(int(p.Port)))
+							rev = append(rev, "--sport", strconv.Itoa(int(p.Port)))
+						}
+					}
+					add(append(fwd, "-j", "ACCEPT"))
+					add(append(rev, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"))
+				}
+			}
+		}
+	}
+
+	return iptRules
+}
+
+// selectEndpoints returns the IPv4 addresses of the endpoints of the network
+// carrying all the labels. An empty label set selects the whole network,
+// reported as a single empty address.
+func (n *bridgeNetwork) selectEndpoints(labels map[string]string) []string {
+	if len(labels) == 0 {
+		return []string{""}
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	var addrs []string
+	for _, ep := range n.endpoints {
+		if ep.addr == nil || ep.config == nil || !netpolicy.MatchLabels(ep.config.Labels, labels) {
+			continue
+		}
+		addrs = append(addrs, ep.addr.IP.String()+"/32")
+	}
+	// Keep the rules in a stable order
+	sort.Strings(addrs)
+
+	return addrs
+}
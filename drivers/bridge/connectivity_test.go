@@ -0,0 +1,144 @@
+package bridge
+
+import (
+	"net"
+	"testing"
+
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/iptables"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/docker/libnetwork/types"
+)
+
+func getConnectivityIPv4Data(t *testing.T, pool, gw string) []driverapi.IPAMData {
+	_, nw, err := net.ParseCIDR(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gwAddr := types.GetIPNetCopy(nw)
+	gwAddr.IP = net.ParseIP(gw).To4()
+	return []driverapi.IPAMData{{Pool: nw, Gateway: gwAddr}}
+}
+
+func createConnectivityEndpoint(t *testing.T, d *driver, nid, eid string, nw *net.IPNet, ordinal byte, labels map[string]string) {
+	te := newTestEndpoint(nw, ordinal)
+	epOptions := map[string]interface{}{netlabel.EndpointLabels: labels}
+	if err := d.CreateEndpoint(nid, eid, te.Interface(), epOptions); err != nil {
+		t.Fatalf("Failed to create endpoint %s: %v", eid, err)
+	}
+}
+
+func TestConnectivityRules(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+	d := newDriver()
+
+	genericOption := make(map[string]interface{})
+	genericOption[netlabel.GenericData] = &configuration{EnableIPTables: true}
+	if err := d.configure(genericOption); err != nil {
+		t.Fatalf("Failed to setup driver config: %v", err)
+	}
+
+	frontData := getConnectivityIPv4Data(t, "172.28.1.0/24", "172.28.1.1")
+	backData := getConnectivityIPv4Data(t, "172.28.2.0/24", "172.28.2.1")
+
+	genericOption = make(map[string]interface{})
+	genericOption[netlabel.GenericData] = &networkConfiguration{BridgeName: "cnntest_front", EnableICC: true}
+	if err := d.CreateNetwork("front", genericOption, nil, frontData, nil); err != nil {
+		t.Fatalf("Failed to create bridge: %v", err)
+	}
+	genericOption = make(map[string]interface{})
+	genericOption[netlabel.GenericData] = &networkConfiguration{BridgeName: "cnntest_back", EnableICC: true}
+	if err := d.CreateNetwork("back", genericOption, nil, backData, nil); err != nil {
+		t.Fatalf("Failed to create bridge: %v", err)
+	}
+
+	createConnectivityEndpoint(t, d, "front", "web1", frontData[0].Pool, 2, map[string]string{"app": "web"})
+	createConnectivityEndpoint(t, d, "front", "admin", frontData[0].Pool, 3, map[string]string{"app": "admin"})
+	createConnectivityEndpoint(t, d, "back", "db", backData[0].Pool, 2, map[string]string{"app": "db", "tier": "data"})
+
+	err := d.DiscoverNew(discoverapi.ConnectivityConfig, discoverapi.ConnectivityConfigData{
+		NetworkID: "back",
+		Rules: []types.ConnectivityRule{{
+			Network: "front",
+			From:    map[string]string{"app": "web"},
+			To:      map[string]string{"tier": "data"},
+			Ports:   []types.TransportPort{{Proto: types.TCP, Port: 5432}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set the connectivity rules: %v", err)
+	}
+
+	web1Rule := []string{"-i", "cnntest_front", "-o", "cnntest_back", "-s", "172.28.1.2/32", "-d", "172.28.2.2/32",
+		"-p", "tcp", "--dport", "5432", "-j", "ACCEPT"}
+	web1RevRule := []string{"-i", "cnntest_back", "-o", "cnntest_front", "-d", "172.28.1.2/32", "-s", "172.28.2.2/32",
+		"-p", "tcp", "--sport", "5432", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}
+	web2Rule := []string{"-i", "cnntest_front", "-o", "cnntest_back", "-s", "172.28.1.4/32", "-d", "172.28.2.2/32",
+		"-p", "tcp", "--dport", "5432", "-j", "ACCEPT"}
+	adminRule := []string{"-i", "cnntest_front", "-o", "cnntest_back", "-s", "172.28.1.3/32", "-d", "172.28.2.2/32",
+		"-p", "tcp", "--dport", "5432", "-j", "ACCEPT"}
+
+	checkRule := func(args []string, expected bool) {
+		if exists := iptables.Exists(iptables.Filter, ConnectivityChain, args...); exists != expected {
+			t.Fatalf("Expected rule %v to exist: %t, got: %t", args, expected, exists)
+		}
+	}
+
+	checkRule(web1Rule, true)
+	checkRule(web1RevRule, true)
+	checkRule(adminRule, false)
+	checkRule(web2Rule, false)
+
+	// The rules must follow the endpoints joining and leaving the peer network
+	createConnectivityEndpoint(t, d, "front", "web2", frontData[0].Pool, 4, map[string]string{"app": "web"})
+	checkRule(web2Rule, true)
+
+	if err := d.DeleteEndpoint("front", "web1"); err != nil {
+		t.Fatalf("Failed to delete endpoint: %v", err)
+	}
+	checkRule(web1Rule, false)
+	checkRule(web1RevRule, false)
+	checkRule(web2Rule, true)
+
+	if err := d.DeleteEndpoint("back", "db"); err != nil {
+		t.Fatalf("Failed to delete endpoint: %v", err)
+	}
+	checkRule(web2Rule, false)
+
+	createConnectivityEndpoint(t, d, "back", "db", backData[0].Pool, 2, map[string]string{"tier": "data"})
+	checkRule(web2Rule, true)
+
+	// The rules of the network are gone with the network
+	if err := d.DeleteNetwork("back"); err != nil {
+		t.Fatalf("Failed to delete network: %v", err)
+	}
+	checkRule(web2Rule, false)
+}
+
+func TestConnectivityRulesIptablesDisabled(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+	d := newDriver()
+
+	genericOption := make(map[string]interface{})
+	genericOption[netlabel.GenericData] = &configuration{EnableIPTables: false}
+	if err := d.configure(genericOption); err != nil {
+		t.Fatalf("Failed to setup driver config: %v", err)
+	}
+
+	ipdList := getConnectivityIPv4Data(t, "172.28.3.0/24", "172.28.3.1")
+	genericOption = make(map[string]interface{})
+	genericOption[netlabel.GenericData] = &networkConfiguration{BridgeName: "cnntest_noipt"}
+	if err := d.CreateNetwork("noipt", genericOption, nil, ipdList, nil); err != nil {
+		t.Fatalf("Failed to create bridge: %v", err)
+	}
+
+	err := d.DiscoverNew(discoverapi.ConnectivityConfig, discoverapi.ConnectivityConfigData{
+		NetworkID: "noipt",
+		Rules:     []types.ConnectivityRule{{From: map[string]string{"app": "web"}}},
+	})
+	if _, ok := err.(types.ForbiddenError); !ok {
+		t.Fatalf("Expected a forbidden error when iptables is disabled, got: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/docker/libnetwork/iptables"
+	"github.com/docker/libnetwork/netpolicy"
+	"github.com/docker/libnetwork/types"
+)
+
+// setNetworkPolicies replaces the network policies of the network and
+// enforces them on its endpoints
+func (n *bridgeNetwork) setNetworkPolicies(policies []types.NetworkPolicy) error {
+	d := n.driver
+	d.Lock()
+	driverConfig := d.config
+	d.Unlock()
+
+	if !driverConfig.EnableIPTables {
+		return types.ForbiddenErrorf("network policies cannot be enforced on network %s, iptables is disabled", n.id)
+	}
+
+	np := make([]types.NetworkPolicy, 0, len(policies))
+	for _, p := range policies {
+		np = append(np, p.GetCopy())
+	}
+
+	n.Lock()
+	old := n.policies
+	n.policies = np
+	n.Unlock()
+
+	if err := n.programNetworkPolicies(); err != nil {
+		n.Lock()
+		n.policies = old
+		n.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// programNetworkPolicies replaces the iptables rules enforcing the network
+// policies with the ones matching the current endpoints of the network
+func (n *bridgeNetwork) programNetworkPolicies() error {
+	rules := n.networkPolicyIptRules()
+
+	n.Lock()
+	defer n.Unlock()
+
+	if len(rules) == 0 && len(n.policyIptRules) == 0 {
+		return nil
+	}
+
+	t := iptables.NewTransaction()
+	for _, r := range n.policyIptRules {
+		r.program(t, false)
+	}
+	for _, r := range rules {
+		r.program(t, true)
+	}
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("unable to enforce the network policies of network %s: %v", n.id, err)
+	}
+	n.policyIptRules = rules
+
+	return nil
+}
+
+// clearNetworkPolicies removes the iptables rules enforcing the network
+// policies of the network
+func (n *bridgeNetwork) clearNetworkPolicies() error {
+	n.Lock()
+	defer n.Unlock()
+
+	t := iptables.NewTransaction()
+	for _, r := range n.policyIptRules {
+		r.program(t, false)
+	}
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("unable to remove the network policies of network %s: %v", n.id, err)
+	}
+	n.policyIptRules = nil
+
+	return nil
+}
+
+// networkPolicyIptRules returns the iptables rules enforcing the network policies
+// on the endpoints of the network
+func (n *bridgeNetwork) networkPolicyIptRules() []iptRule {
+	n.Lock()
+	defer n.Unlock()
+
+	if len(n.policies) == 0 {
+		return nil
+	}
+
+	eps := make([]netpolicy.Endpoint, 0, len(n.endpoints))
+	for _, ep := range n.endpoints {
+		if ep.addr == nil {
+			continue
+		}
+		pep := netpolicy.Endpoint{IP: ep.addr.IP}
+		if ep.config != nil {
+			pep.Labels = ep.config.Labels
+		}
+		eps = append(eps, pep)
+	}
+
+	ingress, egress := netpolicy.Rules(n.policies, eps, n.config.BridgeName)
+
+	// The rules are inserted at the top of the chains, in reverse order
+	var rules []iptRule
+	for i := len(ingress) - 1; i >= 0; i-- {
+		rules = append(rules, iptRule{table: iptables.Filter, chain: PolicyIngressChain, args: ingress[i]})
+	}
+	for i := len(egress) - 1; i >= 0; i-- {
+		rules = append(rules, iptRule{table: iptables.Filter, chain: PolicyEgressChain, args: egress[i]})
+	}
+	return rules
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/iptables"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/docker/libnetwork/types"
+)
+
+func TestNetworkPolicies(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+	d := newDriver()
+
+	genericOption := make(map[string]interface{})
+	genericOption[netlabel.GenericData] = &configuration{EnableIPTables: true}
+	if err := d.configure(genericOption); err != nil {
+		t.Fatalf("Failed to setup driver config: %v", err)
+	}
+
+	ipdList := getConnectivityIPv4Data(t, "172.28.4.0/24", "172.28.4.1")
+	genericOption = make(map[string]interface{})
+	genericOption[netlabel.GenericData] = &networkConfiguration{BridgeName: "nptest", EnableICC: true}
+	if err := d.CreateNetwork("np", genericOption, nil, ipdList, nil); err != nil {
+		t.Fatalf("Failed to create bridge: %v", err)
+	}
+
+	createConnectivityEndpoint(t, d, "np", "db", ipdList[0].Pool, 2, map[string]string{"app": "db"})
+	createConnectivityEndpoint(t, d, "np", "web1", ipdList[0].Pool, 3, map[string]string{"app": "web"})
+
+	err := d.DiscoverNew(discoverapi.NetworkPolicyConfig, discoverapi.NetworkPolicyConfigData{
+		NetworkID: "np",
+		Policies: []types.NetworkPolicy{{
+			Name:     "db",
+			Selector: map[string]string{"app": "db"},
+			Ingress: []types.PolicyRule{{
+				Peers: []types.PolicyPeer{{Labels: map[string]string{"app": "web"}}},
+				Ports: []types.TransportPort{{Proto: types.TCP, Port: 5432}},
+			}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set the network policies: %v", err)
+	}
+
+	web1Rule := []string{"-o", "nptest", "-d", "172.28.4.2/32", "-s", "172.28.4.3/32", "-p", "tcp", "--dport", "5432", "-j", "RETURN"}
+	web2Rule := []string{"-o", "nptest", "-d", "172.28.4.2/32", "-s", "172.28.4.4/32", "-p", "tcp", "--dport", "5432", "-j", "RETURN"}
+	dropRule := []string{"-o", "nptest", "-d", "172.28.4.2/32", "-j", "DROP"}
+
+	checkRule := func(args []string, expected bool) {
+		if exists := iptables.Exists(iptables.Filter, PolicyIngressChain, args...); exists != expected {
+			t.Fatalf("Expected rule %v to exist: %t, got: %t", args, expected, exists)
+		}
+	}
+
+	checkRule(web1Rule, true)
+	checkRule(dropRule, true)
+	checkRule(web2Rule, false)
+
+	// The policies must follow the endpoints joining and leaving the network
+	createConnectivityEndpoint(t, d, "np", "web2", ipdList[0].Pool, 4, map[string]string{"app": "web"})
+	checkRule(web2Rule, true)
+
+	if err := d.DeleteEndpoint("np", "web1"); err != nil {
+		t.Fatalf("Failed to delete endpoint: %v", err)
+	}
+	checkRule(web1Rule, false)
+	checkRule(web2Rule, true)
+
+	if err := d.DeleteEndpoint("np", "db"); err != nil {
+		t.Fatalf("Failed to delete endpoint: %v", err)
+	}
+	checkRule(dropRule, false)
+
+	createConnectivityEndpoint(t, d, "np", "db", ipdList[0].Pool, 2, map[string]string{"app": "db"})
+	checkRule(dropRule, true)
+
+	// The rules of the network are gone with the network
+	if err := d.DeleteNetwork("np"); err != nil {
+		t.Fatalf("Failed to delete network: %v", err)
+	}
+	checkRule(dropRule, false)
+	checkRule(web2Rule, false)
+}
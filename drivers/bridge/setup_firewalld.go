@@ -15,6 +15,8 @@ func (n *bridgeNetwork) setupFirewalld(config *networkConfiguration, i *bridgeIn
 
 	iptables.OnReloaded(func() { n.setupIPTables(config, i) })
 	iptables.OnReloaded(n.portMapper.ReMapAll)
+	iptables.OnReloaded(func() { n.programConnectivityRules() })
+	iptables.OnReloaded(func() { n.programNetworkPolicies() })
 
 	return nil
 }
@@ -12,6 +12,14 @@ import (
 const (
 	DockerChain    = "DOCKER"
 	IsolationChain = "DOCKER-ISOLATION"
+	// ConnectivityChain holds the rules allowing traffic through the
+	// isolation of the networks, it is jumped to before IsolationChain
+	ConnectivityChain = "DOCKER-CONNECTIVITY"
+	// PolicyIngressChain and PolicyEgressChain hold the rules enforcing
+	// the network policies on the traffic to and from the endpoints, they
+	// are jumped to before any other chain
+	PolicyIngressChain = "DOCKER-POLICY-IN"
+	PolicyEgressChain  = "DOCKER-POLICY-OUT"
 )
 
 func setupIPChains(config *configuration) (*iptables.ChainInfo, *iptables.ChainInfo, *iptables.ChainInfo, error) {
@@ -55,6 +63,24 @@ func setupIPChains(config *configuration) (*iptables.ChainInfo, *iptables.ChainI
 		return nil, nil, nil, err
 	}
 
+	if _, err := iptables.NewChain(ConnectivityChain, iptables.Filter, false); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create FILTER connectivity chain: %v", err)
+	}
+
+	if err := addReturnRule(ConnectivityChain); err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, chain := range []string{PolicyIngressChain, PolicyEgressChain} {
+		if _, err := iptables.NewChain(chain, iptables.Filter, false); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create FILTER policy chain %s: %v", chain, err)
+		}
+
+		if err := addReturnRule(chain); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
 	return natChain, filterChain, isolationChain, nil
 }
 
@@ -118,6 +144,18 @@ func (n *bridgeNetwork) setupIPTables(config *networkConfiguration, i *bridgeInt
 		return err
 	}
 
+	// The connectivity rules are evaluated before the isolation rules
+	if err := ensureJumpRule("FORWARD", ConnectivityChain); err != nil {
+		return err
+	}
+
+	// The network policies restrict all the traffic of the endpoints
+	for _, chain := range []string{PolicyEgressChain, PolicyIngressChain} {
+		if err := ensureJumpRule("FORWARD", chain); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -139,75 +177,69 @@ func setupIPTablesInternal(bridgeIface string, addr net.Addr, icc, ipmasq, hairp
 		inRule    = iptRule{table: iptables.Filter, chain: "FORWARD", args: []string{"-o", bridgeIface, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"}}
 	)
 
+	operation := "disable"
+	if enable {
+		operation = "enable"
+	}
+
+	t := iptables.NewTransaction()
+
 	// Set NAT.
 	if ipmasq {
-		if err := programChainRule(natRule, "NAT", enable); err != nil {
-			return err
-		}
+		natRule.program(t, enable)
 	}
 
 	if ipmasq && !hairpin {
-		if err := programChainRule(skipDNAT, "SKIP DNAT", enable); err != nil {
-			return err
-		}
+		skipDNAT.program(t, enable)
 	}
 
 	// In hairpin mode, masquerade traffic from localhost
 	if hairpin {
-		if err := programChainRule(hpNatRule, "MASQ LOCAL HOST", enable); err != nil {
-			return err
-		}
+		hpNatRule.program(t, enable)
 	}
 
 	// Set Inter Container Communication.
-	if err := setIcc(bridgeIface, icc, enable); err != nil {
-		return err
-	}
+	setIcc(t, bridgeIface, icc, enable)
 
 	// Set Accept on all non-intercontainer outgoing packets.
-	if err := programChainRule(outRule, "ACCEPT NON_ICC OUTGOING", enable); err != nil {
-		return err
-	}
+	outRule.program(t, enable)
 
 	// Set Accept on incoming packets for existing connections.
-	if err := programChainRule(inRule, "ACCEPT INCOMING", enable); err != nil {
-		return err
+	inRule.program(t, enable)
+
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("Unable to %s iptables rules of bridge %s: %s", operation, bridgeIface, err.Error())
 	}
 
 	return nil
 }
 
-func programChainRule(rule iptRule, ruleDescr string, insert bool) error {
-	var (
-		prefix    []string
-		operation string
-		condition bool
-		doesExist = iptables.Exists(rule.table, rule.chain, rule.args...)
-	)
+// program queues in the transaction the insertion of the rule at the top of
+// its chain, or its removal.
+func (rule iptRule) program(t *iptables.Transaction, insert bool) {
+	action := iptables.Delete
+	if insert {
+		action = iptables.Insert
+	}
+	t.ProgramRule(rule.table, rule.chain, action, rule.args)
+}
 
+func programChainRule(rule iptRule, ruleDescr string, insert bool) error {
+	operation := "disable"
 	if insert {
-		condition = !doesExist
-		prefix = []string{"-I", rule.chain}
 		operation = "enable"
-	} else {
-		condition = doesExist
-		prefix = []string{"-D", rule.chain}
-		operation = "disable"
-	}
-	if rule.preArgs != nil {
-		prefix = append(rule.preArgs, prefix...)
 	}
 
-	if condition {
-		if err := iptables.RawCombinedOutput(append(prefix, rule.args...)...); err != nil {
-			return fmt.Errorf("Unable to %s %s rule: %s", operation, ruleDescr, err.Error())
-		}
+	t := iptables.NewTransaction()
+	rule.program(t, insert)
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("Unable to %s %s rule: %s", operation, ruleDescr, err.Error())
 	}
 
 	return nil
 }
 
-func setIcc(bridgeIface string, iccEnable, insert bool) error {
+func setIcc(t *iptables.Transaction, bridgeIface string, iccEnable, insert bool) {
 	var (
 		table      = iptables.Filter
 		chain      = "FORWARD"
@@ -218,67 +250,37 @@ func setIcc(bridgeIface string, iccEnable, insert bool) error {
 
 	if insert {
 		if !iccEnable {
-			iptables.Raw(append([]string{"-D", chain}, acceptArgs...)...)
-
-			if !iptables.Exists(table, chain, dropArgs...) {
-				if err := iptables.RawCombinedOutput(append([]string{"-A", chain}, dropArgs...)...); err != nil {
-					return fmt.Errorf("Unable to prevent intercontainer communication: %s", err.Error())
-				}
-			}
+			t.ProgramRule(table, chain, iptables.Delete, acceptArgs)
+			t.ProgramRule(table, chain, iptables.Append, dropArgs)
 		} else {
-			iptables.Raw(append([]string{"-D", chain}, dropArgs...)...)
-
-			if !iptables.Exists(table, chain, acceptArgs...) {
-				if err := iptables.RawCombinedOutput(append([]string{"-I", chain}, acceptArgs...)...); err != nil {
-					return fmt.Errorf("Unable to allow intercontainer communication: %s", err.Error())
-				}
-			}
+			t.ProgramRule(table, chain, iptables.Delete, dropArgs)
+			t.ProgramRule(table, chain, iptables.Insert, acceptArgs)
 		}
 	} else {
 		// Remove any ICC rule.
 		if !iccEnable {
-			if iptables.Exists(table, chain, dropArgs...) {
-				iptables.Raw(append([]string{"-D", chain}, dropArgs...)...)
-			}
+			t.ProgramRule(table, chain, iptables.Delete, dropArgs)
 		} else {
-			if iptables.Exists(table, chain, acceptArgs...) {
-				iptables.Raw(append([]string{"-D", chain}, acceptArgs...)...)
-			}
+			t.ProgramRule(table, chain, iptables.Delete, acceptArgs)
 		}
 	}
-
-	return nil
 }
 
 // Control Inter Network Communication. Install/remove only if it is not/is present.
-func setINC(iface1, iface2 string, enable bool) error {
+func setINC(t *iptables.Transaction, iface1, iface2 string, enable bool) {
 	var (
-		table = iptables.Filter
-		chain = IsolationChain
-		args  = [2][]string{{"-i", iface1, "-o", iface2, "-j", "DROP"}, {"-i", iface2, "-o", iface1, "-j", "DROP"}}
+		table  = iptables.Filter
+		chain  = IsolationChain
+		args   = [2][]string{{"-i", iface1, "-o", iface2, "-j", "DROP"}, {"-i", iface2, "-o", iface1, "-j", "DROP"}}
+		action = iptables.Delete
 	)
 
 	if enable {
-		for i := 0; i < 2; i++ {
-			if iptables.Exists(table, chain, args[i]...) {
-				continue
-			}
-			if err := iptables.RawCombinedOutput(append([]string{"-I", chain}, args[i]...)...); err != nil {
-				return fmt.Errorf("unable to add inter-network communication rule: %v", err)
-			}
-		}
-	} else {
-		for i := 0; i < 2; i++ {
-			if !iptables.Exists(table, chain, args[i]...) {
-				continue
-			}
-			if err := iptables.RawCombinedOutput(append([]string{"-D", chain}, args[i]...)...); err != nil {
-				return fmt.Errorf("unable to remove inter-network communication rule: %v", err)
-			}
-		}
+		action = iptables.Insert
+	}
+	for i := 0; i < 2; i++ {
+		t.ProgramRule(table, chain, action, args[i])
 	}
-
-	return nil
 }
 
 func addReturnRule(chain string) error {
@@ -326,6 +328,9 @@ func removeIPChains() {
 		{Name: DockerChain, Table: iptables.Nat},
 		{Name: DockerChain, Table: iptables.Filter},
 		{Name: IsolationChain, Table: iptables.Filter},
+		{Name: ConnectivityChain, Table: iptables.Filter},
+		{Name: PolicyIngressChain, Table: iptables.Filter},
+		{Name: PolicyEgressChain, Table: iptables.Filter},
 	} {
 		if err := chainInfo.Remove(); err != nil {
 			logrus.Warnf("Failed to remove existing iptables entries in table %s chain %s : %v", chainInfo.Table, chainInfo.Name, err)
@@ -338,15 +343,18 @@ func setupInternalNetworkRules(bridgeIface string, addr net.Addr, icc, insert bo
 		inDropRule  = iptRule{table: iptables.Filter, chain: IsolationChain, args: []string{"-i", bridgeIface, "!", "-d", addr.String(), "-j", "DROP"}}
 		outDropRule = iptRule{table: iptables.Filter, chain: IsolationChain, args: []string{"-o", bridgeIface, "!", "-s", addr.String(), "-j", "DROP"}}
 	)
-	if err := programChainRule(inDropRule, "DROP INCOMING", insert); err != nil {
-		return err
-	}
-	if err := programChainRule(outDropRule, "DROP OUTGOING", insert); err != nil {
-		return err
+	operation := "disable"
+	if insert {
+		operation = "enable"
 	}
+
+	t := iptables.NewTransaction()
+	inDropRule.program(t, insert)
+	outDropRule.program(t, insert)
 	// Set Inter Container Communication.
-	if err := setIcc(bridgeIface, icc, insert); err != nil {
-		return err
+	setIcc(t, bridgeIface, icc, insert)
+	if err := t.Commit(); err != nil {
+		return fmt.Errorf("Unable to %s iptables rules of internal bridge %s: %s", operation, bridgeIface, err.Error())
 	}
 	return nil
 }
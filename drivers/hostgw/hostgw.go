@@ -0,0 +1,114 @@
+// Package hostgw implements a multi-host driver routing the traffic of the
+// containers between hosts sharing a L2 segment, without encapsulation. Each
+// host is assigned a slice of the network subnets for its containers and the
+// hosts program kernel routes to the slices of the other hosts.
+package hostgw
+
+//go:generate protoc -I.:../../Godeps/_workspace/src/github.com/gogo/protobuf  --gogo_out=import_path=github.com/docker/libnetwork/drivers/hostgw,Mgogoproto/gogo.proto=github.com/gogo/protobuf/gogoproto:. hostgw.proto
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/docker/libnetwork/datastore"
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/types"
+)
+
+const (
+	networkType         = "hostgw"
+	vethPrefix          = "veth"
+	vethLen             = 7
+	containerVethPrefix = "eth"
+	peerTable           = "hostgw_peer_table"
+	slicePrefixOpt      = "slice_prefix" // prefix length of the host slices -o slice_prefix
+)
+
+type driver struct {
+	advertiseAddress string
+	networks         networkTable
+	sync.Mutex
+}
+
+// Init registers a new instance of hostgw driver
+func Init(dc driverapi.DriverCallback, config map[string]interface{}) error {
+	c := driverapi.Capability{
+		DataScope:     datastore.GlobalScope,
+		AllocatesIPv4: true,
+	}
+	d := &driver{
+		networks: networkTable{},
+	}
+
+	return dc.RegisterDriver(networkType, d, c)
+}
+
+func (d *driver) NetworkAllocate(id string, option map[string]string, ipV4Data, ipV6Data []driverapi.IPAMData) (map[string]string, error) {
+	return nil, types.NotImplementedErrorf("not implemented")
+}
+
+func (d *driver) NetworkFree(id string) error {
+	return types.NotImplementedErrorf("not implemented")
+}
+
+func (d *driver) Type() string {
+	return networkType
+}
+
+func (d *driver) ProgramExternalConnectivity(nid, eid string, options map[string]interface{}) error {
+	return nil
+}
+
+func (d *driver) RevokeExternalConnectivity(nid, eid string) error {
+	return nil
+}
+
+// hostIP returns the address of the host the other hosts route the traffic
+// to the containers through
+func (d *driver) hostIP() (net.IP, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	ip := net.ParseIP(d.advertiseAddress)
+	if ip == nil || ip.To4() == nil {
+		return nil, fmt.Errorf("hostgw driver requires the IPv4 advertise address of the host, got %q", d.advertiseAddress)
+	}
+	return ip.To4(), nil
+}
+
+// DiscoverNew is a notification for a new discovery event, such as a new node joining a cluster
+func (d *driver) DiscoverNew(dType discoverapi.DiscoveryType, data interface{}) error {
+	if dType != discoverapi.NodeDiscovery {
+		return nil
+	}
+
+	nodeData, ok := data.(discoverapi.NodeDiscoveryData)
+	if !ok || nodeData.Address == "" {
+		return fmt.Errorf("invalid discovery data")
+	}
+	if nodeData.Self {
+		d.Lock()
+		d.advertiseAddress = nodeData.Address
+		d.Unlock()
+	}
+	return nil
+}
+
+// DiscoverDelete is a notification for a discovery delete event, such as a node leaving a cluster
+func (d *driver) DiscoverDelete(dType discoverapi.DiscoveryType, data interface{}) error {
+	return nil
+}
+
+func validateID(nid, eid string) error {
+	if nid == "" {
+		return fmt.Errorf("invalid network id")
+	}
+
+	if eid == "" {
+		return fmt.Errorf("invalid endpoint id")
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package hostgw
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/osl"
+)
+
+type endpointTable map[string]*endpoint
+
+type endpoint struct {
+	id     string
+	nid    string
+	ifName string
+	mac    net.HardwareAddr
+	addr   *net.IPNet
+}
+
+func (n *network) endpoint(eid string) *endpoint {
+	n.Lock()
+	defer n.Unlock()
+
+	return n.endpoints[eid]
+}
+
+// CreateEndpoint assigns an address of the host slice to the endpoint
+// unless it was given one
+func (d *driver) CreateEndpoint(nid, eid string, ifInfo driverapi.InterfaceInfo,
+	epOptions map[string]interface{}) error {
+	if err := validateID(nid, eid); err != nil {
+		return err
+	}
+
+	n := d.network(nid)
+	if n == nil {
+		return fmt.Errorf("network id %q not found", nid)
+	}
+
+	host, err := d.hostIP()
+	if err != nil {
+		return err
+	}
+
+	ep := &endpoint{
+		id:   eid,
+		nid:  nid,
+		addr: ifInfo.Address(),
+		mac:  ifInfo.MacAddress(),
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	if ep.addr == nil {
+		if ep.addr, err = n.allocateAddress(host); err != nil {
+			return err
+		}
+		if err := ifInfo.SetIPAddress(ep.addr); err != nil {
+			n.releaseSlice(n.getSubnetforIP(ep.addr.IP))
+			return err
+		}
+	} else if n.getSubnetforIP(ep.addr.IP) == nil {
+		return fmt.Errorf("no matching subnet for IP %q in network %q", ep.addr, nid)
+	}
+
+	if ep.mac == nil {
+		ep.mac = netutils.GenerateMACFromIP(ep.addr.IP)
+		if err := ifInfo.SetMacAddress(ep.mac); err != nil {
+			n.releaseSlice(n.getSubnetforIP(ep.addr.IP))
+			return err
+		}
+	}
+
+	n.endpoints[eid] = ep
+
+	return nil
+}
+
+func (d *driver) DeleteEndpoint(nid, eid string) error {
+	defer osl.InitOSContext()()
+
+	if err := validateID(nid, eid); err != nil {
+		return err
+	}
+
+	n := d.network(nid)
+	if n == nil {
+		return fmt.Errorf("network id %q not found", nid)
+	}
+
+	n.Lock()
+	ep, ok := n.endpoints[eid]
+	if !ok {
+		n.Unlock()
+		return fmt.Errorf("endpoint id %q not found", eid)
+	}
+	delete(n.endpoints, eid)
+
+	// The routes to the peers in the slice left by the host are
+	// not contested any more
+	if s := n.getSubnetforIP(ep.addr.IP); s != nil && s.slice != nil {
+		n.releaseSlice(s)
+		if s.slice == nil {
+			if err := n.programRoutes(); err != nil {
+				logrus.Warnf("Failed to update the routes of hostgw network %s: %v", nid, err)
+			}
+		}
+	}
+	n.Unlock()
+
+	if ep.ifName == "" {
+		return nil
+	}
+
+	nlh := ns.NlHandle()
+	if link, err := nlh.LinkByName(ep.ifName); err == nil {
+		if err := nlh.LinkDel(link); err != nil {
+			logrus.Warnf("Failed to delete interface (%s)'s link on endpoint (%s) delete: %v", ep.ifName, ep.id, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *driver) EndpointOperInfo(nid, eid string) (map[string]interface{}, error) {
+	return make(map[string]interface{}, 0), nil
+}
@@ -0,0 +1,274 @@
+package hostgw
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/osl"
+	"github.com/docker/libnetwork/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/vishvananda/netlink"
+)
+
+const ipv4ForwardConf = "/proc/sys/net/ipv4/ip_forward"
+
+// Join method is invoked when a Sandbox is attached to an endpoint.
+func (d *driver) Join(nid, eid string, sboxKey string, jinfo driverapi.JoinInfo, options map[string]interface{}) error {
+	defer osl.InitOSContext()()
+
+	if err := validateID(nid, eid); err != nil {
+		return err
+	}
+
+	n := d.network(nid)
+	if n == nil {
+		return fmt.Errorf("could not find network with id %s", nid)
+	}
+
+	ep := n.endpoint(eid)
+	if ep == nil {
+		return fmt.Errorf("could not find endpoint with id %s", eid)
+	}
+
+	host, err := d.hostIP()
+	if err != nil {
+		return err
+	}
+
+	// The host routes the traffic between its containers and the
+	// containers of the other hosts
+	if err := setupIPForwarding(); err != nil {
+		return err
+	}
+
+	hostIfName, containerIfName, err := createVethPair(ep.mac)
+	if err != nil {
+		return err
+	}
+
+	if err := setupHostVeth(hostIfName, ep.addr.IP); err != nil {
+		deleteInterface(hostIfName)
+		return err
+	}
+
+	n.Lock()
+	ep.ifName = hostIfName
+	var slice string
+	if s := n.getSubnetforIP(ep.addr.IP); s != nil && s.slice != nil {
+		slice = s.slice.String()
+	}
+	n.Unlock()
+
+	if iNames := jinfo.InterfaceName(); iNames != nil {
+		if err := iNames.SetNames(containerIfName, containerVethPrefix); err != nil {
+			return err
+		}
+	}
+
+	buf, err := proto.Marshal(&PeerRecord{
+		EndpointIP: ep.addr.String(),
+		HostIP:     host.String(),
+		HostSubnet: slice,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := jinfo.AddTableEntry(peerTable, eid, buf); err != nil {
+		logrus.Errorf("hostgw: Failed adding table entry to joininfo: %v", err)
+	}
+
+	return nil
+}
+
+// Leave method is invoked when a Sandbox detaches from an endpoint.
+func (d *driver) Leave(nid, eid string) error {
+	defer osl.InitOSContext()()
+
+	if err := validateID(nid, eid); err != nil {
+		return err
+	}
+
+	n := d.network(nid)
+	if n == nil {
+		return fmt.Errorf("could not find network with id %s", nid)
+	}
+
+	ep := n.endpoint(eid)
+	if ep == nil {
+		return types.InternalMaskableErrorf("could not find endpoint with id %s", eid)
+	}
+
+	nlh := ns.NlHandle()
+	link, err := nlh.LinkByName(ep.ifName)
+	if err != nil {
+		return nil
+	}
+	r := &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Dst:       &net.IPNet{IP: ep.addr.IP, Mask: net.CIDRMask(32, 32)},
+		Scope:     netlink.SCOPE_LINK,
+	}
+	if err := nlh.RouteDel(r); err != nil {
+		logrus.Debugf("Failed to remove route to endpoint %s: %v", ep.addr.IP, err)
+	}
+
+	return nil
+}
+
+func (d *driver) EventNotify(etype driverapi.EventType, nid, tableName, key string, value []byte) {
+	if tableName != peerTable {
+		logrus.Errorf("Unexpected table notification for table %s received", tableName)
+		return
+	}
+
+	eid := key
+
+	var peer PeerRecord
+	if err := proto.Unmarshal(value, &peer); err != nil {
+		logrus.Errorf("Failed to unmarshal peer record: %v", err)
+		return
+	}
+
+	// Ignore local peers, the route to them goes through their veth
+	d.Lock()
+	self := d.advertiseAddress
+	d.Unlock()
+	if peer.HostIP == self {
+		return
+	}
+
+	if etype == driverapi.Delete {
+		if err := d.peerDelete(nid, eid); err != nil {
+			logrus.Warnf("hostgw: %v", err)
+		}
+		return
+	}
+
+	addr, err := types.ParseCIDR(peer.EndpointIP)
+	if err != nil || addr.IP.To4() == nil {
+		logrus.Errorf("Invalid peer IP %s received in event notify", peer.EndpointIP)
+		return
+	}
+
+	hostIP := net.ParseIP(peer.HostIP)
+	if hostIP == nil || hostIP.To4() == nil {
+		logrus.Errorf("Invalid host IP %s received in event notify", peer.HostIP)
+		return
+	}
+
+	var slice *net.IPNet
+	if peer.HostSubnet != "" {
+		if slice, err = types.ParseCIDR(peer.HostSubnet); err != nil {
+			logrus.Errorf("Invalid host subnet %s received in event notify", peer.HostSubnet)
+			return
+		}
+	}
+
+	if err := d.peerAdd(nid, eid, addr.IP.To4(), hostIP.To4(), slice); err != nil {
+		logrus.Warnf("hostgw: %v", err)
+	}
+}
+
+func createVethPair(mac net.HardwareAddr) (string, string, error) {
+	nlh := ns.NlHandle()
+
+	// Generate a name for what will be the host side pipe interface
+	name1, err := netutils.GenerateIfaceName(nlh, vethPrefix, vethLen)
+	if err != nil {
+		return "", "", fmt.Errorf("error generating veth name1: %v", err)
+	}
+
+	// Generate a name for what will be the sandbox side pipe interface
+	name2, err := netutils.GenerateIfaceName(nlh, vethPrefix, vethLen)
+	if err != nil {
+		return "", "", fmt.Errorf("error generating veth name2: %v", err)
+	}
+
+	// Generate and add the interface pipe host <-> sandbox
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: name1, TxQLen: 0},
+		PeerName:  name2}
+	if err := nlh.LinkAdd(veth); err != nil {
+		return "", "", fmt.Errorf("error creating veth pair: %v", err)
+	}
+
+	sbox, err := nlh.LinkByName(name2)
+	if err != nil {
+		deleteInterface(name1)
+		return "", "", fmt.Errorf("could not find link by name %s: %v", name2, err)
+	}
+	if err := nlh.LinkSetHardwareAddr(sbox, mac); err != nil {
+		deleteInterface(name1)
+		return "", "", fmt.Errorf("could not set mac address (%v) to the container interface: %v", mac, err)
+	}
+
+	return name1, name2, nil
+}
+
+// setupHostVeth brings up the host side of the endpoint veth pair, routes
+// the endpoint address to it and answers the ARP requests of the container
+// for the other addresses of the network
+func setupHostVeth(name string, ip net.IP) error {
+	nlh := ns.NlHandle()
+
+	link, err := nlh.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("could not find link by name %s: %v", name, err)
+	}
+
+	path := fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/proxy_arp", name)
+	if err := ioutil.WriteFile(path, []byte{'1', '\n'}, 0644); err != nil {
+		return fmt.Errorf("could not enable proxy arp on %s: %v", name, err)
+	}
+
+	if err := nlh.LinkSetUp(link); err != nil {
+		return fmt.Errorf("could not bring up %s: %v", name, err)
+	}
+
+	r := &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Dst:       &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)},
+		Scope:     netlink.SCOPE_LINK,
+	}
+	if err := nlh.RouteAdd(r); err != nil {
+		return fmt.Errorf("could not add route to %s: %v", ip, err)
+	}
+
+	return nil
+}
+
+func setupIPForwarding() error {
+	data, err := ioutil.ReadFile(ipv4ForwardConf)
+	if err != nil {
+		return fmt.Errorf("Cannot read IP forwarding setup: %v", err)
+	}
+
+	if len(data) > 0 && data[0] != '1' {
+		if err := ioutil.WriteFile(ipv4ForwardConf, []byte{'1', '\n'}, 0644); err != nil {
+			return fmt.Errorf("Setup IP forwarding failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func deleteInterface(name string) error {
+	nlh := ns.NlHandle()
+
+	link, err := nlh.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("failed to find interface with name %s: %v", name, err)
+	}
+
+	if err := nlh.LinkDel(link); err != nil {
+		return fmt.Errorf("error deleting interface with name %s: %v", name, err)
+	}
+
+	return nil
+}
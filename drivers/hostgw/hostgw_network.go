@@ -0,0 +1,257 @@
+package hostgw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/osl"
+	"github.com/docker/libnetwork/types"
+)
+
+const (
+	// The slices default to 256 addresses out of the larger subnets
+	defaultSliceBits = 8
+	maxSlicePrefix   = 28
+)
+
+type networkTable map[string]*network
+
+type subnet struct {
+	subnetIP *net.IPNet
+	gwIP     *net.IPNet
+	// slice is the part of the subnet assigned to this host, nil
+	// until a local endpoint needs an address out of it
+	slice *net.IPNet
+}
+
+type network struct {
+	id          string
+	driver      *driver
+	subnets     []*subnet
+	slicePrefix int
+	endpoints   endpointTable
+	peers       peerMap
+	// routes are the routes to the peers programmed on the host,
+	// by destination
+	routes map[string]*route
+	sync.Mutex
+}
+
+func (d *driver) CreateNetwork(id string, option map[string]interface{}, nInfo driverapi.NetworkInfo, ipV4Data, ipV6Data []driverapi.IPAMData) error {
+	if id == "" {
+		return fmt.Errorf("invalid network id")
+	}
+	if len(ipV4Data) == 0 || ipV4Data[0].Pool.String() == "0.0.0.0/0" {
+		return types.BadRequestErrorf("ipv4 pool is empty")
+	}
+	if len(ipV6Data) > 0 {
+		return types.BadRequestErrorf("ipv6 is not supported by the %s driver", networkType)
+	}
+
+	n := &network{
+		id:        id,
+		driver:    d,
+		endpoints: endpointTable{},
+		peers:     peerMap{},
+		routes:    map[string]*route{},
+	}
+
+	slicePrefix := 0
+	if gval, ok := option[netlabel.GenericData]; ok {
+		optMap := gval.(map[string]string)
+		if val, ok := optMap[slicePrefixOpt]; ok {
+			var err error
+			if slicePrefix, err = strconv.Atoi(val); err != nil {
+				return types.BadRequestErrorf("invalid slice prefix %q: %v", val, err)
+			}
+		}
+	}
+
+	for _, ipd := range ipV4Data {
+		ones, _ := ipd.Pool.Mask.Size()
+		prefix := slicePrefix
+		if prefix == 0 {
+			prefix = ones + defaultSliceBits
+			if prefix > maxSlicePrefix {
+				prefix = maxSlicePrefix
+			}
+		}
+		if prefix <= ones || prefix > 30 {
+			return types.BadRequestErrorf("slice prefix /%d is not valid for subnet %s", prefix, ipd.Pool)
+		}
+		if n.slicePrefix != 0 && n.slicePrefix != prefix {
+			return types.BadRequestErrorf("subnets of different sizes need the slice prefix to be set")
+		}
+		n.slicePrefix = prefix
+		n.subnets = append(n.subnets, &subnet{
+			subnetIP: ipd.Pool,
+			gwIP:     ipd.Gateway,
+		})
+	}
+
+	if err := nInfo.TableEventRegister(peerTable); err != nil {
+		return err
+	}
+
+	d.addNetwork(n)
+	return nil
+}
+
+func (d *driver) DeleteNetwork(nid string) error {
+	defer osl.InitOSContext()()
+
+	if nid == "" {
+		return fmt.Errorf("invalid network id")
+	}
+
+	n := d.network(nid)
+	if n == nil {
+		return fmt.Errorf("could not find network with id %s", nid)
+	}
+
+	d.deleteNetwork(nid)
+
+	n.Lock()
+	defer n.Unlock()
+
+	for dst, r := range n.routes {
+		if err := r.delete(); err != nil {
+			logrus.Warnf("Failed to remove route to %s via %s: %v", r.dst, r.gw, err)
+		}
+		delete(n.routes, dst)
+	}
+
+	return nil
+}
+
+func (d *driver) addNetwork(n *network) {
+	d.Lock()
+	d.networks[n.id] = n
+	d.Unlock()
+}
+
+func (d *driver) deleteNetwork(nid string) {
+	d.Lock()
+	delete(d.networks, nid)
+	d.Unlock()
+}
+
+func (d *driver) network(nid string) *network {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.networks[nid]
+}
+
+func (n *network) getSubnetforIP(ip net.IP) *subnet {
+	for _, s := range n.subnets {
+		if s.subnetIP.Contains(ip) {
+			return s
+		}
+	}
+	return nil
+}
+
+// obtainSlice returns the slice of the subnet assigned to the host, if
+// needed it picks one not used by the other hosts starting from a position
+// derived from the host address. To be called while holding network lock.
+func (n *network) obtainSlice(s *subnet, host net.IP) (*net.IPNet, error) {
+	if s.slice != nil {
+		return s.slice, nil
+	}
+
+	ones, bits := s.subnetIP.Mask.Size()
+	count := uint32(1) << uint(n.slicePrefix-ones)
+
+	claimed := map[string]bool{}
+	for _, p := range n.peers {
+		if p.slice != nil {
+			claimed[p.slice.String()] = true
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write(host)
+	start := h.Sum32() % count
+	base := ipToUint32(s.subnetIP.IP)
+	for i := uint32(0); i < count; i++ {
+		slice := &net.IPNet{
+			IP:   uint32ToIP(base + ((start+i)%count)<<uint(bits-n.slicePrefix)),
+			Mask: net.CIDRMask(n.slicePrefix, bits),
+		}
+		if !claimed[slice.String()] {
+			s.slice = slice
+			return slice, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no slice of subnet %s left for the host", s.subnetIP)
+}
+
+// releaseSlice releases the slice of the subnet once no local endpoint
+// uses it. To be called while holding network lock.
+func (n *network) releaseSlice(s *subnet) {
+	if s.slice == nil {
+		return
+	}
+	for _, ep := range n.endpoints {
+		if s.slice.Contains(ep.addr.IP) {
+			return
+		}
+	}
+	s.slice = nil
+}
+
+// allocateAddress returns an address of the host slice of the first subnet
+// having one available. To be called while holding network lock.
+func (n *network) allocateAddress(host net.IP) (*net.IPNet, error) {
+	used := map[string]bool{}
+	for _, ep := range n.endpoints {
+		used[ep.addr.IP.String()] = true
+	}
+	for _, p := range n.peers {
+		used[p.addr.String()] = true
+	}
+
+	for _, s := range n.subnets {
+		slice, err := n.obtainSlice(s, host)
+		if err != nil {
+			logrus.Debugf("hostgw network %s: %v", n.id, err)
+			continue
+		}
+
+		ones, bits := slice.Mask.Size()
+		first := ipToUint32(slice.IP)
+		last := first + uint32(1)<<uint(bits-ones) - 1
+		network := ipToUint32(s.subnetIP.IP)
+		broadcast := network | ^binary.BigEndian.Uint32(net.IP(s.subnetIP.Mask).To4())
+		for a := first; a <= last; a++ {
+			ip := uint32ToIP(a)
+			if a == network || a == broadcast || used[ip.String()] ||
+				(s.gwIP != nil && s.gwIP.IP.Equal(ip)) {
+				continue
+			}
+			return &net.IPNet{IP: ip, Mask: s.subnetIP.Mask}, nil
+		}
+		n.releaseSlice(s)
+	}
+
+	return nil, fmt.Errorf("no available address in the host slices of network %s", n.id)
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(v uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, v)
+	return ip
+}
@@ -0,0 +1,437 @@
+package hostgw
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/docker/libnetwork/types"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+type testNetworkInfo struct {
+	tables []string
+}
+
+func (ni *testNetworkInfo) TableEventRegister(tableName string) error {
+	ni.tables = append(ni.tables, tableName)
+	return nil
+}
+
+func (ni *testNetworkInfo) UpdateTableEntry(tableName, key string, value []byte) error {
+	return nil
+}
+
+type testEndpoint struct {
+	mac     net.HardwareAddr
+	addr    *net.IPNet
+	srcName string
+	entries map[string][]byte
+}
+
+func (te *testEndpoint) SetMacAddress(mac net.HardwareAddr) error {
+	te.mac = mac
+	return nil
+}
+
+func (te *testEndpoint) SetIPAddress(address *net.IPNet) error {
+	te.addr = address
+	return nil
+}
+
+func (te *testEndpoint) MacAddress() net.HardwareAddr {
+	return te.mac
+}
+
+func (te *testEndpoint) Address() *net.IPNet {
+	return te.addr
+}
+
+func (te *testEndpoint) AddressIPv6() *net.IPNet {
+	return nil
+}
+
+func (te *testEndpoint) InterfaceName() driverapi.InterfaceNameInfo {
+	return te
+}
+
+func (te *testEndpoint) SetNames(srcName, dstPrefix string) error {
+	te.srcName = srcName
+	return nil
+}
+
+func (te *testEndpoint) SetGateway(net.IP) error {
+	return nil
+}
+
+func (te *testEndpoint) SetGatewayIPv6(net.IP) error {
+	return nil
+}
+
+func (te *testEndpoint) AddStaticRoute(destination *net.IPNet, routeType int, nextHop net.IP) error {
+	return nil
+}
+
+func (te *testEndpoint) DisableGatewayService() {}
+
+func (te *testEndpoint) SetQosPolicy(qos *types.QosPolicy) error {
+	return nil
+}
+
+func (te *testEndpoint) AddTableEntry(tableName, key string, value []byte) error {
+	if te.entries == nil {
+		te.entries = map[string][]byte{}
+	}
+	te.entries[tableName+"/"+key] = value
+	return nil
+}
+
+func testNetwork(t *testing.T, d *driver, nid, subnet string, opts map[string]string) *network {
+	_, pool, _ := net.ParseCIDR(subnet)
+	gw := uint32ToIP(ipToUint32(pool.IP) + 1)
+	ipd := []driverapi.IPAMData{{
+		Pool:    pool,
+		Gateway: &net.IPNet{IP: gw, Mask: pool.Mask},
+	}}
+	ni := &testNetworkInfo{}
+	option := map[string]interface{}{netlabel.GenericData: opts}
+	if err := d.CreateNetwork(nid, option, ni, ipd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ni.tables) != 1 || ni.tables[0] != peerTable {
+		t.Fatalf("expected the driver to register for the peer table, got %v", ni.tables)
+	}
+	return d.network(nid)
+}
+
+func TestCreateNetworkSlicePrefix(t *testing.T) {
+	d := &driver{networks: networkTable{}}
+
+	n := testNetwork(t, d, "net1", "10.20.0.0/16", map[string]string{})
+	if n.slicePrefix != 24 {
+		t.Fatalf("expected /24 slices by default, got /%d", n.slicePrefix)
+	}
+
+	n = testNetwork(t, d, "net2", "10.20.0.0/16", map[string]string{slicePrefixOpt: "20"})
+	if n.slicePrefix != 20 {
+		t.Fatalf("expected /20 slices, got /%d", n.slicePrefix)
+	}
+
+	_, pool, _ := net.ParseCIDR("10.20.0.0/16")
+	ipd := []driverapi.IPAMData{{Pool: pool}}
+	for _, v := range []string{"16", "31", "big"} {
+		option := map[string]interface{}{netlabel.GenericData: map[string]string{slicePrefixOpt: v}}
+		if err := d.CreateNetwork("net3", option, &testNetworkInfo{}, ipd, nil); err == nil {
+			t.Fatalf("expected failure for slice prefix %s", v)
+		}
+	}
+}
+
+func TestAllocateAddress(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	d := &driver{networks: networkTable{}, advertiseAddress: "192.168.61.1"}
+	n := testNetwork(t, d, "net1", "10.20.0.0/24", map[string]string{slicePrefixOpt: "30"})
+
+	// The routes to the peers are programmed once the host slice is released
+	underlay := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "hgtest0", TxQLen: 0}, PeerName: "hgtest1"}
+	if err := netlink.LinkAdd(underlay); err != nil {
+		t.Fatal(err)
+	}
+	addr := &netlink.Addr{IPNet: &net.IPNet{IP: net.ParseIP("192.168.61.1"), Mask: net.CIDRMask(24, 32)}}
+	if err := netlink.AddrAdd(underlay, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.LinkSetUp(underlay); err != nil {
+		t.Fatal(err)
+	}
+
+	// Claim all the slices but the first one, holding the gateway
+	for a := ipToUint32(net.ParseIP("10.20.0.4")); a < ipToUint32(net.ParseIP("10.20.1.0")); a += 4 {
+		slice := &net.IPNet{IP: uint32ToIP(a), Mask: net.CIDRMask(30, 32)}
+		n.peers[slice.String()] = &peer{addr: uint32ToIP(a + 1), host: net.ParseIP("192.168.61.2"), slice: slice}
+	}
+
+	var addrs []string
+	for _, eid := range []string{"ep1", "ep2", "ep3"} {
+		te := &testEndpoint{}
+		err := d.CreateEndpoint("net1", eid, te, nil)
+		if eid == "ep3" {
+			if err == nil {
+				t.Fatal("expected failure once the host slice is exhausted")
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if te.mac == nil {
+			t.Fatal("expected the endpoint mac address to be set")
+		}
+		addrs = append(addrs, te.addr.String())
+	}
+
+	// The network address and the gateway are skipped
+	if len(addrs) != 2 || addrs[0] != "10.20.0.2/24" || addrs[1] != "10.20.0.3/24" {
+		t.Fatalf("unexpected addresses %v", addrs)
+	}
+	if n.subnets[0].slice.String() != "10.20.0.0/30" {
+		t.Fatalf("unexpected host slice %s", n.subnets[0].slice)
+	}
+
+	for _, eid := range []string{"ep1", "ep2"} {
+		if err := d.DeleteEndpoint("net1", eid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n.subnets[0].slice != nil {
+		t.Fatal("expected the host slice to be released with its last endpoint")
+	}
+	if len(n.routes) != len(n.peers) {
+		t.Fatalf("expected a route per peer slice, got %d", len(n.routes))
+	}
+}
+
+func TestPeerRoutes(t *testing.T) {
+	d := &driver{networks: networkTable{}}
+	n := testNetwork(t, d, "net1", "10.20.0.0/16", map[string]string{})
+
+	mustCIDR := func(s string) *net.IPNet {
+		_, c, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	h2 := net.ParseIP("192.168.61.2")
+	h3 := net.ParseIP("192.168.61.3")
+
+	n.subnets[0].slice = mustCIDR("10.20.1.0/24")
+	n.peers = peerMap{
+		// Routed through the slice of the host
+		"ep1": {addr: net.ParseIP("10.20.2.5"), host: h2, slice: mustCIDR("10.20.2.0/24")},
+		"ep2": {addr: net.ParseIP("10.20.2.6"), host: h2, slice: mustCIDR("10.20.2.0/24")},
+		// Out of the slice of its host
+		"ep3": {addr: net.ParseIP("10.20.9.9"), host: h2, slice: mustCIDR("10.20.2.0/24")},
+		// No slice assigned to the host
+		"ep4": {addr: net.ParseIP("10.20.10.1"), host: h3},
+		// Slice claimed by this host too
+		"ep5": {addr: net.ParseIP("10.20.1.7"), host: h3, slice: mustCIDR("10.20.1.0/24")},
+	}
+
+	routes := n.peerRoutes()
+	expected := map[string]net.IP{
+		"10.20.2.0/24":  h2,
+		"10.20.9.9/32":  h2,
+		"10.20.10.1/32": h3,
+		"10.20.1.7/32":  h3,
+	}
+	if len(routes) != len(expected) {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+	for dst, gw := range expected {
+		r, ok := routes[dst]
+		if !ok || !r.gw.Equal(gw) {
+			t.Fatalf("expected route to %s via %s, got %v", dst, gw, r)
+		}
+	}
+}
+
+// TestHostsNamespaces connects the containers of two hosts emulated by
+// network namespaces sharing a veth pair as L2 segment
+func TestHostsNamespaces(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	origin, err := netns.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		netns.Set(origin)
+		ns.Init()
+		origin.Close()
+	}()
+
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "hgtest0", TxQLen: 0}, PeerName: "hgtest1"}
+	if err := ns.NlHandle().LinkAdd(veth); err != nil {
+		t.Fatal(err)
+	}
+
+	type host struct {
+		ifName string
+		ip     net.IP
+		ns     netns.NsHandle
+		cns    netns.NsHandle
+		d      *driver
+		ep     *testEndpoint
+		eid    string
+	}
+	hosts := []*host{
+		{ifName: "hgtest0", ip: net.ParseIP("192.168.61.1"), eid: "ep1"},
+		{ifName: "hgtest1", ip: net.ParseIP("192.168.61.2"), eid: "ep2"},
+	}
+
+	newNs := func() netns.NsHandle {
+		h, err := netns.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := netns.Set(origin); err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	enter := func(h netns.NsHandle) {
+		if err := netns.Set(h); err != nil {
+			t.Fatal(err)
+		}
+		ns.Init()
+	}
+	moveLink := func(name string, to netns.NsHandle) {
+		link, err := ns.NlHandle().LinkByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ns.NlHandle().LinkSetNsFd(link, int(to)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	linkUp := func(name string, addr *net.IPNet) {
+		nlh := ns.NlHandle()
+		link, err := nlh.LinkByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := nlh.AddrAdd(link, &netlink.Addr{IPNet: addr}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nlh.LinkSetUp(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, h := range hosts {
+		h.ns = newNs()
+		defer h.ns.Close()
+		h.cns = newNs()
+		defer h.cns.Close()
+		moveLink(h.ifName, h.ns)
+	}
+
+	for _, h := range hosts {
+		enter(h.ns)
+		linkUp(h.ifName, &net.IPNet{IP: h.ip, Mask: net.CIDRMask(24, 32)})
+
+		h.d = &driver{networks: networkTable{}}
+		if err := h.d.DiscoverNew(discoverapi.NodeDiscovery, discoverapi.NodeDiscoveryData{Address: h.ip.String(), Self: true}); err != nil {
+			t.Fatal(err)
+		}
+		testNetwork(t, h.d, "net1", "10.20.0.0/16", map[string]string{})
+
+		h.ep = &testEndpoint{}
+		if err := h.d.CreateEndpoint("net1", h.eid, h.ep, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.d.Join("net1", h.eid, "", h.ep, nil); err != nil {
+			t.Fatal(err)
+		}
+		if h.ep.srcName == "" || len(h.ep.entries) != 1 {
+			t.Fatalf("unexpected join info %+v", h.ep)
+		}
+
+		// The sandbox of the container
+		moveLink(h.ep.srcName, h.cns)
+		enter(h.cns)
+		linkUp(h.ep.srcName, h.ep.addr)
+	}
+
+	slice0 := hosts[0].d.network("net1").subnets[0].slice
+	slice1 := hosts[1].d.network("net1").subnets[0].slice
+	if slice0 == nil || slice1 == nil || slice0.String() == slice1.String() {
+		t.Fatalf("expected distinct slices for the hosts, got %v and %v", slice0, slice1)
+	}
+	for i, h := range hosts {
+		if ![]*net.IPNet{slice0, slice1}[i].Contains(h.ep.addr.IP) {
+			t.Fatalf("endpoint address %s out of the host slice", h.ep.addr)
+		}
+	}
+
+	// Exchange the peer records as the networkdb would
+	notify := func(etype driverapi.EventType) {
+		for i, h := range hosts {
+			peer := hosts[1-i]
+			enter(h.ns)
+			h.d.EventNotify(etype, "net1", peerTable, peer.eid, peer.ep.entries[peerTable+"/"+peer.eid])
+		}
+	}
+	notify(driverapi.Create)
+
+	enter(hosts[0].ns)
+	routes, err := ns.NlHandle().RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, r := range routes {
+		if r.Dst != nil && r.Dst.String() == slice1.String() && r.Gw.Equal(hosts[1].ip) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a route to %s via %s, got %v", slice1, hosts[1].ip, routes)
+	}
+
+	send := func(from, to *host) {
+		enter(to.cns)
+		l, err := net.ListenUDP("udp4", &net.UDPAddr{IP: to.ep.addr.IP, Port: 5000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		enter(from.cns)
+		c, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: to.ep.addr.IP, Port: 5000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if _, err := c.Write([]byte("hostgw")); err != nil {
+			t.Fatal(err)
+		}
+
+		l.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 64)
+		_, src, err := l.ReadFromUDP(b)
+		if err != nil {
+			t.Fatalf("packet from %s to %s not received: %v", from.ep.addr.IP, to.ep.addr.IP, err)
+		}
+		if !src.IP.Equal(from.ep.addr.IP) {
+			t.Fatalf("unexpected packet source %s", src.IP)
+		}
+	}
+	send(hosts[0], hosts[1])
+	send(hosts[1], hosts[0])
+
+	notify(driverapi.Delete)
+	for _, h := range hosts {
+		enter(h.ns)
+		if n := h.d.network("net1"); len(n.routes) != 0 {
+			t.Fatalf("expected the routes to the peers to be removed, got %v", n.routes)
+		}
+		if err := h.d.Leave("net1", h.eid); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.d.DeleteEndpoint("net1", h.eid); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.d.DeleteNetwork("net1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
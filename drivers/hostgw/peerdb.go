@@ -0,0 +1,144 @@
+package hostgw
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/osl"
+	"github.com/vishvananda/netlink"
+)
+
+type peerMap map[string]*peer
+
+// peer is an endpoint of the network on another host
+type peer struct {
+	addr  net.IP
+	host  net.IP
+	slice *net.IPNet
+}
+
+type route struct {
+	dst *net.IPNet
+	gw  net.IP
+}
+
+func (r *route) add() error {
+	return ns.NlHandle().RouteAdd(&netlink.Route{Dst: r.dst, Gw: r.gw})
+}
+
+func (r *route) delete() error {
+	return ns.NlHandle().RouteDel(&netlink.Route{Dst: r.dst, Gw: r.gw})
+}
+
+func (d *driver) peerAdd(nid, eid string, peerIP, hostIP net.IP, hostSubnet *net.IPNet) error {
+	if err := validateID(nid, eid); err != nil {
+		return err
+	}
+
+	n := d.network(nid)
+	if n == nil {
+		return nil
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	n.peers[eid] = &peer{
+		addr:  peerIP,
+		host:  hostIP,
+		slice: hostSubnet,
+	}
+
+	return n.programRoutes()
+}
+
+func (d *driver) peerDelete(nid, eid string) error {
+	if err := validateID(nid, eid); err != nil {
+		return err
+	}
+
+	n := d.network(nid)
+	if n == nil {
+		return nil
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	if _, ok := n.peers[eid]; !ok {
+		return nil
+	}
+	delete(n.peers, eid)
+
+	return n.programRoutes()
+}
+
+// peerRoutes returns the routes to the peers. The slices are routed to the
+// host they are assigned to, the peers out of the slice of their host, or in
+// a slice claimed by several hosts, get a host route. To be called while
+// holding network lock.
+func (n *network) peerRoutes() map[string]*route {
+	owners := map[string]map[string]bool{}
+	claim := func(slice *net.IPNet, host string) {
+		if owners[slice.String()] == nil {
+			owners[slice.String()] = map[string]bool{}
+		}
+		owners[slice.String()][host] = true
+	}
+	for _, s := range n.subnets {
+		if s.slice != nil {
+			claim(s.slice, "")
+		}
+	}
+	for _, p := range n.peers {
+		if p.slice != nil {
+			claim(p.slice, p.host.String())
+		}
+	}
+
+	routes := map[string]*route{}
+	for _, p := range n.peers {
+		if p.slice != nil && p.slice.Contains(p.addr) && len(owners[p.slice.String()]) == 1 {
+			routes[p.slice.String()] = &route{dst: p.slice, gw: p.host}
+			continue
+		}
+		dst := &net.IPNet{IP: p.addr, Mask: net.CIDRMask(32, 32)}
+		routes[dst.String()] = &route{dst: dst, gw: p.host}
+	}
+
+	return routes
+}
+
+// programRoutes brings the routes programmed on the host in line with the
+// peers. To be called while holding network lock.
+func (n *network) programRoutes() error {
+	defer osl.InitOSContext()()
+
+	routes := n.peerRoutes()
+
+	for dst, r := range n.routes {
+		if nr, ok := routes[dst]; ok && nr.gw.Equal(r.gw) {
+			continue
+		}
+		if err := r.delete(); err != nil {
+			logrus.Warnf("Failed to remove route to %s via %s: %v", r.dst, r.gw, err)
+		}
+		delete(n.routes, dst)
+	}
+
+	var lastErr error
+	for dst, r := range routes {
+		if _, ok := n.routes[dst]; ok {
+			continue
+		}
+		if err := r.add(); err != nil {
+			lastErr = fmt.Errorf("could not add route to %s via %s: %v", r.dst, r.gw, err)
+			continue
+		}
+		n.routes[dst] = r
+	}
+
+	return lastErr
+}
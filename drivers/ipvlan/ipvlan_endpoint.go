@@ -51,6 +51,13 @@ func (d *driver) CreateEndpoint(nid, eid string, ifInfo driverapi.InterfaceInfo,
 			}
 		}
 	}
+	if opt, ok := epOptions[netlabel.QosPolicy]; ok {
+		qos, ok := opt.(types.QosPolicy)
+		if !ok {
+			return fmt.Errorf("invalid qos policy for %s endpoint: %v", ipvlanType, opt)
+		}
+		ep.qos = &qos
+	}
 
 	if err := d.storeUpdate(ep); err != nil {
 		return fmt.Errorf("failed to save ipvlan endpoint %s to store: %v", ep.id[0:7], err)
@@ -7,6 +7,7 @@ import (
 	"github.com/docker/libnetwork/datastore"
 	"github.com/docker/libnetwork/discoverapi"
 	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netlabel"
 	"github.com/docker/libnetwork/osl"
 	"github.com/docker/libnetwork/types"
 )
@@ -44,6 +45,7 @@ type endpoint struct {
 	addr     *net.IPNet
 	addrv6   *net.IPNet
 	srcName  string
+	qos      *types.QosPolicy
 	dbIndex  uint64
 	dbExists bool
 }
@@ -79,7 +81,17 @@ func (d *driver) NetworkFree(id string) error {
 }
 
 func (d *driver) EndpointOperInfo(nid, eid string) (map[string]interface{}, error) {
-	return make(map[string]interface{}, 0), nil
+	m := make(map[string]interface{}, 0)
+
+	n := d.network(nid)
+	if n == nil {
+		return m, nil
+	}
+	if ep := n.endpoint(eid); ep != nil && ep.qos != nil {
+		m[netlabel.QosPolicy] = *ep.qos
+	}
+
+	return m, nil
 }
 
 func (d *driver) Type() string {
@@ -77,6 +77,11 @@ func (d *driver) Join(nid, eid string, sboxKey string, jinfo driverapi.JoinInfo,
 	if err != nil {
 		return err
 	}
+	if ep.qos != nil {
+		if err = jinfo.SetQosPolicy(ep.qos); err != nil {
+			return err
+		}
+	}
 	if err := d.storeUpdate(ep); err != nil {
 		return fmt.Errorf("failed to save macvlan endpoint %s to store: %v", ep.id[0:7], err)
 	}
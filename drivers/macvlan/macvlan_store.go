@@ -264,6 +264,9 @@ func (ep *endpoint) MarshalJSON() ([]byte, error) {
 	if ep.addrv6 != nil {
 		epMap["Addrv6"] = ep.addrv6.String()
 	}
+	if ep.qos != nil {
+		epMap["QosPolicy"] = ep.qos
+	}
 	return json.Marshal(epMap)
 }
 
@@ -292,6 +295,13 @@ func (ep *endpoint) UnmarshalJSON(b []byte) error {
 			return types.InternalErrorf("failed to decode macvlan endpoint IPv6 address (%s) after json unmarshal: %v", v.(string), err)
 		}
 	}
+	if v, ok := epMap["QosPolicy"]; ok {
+		qb, _ := json.Marshal(v)
+		ep.qos = &types.QosPolicy{}
+		if err = json.Unmarshal(qb, ep.qos); err != nil {
+			return types.InternalErrorf("failed to decode %s endpoint qos policy after json unmarshal: %v", macvlanType, err)
+		}
+	}
 	ep.id = epMap["id"].(string)
 	ep.nid = epMap["nid"].(string)
 	ep.srcName = epMap["SrcName"].(string)
@@ -84,7 +84,8 @@ func (d *driver) checkEncryption(nid string, rIP net.IP, vxlanID uint32, isLocal
 		return nil
 	}
 
-	if len(d.keys) == 0 {
+	// The wireguard keys are generated by the nodes
+	if len(d.keys) == 0 && !n.wireguard {
 		return types.ForbiddenErrorf("encryption key is not present")
 	}
 
@@ -112,13 +113,25 @@ func (d *driver) checkEncryption(nid string, rIP net.IP, vxlanID uint32, isLocal
 
 	if add {
 		for _, rIP := range nodes {
-			if err := setupEncryption(lIP, aIP, rIP, vxlanID, d.secMap, d.keys); err != nil {
+			var err error
+			if n.wireguard {
+				err = d.setupWireGuard(aIP, rIP, vxlanID, n.maxMTU()+vxlanEncap)
+			} else {
+				err = setupEncryption(lIP, aIP, rIP, vxlanID, d.secMap, d.keys)
+			}
+			if err != nil {
 				log.Warnf("Failed to program network encryption between %s and %s: %v", lIP, rIP, err)
 			}
 		}
 	} else {
 		if len(nodes) == 0 {
-			if err := removeEncryption(lIP, rIP, d.secMap); err != nil {
+			var err error
+			if n.wireguard {
+				err = d.removeWireGuard(rIP)
+			} else {
+				err = removeEncryption(lIP, rIP, d.secMap)
+			}
+			if err != nil {
 				log.Warnf("Failed to remove network encryption between %s and %s: %v", lIP, rIP, err)
 			}
 		}
@@ -133,7 +146,7 @@ func setupEncryption(localIP, advIP, remoteIP net.IP, vni uint32, em *encrMap, k
 
 	indices := make([]*spi, 0, len(keys))
 
-	err := programMangle(vni, true)
+	err := programMangle(vni, mark, true)
 	if err != nil {
 		log.Warn(err)
 	}
@@ -192,7 +205,7 @@ func removeEncryption(localIP, remoteIP net.IP, em *encrMap) error {
 	return nil
 }
 
-func programMangle(vni uint32, add bool) (err error) {
+func programMangle(vni, mark uint32, add bool) (err error) {
 	var (
 		p      = strconv.FormatUint(uint64(vxlanPort), 10)
 		c      = fmt.Sprintf("0>>22&0x3C@12&0xFFFFFF00=%d", int(vni)<<8)
@@ -461,6 +474,11 @@ func (d *driver) updateKeys(newKey, primary, pruneKey *key) error {
 
 	log.Debugf("Updated: %v", d.keys)
 
+	// The wireguard key of the node follows the primary key
+	if priIdx > 0 {
+		return d.rotateWireGuard()
+	}
+
 	return nil
 }
 
@@ -548,13 +566,30 @@ func updateNodeKey(lIP, rIP net.IP, idxs []*spi, curKeys []*key, newIdx, priIdx,
 	return spis
 }
 
+// encryptionMark returns the mark of the vxlan traffic of the network
+// selecting how it is encrypted
+func (n *network) encryptionMark() uint32 {
+	if n.wireguard {
+		return wgMark
+	}
+	return mark
+}
+
 func (n *network) maxMTU() int {
 	mtu := 1500
 	if n.mtu != 0 {
 		mtu = n.mtu
 	}
-	mtu -= vxlanEncap
-	if n.secure {
+	if n.isGeneve() {
+		mtu -= geneveEncap + geneveOptionsLen(n.geneveOpts)
+	} else {
+		mtu -= vxlanEncap
+	}
+	if n.wireguard {
+		// Account for the wireguard encapsulation
+		// of the vxlan packets
+		mtu -= wgExpansion
+	} else if n.secure {
 		// In case of encryption account for the
 		// esp packet espansion and padding
 		mtu -= pktExpansion
@@ -0,0 +1,473 @@
+package overlay
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/osl"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// The kernel geneve interfaces have no forwarding database. A geneve network
+// has a point to point geneve interface per remote node attached to the subnet
+// bridge, the bridge forwards the frames to the node of the destination mac.
+const (
+	encapVxlan   = "vxlan"
+	encapGeneve  = "geneve"
+	genevePort   = 6081
+	geneveEncap  = 50 // inner eth(14) + outer IP(20) + outer UDP(8) + geneve header without options(8)
+	genevePrefix = "gn"
+	testGeneve   = "testgeneve"
+)
+
+// Geneve link and bridge port attributes missing from the netlink package
+const (
+	iflaGeneveID       = 1
+	iflaGeneveRemote   = 2
+	iflaGenevePort     = 5
+	iflaGeneveRemote6  = 7
+	iflaBrportIsolated = 33
+)
+
+var (
+	geneveOnce      sync.Once
+	geneveAvailable bool
+)
+
+// setEncap sets the encapsulation of the network from the driver options
+func (n *network) setEncap(optMap map[string]string) error {
+	n.encap = encapVxlan
+	if val, ok := optMap[netlabel.OverlayEncap]; ok {
+		if val != encapVxlan && val != encapGeneve {
+			return fmt.Errorf("invalid encapsulation %q", val)
+		}
+		n.encap = val
+	}
+
+	if val, ok := optMap[netlabel.OverlayEncapPort]; ok {
+		if n.encap != encapGeneve {
+			return fmt.Errorf("encapsulation port is only supported with geneve encapsulation")
+		}
+		port, err := strconv.Atoi(val)
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid encapsulation port %q", val)
+		}
+		n.encapPort = port
+	}
+
+	if val, ok := optMap[netlabel.OverlayGeneveOptions]; ok {
+		if n.encap != encapGeneve {
+			return fmt.Errorf("geneve options are only supported with geneve encapsulation")
+		}
+		opts, err := parseGeneveOptions(val)
+		if err != nil {
+			return err
+		}
+		n.geneveOpts = opts
+	}
+
+	if n.encap == encapGeneve {
+		if n.secure {
+			return fmt.Errorf("encryption is not supported with geneve encapsulation")
+		}
+		if n.encapPort == 0 {
+			n.encapPort = genevePort
+		}
+	}
+
+	return nil
+}
+
+func (n *network) isGeneve() bool {
+	return n.encap == encapGeneve
+}
+
+// checkGenevePort makes sure the port of a geneve network is not shared with
+// a network in metadata mode, the kernel does not let a geneve interface in
+// metadata mode share its port with any other geneve interface
+func (d *driver) checkGenevePort(n *network) error {
+	if !n.isGeneve() {
+		return nil
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	for _, other := range d.networks {
+		if other.id == n.id || !other.isGeneve() || other.encapPort != n.encapPort {
+			continue
+		}
+		if len(n.geneveOpts) > 0 || len(other.geneveOpts) > 0 {
+			return fmt.Errorf("geneve port %d is already used by network %s, a network with geneve options needs its own port", n.encapPort, other.id)
+		}
+	}
+	return nil
+}
+
+// geneveSupported probes the kernel for the geneve interfaces on the first
+// call and caches the result
+func geneveSupported() bool {
+	geneveOnce.Do(func() {
+		if err := createGeneve(testGeneve, 1, net.IPv4(127, 0, 0, 1), genevePort, 0); err != nil {
+			logrus.Debugf("Geneve is not supported on host: %v", err)
+			return
+		}
+		deleteInterface(testGeneve)
+		geneveAvailable = true
+	})
+	return geneveAvailable
+}
+
+func (n *network) generateGeneveName(s *subnet, vtep net.IP) string {
+	h := fnv.New32a()
+	h.Write([]byte(n.id))
+	h.Write(vtep)
+	return genevePrefix + fmt.Sprintf("%06x", n.vxlanID(s)) + fmt.Sprintf("%07x", h.Sum32()&0xfffffff)
+}
+
+// createGeneve creates a point to point geneve interface to the remote node,
+// or a geneve interface in metadata mode if the remote node is nil
+func createGeneve(name string, vni uint32, remote net.IP, port, mtu int) error {
+	defer osl.InitOSContext()()
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWLINK, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	req.AddData(nl.NewIfInfomsg(syscall.AF_UNSPEC))
+	req.AddData(nl.NewRtAttr(syscall.IFLA_IFNAME, nl.ZeroTerminated(name)))
+	if mtu > 0 {
+		req.AddData(nl.NewRtAttr(syscall.IFLA_MTU, nl.Uint32Attr(uint32(mtu))))
+	}
+
+	linkInfo := nl.NewRtAttr(syscall.IFLA_LINKINFO, nil)
+	nl.NewRtAttrChild(linkInfo, nl.IFLA_INFO_KIND, nl.NonZeroTerminated(encapGeneve))
+	data := nl.NewRtAttrChild(linkInfo, nl.IFLA_INFO_DATA, nil)
+	if remote == nil {
+		nl.NewRtAttrChild(data, iflaGeneveCollectMetadata, []byte{})
+	} else {
+		nl.NewRtAttrChild(data, iflaGeneveID, nl.Uint32Attr(vni))
+		if ip := remote.To4(); ip != nil {
+			nl.NewRtAttrChild(data, iflaGeneveRemote, ip)
+		} else {
+			nl.NewRtAttrChild(data, iflaGeneveRemote6, remote.To16())
+		}
+	}
+	p := make([]byte, 2)
+	binary.BigEndian.PutUint16(p, uint16(port))
+	nl.NewRtAttrChild(data, iflaGenevePort, p)
+	req.AddData(linkInfo)
+
+	if _, err := req.Execute(syscall.NETLINK_ROUTE, 0); err != nil {
+		return fmt.Errorf("error creating geneve interface: %v", err)
+	}
+
+	return nil
+}
+
+// addGenevePeer makes the peer mac reachable through the geneve interface
+// to the remote node
+func (n *network) addGenevePeer(s *subnet, vtep, peerIP net.IP, peerMac net.HardwareAddr) error {
+	if len(n.geneveOpts) > 0 {
+		return n.addGeneveMetadataPeer(s, vtep, peerIP, peerMac)
+	}
+
+	sbox := n.sandbox()
+
+	n.geneveMu.Lock()
+	defer n.geneveMu.Unlock()
+
+	n.Lock()
+	name, ok := s.genevePorts[vtep.String()]
+	n.Unlock()
+
+	if !ok {
+		name = n.generateGeneveName(s, vtep)
+		if err := createGeneve(name, n.vxlanID(s), vtep, n.encapPort, n.maxMTU()); err != nil {
+			return err
+		}
+		if err := sbox.AddInterface(name, genevePrefix, sbox.InterfaceOptions().Master(s.brName)); err != nil {
+			deleteInterface(name)
+			return fmt.Errorf("geneve interface creation failed for subnet %q: %v", s.subnetIP.String(), err)
+		}
+
+		n.Lock()
+		if s.genevePorts == nil {
+			s.genevePorts = map[string]string{}
+		}
+		s.genevePorts[vtep.String()] = name
+		n.Unlock()
+
+		// The frames coming from a remote node must not be
+		// forwarded to the other remote nodes
+		if err := invokeInSandbox(sbox, func() error {
+			return setGenevePort(sandboxIfaceName(sbox, name), false)
+		}); err != nil {
+			return fmt.Errorf("could not configure geneve interface %s: %v", name, err)
+		}
+	}
+
+	return invokeInSandbox(sbox, func() error {
+		return programGeneveFdb(sandboxIfaceName(sbox, name), peerMac, true)
+	})
+}
+
+// deleteGenevePeer removes the peer mac from the bridge and the geneve
+// interface to the remote node once no peer is left behind it
+func (d *driver) deleteGenevePeer(n *network, s *subnet, vtep, peerIP net.IP, peerMac net.HardwareAddr, osDelete bool) error {
+	if len(n.geneveOpts) > 0 {
+		return n.deleteGeneveMetadataPeer(s, peerIP, peerMac, osDelete)
+	}
+
+	sbox := n.sandbox()
+
+	n.geneveMu.Lock()
+	defer n.geneveMu.Unlock()
+
+	n.Lock()
+	name, ok := s.genevePorts[vtep.String()]
+	n.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if osDelete {
+		if err := invokeInSandbox(sbox, func() error {
+			return programGeneveFdb(sandboxIfaceName(sbox, name), peerMac, false)
+		}); err != nil {
+			logrus.Warnf("Failed to delete fdb entry for %s on geneve interface %s: %v", peerMac, name, err)
+		}
+	}
+
+	if d.vtepInUse(n, s, vtep) {
+		return nil
+	}
+
+	n.Lock()
+	delete(s.genevePorts, vtep.String())
+	n.Unlock()
+
+	for _, iface := range sbox.Info().Interfaces() {
+		if iface.SrcName() == name {
+			if err := iface.Remove(); err != nil {
+				logrus.Debugf("Remove interface %s failed: %v", name, err)
+			}
+			break
+		}
+	}
+
+	return deleteInterface(name)
+}
+
+// addGeneveMetadataPeer makes the peer reachable through the geneve interface
+// of the subnet in metadata mode. The frames to the peer mac are given the
+// remote node and the options of the network on egress.
+func (n *network) addGeneveMetadataPeer(s *subnet, vtep, peerIP net.IP, peerMac net.HardwareAddr) error {
+	sbox := n.sandbox()
+
+	n.geneveMu.Lock()
+	defer n.geneveMu.Unlock()
+
+	n.Lock()
+	name, ok := s.genevePorts[""]
+	n.Unlock()
+
+	if !ok {
+		name = n.generateGeneveName(s, nil)
+		if err := createGeneve(name, 0, nil, n.encapPort, n.maxMTU()); err != nil {
+			return err
+		}
+		if err := sbox.AddInterface(name, genevePrefix, sbox.InterfaceOptions().Master(s.brName)); err != nil {
+			deleteInterface(name)
+			return fmt.Errorf("geneve interface creation failed for subnet %q: %v", s.subnetIP.String(), err)
+		}
+
+		n.Lock()
+		if s.genevePorts == nil {
+			s.genevePorts = map[string]string{}
+		}
+		s.genevePorts[""] = name
+		n.Unlock()
+
+		if err := invokeInSandbox(sbox, func() error {
+			ifName := sandboxIfaceName(sbox, name)
+			if err := setGenevePort(ifName, true); err != nil {
+				return err
+			}
+			return addClsactQdisc(ifName)
+		}); err != nil {
+			return fmt.Errorf("could not configure geneve interface %s: %v", name, err)
+		}
+	}
+
+	n.Lock()
+	handle, ok := s.geneveFilters[peerMac.String()]
+	if !ok {
+		if s.geneveFilters == nil {
+			s.geneveFilters = map[string]uint32{}
+		}
+		s.geneveHandle++
+		handle = s.geneveHandle
+		s.geneveFilters[peerMac.String()] = handle
+	}
+	n.Unlock()
+
+	if err := invokeInSandbox(sbox, func() error {
+		ifName := sandboxIfaceName(sbox, name)
+		if err := programGeneveFilter(ifName, handle, peerMac, vtep, n.vxlanID(s), n.encapPort, n.geneveOpts, true); err != nil {
+			return err
+		}
+		return programGeneveFdb(ifName, peerMac, true)
+	}); err != nil {
+		return err
+	}
+
+	// The arp requests are answered by the bridge
+	if err := sbox.AddNeighbor(peerIP, peerMac, sbox.NeighborOptions().LinkName(s.brName)); err != nil {
+		return fmt.Errorf("could not add neighbor entry into the sandbox: %v", err)
+	}
+
+	return nil
+}
+
+// deleteGeneveMetadataPeer removes the filter and the neighbor entry of the
+// peer, the geneve interface stays with the subnet
+func (n *network) deleteGeneveMetadataPeer(s *subnet, peerIP net.IP, peerMac net.HardwareAddr, osDelete bool) error {
+	sbox := n.sandbox()
+
+	n.geneveMu.Lock()
+	defer n.geneveMu.Unlock()
+
+	if err := sbox.DeleteNeighbor(peerIP, peerMac, osDelete); err != nil {
+		logrus.Warnf("Failed to delete neighbor entry for %s: %v", peerIP, err)
+	}
+	if !osDelete {
+		return nil
+	}
+
+	n.Lock()
+	name, ok := s.genevePorts[""]
+	handle, hok := s.geneveFilters[peerMac.String()]
+	delete(s.geneveFilters, peerMac.String())
+	n.Unlock()
+	if !ok || !hok {
+		return nil
+	}
+
+	return invokeInSandbox(sbox, func() error {
+		ifName := sandboxIfaceName(sbox, name)
+		if err := programGeneveFdb(ifName, peerMac, false); err != nil {
+			logrus.Warnf("Failed to delete fdb entry for %s on geneve interface %s: %v", peerMac, name, err)
+		}
+		return programGeneveFilter(ifName, handle, peerMac, nil, 0, 0, nil, false)
+	})
+}
+
+// sandboxIfaceName returns the name of the interface in the sandbox
+func sandboxIfaceName(sbox osl.Sandbox, name string) string {
+	for _, iface := range sbox.Info().Interfaces() {
+		if iface.SrcName() == name {
+			return iface.DstName()
+		}
+	}
+	return name
+}
+
+func invokeInSandbox(sbox osl.Sandbox, f func() error) error {
+	if hostMode {
+		defer osl.InitOSContext()()
+		return f()
+	}
+
+	var err error
+	if ierr := sbox.InvokeFunc(func() {
+		err = f()
+	}); ierr != nil {
+		return fmt.Errorf("failed to enter the network sandbox: %v", ierr)
+	}
+	return err
+}
+
+// setGenevePort isolates the bridge port from the other geneve interfaces
+// and disables mac learning, the bridge is programmed from the peer db.
+// With neighSuppress the bridge answers the arp requests instead of
+// flooding them to the port.
+func setGenevePort(name string, neighSuppress bool) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	req := nl.NewNetlinkRequest(syscall.RTM_SETLINK, syscall.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(syscall.AF_BRIDGE)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	br := nl.NewRtAttr(syscall.IFLA_PROTINFO|syscall.NLA_F_NESTED, nil)
+	nl.NewRtAttrChild(br, nl.IFLA_BRPORT_LEARNING, []byte{0})
+	nl.NewRtAttrChild(br, iflaBrportIsolated, []byte{1})
+	if neighSuppress {
+		nl.NewRtAttrChild(br, iflaBrportNeighSuppress, []byte{1})
+	}
+	req.AddData(br)
+
+	_, err = req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
+
+func programGeneveFdb(name string, mac net.HardwareAddr, add bool) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	fdb := &netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       syscall.AF_BRIDGE,
+		Flags:        netlink.NTF_MASTER,
+		State:        netlink.NUD_NOARP,
+		HardwareAddr: mac,
+	}
+	if add {
+		return netlink.NeighSet(fdb)
+	}
+	return netlink.NeighDel(fdb)
+}
+
+// deleteGeneveInterfaces deletes the geneve interfaces of the subnet left in
+// the namespace, they are created again as the peers are added
+func (n *network) deleteGeneveInterfaces(s *subnet) error {
+	prefix := genevePrefix + fmt.Sprintf("%06x", n.vxlanID(s))
+	return invokeInSandbox(n.sandbox(), func() error {
+		links, err := netlink.LinkList()
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			// Out of host mode the namespace belongs to the network
+			if l.Type() == encapGeneve && (!hostMode || strings.HasPrefix(l.Attrs().Name, prefix)) {
+				if err := netlink.LinkDel(l); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// removeGeneveInterfaces deletes the geneve interfaces of the subnet moved
+// back from the network sandbox, to be called while holding network lock
+func (n *network) removeGeneveInterfaces(s *subnet) {
+	for _, name := range s.genevePorts {
+		if err := deleteInterface(name); err != nil {
+			logrus.Warnf("could not cleanup geneve interface %s: %v", name, err)
+		}
+	}
+	s.genevePorts = nil
+	s.geneveFilters = nil
+}
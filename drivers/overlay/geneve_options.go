@@ -0,0 +1,210 @@
+package overlay
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// The kernel geneve interfaces only carry options in metadata mode, where the
+// destination of the frames is set on egress by the tc tunnel_key action. A
+// network with options has a single geneve interface per subnet in metadata
+// mode, with a flower filter per peer mac setting the destination and the
+// options of the frames. As the broadcast frames are not replicated, the arp
+// requests are answered by the bridge from the neighbor table.
+const (
+	geneveMaxOptLen  = 124 // The length of an option data is a 5 bits count of 4 bytes
+	geneveMaxOptsLen = 252 // The length of the options is a 6 bits count of 4 bytes
+	geneveFilterPrio = 1
+)
+
+// tc and bridge port attributes missing from the netlink package
+const (
+	iflaGeneveCollectMetadata = 6
+	iflaBrportNeighSuppress   = 32
+
+	tcHClsact    = 0xfffffff1
+	tcHMinEgress = 0xfff3
+	tcActPipe    = 3
+
+	tcaFlowerAct           = 3
+	tcaFlowerKeyEthDst     = 4
+	tcaFlowerKeyEthDstMask = 5
+
+	tcaTunnelKeyParms             = 2
+	tcaTunnelKeyEncIPv4Dst        = 4
+	tcaTunnelKeyEncIPv6Dst        = 6
+	tcaTunnelKeyEncKeyID          = 7
+	tcaTunnelKeyEncDstPort        = 9
+	tcaTunnelKeyEncOpts           = 11
+	tcaTunnelKeyEncOptsGeneve     = 1
+	tcaTunnelKeyEncOptGeneveClass = 1
+	tcaTunnelKeyEncOptGeneveType  = 2
+	tcaTunnelKeyEncOptGeneveData  = 3
+	tunnelKeyActSet               = 1
+)
+
+// geneveOption is a TLV option added to the geneve header of the frames of
+// the network
+type geneveOption struct {
+	class uint16
+	typ   uint8
+	data  []byte
+}
+
+func (o geneveOption) String() string {
+	return fmt.Sprintf("%04x:%02x:%s", o.class, o.typ, hex.EncodeToString(o.data))
+}
+
+// parseGeneveOptions parses the comma separated class:type:data options, in
+// hexadecimal as iproute2 takes them. The data length must be a multiple of
+// 4 bytes.
+func parseGeneveOptions(val string) ([]geneveOption, error) {
+	var (
+		opts []geneveOption
+		size int
+	)
+	for _, s := range strings.Split(val, ",") {
+		f := strings.Split(s, ":")
+		if len(f) != 3 {
+			return nil, fmt.Errorf("invalid geneve option %q, expected class:type:data", s)
+		}
+		class, err := strconv.ParseUint(f[0], 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid class of geneve option %q", s)
+		}
+		typ, err := strconv.ParseUint(f[1], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type of geneve option %q", s)
+		}
+		data, err := hex.DecodeString(f[2])
+		if err != nil || len(data)%4 != 0 || len(data) > geneveMaxOptLen {
+			return nil, fmt.Errorf("invalid data of geneve option %q", s)
+		}
+		size += 4 + len(data)
+		opts = append(opts, geneveOption{class: uint16(class), typ: uint8(typ), data: data})
+	}
+	if size > geneveMaxOptsLen {
+		return nil, fmt.Errorf("geneve options longer than %d bytes", geneveMaxOptsLen)
+	}
+	return opts, nil
+}
+
+func formatGeneveOptions(opts []geneveOption) string {
+	s := make([]string, 0, len(opts))
+	for _, o := range opts {
+		s = append(s, o.String())
+	}
+	return strings.Join(s, ",")
+}
+
+// geneveOptionsLen returns the length the options add to the geneve header
+func geneveOptionsLen(opts []geneveOption) int {
+	size := 0
+	for _, o := range opts {
+		size += 4 + len(o.data)
+	}
+	return size
+}
+
+// addClsactQdisc adds the qdisc the egress filters of the interface hang on
+func addClsactQdisc(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWQDISC, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(link.Attrs().Index),
+		Handle:  tcHClsact & 0xffff0000,
+		Parent:  tcHClsact,
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("clsact")))
+
+	if _, err := req.Execute(syscall.NETLINK_ROUTE, 0); err != nil && err != syscall.EEXIST {
+		return fmt.Errorf("could not add the clsact qdisc to %s: %v", name, err)
+	}
+	return nil
+}
+
+// programGeneveFilter adds or removes the egress filter sending the frames to
+// the peer mac to the remote node with the options of the network
+func programGeneveFilter(name string, handle uint32, mac net.HardwareAddr, vtep net.IP, vni uint32, port int, opts []geneveOption, add bool) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	msgType, flags := syscall.RTM_DELTFILTER, syscall.NLM_F_ACK
+	if add {
+		msgType, flags = syscall.RTM_NEWTFILTER, syscall.NLM_F_CREATE|syscall.NLM_F_REPLACE|syscall.NLM_F_ACK
+	}
+	req := nl.NewNetlinkRequest(msgType, flags)
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(link.Attrs().Index),
+		Handle:  handle,
+		Parent:  tcHClsact&0xffff0000 | tcHMinEgress,
+		Info:    netlink.MakeHandle(geneveFilterPrio, nl.Swap16(syscall.ETH_P_ALL)),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("flower")))
+	if add {
+		req.AddData(geneveFlowerAttr(mac, vtep, vni, port, opts))
+	}
+
+	if _, err := req.Execute(syscall.NETLINK_ROUTE, 0); err != nil {
+		return fmt.Errorf("could not program the geneve filter for %s on %s: %v", mac, name, err)
+	}
+	return nil
+}
+
+func geneveFlowerAttr(mac net.HardwareAddr, vtep net.IP, vni uint32, port int, opts []geneveOption) *nl.RtAttr {
+	attr := nl.NewRtAttr(nl.TCA_OPTIONS|nlaFNested, nil)
+	nl.NewRtAttrChild(attr, tcaFlowerKeyEthDst, mac)
+	nl.NewRtAttrChild(attr, tcaFlowerKeyEthDstMask, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	acts := nl.NewRtAttrChild(attr, tcaFlowerAct|nlaFNested, nil)
+	act := nl.NewRtAttrChild(acts, 1|nlaFNested, nil)
+	nl.NewRtAttrChild(act, nl.TCA_ACT_KIND, nl.ZeroTerminated("tunnel_key"))
+	aOpts := nl.NewRtAttrChild(act, nl.TCA_ACT_OPTIONS|nlaFNested, nil)
+
+	// struct tc_tunnel_key is struct tc_gen followed by t_action
+	parms := make([]byte, nl.SizeofTcGen+4)
+	native := nl.NativeEndian()
+	native.PutUint32(parms[8:12], tcActPipe)
+	native.PutUint32(parms[nl.SizeofTcGen:], tunnelKeyActSet)
+	nl.NewRtAttrChild(aOpts, tcaTunnelKeyParms, parms)
+
+	if ip := vtep.To4(); ip != nil {
+		nl.NewRtAttrChild(aOpts, tcaTunnelKeyEncIPv4Dst, ip)
+	} else {
+		nl.NewRtAttrChild(aOpts, tcaTunnelKeyEncIPv6Dst, vtep.To16())
+	}
+	id := make([]byte, 4)
+	binary.BigEndian.PutUint32(id, vni)
+	nl.NewRtAttrChild(aOpts, tcaTunnelKeyEncKeyID, id)
+	p := make([]byte, 2)
+	binary.BigEndian.PutUint16(p, uint16(port))
+	nl.NewRtAttrChild(aOpts, tcaTunnelKeyEncDstPort, p)
+
+	encOpts := nl.NewRtAttrChild(aOpts, tcaTunnelKeyEncOpts|nlaFNested, nil)
+	for _, o := range opts {
+		gOpt := nl.NewRtAttrChild(encOpts, tcaTunnelKeyEncOptsGeneve|nlaFNested, nil)
+		class := make([]byte, 2)
+		binary.BigEndian.PutUint16(class, o.class)
+		nl.NewRtAttrChild(gOpt, tcaTunnelKeyEncOptGeneveClass, class)
+		nl.NewRtAttrChild(gOpt, tcaTunnelKeyEncOptGeneveType, []byte{o.typ})
+		nl.NewRtAttrChild(gOpt, tcaTunnelKeyEncOptGeneveData, o.data)
+	}
+
+	return attr
+}
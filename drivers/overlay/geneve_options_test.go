@@ -0,0 +1,131 @@
+package overlay
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/vishvananda/netlink/nl"
+)
+
+func TestGeneveOptions(t *testing.T) {
+	opts, err := parseGeneveOptions("0102:80:00800022,ffff:1:deadbeefcafe0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 2 || opts[0].class != 0x0102 || opts[0].typ != 0x80 ||
+		!bytes.Equal(opts[0].data, []byte{0x00, 0x80, 0x00, 0x22}) || opts[1].class != 0xffff || opts[1].typ != 1 {
+		t.Fatalf("unexpected geneve options %v", opts)
+	}
+	if l := geneveOptionsLen(opts); l != 20 {
+		t.Fatalf("unexpected geneve options length %d", l)
+	}
+	if s := formatGeneveOptions(opts); s != "0102:80:00800022,ffff:01:deadbeefcafe0001" {
+		t.Fatalf("unexpected formatted geneve options %s", s)
+	}
+
+	for _, val := range []string{
+		"",
+		"0102:80",
+		"10000:80:00800022",
+		"0102:100:00800022",
+		"0102:80:008000",
+		"0102:80:0080002g",
+		"0102:80:" + string(bytes.Repeat([]byte("00"), geneveMaxOptLen+4)),
+		"0102:80:" + string(bytes.Repeat([]byte("00"), geneveMaxOptLen)) + ",0102:81:" + string(bytes.Repeat([]byte("00"), geneveMaxOptLen)) + ",0102:82:00000000",
+	} {
+		if _, err := parseGeneveOptions(val); err == nil {
+			t.Fatalf("expected failure for geneve options %q", val)
+		}
+	}
+}
+
+func TestGeneveOptionsEncap(t *testing.T) {
+	n := &network{}
+	if err := n.setEncap(map[string]string{
+		netlabel.OverlayEncap:         "geneve",
+		netlabel.OverlayGeneveOptions: "0102:80:00800022",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.geneveOpts) != 1 {
+		t.Fatalf("unexpected geneve options %v", n.geneveOpts)
+	}
+	if mtu := n.maxMTU(); mtu != 1500-geneveEncap-8 {
+		t.Fatalf("unexpected geneve mtu %d", mtu)
+	}
+	if err := n.setMulticast(map[string]string{netlabel.OverlayMulticast: multicastReplication}); err == nil {
+		t.Fatal("expected failure for replication multicast mode with geneve options")
+	}
+
+	n = &network{}
+	if err := n.setEncap(map[string]string{netlabel.OverlayGeneveOptions: "0102:80:00800022"}); err == nil {
+		t.Fatal("expected failure for geneve options with vxlan encapsulation")
+	}
+}
+
+func TestGeneveFlowerAttr(t *testing.T) {
+	mac, _ := net.ParseMAC("02:42:0a:00:00:05")
+	opts := []geneveOption{{class: 0x0102, typ: 0x80, data: []byte{0x00, 0x80, 0x00, 0x22}}}
+	attr := geneveFlowerAttr(mac, net.ParseIP("192.168.51.2"), 4097, 6082, opts)
+
+	b := attr.Serialize()
+	attrs, err := nl.ParseRouteAttr(b[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fAttrs := map[uint16][]byte{}
+	for _, a := range attrs {
+		fAttrs[a.Attr.Type] = a.Value
+	}
+	if !bytes.Equal(fAttrs[tcaFlowerKeyEthDst], mac) {
+		t.Fatalf("unexpected filter mac %v", fAttrs[tcaFlowerKeyEthDst])
+	}
+
+	acts, err := nl.ParseRouteAttr(fAttrs[tcaFlowerAct|nlaFNested])
+	if err != nil || len(acts) != 1 {
+		t.Fatalf("unexpected filter actions %v: %v", acts, err)
+	}
+	act, err := nl.ParseRouteAttr(acts[0].Value)
+	if err != nil || len(act) != 2 || string(act[0].Value) != "tunnel_key\x00" {
+		t.Fatalf("unexpected tunnel_key action %v: %v", act, err)
+	}
+	kAttrs := map[uint16][]byte{}
+	aOpts, err := nl.ParseRouteAttr(act[1].Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range aOpts {
+		kAttrs[a.Attr.Type] = a.Value
+	}
+
+	native := nl.NativeEndian()
+	parms := kAttrs[tcaTunnelKeyParms]
+	if len(parms) != nl.SizeofTcGen+4 || native.Uint32(parms[8:12]) != tcActPipe ||
+		native.Uint32(parms[nl.SizeofTcGen:]) != tunnelKeyActSet {
+		t.Fatalf("unexpected tunnel_key parameters %v", parms)
+	}
+	if !net.IP(kAttrs[tcaTunnelKeyEncIPv4Dst]).Equal(net.ParseIP("192.168.51.2")) {
+		t.Fatalf("unexpected tunnel destination %v", kAttrs[tcaTunnelKeyEncIPv4Dst])
+	}
+	if !bytes.Equal(kAttrs[tcaTunnelKeyEncKeyID], []byte{0, 0, 0x10, 0x01}) {
+		t.Fatalf("unexpected tunnel id %v", kAttrs[tcaTunnelKeyEncKeyID])
+	}
+	if !bytes.Equal(kAttrs[tcaTunnelKeyEncDstPort], []byte{0x17, 0xc2}) {
+		t.Fatalf("unexpected tunnel port %v", kAttrs[tcaTunnelKeyEncDstPort])
+	}
+
+	encOpts, err := nl.ParseRouteAttr(kAttrs[tcaTunnelKeyEncOpts|nlaFNested])
+	if err != nil || len(encOpts) != 1 {
+		t.Fatalf("unexpected tunnel options %v: %v", encOpts, err)
+	}
+	gOpt, err := nl.ParseRouteAttr(encOpts[0].Value)
+	if err != nil || len(gOpt) != 3 {
+		t.Fatalf("unexpected geneve option %v: %v", gOpt, err)
+	}
+	if !bytes.Equal(gOpt[0].Value, []byte{0x01, 0x02}) || !bytes.Equal(gOpt[1].Value, []byte{0x80}) ||
+		!bytes.Equal(gOpt[2].Value, opts[0].data) {
+		t.Fatalf("unexpected geneve option attributes %v", gOpt)
+	}
+}
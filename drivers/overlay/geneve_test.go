@@ -0,0 +1,177 @@
+package overlay
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/vishvananda/netlink"
+)
+
+func TestGeneveEncapOptions(t *testing.T) {
+	n := &network{}
+	if err := n.setEncap(map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if n.isGeneve() || n.encapPort != 0 {
+		t.Fatalf("expected vxlan encapsulation by default, got %s:%d", n.encap, n.encapPort)
+	}
+
+	n = &network{}
+	if err := n.setEncap(map[string]string{netlabel.OverlayEncap: "geneve"}); err != nil {
+		t.Fatal(err)
+	}
+	if !n.isGeneve() || n.encapPort != genevePort {
+		t.Fatalf("unexpected encapsulation %s:%d", n.encap, n.encapPort)
+	}
+
+	n = &network{}
+	if err := n.setEncap(map[string]string{netlabel.OverlayEncap: "geneve", netlabel.OverlayEncapPort: "6082"}); err != nil {
+		t.Fatal(err)
+	}
+	if n.encapPort != 6082 {
+		t.Fatalf("unexpected encapsulation port %d", n.encapPort)
+	}
+
+	for _, c := range []struct {
+		secure bool
+		opts   map[string]string
+	}{
+		{opts: map[string]string{netlabel.OverlayEncap: "gre"}},
+		{opts: map[string]string{netlabel.OverlayEncapPort: "6082"}},
+		{opts: map[string]string{netlabel.OverlayEncap: "geneve", netlabel.OverlayEncapPort: "0"}},
+		{opts: map[string]string{netlabel.OverlayEncap: "geneve", netlabel.OverlayEncapPort: "65536"}},
+		{secure: true, opts: map[string]string{netlabel.OverlayEncap: "geneve"}},
+	} {
+		n := &network{secure: c.secure}
+		if err := n.setEncap(c.opts); err == nil {
+			t.Fatalf("expected failure for options %v, secure %v", c.opts, c.secure)
+		}
+	}
+}
+
+func TestGenevePortExclusive(t *testing.T) {
+	opts := []geneveOption{{class: 0x0102, typ: 0x80, data: []byte{0, 0x80, 0, 0x22}}}
+	d := &driver{networks: networkTable{
+		"plain":    {id: "plain", encap: encapGeneve, encapPort: genevePort},
+		"metadata": {id: "metadata", encap: encapGeneve, encapPort: 6082, geneveOpts: opts},
+	}}
+
+	for _, n := range []*network{
+		{id: "n1", encap: encapVxlan},
+		{id: "n1", encap: encapGeneve, encapPort: genevePort},
+		{id: "n1", encap: encapGeneve, encapPort: 6083, geneveOpts: opts},
+	} {
+		if err := d.checkGenevePort(n); err != nil {
+			t.Fatalf("unexpected failure for network on port %d: %v", n.encapPort, err)
+		}
+	}
+
+	for _, n := range []*network{
+		{id: "n1", encap: encapGeneve, encapPort: genevePort, geneveOpts: opts},
+		{id: "n1", encap: encapGeneve, encapPort: 6082},
+		{id: "n1", encap: encapGeneve, encapPort: 6082, geneveOpts: opts},
+	} {
+		if err := d.checkGenevePort(n); err == nil {
+			t.Fatalf("expected failure for network on port %d with %d options", n.encapPort, len(n.geneveOpts))
+		}
+	}
+}
+
+func TestGeneveMTU(t *testing.T) {
+	n := &network{encap: encapGeneve}
+	if mtu := n.maxMTU(); mtu != 1500-geneveEncap {
+		t.Fatalf("unexpected geneve mtu %d", mtu)
+	}
+
+	n.mtu = 9000
+	if mtu := n.maxMTU(); mtu != 9000-geneveEncap {
+		t.Fatalf("unexpected geneve mtu %d", mtu)
+	}
+
+	n.encap = encapVxlan
+	if mtu := n.maxMTU(); mtu != 9000-vxlanEncap {
+		t.Fatalf("unexpected vxlan mtu %d", mtu)
+	}
+}
+
+func TestGeneveName(t *testing.T) {
+	s := &subnet{vni: 4097}
+	n := &network{id: "network1", subnets: []*subnet{s}}
+	vtep1 := net.ParseIP("192.168.51.1")
+	vtep2 := net.ParseIP("192.168.51.2")
+
+	name := n.generateGeneveName(s, vtep1)
+	if len(name) > syscall.IFNAMSIZ-1 {
+		t.Fatalf("geneve interface name %s is too long", name)
+	}
+	if name != n.generateGeneveName(s, vtep1) {
+		t.Fatal("expected the same name for the same remote node")
+	}
+	if name == n.generateGeneveName(s, vtep2) {
+		t.Fatal("expected a different name for a different remote node")
+	}
+}
+
+// TestGenevePort programs a bridge port the way the geneve interfaces to
+// the remote nodes are, using a veth when geneve is not supported
+func TestGenevePort(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	br := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "gntestbr"}}
+	if err := netlink.LinkAdd(br); err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(br)
+
+	name := "gntest0"
+	if err := createGeneve(name, 4097, net.ParseIP("192.168.51.2"), genevePort, 1450); err != nil {
+		t.Logf("using a veth interface: %v", err)
+		veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: name, TxQLen: 0}, PeerName: "gntest1"}
+		if err := netlink.LinkAdd(veth); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(link)
+
+	if err := netlink.LinkSetMaster(link, br); err != nil {
+		t.Fatal(err)
+	}
+	if err := setGenevePort(name, false); err != nil {
+		t.Fatal(err)
+	}
+
+	mac, _ := net.ParseMAC("02:42:0a:00:00:05")
+	if err := programGeneveFdb(name, mac, true); err != nil {
+		t.Fatal(err)
+	}
+	if !geneveFdbExists(t, link, mac) {
+		t.Fatalf("expected fdb entry for %s on %s", mac, name)
+	}
+
+	if err := programGeneveFdb(name, mac, false); err != nil {
+		t.Fatal(err)
+	}
+	if geneveFdbExists(t, link, mac) {
+		t.Fatalf("expected fdb entry for %s on %s to be removed", mac, name)
+	}
+}
+
+func geneveFdbExists(t *testing.T, link netlink.Link, mac net.HardwareAddr) bool {
+	neighs, err := netlink.NeighList(link.Attrs().Index, syscall.AF_BRIDGE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range neighs {
+		if n.HardwareAddr.String() == mac.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -28,16 +28,24 @@ func (d *driver) Join(nid, eid string, sboxKey string, jinfo driverapi.JoinInfo,
 		return fmt.Errorf("could not find endpoint with id %s", eid)
 	}
 
-	if n.secure && len(d.keys) == 0 {
+	if n.secure && !n.wireguard && len(d.keys) == 0 {
 		return fmt.Errorf("cannot join secure network: encryption keys not present")
 	}
 
 	nlh := ns.NlHandle()
 
-	if n.secure && !nlh.SupportsNetlinkFamily(syscall.NETLINK_XFRM) {
+	if n.wireguard {
+		if !wireGuardSupported() {
+			return fmt.Errorf("cannot join secure network: wireguard is not supported on host")
+		}
+	} else if n.secure && !nlh.SupportsNetlinkFamily(syscall.NETLINK_XFRM) {
 		return fmt.Errorf("cannot join secure network: required modules to install IPSEC rules are missing on host")
 	}
 
+	if n.isGeneve() && !geneveSupported() {
+		return fmt.Errorf("cannot join geneve network: geneve is not supported on host")
+	}
+
 	s := n.getSubnetforIP(ep.addr)
 	if s == nil {
 		return fmt.Errorf("could not find subnet for endpoint %s", eid)
@@ -59,6 +67,12 @@ func (d *driver) Join(nid, eid string, sboxKey string, jinfo driverapi.JoinInfo,
 	// overlay network. Hence the Endpoint count should be updated outside joinSubnetSandbox
 	n.incEndpointCount()
 
+	// The policies are enforced in the sandbox, which may have just been
+	// created
+	if err := n.programNetworkPolicies(); err != nil {
+		return err
+	}
+
 	sbox := n.sandbox()
 
 	overlayIfName, containerIfName, err := createVethPair()
@@ -127,11 +141,16 @@ func (d *driver) Join(nid, eid string, sboxKey string, jinfo driverapi.JoinInfo,
 		log.Warn(err)
 	}
 
-	buf, err := proto.Marshal(&PeerRecord{
-		EndpointIP:       ep.addr.String(),
-		EndpointMAC:      ep.mac.String(),
-		TunnelEndpointIP: d.advertiseAddress,
-	})
+	var wgKey []byte
+	if n.wireguard {
+		// The remote nodes need the public key of the node to
+		// encrypt the traffic of the network
+		if wgKey, err = d.wireGuardPublicKey(n.maxMTU() + vxlanEncap); err != nil {
+			return err
+		}
+	}
+
+	buf, err := d.peerRecord(ep, wgKey)
 	if err != nil {
 		return err
 	}
@@ -145,6 +164,19 @@ func (d *driver) Join(nid, eid string, sboxKey string, jinfo driverapi.JoinInfo,
 	return nil
 }
 
+// peerRecord returns the record of the local endpoint gossiped to the remote
+// nodes, along with the wireguard public key of the node if any. The labels
+// let the network policies of the remote nodes select the endpoint.
+func (d *driver) peerRecord(ep *endpoint, wgKey []byte) ([]byte, error) {
+	return proto.Marshal(&PeerRecord{
+		EndpointIP:       ep.addr.String(),
+		EndpointMAC:      ep.mac.String(),
+		TunnelEndpointIP: d.advertiseAddress,
+		WireGuardKey:     wgKey,
+		EndpointLabels:   encodeLabels(ep.labels),
+	})
+}
+
 func (d *driver) EventNotify(etype driverapi.EventType, nid, tableName, key string, value []byte) {
 	if tableName != ovPeerTable {
 		log.Errorf("Unexpected table notification for table %s received", tableName)
@@ -183,11 +215,23 @@ func (d *driver) EventNotify(etype driverapi.EventType, nid, tableName, key stri
 		return
 	}
 
+	n := d.network(nid)
 	if etype == driverapi.Delete {
+		if n != nil {
+			n.deleteRemoteEndpoint(eid)
+		}
 		d.peerDelete(nid, eid, addr.IP, addr.Mask, mac, vtep, true)
 		return
 	}
 
+	if n != nil {
+		n.setRemoteEndpoint(eid, addr.IP, decodeLabels(peer.EndpointLabels))
+	}
+
+	if len(peer.WireGuardKey) > 0 {
+		d.setWireGuardPeerKey(vtep, peer.WireGuardKey)
+	}
+
 	d.peerAdd(nid, eid, addr.IP, addr.Mask, mac, vtep, true)
 }
 
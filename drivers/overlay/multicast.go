@@ -0,0 +1,222 @@
+package overlay
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/osl"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// The overlay networks forward the broadcast and multicast frames to the
+// other nodes either by replicating them to every node having peers on the
+// subnet, or by sending them to a multicast group of the underlay network.
+const (
+	multicastReplication = "replication"
+	multicastUnderlay    = "underlay"
+)
+
+// Bridge attribute missing from the netlink package
+const iflaBrMcastSnooping = 23
+
+var floodMac = net.HardwareAddr{0, 0, 0, 0, 0, 0}
+
+// setMulticast sets the multicast mode of the network from the driver
+// options, to be called once the encapsulation is set
+func (n *network) setMulticast(optMap map[string]string) error {
+	if val, ok := optMap[netlabel.OverlayMulticast]; ok {
+		if val != multicastReplication && val != multicastUnderlay {
+			return fmt.Errorf("invalid multicast mode %q", val)
+		}
+		n.multicast = val
+	}
+
+	if val, ok := optMap[netlabel.OverlayMulticastGroup]; ok {
+		if n.multicast != multicastUnderlay {
+			return fmt.Errorf("multicast group is only supported with the %s multicast mode", multicastUnderlay)
+		}
+		group := net.ParseIP(val)
+		if group == nil || group.To4() == nil || !group.IsMulticast() {
+			return fmt.Errorf("invalid multicast group %q", val)
+		}
+		n.mcastGroup = group.To4()
+	}
+
+	if n.multicast == multicastUnderlay {
+		if n.secure {
+			return fmt.Errorf("%s multicast mode is not supported on encrypted networks", multicastUnderlay)
+		}
+		if n.isGeneve() {
+			return fmt.Errorf("%s multicast mode is not supported with geneve encapsulation", multicastUnderlay)
+		}
+	}
+
+	// The frames of the geneve interface in metadata mode are not flooded
+	if n.multicast == multicastReplication && len(n.geneveOpts) > 0 {
+		return fmt.Errorf("%s multicast mode is not supported with geneve options", multicastReplication)
+	}
+
+	return nil
+}
+
+// multicastGroup returns the underlay group of the subnet, by default
+// 239.0.0.0/8 with the vxlan id as lower bits
+func (n *network) multicastGroup(s *subnet) net.IP {
+	if n.multicast != multicastUnderlay {
+		return nil
+	}
+	if n.mcastGroup != nil {
+		return n.mcastGroup
+	}
+	vni := n.vxlanID(s)
+	return net.IPv4(239, byte(vni>>16), byte(vni>>8), byte(vni)).To4()
+}
+
+// underlayIndex returns the index of the host interface the address
+// belongs to, the vxlan interfaces join the multicast groups on it
+func underlayIndex(addr string) (int, error) {
+	defer osl.InitOSContext()()
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return 0, fmt.Errorf("invalid underlay address %q", addr)
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return 0, err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface.Index, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("could not find the interface of the underlay address %s", addr)
+}
+
+// vxlanUnderlay returns the group and the underlay interface of the vxlan
+// interface of the subnet, if any
+func (n *network) vxlanUnderlay(s *subnet) (net.IP, int, error) {
+	group := n.multicastGroup(s)
+	if group == nil {
+		return nil, 0, nil
+	}
+
+	n.driver.Lock()
+	addr := n.driver.bindAddress
+	if addr == "" {
+		addr = n.driver.advertiseAddress
+	}
+	n.driver.Unlock()
+
+	index, err := underlayIndex(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return group, index, nil
+}
+
+// setupMulticast lets the subnet bridge flood the multicast frames to all
+// its ports, the frames to the remote nodes included
+func (n *network) setupMulticast(s *subnet) error {
+	if n.multicast == "" {
+		return nil
+	}
+
+	sbox := n.sandbox()
+	return invokeInSandbox(sbox, func() error {
+		return setMulticastSnooping(sandboxIfaceName(sbox, s.brName), false)
+	})
+}
+
+func setMulticastSnooping(name string, enable bool) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWLINK, syscall.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(syscall.AF_UNSPEC)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	val := []byte{0}
+	if enable {
+		val[0] = 1
+	}
+	linkInfo := nl.NewRtAttr(syscall.IFLA_LINKINFO, nil)
+	nl.NewRtAttrChild(linkInfo, nl.IFLA_INFO_KIND, nl.NonZeroTerminated("bridge"))
+	data := nl.NewRtAttrChild(linkInfo, nl.IFLA_INFO_DATA, nil)
+	nl.NewRtAttrChild(data, iflaBrMcastSnooping, val)
+	req.AddData(linkInfo)
+
+	if _, err := req.Execute(syscall.NETLINK_ROUTE, 0); err != nil {
+		return fmt.Errorf("could not set multicast snooping on %s: %v", name, err)
+	}
+	return nil
+}
+
+// addFloodPeer replicates the broadcast and multicast frames of the subnet
+// to the remote node
+func (n *network) addFloodPeer(s *subnet, vtep net.IP) error {
+	if n.multicast != multicastReplication || n.isGeneve() {
+		return nil
+	}
+
+	sbox := n.sandbox()
+	return invokeInSandbox(sbox, func() error {
+		return programFloodEntry(sandboxIfaceName(sbox, s.vxlanName), vtep, true)
+	})
+}
+
+// deleteFloodPeer stops the replication to the remote node once no peer of
+// the subnet is left behind it
+func (d *driver) deleteFloodPeer(n *network, s *subnet, vtep net.IP) {
+	if n.multicast != multicastReplication || n.isGeneve() {
+		return
+	}
+
+	if d.vtepInUse(n, s, vtep) {
+		return
+	}
+
+	sbox := n.sandbox()
+	if err := invokeInSandbox(sbox, func() error {
+		return programFloodEntry(sandboxIfaceName(sbox, s.vxlanName), vtep, false)
+	}); err != nil {
+		logrus.Warnf("Failed to delete flood entry for %s on %s: %v", vtep, s.vxlanName, err)
+	}
+}
+
+// programFloodEntry adds or removes an all-zero fdb entry to the vtep on the
+// vxlan interface, the frames without a matching entry are sent to all of them
+func programFloodEntry(name string, vtep net.IP, add bool) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	fdb := &netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       syscall.AF_BRIDGE,
+		Flags:        netlink.NTF_SELF,
+		State:        netlink.NUD_PERMANENT | netlink.NUD_NOARP,
+		IP:           vtep,
+		HardwareAddr: floodMac,
+	}
+	if add {
+		return netlink.NeighAppend(fdb)
+	}
+	return netlink.NeighDel(fdb)
+}
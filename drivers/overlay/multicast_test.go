@@ -0,0 +1,185 @@
+package overlay
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/vishvananda/netlink"
+)
+
+func TestMulticastOptions(t *testing.T) {
+	n := &network{}
+	if err := n.setMulticast(map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if n.multicast != "" {
+		t.Fatalf("expected no multicast forwarding by default, got %s", n.multicast)
+	}
+
+	s := &subnet{vni: 4097}
+	n = &network{}
+	if err := n.setMulticast(map[string]string{netlabel.OverlayMulticast: multicastReplication}); err != nil {
+		t.Fatal(err)
+	}
+	if group := n.multicastGroup(s); group != nil {
+		t.Fatalf("unexpected multicast group %s in replication mode", group)
+	}
+
+	n = &network{}
+	if err := n.setMulticast(map[string]string{netlabel.OverlayMulticast: multicastUnderlay}); err != nil {
+		t.Fatal(err)
+	}
+	if group := n.multicastGroup(s); !group.Equal(net.ParseIP("239.0.16.1")) {
+		t.Fatalf("unexpected default multicast group %s", group)
+	}
+
+	n = &network{}
+	if err := n.setMulticast(map[string]string{
+		netlabel.OverlayMulticast:      multicastUnderlay,
+		netlabel.OverlayMulticastGroup: "239.10.10.10",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if group := n.multicastGroup(s); !group.Equal(net.ParseIP("239.10.10.10")) {
+		t.Fatalf("unexpected multicast group %s", group)
+	}
+
+	n = &network{encap: encapGeneve}
+	if err := n.setMulticast(map[string]string{netlabel.OverlayMulticast: multicastReplication}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		secure bool
+		encap  string
+		opts   map[string]string
+	}{
+		{opts: map[string]string{netlabel.OverlayMulticast: "flood"}},
+		{opts: map[string]string{netlabel.OverlayMulticastGroup: "239.10.10.10"}},
+		{opts: map[string]string{netlabel.OverlayMulticast: multicastReplication, netlabel.OverlayMulticastGroup: "239.10.10.10"}},
+		{opts: map[string]string{netlabel.OverlayMulticast: multicastUnderlay, netlabel.OverlayMulticastGroup: "10.10.10.10"}},
+		{opts: map[string]string{netlabel.OverlayMulticast: multicastUnderlay, netlabel.OverlayMulticastGroup: "ff02::1"}},
+		{secure: true, opts: map[string]string{netlabel.OverlayMulticast: multicastUnderlay}},
+		{encap: encapGeneve, opts: map[string]string{netlabel.OverlayMulticast: multicastUnderlay}},
+	} {
+		n := &network{secure: c.secure, encap: c.encap}
+		if err := n.setMulticast(c.opts); err == nil {
+			t.Fatalf("expected failure for options %v, secure %v, encap %q", c.opts, c.secure, c.encap)
+		}
+	}
+}
+
+func TestMulticastUnderlay(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "mctest0", TxQLen: 0}, PeerName: "mctest1"}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(veth)
+
+	link, err := netlink.LinkByName("mctest0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := netlink.ParseAddr("192.168.52.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.AddrAdd(link, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := underlayIndex("192.168.52.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index != link.Attrs().Index {
+		t.Fatalf("expected underlay interface %d, got %d", link.Attrs().Index, index)
+	}
+	if _, err := underlayIndex("192.168.53.1"); err == nil {
+		t.Fatal("expected failure for an address not on the host")
+	}
+
+	group := net.ParseIP("239.0.16.1").To4()
+	if err := createVxlan("mctestvxlan", 4097, 0, group, index); err != nil {
+		t.Fatal(err)
+	}
+	vl, err := netlink.LinkByName("mctestvxlan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(vl)
+
+	vxlan, ok := vl.(*netlink.Vxlan)
+	if !ok {
+		t.Fatalf("unexpected link type %s", vl.Type())
+	}
+	if !vxlan.Group.Equal(group) || vxlan.VtepDevIndex != index {
+		t.Fatalf("unexpected vxlan underlay %s on %d", vxlan.Group, vxlan.VtepDevIndex)
+	}
+}
+
+func TestMulticastReplication(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	if err := createVxlan("mctestvxlan", 4097, 0, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	link, err := netlink.LinkByName("mctestvxlan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(link)
+
+	br := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "mctestbr"}}
+	if err := netlink.LinkAdd(br); err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(br)
+	if err := setMulticastSnooping("mctestbr", false); err != nil {
+		t.Fatal(err)
+	}
+
+	vteps := []net.IP{net.ParseIP("192.168.52.2"), net.ParseIP("192.168.52.3")}
+	for _, vtep := range vteps {
+		if err := programFloodEntry("mctestvxlan", vtep, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The entries are appended once per remote node
+	if err := programFloodEntry("mctestvxlan", vteps[0], true); err != nil {
+		t.Fatal(err)
+	}
+
+	floodVteps := func() []net.IP {
+		neighs, err := netlink.NeighList(link.Attrs().Index, syscall.AF_BRIDGE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ips []net.IP
+		for _, neigh := range neighs {
+			if neigh.HardwareAddr.String() == floodMac.String() {
+				ips = append(ips, neigh.IP)
+			}
+		}
+		return ips
+	}
+
+	if ips := floodVteps(); len(ips) != 2 {
+		t.Fatalf("expected flood entries to %v, got %v", vteps, ips)
+	}
+
+	if err := programFloodEntry("mctestvxlan", vteps[0], false); err != nil {
+		t.Fatal(err)
+	}
+	if ips := floodVteps(); len(ips) != 1 || !ips[0].Equal(vteps[1]) {
+		t.Fatalf("expected flood entry to %s, got %v", vteps[1], ips)
+	}
+}
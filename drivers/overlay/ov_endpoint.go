@@ -8,6 +8,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/libnetwork/datastore"
 	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/netlabel"
 	"github.com/docker/libnetwork/netutils"
 	"github.com/docker/libnetwork/ns"
 	"github.com/docker/libnetwork/types"
@@ -23,6 +24,7 @@ type endpoint struct {
 	ifName   string
 	mac      net.HardwareAddr
 	addr     *net.IPNet
+	labels   map[string]string
 	dbExists bool
 	dbIndex  uint64
 }
@@ -76,6 +78,10 @@ func (d *driver) CreateEndpoint(nid, eid string, ifInfo driverapi.InterfaceInfo,
 		return fmt.Errorf("create endpoint was not passed interface IP address")
 	}
 
+	if ep.labels, err = netlabel.GetEndpointLabels(epOptions); err != nil {
+		return err
+	}
+
 	if s := n.getSubnetforIP(ep.addr); s == nil {
 		return fmt.Errorf("no matching subnet for IP %q in network %q\n", ep.addr, nid)
 	}
@@ -89,6 +95,11 @@ func (d *driver) CreateEndpoint(nid, eid string, ifInfo driverapi.InterfaceInfo,
 
 	n.addEndpoint(ep)
 
+	if err := n.programNetworkPolicies(); err != nil {
+		n.deleteEndpoint(eid)
+		return err
+	}
+
 	if err := d.writeEndpointToStore(ep); err != nil {
 		return fmt.Errorf("failed to update overlay endpoint %s to local store: %v", ep.id[0:7], err)
 	}
@@ -115,6 +126,10 @@ func (d *driver) DeleteEndpoint(nid, eid string) error {
 
 	n.deleteEndpoint(eid)
 
+	if err := n.programNetworkPolicies(); err != nil {
+		log.Warn(err)
+	}
+
 	if err := d.deleteEndpointFromStore(ep); err != nil {
 		log.Warnf("Failed to delete overlay endpoint %s from local store: %v", ep.id[0:7], err)
 	}
@@ -227,6 +242,9 @@ func (ep *endpoint) MarshalJSON() ([]byte, error) {
 	if len(ep.mac) != 0 {
 		epMap["mac"] = ep.mac.String()
 	}
+	if len(ep.labels) != 0 {
+		epMap["labels"] = ep.labels
+	}
 
 	return json.Marshal(epMap)
 }
@@ -254,6 +272,12 @@ func (ep *endpoint) UnmarshalJSON(value []byte) error {
 	if v, ok := epMap["ifName"]; ok {
 		ep.ifName = v.(string)
 	}
+	if v, ok := epMap["labels"]; ok {
+		ep.labels = make(map[string]string)
+		for k, l := range v.(map[string]interface{}) {
+			ep.labels[k] = l.(string)
+		}
+	}
 
 	return nil
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/docker/libnetwork/datastore"
 	"github.com/docker/libnetwork/driverapi"
 	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/netpolicy"
 	"github.com/docker/libnetwork/netutils"
 	"github.com/docker/libnetwork/ns"
 	"github.com/docker/libnetwork/osl"
@@ -42,6 +43,13 @@ type subnet struct {
 	initErr   error
 	subnetIP  *net.IPNet
 	gwIP      *net.IPNet
+	// genevePorts maps the remote nodes to their geneve interface, the
+	// empty key to the interface in metadata mode
+	genevePorts map[string]string
+	// geneveFilters maps the peer macs to the handle of their egress
+	// filter on the geneve interface in metadata mode
+	geneveFilters map[string]uint32
+	geneveHandle  uint32
 }
 
 type subnetJSON struct {
@@ -63,7 +71,25 @@ type network struct {
 	initErr   error
 	subnets   []*subnet
 	secure    bool
+	wireguard bool
+	encap     string
+	encapPort int
 	mtu       int
+	policies  []types.NetworkPolicy
+	// policyChains is set once the network policies are enforced
+	policyChains bool
+	// remoteEps are the endpoints on the other hosts, selected as peers
+	// by the network policies
+	remoteEps map[string]netpolicy.Endpoint
+	geneveMu  sync.Mutex
+	// geneveOpts are the options added to the geneve header
+	geneveOpts []geneveOption
+	// multicast is the forwarding mode of the broadcast and multicast
+	// frames, none if empty
+	multicast  string
+	mcastGroup net.IP
+	// nInfo updates the records gossiped for the network
+	nInfo driverapi.NetworkInfo
 	sync.Mutex
 }
 
@@ -112,8 +138,9 @@ func (d *driver) CreateNetwork(id string, option map[string]interface{}, nInfo d
 				vnis = append(vnis, uint32(vni))
 			}
 		}
-		if _, ok := optMap[secureOption]; ok {
+		if val, ok := optMap[secureOption]; ok {
 			n.secure = true
+			n.wireguard = val == wireGuardOption
 		}
 		if val, ok := optMap[netlabel.DriverMTU]; ok {
 			var err error
@@ -124,6 +151,12 @@ func (d *driver) CreateNetwork(id string, option map[string]interface{}, nInfo d
 				return fmt.Errorf("invalid MTU value: %v", n.mtu)
 			}
 		}
+		if err := n.setEncap(optMap); err != nil {
+			return err
+		}
+		if err := n.setMulticast(optMap); err != nil {
+			return err
+		}
 	}
 
 	// If we are getting vnis from libnetwork, either we get for
@@ -132,6 +165,15 @@ func (d *driver) CreateNetwork(id string, option map[string]interface{}, nInfo d
 		return fmt.Errorf("insufficient vnis(%d) passed to overlay", len(vnis))
 	}
 
+	// The geneve interface in metadata mode is the only one on its port
+	if len(n.geneveOpts) > 0 && len(ipV4Data) > 1 {
+		return fmt.Errorf("geneve options are only supported on networks with a single subnet")
+	}
+
+	if err := d.checkGenevePort(n); err != nil {
+		return err
+	}
+
 	for i, ipd := range ipV4Data {
 		s := &subnet{
 			subnetIP: ipd.Pool,
@@ -151,9 +193,12 @@ func (d *driver) CreateNetwork(id string, option map[string]interface{}, nInfo d
 	}
 
 	// Make sure no rule is on the way from any stale secure network
-	if !n.secure {
-		for _, vni := range vnis {
-			programMangle(vni, false)
+	for _, vni := range vnis {
+		if !n.secure || n.wireguard {
+			programMangle(vni, mark, false)
+		}
+		if !n.wireguard {
+			programMangle(vni, wgMark, false)
 		}
 	}
 
@@ -161,6 +206,7 @@ func (d *driver) CreateNetwork(id string, option map[string]interface{}, nInfo d
 		if err := nInfo.TableEventRegister(ovPeerTable); err != nil {
 			return err
 		}
+		n.nInfo = nInfo
 	}
 
 	d.addNetwork(n)
@@ -203,7 +249,7 @@ func (d *driver) DeleteNetwork(nid string) error {
 
 	if n.secure {
 		for _, vni := range vnis {
-			programMangle(vni, false)
+			programMangle(vni, n.encryptionMark(), false)
 		}
 	}
 
@@ -278,6 +324,8 @@ func (n *network) destroySandbox() {
 				}
 			}
 
+			n.removeGeneveInterfaces(s)
+
 			if s.vxlanName != "" {
 				err := deleteInterface(s.vxlanName)
 				if err != nil {
@@ -292,6 +340,8 @@ func (n *network) destroySandbox() {
 			}
 		}
 
+		n.removePolicyChains()
+
 		n.sbox.Destroy()
 		n.sbox = nil
 	}
@@ -344,7 +394,7 @@ func networkOnceInit() {
 		return
 	}
 
-	err := createVxlan("testvxlan", 1, 0)
+	err := createVxlan("testvxlan", 1, 0, nil, 0)
 	if err != nil {
 		logrus.Errorf("Failed to create testvxlan interface: %v", err)
 		return
@@ -430,6 +480,11 @@ func (n *network) restoreSubnetSandbox(s *subnet, brName, vxlanName string) erro
 		return err
 	}
 
+	// The geneve interfaces are created again as the peers are added
+	if n.isGeneve() {
+		return n.deleteGeneveInterfaces(s)
+	}
+
 	Ifaces = make(map[string][]osl.IfaceOption)
 	vxlanIfaceOption := make([]osl.IfaceOption, 1)
 	vxlanIfaceOption = append(vxlanIfaceOption, sbox.InterfaceOptions().Master(brName))
@@ -449,14 +504,16 @@ func (n *network) setupSubnetSandbox(s *subnet, brName, vxlanName string) error
 			deleteInterfaceBySubnet(n.getBridgeNamePrefix(s), s)
 		}
 		// Try to delete the vxlan interface by vni if already present
-		deleteVxlanByVNI("", n.vxlanID(s))
+		if !n.isGeneve() {
+			deleteVxlanByVNI("", n.vxlanID(s))
+		}
 
 		if isOverlap(s.subnetIP) {
 			return fmt.Errorf("overlay subnet %s has conflicts in the host while running in host mode", s.subnetIP.String())
 		}
 	}
 
-	if !hostMode {
+	if !hostMode && !n.isGeneve() {
 		// Try to find this subnet's vni is being used in some
 		// other namespace by looking at vniTbl that we just
 		// populated in the once init. If a hit is found then
@@ -488,14 +545,22 @@ func (n *network) setupSubnetSandbox(s *subnet, brName, vxlanName string) error
 		return fmt.Errorf("bridge creation in sandbox failed for subnet %q: %v", s.subnetIP.String(), err)
 	}
 
-	err := createVxlan(vxlanName, n.vxlanID(s), n.maxMTU())
-	if err != nil {
-		return err
-	}
+	// The geneve interfaces are added with the peers
+	if !n.isGeneve() {
+		group, vtepDev, err := n.vxlanUnderlay(s)
+		if err != nil {
+			return err
+		}
+
+		err = createVxlan(vxlanName, n.vxlanID(s), n.maxMTU(), group, vtepDev)
+		if err != nil {
+			return err
+		}
 
-	if err := sbox.AddInterface(vxlanName, "vxlan",
-		sbox.InterfaceOptions().Master(brName)); err != nil {
-		return fmt.Errorf("vxlan interface creation failed for subnet %q: %v", s.subnetIP.String(), err)
+		if err := sbox.AddInterface(vxlanName, "vxlan",
+			sbox.InterfaceOptions().Master(brName)); err != nil {
+			return fmt.Errorf("vxlan interface creation failed for subnet %q: %v", s.subnetIP.String(), err)
+		}
 	}
 
 	if hostMode {
@@ -509,7 +574,10 @@ func (n *network) setupSubnetSandbox(s *subnet, brName, vxlanName string) error
 
 func (n *network) initSubnetSandbox(s *subnet, restore bool) error {
 	brName := n.generateBridgeName(s)
-	vxlanName := n.generateVxlanName(s)
+	vxlanName := ""
+	if !n.isGeneve() {
+		vxlanName = n.generateVxlanName(s)
+	}
 
 	if restore {
 		if err := n.restoreSubnetSandbox(s, brName, vxlanName); err != nil {
@@ -526,6 +594,10 @@ func (n *network) initSubnetSandbox(s *subnet, restore bool) error {
 	s.brName = brName
 	n.Unlock()
 
+	if err := n.setupMulticast(s); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -768,6 +840,16 @@ func (n *network) Value() []byte {
 	}
 
 	m["secure"] = n.secure
+	m["wireguard"] = n.wireguard
+	m["encap"] = n.encap
+	m["encap_port"] = n.encapPort
+	if len(n.geneveOpts) > 0 {
+		m["geneve_options"] = formatGeneveOptions(n.geneveOpts)
+	}
+	m["multicast"] = n.multicast
+	if n.mcastGroup != nil {
+		m["multicast_group"] = n.mcastGroup.String()
+	}
 	m["subnets"] = netJSON
 	m["mtu"] = n.mtu
 	b, err = json.Marshal(m)
@@ -819,6 +901,28 @@ func (n *network) SetValue(value []byte) error {
 		if val, ok := m["secure"]; ok {
 			n.secure = val.(bool)
 		}
+		if val, ok := m["wireguard"]; ok {
+			n.wireguard = val.(bool)
+		}
+		if val, ok := m["encap"]; ok {
+			n.encap = val.(string)
+		}
+		if val, ok := m["encap_port"]; ok {
+			n.encapPort = int(val.(float64))
+		}
+		if val, ok := m["geneve_options"]; ok {
+			opts, err := parseGeneveOptions(val.(string))
+			if err != nil {
+				return err
+			}
+			n.geneveOpts = opts
+		}
+		if val, ok := m["multicast"]; ok {
+			n.multicast = val.(string)
+		}
+		if val, ok := m["multicast_group"]; ok {
+			n.mcastGroup = net.ParseIP(val.(string)).To4()
+		}
 		if val, ok := m["mtu"]; ok {
 			n.mtu = int(val.(float64))
 		}
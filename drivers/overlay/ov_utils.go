@@ -2,6 +2,7 @@ package overlay
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"syscall"
 
@@ -52,7 +53,7 @@ func createVethPair() (string, string, error) {
 	return name1, name2, nil
 }
 
-func createVxlan(name string, vni uint32, mtu int) error {
+func createVxlan(name string, vni uint32, mtu int, group net.IP, vtepDev int) error {
 	defer osl.InitOSContext()()
 
 	vxlan := &netlink.Vxlan{
@@ -65,6 +66,12 @@ func createVxlan(name string, vni uint32, mtu int) error {
 		L2miss:    true,
 	}
 
+	// The broadcast and multicast frames are sent to the underlay group
+	if group != nil {
+		vxlan.Group = group
+		vxlan.VtepDevIndex = vtepDev
+	}
+
 	if err := ns.NlHandle().LinkAdd(vxlan); err != nil {
 		return fmt.Errorf("error creating vxlan interface: %v", err)
 	}
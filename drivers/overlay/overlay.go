@@ -41,6 +41,7 @@ type driver struct {
 	config           map[string]interface{}
 	peerDb           peerNetworkMap
 	secMap           *encrMap
+	wgPeers          *wgPeerMap
 	serfInstance     *serf.Serf
 	networks         networkTable
 	store            datastore.DataStore
@@ -55,15 +56,17 @@ type driver struct {
 // Init registers a new instance of overlay driver
 func Init(dc driverapi.DriverCallback, config map[string]interface{}) error {
 	c := driverapi.Capability{
-		DataScope: datastore.GlobalScope,
+		DataScope:       datastore.GlobalScope,
+		NetworkPolicies: true,
 	}
 	d := &driver{
 		networks: networkTable{},
 		peerDb: peerNetworkMap{
 			mp: map[string]*peerMap{},
 		},
-		secMap: &encrMap{nodes: map[string][]*spi{}},
-		config: config,
+		secMap:  &encrMap{nodes: map[string][]*spi{}},
+		wgPeers: &wgPeerMap{nodes: map[string]wgPeer{}, keys: map[string][]byte{}},
+		config:  config,
 	}
 
 	if data, ok := config[netlabel.GlobalKVClient]; ok {
@@ -366,6 +369,16 @@ func (d *driver) DiscoverNew(dType discoverapi.DiscoveryType, data interface{})
 		if err := d.updateKeys(newKey, priKey, delKey); err != nil {
 			logrus.Warn(err)
 		}
+	case discoverapi.NetworkPolicyConfig:
+		cfg, ok := data.(discoverapi.NetworkPolicyConfigData)
+		if !ok {
+			return types.BadRequestErrorf("invalid network policy configuration: %v", data)
+		}
+		n := d.network(cfg.NetworkID)
+		if n == nil {
+			return types.NotFoundErrorf("network id %q not found", cfg.NetworkID)
+		}
+		return n.setNetworkPolicies(cfg.Policies)
 	default:
 	}
 	return nil
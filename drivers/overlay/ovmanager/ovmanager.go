@@ -42,6 +42,10 @@ type network struct {
 	id      string
 	driver  *driver
 	subnets []*subnet
+	// genevePort is the udp port of a geneve network, 0 for vxlan
+	genevePort int
+	// geneveOpts is set when the geneve interfaces run in metadata mode
+	geneveOpts bool
 	sync.Mutex
 }
 
@@ -99,6 +103,22 @@ func (d *driver) NetworkAllocate(id string, option map[string]string, ipV4Data,
 		}
 	}
 
+	if err := validateEncap(opts); err != nil {
+		return nil, err
+	}
+
+	if err := validateMulticast(opts); err != nil {
+		return nil, err
+	}
+
+	if opts[netlabel.OverlayEncap] == "geneve" {
+		n.genevePort = 6081
+		if val, ok := opts[netlabel.OverlayEncapPort]; ok {
+			n.genevePort, _ = strconv.Atoi(val)
+		}
+		_, n.geneveOpts = opts[netlabel.OverlayGeneveOptions]
+	}
+
 	for i, ipd := range ipV4Data {
 		s := &subnet{
 			subnetIP: ipd.Pool,
@@ -124,12 +144,95 @@ func (d *driver) NetworkAllocate(id string, option map[string]string, ipV4Data,
 	opts[netlabel.OverlayVxlanIDList] = val
 
 	d.Lock()
+	if err := d.checkGenevePort(n); err != nil {
+		d.Unlock()
+		n.releaseVxlanID()
+		return nil, err
+	}
 	d.networks[id] = n
 	d.Unlock()
 
 	return opts, nil
 }
 
+// validateEncap checks the encapsulation options of the network, the geneve
+// networks share the vxlan id space for their virtual network identifiers
+func validateEncap(opts map[string]string) error {
+	encap, ok := opts[netlabel.OverlayEncap]
+	if ok && encap != "vxlan" && encap != "geneve" {
+		return fmt.Errorf("invalid encapsulation %q passed", encap)
+	}
+
+	if val, ok := opts[netlabel.OverlayEncapPort]; ok {
+		if encap != "geneve" {
+			return fmt.Errorf("encapsulation port is only supported with geneve encapsulation")
+		}
+		port, err := strconv.Atoi(val)
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid encapsulation port %q passed", val)
+		}
+	}
+
+	if _, ok := opts["encrypted"]; ok && encap == "geneve" {
+		return fmt.Errorf("encryption is not supported with geneve encapsulation")
+	}
+
+	if _, ok := opts[netlabel.OverlayGeneveOptions]; ok {
+		if encap != "geneve" {
+			return fmt.Errorf("geneve options are only supported with geneve encapsulation")
+		}
+		if opts[netlabel.OverlayMulticast] == "replication" {
+			return fmt.Errorf("replication multicast mode is not supported with geneve options")
+		}
+	}
+
+	return nil
+}
+
+// checkGenevePort makes sure the port of a geneve network is not shared with
+// a network using geneve options, the geneve interfaces of those networks run
+// in metadata mode and the kernel does not let them share their port. The
+// driver lock must be held.
+func (d *driver) checkGenevePort(n *network) error {
+	if n.genevePort == 0 {
+		return nil
+	}
+	for _, other := range d.networks {
+		if other.id == n.id || other.genevePort != n.genevePort {
+			continue
+		}
+		if n.geneveOpts || other.geneveOpts {
+			return fmt.Errorf("geneve port %d is already used by network %s, a network with geneve options needs its own port", n.genevePort, other.id)
+		}
+	}
+	return nil
+}
+
+// validateMulticast checks the multicast options of the network, the
+// underlay groups are not available to the geneve networks
+func validateMulticast(opts map[string]string) error {
+	mode, ok := opts[netlabel.OverlayMulticast]
+	if ok && mode != "replication" && mode != "underlay" {
+		return fmt.Errorf("invalid multicast mode %q passed", mode)
+	}
+
+	if mode == "underlay" && opts[netlabel.OverlayEncap] == "geneve" {
+		return fmt.Errorf("underlay multicast mode is not supported with geneve encapsulation")
+	}
+
+	if val, ok := opts[netlabel.OverlayMulticastGroup]; ok {
+		if mode != "underlay" {
+			return fmt.Errorf("multicast group is only supported with the underlay multicast mode")
+		}
+		group := net.ParseIP(val)
+		if group == nil || group.To4() == nil || !group.IsMulticast() {
+			return fmt.Errorf("invalid multicast group %q passed", val)
+		}
+	}
+
+	return nil
+}
+
 func (d *driver) NetworkFree(id string) error {
 	if id == "" {
 		return fmt.Errorf("invalid network id passed while freeing overlay network")
@@ -86,3 +86,106 @@ func TestNetworkAllocateUserDefinedVNIs(t *testing.T) {
 	err = d.NetworkFree("testnetwork")
 	require.NoError(t, err)
 }
+
+func TestNetworkAllocateEncap(t *testing.T) {
+	d := newDriver(t)
+
+	ipamData := []driverapi.IPAMData{
+		{
+			Pool: parseCIDR(t, "10.1.1.0/24"),
+		},
+	}
+
+	options := map[string]string{
+		netlabel.OverlayEncap:     "geneve",
+		netlabel.OverlayEncapPort: "6082",
+	}
+	vals, err := d.NetworkAllocate("testnetwork", options, ipamData, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "geneve", vals[netlabel.OverlayEncap])
+	assert.Equal(t, "6082", vals[netlabel.OverlayEncapPort])
+	_, ok := vals[netlabel.OverlayVxlanIDList]
+	assert.Equal(t, true, ok)
+	require.NoError(t, d.NetworkFree("testnetwork"))
+
+	for _, options := range []map[string]string{
+		{netlabel.OverlayEncap: "gre"},
+		{netlabel.OverlayEncapPort: "6082"},
+		{netlabel.OverlayEncap: "vxlan", netlabel.OverlayEncapPort: "6082"},
+		{netlabel.OverlayEncap: "geneve", netlabel.OverlayEncapPort: "70000"},
+		{netlabel.OverlayEncap: "geneve", netlabel.OverlayEncapPort: "port"},
+		{netlabel.OverlayGeneveOptions: "0102:80:00800022"},
+		{netlabel.OverlayEncap: "geneve", netlabel.OverlayGeneveOptions: "0102:80:00800022", netlabel.OverlayMulticast: "replication"},
+		{netlabel.OverlayEncap: "geneve", "encrypted": ""},
+	} {
+		_, err := d.NetworkAllocate("testnetwork", options, ipamData, nil)
+		assert.Error(t, err, "expected failure for options %v", options)
+	}
+	assert.Equal(t, 0, len(d.networks))
+}
+
+func TestNetworkAllocateGenevePort(t *testing.T) {
+	d := newDriver(t)
+
+	ipamData := []driverapi.IPAMData{
+		{
+			Pool: parseCIDR(t, "10.1.1.0/24"),
+		},
+	}
+
+	_, err := d.NetworkAllocate("plain", map[string]string{netlabel.OverlayEncap: "geneve"}, ipamData, nil)
+	require.NoError(t, err)
+	_, err = d.NetworkAllocate("metadata", map[string]string{
+		netlabel.OverlayEncap:         "geneve",
+		netlabel.OverlayEncapPort:     "6082",
+		netlabel.OverlayGeneveOptions: "0102:80:00800022",
+	}, ipamData, nil)
+	require.NoError(t, err)
+
+	for _, options := range []map[string]string{
+		{netlabel.OverlayEncap: "geneve", netlabel.OverlayGeneveOptions: "0102:80:00800022"},
+		{netlabel.OverlayEncap: "geneve", netlabel.OverlayEncapPort: "6082"},
+	} {
+		_, err := d.NetworkAllocate("testnetwork", options, ipamData, nil)
+		assert.Error(t, err, "expected failure for options %v", options)
+	}
+	assert.Equal(t, 2, len(d.networks))
+
+	_, err = d.NetworkAllocate("testnetwork", map[string]string{netlabel.OverlayEncap: "geneve"}, ipamData, nil)
+	require.NoError(t, err)
+	require.NoError(t, d.NetworkFree("metadata"))
+	_, err = d.NetworkAllocate("other", map[string]string{netlabel.OverlayEncap: "geneve", netlabel.OverlayEncapPort: "6082"}, ipamData, nil)
+	require.NoError(t, err)
+}
+
+func TestNetworkAllocateMulticast(t *testing.T) {
+	d := newDriver(t)
+
+	ipamData := []driverapi.IPAMData{
+		{
+			Pool: parseCIDR(t, "10.1.1.0/24"),
+		},
+	}
+
+	options := map[string]string{
+		netlabel.OverlayMulticast:      "underlay",
+		netlabel.OverlayMulticastGroup: "239.1.1.1",
+	}
+	vals, err := d.NetworkAllocate("testnetwork", options, ipamData, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "underlay", vals[netlabel.OverlayMulticast])
+	assert.Equal(t, "239.1.1.1", vals[netlabel.OverlayMulticastGroup])
+	require.NoError(t, d.NetworkFree("testnetwork"))
+
+	for _, options := range []map[string]string{
+		{netlabel.OverlayMulticast: "flood"},
+		{netlabel.OverlayMulticastGroup: "239.1.1.1"},
+		{netlabel.OverlayMulticast: "replication", netlabel.OverlayMulticastGroup: "239.1.1.1"},
+		{netlabel.OverlayMulticast: "underlay", netlabel.OverlayMulticastGroup: "10.1.1.1"},
+		{netlabel.OverlayMulticast: "underlay", netlabel.OverlayEncap: "geneve"},
+	} {
+		_, err := d.NetworkAllocate("testnetwork", options, ipamData, nil)
+		assert.Error(t, err, "expected failure for options %v", options)
+	}
+	assert.Equal(t, 0, len(d.networks))
+}
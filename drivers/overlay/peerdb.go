@@ -292,6 +292,13 @@ func (d *driver) peerAdd(nid, eid string, peerIP net.IP, peerIPMask net.IPMask,
 		log.Warn(err)
 	}
 
+	if n.isGeneve() {
+		if err := n.addGenevePeer(s, vtep, peerIP, peerMac); err != nil {
+			return fmt.Errorf("could not add geneve peer into the sandbox: %v", err)
+		}
+		return nil
+	}
+
 	// Add neighbor entry for the peer IP
 	if err := sbox.AddNeighbor(peerIP, peerMac, sbox.NeighborOptions().LinkName(s.vxlanName)); err != nil {
 		return fmt.Errorf("could not add neighbor entry into the sandbox: %v", err)
@@ -303,6 +310,10 @@ func (d *driver) peerAdd(nid, eid string, peerIP net.IP, peerIPMask net.IPMask,
 		return fmt.Errorf("could not add fdb entry into the sandbox: %v", err)
 	}
 
+	if err := n.addFloodPeer(s, vtep); err != nil {
+		return fmt.Errorf("could not add flood entry into the sandbox: %v", err)
+	}
+
 	return nil
 }
 
@@ -328,6 +339,14 @@ func (d *driver) peerDelete(nid, eid string, peerIP net.IP, peerIPMask net.IPMas
 		return nil
 	}
 
+	if n.isGeneve() {
+		s := n.getSubnetforIP(&net.IPNet{IP: peerIP, Mask: peerIPMask})
+		if s == nil {
+			return fmt.Errorf("couldn't find the subnet %q in network %q", peerIP.String(), n.id)
+		}
+		return d.deleteGenevePeer(n, s, vtep, peerIP, peerMac, eid == pEntry.eid && vtep.Equal(pEntry.vtep))
+	}
+
 	// Delete fdb entry to the bridge for the peer mac only if the
 	// entry existed in local peerdb. If it is a stale delete
 	// request, still call DeleteNeighbor but only to cleanup any
@@ -346,6 +365,10 @@ func (d *driver) peerDelete(nid, eid string, peerIP net.IP, peerIPMask net.IPMas
 		if err := sbox.DeleteNeighbor(peerIP, peerMac, true); err != nil {
 			return fmt.Errorf("could not delete neighbor entry into the sandbox: %v", err)
 		}
+
+		if s := n.getSubnetforIP(&net.IPNet{IP: peerIP, Mask: peerIPMask}); s != nil {
+			d.deleteFloodPeer(n, s, vtep)
+		}
 	}
 
 	if err := d.checkEncryption(nid, vtep, 0, false, false); err != nil {
@@ -355,6 +378,17 @@ func (d *driver) peerDelete(nid, eid string, peerIP net.IP, peerIPMask net.IPMas
 	return nil
 }
 
+// vtepInUse returns whether peers of the subnet are still reachable
+// through the remote vtep
+func (d *driver) vtepInUse(n *network, s *subnet, vtep net.IP) bool {
+	inUse := false
+	d.peerDbNetworkWalk(n.id, func(pKey *peerKey, pEntry *peerEntry) bool {
+		inUse = !pEntry.isLocal && pEntry.vtep.Equal(vtep) && s.subnetIP.Contains(pKey.peerIP)
+		return inUse
+	})
+	return inUse
+}
+
 func (d *driver) pushLocalDb() {
 	d.peerDbWalk(func(nid string, pKey *peerKey, pEntry *peerEntry) bool {
 		if pEntry.isLocal {
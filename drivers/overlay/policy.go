@@ -0,0 +1,237 @@
+package overlay
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/iptables"
+	"github.com/docker/libnetwork/netpolicy"
+	"github.com/docker/libnetwork/types"
+)
+
+// The chains holding the rules enforcing the network policies on the
+// traffic to and from the endpoints of a network, suffixed by the short
+// network ID
+const (
+	policyIngressChainPrefix = "DOCKER-NP-IN-"
+	policyEgressChainPrefix  = "DOCKER-NP-OUT-"
+)
+
+const bridgeNFCallIptables = "/proc/sys/net/bridge/bridge-nf-call-iptables"
+
+func (n *network) setNetworkPolicies(policies []types.NetworkPolicy) error {
+	np := make([]types.NetworkPolicy, 0, len(policies))
+	for _, p := range policies {
+		np = append(np, p.GetCopy())
+	}
+
+	n.Lock()
+	old := n.policies
+	n.policies = np
+	n.Unlock()
+
+	if err := n.programNetworkPolicies(); err != nil {
+		n.Lock()
+		n.policies = old
+		n.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// setRemoteEndpoint records the address and the labels of an endpoint on
+// another host, learned from its peer record, and enforces the policies
+// again if they may select it
+func (n *network) setRemoteEndpoint(eid string, ip net.IP, labels map[string]string) {
+	n.Lock()
+	if old, ok := n.remoteEps[eid]; ok && old.IP.Equal(ip) && reflect.DeepEqual(old.Labels, labels) {
+		n.Unlock()
+		return
+	}
+	if n.remoteEps == nil {
+		n.remoteEps = make(map[string]netpolicy.Endpoint)
+	}
+	n.remoteEps[eid] = netpolicy.Endpoint{IP: ip, Labels: labels, Remote: true}
+	reprogram := len(n.policies) > 0
+	n.Unlock()
+
+	if reprogram {
+		if err := n.programNetworkPolicies(); err != nil {
+			logrus.Warnf("Failed to enforce the network policies of network %s for remote endpoint %s: %v", n.id, eid, err)
+		}
+	}
+}
+
+func (n *network) deleteRemoteEndpoint(eid string) {
+	n.Lock()
+	if _, ok := n.remoteEps[eid]; !ok {
+		n.Unlock()
+		return
+	}
+	delete(n.remoteEps, eid)
+	reprogram := len(n.policies) > 0
+	n.Unlock()
+
+	if reprogram {
+		if err := n.programNetworkPolicies(); err != nil {
+			logrus.Warnf("Failed to enforce the network policies of network %s without remote endpoint %s: %v", n.id, eid, err)
+		}
+	}
+}
+
+// programNetworkPolicies enforces the network policies on the endpoints of
+// the network, in the network sandbox. Without a sandbox there is no traffic
+// to filter, the policies are enforced once the first endpoint joins.
+// The remote endpoints, known from their peer records, are only selected as
+// peers.
+func (n *network) programNetworkPolicies() error {
+	n.Lock()
+	sbox := n.sbox
+	programmed := n.policyChains
+	eps := make([]netpolicy.Endpoint, 0, len(n.endpoints)+len(n.remoteEps))
+	for _, ep := range n.endpoints {
+		eps = append(eps, netpolicy.Endpoint{IP: ep.addr.IP, Labels: ep.labels})
+	}
+	for _, ep := range n.remoteEps {
+		eps = append(eps, ep)
+	}
+	ingress, egress := netpolicy.Rules(n.policies, eps, "")
+	n.Unlock()
+
+	if sbox == nil || (len(ingress) == 0 && len(egress) == 0 && !programmed) {
+		return nil
+	}
+
+	var err error
+	program := func() {
+		if err = n.setupPolicyChains(); err != nil {
+			return
+		}
+		inChain, outChain := n.policyChainNames()
+		if err = fillPolicyChain(inChain, ingress); err != nil {
+			return
+		}
+		err = fillPolicyChain(outChain, egress)
+	}
+	if hostMode {
+		program()
+	} else if ierr := sbox.InvokeFunc(program); ierr != nil {
+		return fmt.Errorf("failed to enter the sandbox of network %s: %v", n.id, ierr)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to enforce the network policies of network %s: %v", n.id, err)
+	}
+
+	n.Lock()
+	n.policyChains = true
+	n.Unlock()
+
+	return nil
+}
+
+func (n *network) policyChainNames() (string, string) {
+	return policyIngressChainPrefix + n.id[:12], policyEgressChainPrefix + n.id[:12]
+}
+
+// setupPolicyChains creates or flushes the policy chains of the network and
+// makes sure the forwarded traffic goes through them
+func (n *network) setupPolicyChains() error {
+	// The containers of the network talk through the bridges of the
+	// sandbox, the bridged traffic must go through iptables
+	if !hostMode {
+		if err := ioutil.WriteFile(bridgeNFCallIptables, []byte{'1', '\n'}, 0644); err != nil {
+			logrus.Debugf("Failed to enable bridge netfiltering in the sandbox of network %s: %v", n.id, err)
+		}
+	}
+
+	inChain, outChain := n.policyChainNames()
+	for _, chain := range []string{outChain, inChain} {
+		// Flushing the chain fails when it does not exist yet
+		if err := iptables.RawCombinedOutputNative("-F", chain); err != nil {
+			if err := iptables.RawCombinedOutputNative("-N", chain); err != nil {
+				return fmt.Errorf("could not create network policy chain %s: %v", chain, err)
+			}
+		}
+		// Keep the jump at the top, ahead of the overlay filters
+		if iptables.ExistsNative(iptables.Filter, "FORWARD", "-j", chain) {
+			if err := iptables.RawCombinedOutputNative("-D", "FORWARD", "-j", chain); err != nil {
+				return fmt.Errorf("could not move the jump to network policy chain %s: %v", chain, err)
+			}
+		}
+		if err := iptables.RawCombinedOutputNative("-I", "FORWARD", "-j", chain); err != nil {
+			return fmt.Errorf("could not insert the jump to network policy chain %s: %v", chain, err)
+		}
+	}
+
+	return nil
+}
+
+// fillPolicyChain appends the rules to the empty chain
+func fillPolicyChain(chain string, rules [][]string) error {
+	for _, rule := range append(rules, []string{"-j", "RETURN"}) {
+		if err := iptables.RawCombinedOutputNative(append([]string{"-A", chain}, rule...)...); err != nil {
+			return fmt.Errorf("failed to add rule to network policy chain %s: %v", chain, err)
+		}
+	}
+	return nil
+}
+
+// removePolicyChains removes the policy chains of the network from the host
+// namespace, to be called while holding network lock
+func (n *network) removePolicyChains() {
+	if !n.policyChains {
+		return
+	}
+	n.policyChains = false
+	if !hostMode {
+		// The chains are gone with the sandbox
+		return
+	}
+
+	inChain, outChain := n.policyChainNames()
+	for _, chain := range []string{inChain, outChain} {
+		if err := iptables.RawCombinedOutputNative("-D", "FORWARD", "-j", chain); err != nil {
+			logrus.Warnf("Failed to remove the jump to network policy chain %s: %v", chain, err)
+		}
+		if err := iptables.RawCombinedOutputNative("-F", chain); err != nil {
+			logrus.Warnf("Failed to flush network policy chain %s: %v", chain, err)
+		}
+		if err := iptables.RawCombinedOutputNative("-X", chain); err != nil {
+			logrus.Warnf("Failed to remove network policy chain %s: %v", chain, err)
+		}
+	}
+}
+
+// encodeLabels returns the labels as key=value pairs, in a stable order, to
+// be carried by the peer records
+func encodeLabels(labels map[string]string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	l := make([]string, 0, len(labels))
+	for k, v := range labels {
+		l = append(l, k+"="+v)
+	}
+	sort.Strings(l)
+	return l
+}
+
+func decodeLabels(l []string) map[string]string {
+	if len(l) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(l))
+	for _, kv := range l {
+		p := strings.SplitN(kv, "=", 2)
+		if len(p) == 2 {
+			labels[p[0]] = p[1]
+		}
+	}
+	return labels
+}
@@ -0,0 +1,564 @@
+package overlay
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+	"syscall"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/osl"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// With the wireguard encryption the vxlan traffic of the network is marked
+// and routed to the remote nodes through the wireguard interface of the node
+const (
+	wireGuardOption = "wireguard"
+	wgDevice        = "wg-overlay"
+	wgPort          = 51820
+	wgMark          = uint32(0xD0C4E4)
+	wgTable         = 0xD0
+	wgExpansion     = 60 // IP(20) + UDP(8) + Type/Receiver/Counter(16) + Poly1305 tag(16)
+	wgKeyLen        = 32
+)
+
+// Generic netlink controller and wireguard family definitions
+const (
+	genlCtrlID             = 0x10
+	genlCtrlCmdGetFamily   = 3
+	genlCtrlAttrFamilyID   = 1
+	genlCtrlAttrFamilyName = 2
+
+	wgGenlName    = "wireguard"
+	wgGenlVersion = 1
+	wgCmdGetDev   = 0
+	wgCmdSetDev   = 1
+
+	wgDeviceAIfname      = 2
+	wgDeviceAPrivateKey  = 3
+	wgDeviceAPublicKey   = 4
+	wgDeviceAFlags       = 5
+	wgDeviceAListenPort  = 6
+	wgDeviceAPeers       = 8
+	wgDeviceFReplacePeer = 1
+
+	wgPeerAPublicKey         = 1
+	wgPeerAFlags             = 3
+	wgPeerAEndpoint          = 4
+	wgPeerAAllowedIPs        = 9
+	wgPeerFRemoveMe          = 1
+	wgPeerFReplaceAllowedIPs = 2
+
+	wgAllowedIPAFamily   = 1
+	wgAllowedIPAIPAddr   = 2
+	wgAllowedIPACidrMask = 3
+
+	nlaFNested = 0x8000
+)
+
+// wgPeerMap holds the wireguard key of the node and its remote nodes. The
+// private key is random and never leaves the node, the public keys are
+// distributed with the peer records of the network.
+type wgPeerMap struct {
+	privateKey []byte
+	publicKey  []byte
+	// The remote nodes configured as peers of the wireguard interface
+	nodes map[string]wgPeer
+	// The public keys of the remote nodes, learned from their peer records
+	keys map[string][]byte
+	sync.Mutex
+}
+
+type wgPeer struct {
+	publicKey []byte
+	endpoint  net.IP
+	remove    bool
+}
+
+type genlMsg struct {
+	cmd     uint8
+	version uint8
+}
+
+func (m *genlMsg) Len() int {
+	return 4
+}
+
+func (m *genlMsg) Serialize() []byte {
+	return []byte{m.cmd, m.version, 0, 0}
+}
+
+// newWireGuardKey generates a random curve25519 private key
+func newWireGuardKey() ([]byte, error) {
+	k := make([]byte, wgKeyLen)
+	if _, err := rand.Read(k); err != nil {
+		return nil, fmt.Errorf("failed to generate the wireguard key: %v", err)
+	}
+	k[0] &= 248
+	k[31] = (k[31] & 127) | 64
+	return k, nil
+}
+
+func wireGuardSupported() bool {
+	defer osl.InitOSContext()()
+
+	_, err := genlFamilyID(wgGenlName)
+	return err == nil
+}
+
+// wireGuardPublicKey returns the public key of the node, to be published
+// with its peer records. The wireguard interface is set up if needed.
+func (d *driver) wireGuardPublicKey(mtu int) ([]byte, error) {
+	d.wgPeers.Lock()
+	defer d.wgPeers.Unlock()
+
+	if err := d.wgPeers.setupDevice(mtu); err != nil {
+		return nil, err
+	}
+	return d.wgPeers.publicKey, nil
+}
+
+// setWireGuardPeerKey records the public key published by the remote node
+func (d *driver) setWireGuardPeerKey(node net.IP, publicKey []byte) {
+	if len(publicKey) != wgKeyLen {
+		return
+	}
+
+	d.wgPeers.Lock()
+	d.wgPeers.keys[node.String()] = publicKey
+	d.wgPeers.Unlock()
+}
+
+func (d *driver) setupWireGuard(localIP, remoteIP net.IP, vni uint32, mtu int) error {
+	log.Debugf("Programming wireguard encryption for vxlan %d between %s and %s", vni, localIP, remoteIP)
+
+	d.wgPeers.Lock()
+	defer d.wgPeers.Unlock()
+
+	if err := d.wgPeers.setupDevice(mtu); err != nil {
+		return err
+	}
+
+	publicKey, ok := d.wgPeers.keys[remoteIP.String()]
+	if !ok {
+		return fmt.Errorf("the wireguard key of node %s is not known", remoteIP)
+	}
+	peer := wgPeer{publicKey: publicKey, endpoint: remoteIP}
+
+	peers := []wgPeer{peer}
+	// The remote node may have published a new key
+	if old, ok := d.wgPeers.nodes[remoteIP.String()]; ok && !bytes.Equal(old.publicKey, publicKey) {
+		old.remove = true
+		peers = append(peers, old)
+	}
+	if err := configureWireGuard(nil, peers, false); err != nil {
+		return fmt.Errorf("failed to add wireguard peer %s: %v", remoteIP, err)
+	}
+	if err := programWireGuardRoute(remoteIP, true); err != nil {
+		return err
+	}
+
+	d.wgPeers.nodes[remoteIP.String()] = peer
+
+	// Only mark the traffic once the peer can encrypt it
+	if err := programMangle(vni, wgMark, true); err != nil {
+		log.Warn(err)
+	}
+
+	return nil
+}
+
+// rotateWireGuard replaces the key of the node with a new random one when the
+// primary encryption key changes, and publishes its public key again with the
+// peer records of the local endpoints. The remote nodes replace the peer of
+// the node when they receive the updated records.
+func (d *driver) rotateWireGuard() error {
+	d.wgPeers.Lock()
+	if d.wgPeers.privateKey == nil {
+		d.wgPeers.Unlock()
+		return nil
+	}
+
+	log.Debugf("Rotating the wireguard key of the node")
+
+	private, err := newWireGuardKey()
+	if err != nil {
+		d.wgPeers.Unlock()
+		return err
+	}
+	if err := configureWireGuard(private, nil, false); err != nil {
+		d.wgPeers.Unlock()
+		return fmt.Errorf("failed to update the wireguard key: %v", err)
+	}
+	_, public, err := wireGuardDeviceKeys()
+	if err == nil && public == nil {
+		err = fmt.Errorf("no public key set on the wireguard interface")
+	}
+	if err != nil {
+		d.wgPeers.Unlock()
+		return fmt.Errorf("failed to retrieve the wireguard public key: %v", err)
+	}
+	d.wgPeers.privateKey = private
+	d.wgPeers.publicKey = public
+	d.wgPeers.Unlock()
+
+	d.Lock()
+	networks := make([]*network, 0, len(d.networks))
+	for _, n := range d.networks {
+		if n.wireguard && n.nInfo != nil {
+			networks = append(networks, n)
+		}
+	}
+	d.Unlock()
+
+	for _, n := range networks {
+		n.Lock()
+		eps := make([]*endpoint, 0, len(n.endpoints))
+		for _, ep := range n.endpoints {
+			eps = append(eps, ep)
+		}
+		n.Unlock()
+
+		for _, ep := range eps {
+			buf, err := d.peerRecord(ep, public)
+			if err != nil {
+				return err
+			}
+			// Only the joined endpoints have published a record
+			if err := n.nInfo.UpdateTableEntry(ovPeerTable, ep.id, buf); err != nil {
+				log.Debugf("Could not update the peer record of endpoint %s: %v", ep.id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *driver) removeWireGuard(remoteIP net.IP) error {
+	d.wgPeers.Lock()
+	defer d.wgPeers.Unlock()
+
+	peer, ok := d.wgPeers.nodes[remoteIP.String()]
+	if !ok {
+		return nil
+	}
+	delete(d.wgPeers.nodes, remoteIP.String())
+
+	// The wireguard interface is not needed without peers
+	if len(d.wgPeers.nodes) == 0 {
+		return removeWireGuardDevice()
+	}
+
+	if err := programWireGuardRoute(remoteIP, false); err != nil {
+		log.Warn(err)
+	}
+
+	peer.remove = true
+	if err := configureWireGuard(nil, []wgPeer{peer}, false); err != nil {
+		return fmt.Errorf("failed to remove wireguard peer %s: %v", remoteIP, err)
+	}
+
+	return nil
+}
+
+// setupDevice creates the wireguard interface of the node if not present
+// and makes sure it fits the vxlan packets of the network. Must be called
+// with the map locked.
+func (m *wgPeerMap) setupDevice(mtu int) error {
+	defer osl.InitOSContext()()
+
+	nlh := ns.NlHandle()
+	link, err := nlh.LinkByName(wgDevice)
+	if err != nil {
+		link = &netlink.GenericLink{
+			LinkAttrs: netlink.LinkAttrs{Name: wgDevice, MTU: mtu},
+			LinkType:  "wireguard",
+		}
+		if err := nlh.LinkAdd(link); err != nil {
+			return fmt.Errorf("error creating wireguard interface: %v", err)
+		}
+	} else if link.Attrs().MTU < mtu {
+		if err := nlh.LinkSetMTU(link, mtu); err != nil {
+			return fmt.Errorf("could not set the mtu of the wireguard interface: %v", err)
+		}
+	}
+
+	if m.privateKey == nil {
+		// Keep the key of the interface left over by a previous
+		// daemon life, the remote nodes may still know it
+		private, _, err := wireGuardDeviceKeys()
+		if err != nil || private == nil {
+			if private, err = newWireGuardKey(); err != nil {
+				return err
+			}
+		}
+		m.privateKey = private
+	}
+	if err := configureWireGuard(m.privateKey, nil, false); err != nil {
+		return fmt.Errorf("failed to configure wireguard interface: %v", err)
+	}
+	if m.publicKey == nil {
+		// The public key is derived by the kernel
+		_, public, err := wireGuardDeviceKeys()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the wireguard public key: %v", err)
+		}
+		if public == nil {
+			return fmt.Errorf("no public key set on the wireguard interface")
+		}
+		m.publicKey = public
+	}
+
+	// The decrypted packets come from the address of the remote node,
+	// reachable through the underlay
+	path := fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/rp_filter", wgDevice)
+	if err := ioutil.WriteFile(path, []byte{'2', '\n'}, 0644); err != nil {
+		log.Warnf("Failed to relax the reverse path filter on %s: %v", wgDevice, err)
+	}
+
+	if err := nlh.LinkSetUp(link); err != nil {
+		return fmt.Errorf("could not bring up the wireguard interface: %v", err)
+	}
+
+	// The marked traffic to the nodes which are not peers yet is dropped
+	// rather than sent in the clear through the main table
+	if err := nlh.RouteAdd(wireGuardUnreachableRoute()); err != nil && err != syscall.EEXIST {
+		return fmt.Errorf("failed to add the unreachable route to the wireguard table: %v", err)
+	}
+
+	rules, err := nlh.RuleList(syscall.AF_INET)
+	if err != nil {
+		return fmt.Errorf("failed to list the routing rules: %v", err)
+	}
+	for _, r := range rules {
+		if r.Mark == int(wgMark) && r.Table == wgTable {
+			return nil
+		}
+	}
+	if err := nlh.RuleAdd(wireGuardRule()); err != nil {
+		return fmt.Errorf("failed to route the encrypted vxlan traffic: %v", err)
+	}
+
+	return nil
+}
+
+func removeWireGuardDevice() error {
+	defer osl.InitOSContext()()
+
+	nlh := ns.NlHandle()
+	if err := nlh.RuleDel(wireGuardRule()); err != nil {
+		log.Warnf("Failed to remove the routing rule of the wireguard interface: %v", err)
+	}
+	if err := nlh.RouteDel(wireGuardUnreachableRoute()); err != nil {
+		log.Warnf("Failed to remove the unreachable route of the wireguard table: %v", err)
+	}
+
+	link, err := nlh.LinkByName(wgDevice)
+	if err != nil {
+		return nil
+	}
+	if err := nlh.LinkDel(link); err != nil {
+		return fmt.Errorf("failed to delete the wireguard interface: %v", err)
+	}
+
+	return nil
+}
+
+// wireGuardRule returns the rule routing the marked vxlan traffic through the
+// wireguard table
+func wireGuardRule() *netlink.Rule {
+	r := netlink.NewRule()
+	r.Table = wgTable
+	r.Mark = int(wgMark)
+	r.Mask = -1
+	r.Flow = -1
+	r.Goto = -1
+	return r
+}
+
+// wireGuardUnreachableRoute returns the default route of the wireguard table,
+// matched by the marked traffic to the nodes without a peer route
+func wireGuardUnreachableRoute() *netlink.Route {
+	return &netlink.Route{
+		Dst:   &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		Table: wgTable,
+		Type:  syscall.RTN_UNREACHABLE,
+	}
+}
+
+func programWireGuardRoute(remoteIP net.IP, add bool) error {
+	defer osl.InitOSContext()()
+
+	nlh := ns.NlHandle()
+	link, err := nlh.LinkByName(wgDevice)
+	if err != nil {
+		return fmt.Errorf("could not find the wireguard interface: %v", err)
+	}
+
+	bits := 8 * len(remoteIP.To4())
+	if bits == 0 {
+		bits = 8 * net.IPv6len
+	}
+	route := &netlink.Route{
+		LinkIndex: link.Attrs().Index,
+		Dst:       &net.IPNet{IP: remoteIP, Mask: net.CIDRMask(bits, bits)},
+		Scope:     netlink.SCOPE_LINK,
+		Table:     wgTable,
+	}
+
+	if !add {
+		if err := nlh.RouteDel(route); err != nil {
+			return fmt.Errorf("failed to remove the wireguard route to %s: %v", remoteIP, err)
+		}
+		return nil
+	}
+	if err := nlh.RouteAdd(route); err != nil && err != syscall.EEXIST {
+		return fmt.Errorf("failed to add the wireguard route to %s: %v", remoteIP, err)
+	}
+
+	return nil
+}
+
+// configureWireGuard sets the private key and the peers of the wireguard
+// interface. The current peers are replaced when replace is set.
+func configureWireGuard(private []byte, peers []wgPeer, replace bool) error {
+	defer osl.InitOSContext()()
+
+	family, err := genlFamilyID(wgGenlName)
+	if err != nil {
+		return err
+	}
+
+	req := nl.NewNetlinkRequest(int(family), syscall.NLM_F_ACK)
+	req.AddData(&genlMsg{cmd: wgCmdSetDev, version: wgGenlVersion})
+	req.AddData(nl.NewRtAttr(wgDeviceAIfname, nl.ZeroTerminated(wgDevice)))
+	if private != nil {
+		req.AddData(nl.NewRtAttr(wgDeviceAPrivateKey, private))
+		req.AddData(nl.NewRtAttr(wgDeviceAListenPort, nl.Uint16Attr(wgPort)))
+	}
+	if replace {
+		req.AddData(nl.NewRtAttr(wgDeviceAFlags, nl.Uint32Attr(wgDeviceFReplacePeer)))
+	}
+	if len(peers) > 0 {
+		req.AddData(wireGuardPeersAttr(peers))
+	}
+
+	_, err = req.Execute(syscall.NETLINK_GENERIC, 0)
+	return err
+}
+
+// wireGuardDeviceKeys returns the private and public keys set on the
+// wireguard interface, if any
+func wireGuardDeviceKeys() ([]byte, []byte, error) {
+	family, err := genlFamilyID(wgGenlName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := nl.NewNetlinkRequest(int(family), syscall.NLM_F_DUMP)
+	req.AddData(&genlMsg{cmd: wgCmdGetDev, version: wgGenlVersion})
+	req.AddData(nl.NewRtAttr(wgDeviceAIfname, nl.ZeroTerminated(wgDevice)))
+
+	msgs, err := req.Execute(syscall.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var private, public []byte
+	for _, m := range msgs {
+		if len(m) < 4 {
+			continue
+		}
+		attrs, err := nl.ParseRouteAttr(m[4:])
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case wgDeviceAPrivateKey:
+				private = a.Value
+			case wgDeviceAPublicKey:
+				public = a.Value
+			}
+		}
+	}
+
+	return private, public, nil
+}
+
+func wireGuardPeersAttr(peers []wgPeer) *nl.RtAttr {
+	attr := nl.NewRtAttr(wgDeviceAPeers|nlaFNested, nil)
+	for _, p := range peers {
+		pAttr := nl.NewRtAttrChild(attr, nlaFNested, nil)
+		nl.NewRtAttrChild(pAttr, wgPeerAPublicKey, p.publicKey)
+		if p.remove {
+			nl.NewRtAttrChild(pAttr, wgPeerAFlags, nl.Uint32Attr(wgPeerFRemoveMe))
+			continue
+		}
+
+		ip, family, bits := p.endpoint.To4(), syscall.AF_INET, 32
+		if ip == nil {
+			ip, family, bits = p.endpoint.To16(), syscall.AF_INET6, 128
+		}
+		nl.NewRtAttrChild(pAttr, wgPeerAFlags, nl.Uint32Attr(wgPeerFReplaceAllowedIPs))
+		nl.NewRtAttrChild(pAttr, wgPeerAEndpoint, sockaddr(ip, family, wgPort))
+
+		ipsAttr := nl.NewRtAttrChild(pAttr, wgPeerAAllowedIPs|nlaFNested, nil)
+		ipAttr := nl.NewRtAttrChild(ipsAttr, nlaFNested, nil)
+		nl.NewRtAttrChild(ipAttr, wgAllowedIPAFamily, nl.Uint16Attr(uint16(family)))
+		nl.NewRtAttrChild(ipAttr, wgAllowedIPAIPAddr, ip)
+		nl.NewRtAttrChild(ipAttr, wgAllowedIPACidrMask, nl.Uint8Attr(uint8(bits)))
+	}
+	return attr
+}
+
+// sockaddr returns the sockaddr_in or sockaddr_in6 structure for the address
+func sockaddr(ip net.IP, family int, port uint16) []byte {
+	var b []byte
+	if family == syscall.AF_INET {
+		b = make([]byte, syscall.SizeofSockaddrInet4)
+		copy(b[4:8], ip)
+	} else {
+		b = make([]byte, syscall.SizeofSockaddrInet6)
+		copy(b[8:24], ip)
+	}
+	nl.NativeEndian().PutUint16(b[0:2], uint16(family))
+	binary.BigEndian.PutUint16(b[2:4], port)
+	return b
+}
+
+// genlFamilyID resolves the generic netlink family name
+func genlFamilyID(name string) (uint16, error) {
+	req := nl.NewNetlinkRequest(genlCtrlID, 0)
+	req.AddData(&genlMsg{cmd: genlCtrlCmdGetFamily, version: 1})
+	req.AddData(nl.NewRtAttr(genlCtrlAttrFamilyName, nl.ZeroTerminated(name)))
+
+	msgs, err := req.Execute(syscall.NETLINK_GENERIC, 0)
+	if err != nil {
+		return 0, fmt.Errorf("could not resolve generic netlink family %s: %v", name, err)
+	}
+
+	for _, m := range msgs {
+		if len(m) < 4 {
+			continue
+		}
+		attrs, err := nl.ParseRouteAttr(m[4:])
+		if err != nil {
+			return 0, err
+		}
+		for _, a := range attrs {
+			if a.Attr.Type == genlCtrlAttrFamilyID && len(a.Value) >= 2 {
+				return nl.NativeEndian().Uint16(a.Value[0:2]), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("generic netlink family %s not found", name)
+}
@@ -0,0 +1,277 @@
+package overlay
+
+import (
+	"bytes"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+)
+
+func TestNewWireGuardKey(t *testing.T) {
+	k1, err := newWireGuardKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k1) != wgKeyLen {
+		t.Fatalf("unexpected key length %d", len(k1))
+	}
+	if k1[0]&7 != 0 || k1[31]&128 != 0 || k1[31]&64 == 0 {
+		t.Fatalf("expected a clamped curve25519 key, got %x", k1)
+	}
+
+	k2, err := newWireGuardKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(k1, k2) {
+		t.Fatal("expected a different key to be generated")
+	}
+}
+
+func TestWireGuardPeersAttr(t *testing.T) {
+	pk := bytes.Repeat([]byte{0xab}, 32)
+	attr := wireGuardPeersAttr([]wgPeer{
+		{publicKey: pk, endpoint: net.ParseIP("192.168.51.2")},
+		{publicKey: pk, remove: true},
+	})
+	if attr.Type != wgDeviceAPeers|nlaFNested {
+		t.Fatalf("unexpected peers attribute type 0x%x", attr.Type)
+	}
+
+	b := attr.Serialize()
+	peers, err := nl.ParseRouteAttr(b[syscall.SizeofRtAttr:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(peers))
+	}
+
+	native := nl.NativeEndian()
+	pAttrs := map[uint16][]byte{}
+	attrs, err := nl.ParseRouteAttr(peers[0].Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range attrs {
+		pAttrs[a.Attr.Type] = a.Value
+	}
+	if !bytes.Equal(pAttrs[wgPeerAPublicKey], pk) {
+		t.Fatal("unexpected peer public key")
+	}
+	if native.Uint32(pAttrs[wgPeerAFlags]) != wgPeerFReplaceAllowedIPs {
+		t.Fatalf("unexpected peer flags %v", pAttrs[wgPeerAFlags])
+	}
+	sa := pAttrs[wgPeerAEndpoint]
+	if len(sa) != syscall.SizeofSockaddrInet4 || native.Uint16(sa[0:2]) != syscall.AF_INET ||
+		sa[2] != wgPort>>8 || sa[3] != wgPort&0xff || !net.IP(sa[4:8]).Equal(net.ParseIP("192.168.51.2")) {
+		t.Fatalf("unexpected peer endpoint %v", sa)
+	}
+	if _, ok := pAttrs[wgPeerAAllowedIPs|nlaFNested]; !ok {
+		t.Fatal("expected the peer allowed ips")
+	}
+
+	attrs, err = nl.ParseRouteAttr(peers[1].Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs) != 2 || attrs[1].Attr.Type != wgPeerAFlags || native.Uint32(attrs[1].Value) != wgPeerFRemoveMe {
+		t.Fatalf("unexpected removed peer attributes %v", attrs)
+	}
+}
+
+// TestWireGuardNamespaces encrypts the vxlan traffic between two nodes
+// emulated by network namespaces connected by a veth pair
+func TestWireGuardNamespaces(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	if !wireGuardSupported() {
+		t.Skip("wireguard is not supported on host")
+	}
+
+	origin, err := netns.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		netns.Set(origin)
+		ns.Init()
+		origin.Close()
+	}()
+
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "wgtest0", TxQLen: 0}, PeerName: "wgtest1"}
+	if err := ns.NlHandle().LinkAdd(veth); err != nil {
+		t.Fatal(err)
+	}
+
+	vni := uint32(4001)
+	nodes := []struct {
+		ifName string
+		ip     net.IP
+		ns     netns.NsHandle
+		d      *driver
+	}{
+		{ifName: "wgtest0", ip: net.ParseIP("192.168.51.1")},
+		{ifName: "wgtest1", ip: net.ParseIP("192.168.51.2")},
+	}
+	for i := range nodes {
+		if nodes[i].ns, err = netns.New(); err != nil {
+			t.Fatal(err)
+		}
+		defer nodes[i].ns.Close()
+		if err := netns.Set(origin); err != nil {
+			t.Fatal(err)
+		}
+		link, err := ns.NlHandle().LinkByName(nodes[i].ifName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ns.NlHandle().LinkSetNsFd(link, int(nodes[i].ns)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enter := func(i int) {
+		if err := netns.Set(nodes[i].ns); err != nil {
+			t.Fatal(err)
+		}
+		ns.Init()
+	}
+
+	for i := range nodes {
+		enter(i)
+		nlh := ns.NlHandle()
+		link, err := nlh.LinkByName(nodes[i].ifName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := &netlink.Addr{IPNet: &net.IPNet{IP: nodes[i].ip, Mask: net.CIDRMask(24, 32)}}
+		if err := nlh.AddrAdd(link, addr); err != nil {
+			t.Fatal(err)
+		}
+		if err := nlh.LinkSetUp(link); err != nil {
+			t.Fatal(err)
+		}
+		nodes[i].d = &driver{
+			advertiseAddress: nodes[i].ip.String(),
+			bindAddress:      nodes[i].ip.String(),
+			secMap:           &encrMap{nodes: map[string][]*spi{}},
+			wgPeers:          &wgPeerMap{nodes: map[string]wgPeer{}, keys: map[string][]byte{}},
+		}
+	}
+
+	// Exchange the public keys as the peer records do
+	var publicKeys [2][]byte
+	for i := range nodes {
+		enter(i)
+		if publicKeys[i], err = nodes[i].d.wireGuardPublicKey(1500 - wgExpansion); err != nil {
+			t.Fatal(err)
+		}
+		if len(publicKeys[i]) != wgKeyLen {
+			t.Fatalf("unexpected public key %x", publicKeys[i])
+		}
+		nodes[1-i].d.setWireGuardPeerKey(nodes[i].ip, publicKeys[i])
+	}
+	if bytes.Equal(publicKeys[0], publicKeys[1]) {
+		t.Fatal("expected the nodes to have different keys")
+	}
+
+	for i := range nodes {
+		enter(i)
+		if err := nodes[i].d.setupWireGuard(nodes[i].ip, nodes[1-i].ip, vni, 1500-wgExpansion); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The vxlan port of the second node
+	enter(1)
+	l, err := net.ListenUDP("udp4", &net.UDPAddr{IP: nodes[1].ip, Port: vxlanPort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	send := func() {
+		enter(0)
+		c, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: nodes[1].ip, Port: vxlanPort})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		hdr := []byte{0x08, 0, 0, 0, byte(vni >> 16), byte(vni >> 8), byte(vni), 0}
+		if _, err := c.Write(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		l.SetReadDeadline(time.Now().Add(5 * time.Second))
+		b := make([]byte, 64)
+		_, from, err := l.ReadFromUDP(b)
+		if err != nil {
+			t.Fatalf("vxlan packet not received: %v", err)
+		}
+		if !from.IP.Equal(nodes[0].ip) {
+			t.Fatalf("unexpected vxlan packet source %s", from.IP)
+		}
+
+		link, err := ns.NlHandle().LinkByName(wgDevice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := link.Attrs().Statistics; s == nil || s.TxPackets == 0 {
+			t.Fatal("expected the vxlan packet to go through the wireguard interface")
+		}
+	}
+	send()
+
+	// The marked traffic to the nodes which are not peers is dropped
+	routes, err := ns.NlHandle().RouteListFiltered(syscall.AF_INET, &netlink.Route{Table: wgTable}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable := false
+	for _, r := range routes {
+		if r.Type == syscall.RTN_UNREACHABLE {
+			unreachable = true
+		}
+	}
+	if !unreachable {
+		t.Fatalf("expected an unreachable route in the wireguard table, got %v", routes)
+	}
+
+	// The first node rotates its key, the second one learns it from the
+	// updated peer records
+	enter(0)
+	if err := nodes[0].d.rotateWireGuard(); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := nodes[0].d.wireGuardPublicKey(1500 - wgExpansion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(rotated, publicKeys[0]) {
+		t.Fatal("expected the key of the node to be rotated")
+	}
+	enter(1)
+	nodes[1].d.setWireGuardPeerKey(nodes[0].ip, rotated)
+	if err := nodes[1].d.setupWireGuard(nodes[1].ip, nodes[0].ip, vni, 1500-wgExpansion); err != nil {
+		t.Fatal(err)
+	}
+	send()
+
+	for i := range nodes {
+		enter(i)
+		if err := nodes[i].d.removeWireGuard(nodes[1-i].ip); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ns.NlHandle().LinkByName(wgDevice); err == nil {
+			t.Fatal("expected the wireguard interface to be removed with its last peer")
+		}
+	}
+}
@@ -199,6 +199,10 @@ func (test *testEndpoint) DisableGatewayService() {
 	test.disableGatewayService = true
 }
 
+func (test *testEndpoint) SetQosPolicy(qos *types.QosPolicy) error {
+	return nil
+}
+
 func (test *testEndpoint) AddTableEntry(tableName string, key string, value []byte) error {
 	return nil
 }
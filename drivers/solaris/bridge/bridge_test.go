@@ -495,6 +495,10 @@ func (te *testEndpoint) AddTableEntry(tableName string, key string, value []byte
 
 func (te *testEndpoint) DisableGatewayService() {}
 
+func (te *testEndpoint) SetQosPolicy(qos *types.QosPolicy) error {
+	return nil
+}
+
 func TestQueryEndpointInfo(t *testing.T) {
 	testQueryEndpointInfo(t, true)
 }
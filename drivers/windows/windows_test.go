@@ -139,3 +139,7 @@ func (test *testEndpoint) AddStaticRoute(destination *net.IPNet, routeType int,
 func (test *testEndpoint) DisableGatewayService() {
 	test.disableGatewayService = true
 }
+
+func (test *testEndpoint) SetQosPolicy(qos *types.QosPolicy) error {
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/docker/libnetwork/drvregistry"
 	"github.com/docker/libnetwork/ipamapi"
 	builtinIpam "github.com/docker/libnetwork/ipams/builtin"
+	dhcpIpam "github.com/docker/libnetwork/ipams/dhcp"
 	nullIpam "github.com/docker/libnetwork/ipams/null"
 	remoteIpam "github.com/docker/libnetwork/ipams/remote"
 )
@@ -13,6 +14,7 @@ func initIPAMDrivers(r *drvregistry.DrvRegistry, lDs, gDs interface{}) error {
 		builtinIpam.Init,
 		remoteIpam.Init,
 		nullIpam.Init,
+		dhcpIpam.Init,
 	} {
 		if err := fn(r, lDs, gDs); err != nil {
 			return err
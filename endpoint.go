@@ -7,6 +7,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/libnetwork/datastore"
@@ -49,6 +50,17 @@ type Endpoint interface {
 // provided by libnetwork, they look like <Create|Join|Leave>Option[...](...)
 type EndpointOption func(ep *endpoint)
 
+// ServiceOption is an option setter function type used to pass the
+// load balancing options of the service to CreateOptionService.
+type ServiceOption func(so *serviceOptions)
+
+// serviceOptions are the load balancing options of the service and
+// the weight of the endpoint among its backends.
+type serviceOptions struct {
+	lbConfig lbConfig
+	weight   uint32
+}
+
 type endpoint struct {
 	name              string
 	id                string
@@ -72,6 +84,8 @@ type endpoint struct {
 	virtualIP         net.IP
 	svcAliases        []string
 	ingressPorts      []*PortConfig
+	lbConfig          lbConfig
+	lbWeight          uint32
 	dbIndex           uint64
 	dbExists          bool
 	serviceEnabled    bool
@@ -101,6 +115,14 @@ func (ep *endpoint) MarshalJSON() ([]byte, error) {
 	epMap["virtualIP"] = ep.virtualIP.String()
 	epMap["ingressPorts"] = ep.ingressPorts
 	epMap["svcAliases"] = ep.svcAliases
+	epMap["lbScheduler"] = ep.lbConfig.scheduler
+	epMap["lbPersistence"] = ep.lbConfig.persistence
+	epMap["lbWeight"] = ep.lbWeight
+	epMap["lbHealthCheckProtocol"] = ep.lbConfig.healthCheck.protocol
+	epMap["lbHealthCheckPort"] = ep.lbConfig.healthCheck.port
+	epMap["lbHealthCheckPath"] = ep.lbConfig.healthCheck.path
+	epMap["lbHealthCheckInterval"] = ep.lbConfig.healthCheck.interval
+	epMap["lbHealthCheckTimeout"] = ep.lbConfig.healthCheck.timeout
 
 	return json.Marshal(epMap)
 }
@@ -211,6 +233,31 @@ func (ep *endpoint) UnmarshalJSON(b []byte) (err error) {
 	json.Unmarshal(pc, &ingressPorts)
 	ep.ingressPorts = ingressPorts
 
+	if v, ok := epMap["lbScheduler"]; ok {
+		ep.lbConfig.scheduler = v.(string)
+	}
+	if v, ok := epMap["lbPersistence"]; ok {
+		ep.lbConfig.persistence = uint32(v.(float64))
+	}
+	if v, ok := epMap["lbWeight"]; ok {
+		ep.lbWeight = uint32(v.(float64))
+	}
+	if v, ok := epMap["lbHealthCheckProtocol"]; ok {
+		ep.lbConfig.healthCheck.protocol = v.(string)
+	}
+	if v, ok := epMap["lbHealthCheckPort"]; ok {
+		ep.lbConfig.healthCheck.port = uint32(v.(float64))
+	}
+	if v, ok := epMap["lbHealthCheckPath"]; ok {
+		ep.lbConfig.healthCheck.path = v.(string)
+	}
+	if v, ok := epMap["lbHealthCheckInterval"]; ok {
+		ep.lbConfig.healthCheck.interval = time.Duration(v.(float64))
+	}
+	if v, ok := epMap["lbHealthCheckTimeout"]; ok {
+		ep.lbConfig.healthCheck.timeout = time.Duration(v.(float64))
+	}
+
 	ma, _ := json.Marshal(epMap["myAliases"])
 	var myAliases []string
 	json.Unmarshal(ma, &myAliases)
@@ -238,6 +285,8 @@ func (ep *endpoint) CopyTo(o datastore.KVObject) error {
 	dstEp.svcName = ep.svcName
 	dstEp.svcID = ep.svcID
 	dstEp.virtualIP = ep.virtualIP
+	dstEp.lbConfig = ep.lbConfig
+	dstEp.lbWeight = ep.lbWeight
 
 	dstEp.svcAliases = make([]string, len(ep.svcAliases))
 	copy(dstEp.svcAliases, ep.svcAliases)
@@ -516,7 +565,11 @@ func (ep *endpoint) sbJoin(sb *sandbox, options ...EndpointOption) error {
 	}
 
 	if sb.needDefaultGW() && sb.getEndpointInGWNetwork() == nil {
-		return sb.setupDefaultGW()
+		if e := sb.setupDefaultGW(); e != nil {
+			return e
+		}
+		n.getController().publishEndpointEvent(EventEndpointJoin, ep, sb)
+		return nil
 	}
 
 	moveExtConn := sb.getGatewayEndpoint() != extEp
@@ -564,6 +617,8 @@ func (ep *endpoint) sbJoin(sb *sandbox, options ...EndpointOption) error {
 		}
 	}
 
+	n.getController().publishEndpointEvent(EventEndpointJoin, ep, sb)
+
 	return nil
 }
 
@@ -714,6 +769,8 @@ func (ep *endpoint) sbLeave(sb *sandbox, force bool, options ...EndpointOption)
 	}
 
 	sb.deleteHostsEntries(n.getSvcRecords(ep))
+	n.getController().publishEndpointEvent(EventEndpointLeave, ep, sb)
+
 	if !sb.inDelete && sb.needDefaultGW() && sb.getEndpointInGWNetwork() == nil {
 		return sb.setupDefaultGW()
 	}
@@ -801,6 +858,8 @@ func (ep *endpoint) Delete(force bool) error {
 		log.Warnf("failed to decrement endpoint count for ep %s: %v", ep.ID(), err)
 	}
 
+	n.getController().publishEndpointEvent(EventEndpointDelete, ep, nil)
+
 	return nil
 }
 
@@ -914,6 +973,14 @@ func CreateOptionPortMapping(portBindings []types.PortBinding) EndpointOption {
 	}
 }
 
+// CreateOptionQosPolicy function returns an option setter for the endpoint
+// bandwidth limits to be passed to network.CreateEndpoint() method.
+func CreateOptionQosPolicy(qos types.QosPolicy) EndpointOption {
+	return func(ep *endpoint) {
+		ep.generic[netlabel.QosPolicy] = qos
+	}
+}
+
 // CreateOptionDNS function returns an option setter for dns entry option to
 // be passed to container Create method.
 func CreateOptionDNS(dns []string) EndpointOption {
@@ -949,13 +1016,56 @@ func CreateOptionAlias(name string, alias string) EndpointOption {
 }
 
 // CreateOptionService function returns an option setter for setting service binding configuration
-func CreateOptionService(name, id string, vip net.IP, ingressPorts []*PortConfig, aliases []string) EndpointOption {
+func CreateOptionService(name, id string, vip net.IP, ingressPorts []*PortConfig, aliases []string, options ...ServiceOption) EndpointOption {
 	return func(ep *endpoint) {
 		ep.svcName = name
 		ep.svcID = id
 		ep.virtualIP = vip
 		ep.ingressPorts = ingressPorts
 		ep.svcAliases = aliases
+
+		so := &serviceOptions{lbConfig: ep.lbConfig, weight: ep.lbWeight}
+		for _, opt := range options {
+			opt(so)
+		}
+		ep.lbConfig = so.lbConfig
+		ep.lbWeight = so.weight
+	}
+}
+
+// ServiceOptionScheduler function returns an option setter for the IPVS
+// scheduler (rr, wrr, lc, sh or mh) balancing the connections to the
+// service, and the timeout in seconds of the affinity of the clients to
+// a backend
+func ServiceOptionScheduler(scheduler string, persistenceTimeout uint32) ServiceOption {
+	return func(so *serviceOptions) {
+		so.lbConfig.scheduler = scheduler
+		so.lbConfig.persistence = persistenceTimeout
+	}
+}
+
+// ServiceOptionHealthCheck function returns an option setter for the
+// health check of the backends of the service. The backends are probed
+// every interval from a sandbox of the node, with a tcp connection to
+// the port or an http GET of the path, and receive no new connections
+// after failing three consecutive probes.
+func ServiceOptionHealthCheck(protocol string, port uint16, path string, interval, timeout time.Duration) ServiceOption {
+	return func(so *serviceOptions) {
+		so.lbConfig.healthCheck = lbHealthCheck{
+			protocol: protocol,
+			port:     uint32(port),
+			path:     path,
+			interval: interval,
+			timeout:  timeout,
+		}
+	}
+}
+
+// ServiceOptionWeight function returns an option setter for the weight
+// of the endpoint among the backends of the service
+func ServiceOptionWeight(weight uint32) ServiceOption {
+	return func(so *serviceOptions) {
+		so.weight = weight
 	}
 }
 
@@ -31,6 +31,11 @@ type EndpointInfo interface {
 
 	// Sandbox returns the attached sandbox if there, nil otherwise.
 	Sandbox() Sandbox
+
+	// LoadBalancerHealth returns the state of the health check of the
+	// endpoint as a backend of its service, nil if the service is
+	// not health checked.
+	LoadBalancerHealth() *BackendHealth
 }
 
 // InterfaceInfo provides an interface to retrieve interface addresses bound to the endpoint.
@@ -56,6 +61,7 @@ type endpointInterface struct {
 	srcName   string
 	dstPrefix string
 	routes    []*net.IPNet
+	qos       *types.QosPolicy
 	v4PoolID  string
 	v6PoolID  string
 }
@@ -85,6 +91,9 @@ func (epi *endpointInterface) MarshalJSON() ([]byte, error) {
 		routes = append(routes, route.String())
 	}
 	epMap["routes"] = routes
+	if epi.qos != nil {
+		epMap["qos"] = epi.qos
+	}
 	epMap["v4PoolID"] = epi.v4PoolID
 	epMap["v6PoolID"] = epi.v6PoolID
 	return json.Marshal(epMap)
@@ -138,6 +147,13 @@ func (epi *endpointInterface) UnmarshalJSON(b []byte) error {
 			epi.routes = append(epi.routes, ipr)
 		}
 	}
+	if v, ok := epMap["qos"]; ok {
+		qb, _ := json.Marshal(v)
+		epi.qos = &types.QosPolicy{}
+		if err := json.Unmarshal(qb, epi.qos); err != nil {
+			return types.InternalErrorf("failed to decode endpoint interface qos policy after json unmarshal: %v", err)
+		}
+	}
 	epi.v4PoolID = epMap["v4PoolID"].(string)
 	epi.v6PoolID = epMap["v6PoolID"].(string)
 
@@ -163,6 +179,11 @@ func (epi *endpointInterface) CopyTo(dstEpi *endpointInterface) error {
 		dstEpi.routes = append(dstEpi.routes, types.GetIPNetCopy(route))
 	}
 
+	if epi.qos != nil {
+		qos := *epi.qos
+		dstEpi.qos = &qos
+	}
+
 	return nil
 }
 
@@ -351,6 +372,18 @@ func (ep *endpoint) Sandbox() Sandbox {
 	return cnt
 }
 
+func (ep *endpoint) LoadBalancerHealth() *BackendHealth {
+	n := ep.getNetwork()
+	ep.Lock()
+	svcID := ep.svcID
+	ep.Unlock()
+
+	if svcID == "" || n == nil {
+		return nil
+	}
+	return n.getController().backendHealth(svcID, n.ID(), ep.ID())
+}
+
 func (ep *endpoint) StaticRoutes() []*types.StaticRoute {
 	ep.Lock()
 	defer ep.Unlock()
@@ -415,6 +448,20 @@ func (ep *endpoint) DisableGatewayService() {
 	ep.joinInfo.disableGatewayService = true
 }
 
+func (ep *endpoint) SetQosPolicy(qos *types.QosPolicy) error {
+	ep.Lock()
+	defer ep.Unlock()
+
+	if qos == nil {
+		ep.iface.qos = nil
+		return nil
+	}
+
+	q := *qos
+	ep.iface.qos = &q
+	return nil
+}
+
 func (epj *endpointJoinInfo) MarshalJSON() ([]byte, error) {
 	epMap := make(map[string]interface{})
 	if epj.gw != nil {
@@ -0,0 +1,195 @@
+package libnetwork
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// EventType identifies the kind of lifecycle change carried by an Event
+type EventType string
+
+const (
+	// EventNetworkCreate is emitted when a network has been created
+	EventNetworkCreate EventType = "network.create"
+	// EventNetworkDelete is emitted when a network has been deleted
+	EventNetworkDelete EventType = "network.delete"
+	// EventEndpointCreate is emitted when an endpoint has been created
+	EventEndpointCreate EventType = "endpoint.create"
+	// EventEndpointJoin is emitted when an endpoint has joined a sandbox
+	EventEndpointJoin EventType = "endpoint.join"
+	// EventEndpointLeave is emitted when an endpoint has left a sandbox
+	EventEndpointLeave EventType = "endpoint.leave"
+	// EventEndpointDelete is emitted when an endpoint has been deleted
+	EventEndpointDelete EventType = "endpoint.delete"
+	// EventSandboxCreate is emitted when a sandbox has been created
+	EventSandboxCreate EventType = "sandbox.create"
+	// EventSandboxDestroy is emitted when a sandbox has been destroyed
+	EventSandboxDestroy EventType = "sandbox.destroy"
+	// EventServiceBindingAdd is emitted when a backend is added to a service
+	EventServiceBindingAdd EventType = "service.binding.add"
+	// EventServiceBindingRemove is emitted when a backend is removed from a service
+	EventServiceBindingRemove EventType = "service.binding.remove"
+)
+
+// eventQueueSize is the number of events buffered for each subscriber.
+// Events are dropped for a subscriber whose queue is full so that a slow
+// consumer never stalls the controller.
+const eventQueueSize = 256
+
+// Event describes a network, endpoint, sandbox or service binding lifecycle change.
+// Only the fields relevant to the event type are populated.
+type Event struct {
+	Type         EventType `json:"type"`
+	Time         time.Time `json:"time"`
+	NetworkID    string    `json:"network_id,omitempty"`
+	NetworkName  string    `json:"network_name,omitempty"`
+	EndpointID   string    `json:"endpoint_id,omitempty"`
+	EndpointName string    `json:"endpoint_name,omitempty"`
+	SandboxID    string    `json:"sandbox_id,omitempty"`
+	ContainerID  string    `json:"container_id,omitempty"`
+	ServiceID    string    `json:"service_id,omitempty"`
+	ServiceName  string    `json:"service_name,omitempty"`
+	IP           net.IP    `json:"ip,omitempty"`
+}
+
+// EventFilter selects the events delivered to a subscriber. Empty fields
+// match everything.
+type EventFilter struct {
+	Types     []EventType
+	NetworkID string
+	SandboxID string
+}
+
+func (f *EventFilter) match(ev *Event) bool {
+	if f.NetworkID != "" && f.NetworkID != ev.NetworkID {
+		return false
+	}
+	if f.SandboxID != "" && f.SandboxID != ev.SandboxID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == ev.Type {
+			return true
+		}
+	}
+	return false
+}
+
+type eventSubscriber struct {
+	filter EventFilter
+	ch     chan Event
+}
+
+type eventBroker struct {
+	subscribers map[*eventSubscriber]struct{}
+	sync.Mutex
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[*eventSubscriber]struct{})}
+}
+
+func (b *eventBroker) subscribe(filter EventFilter) (<-chan Event, func()) {
+	s := &eventSubscriber{filter: filter, ch: make(chan Event, eventQueueSize)}
+
+	b.Lock()
+	b.subscribers[s] = struct{}{}
+	b.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.Lock()
+			delete(b.subscribers, s)
+			b.Unlock()
+			close(s.ch)
+		})
+	}
+
+	return s.ch, cancel
+}
+
+func (b *eventBroker) publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	for s := range b.subscribers {
+		if !s.filter.match(&ev) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			log.Warnf("Dropping %s event for slow subscriber", ev.Type)
+		}
+	}
+}
+
+// Subscribe returns a channel on which the events matching the passed filter
+// are delivered, and a function which cancels the subscription and closes the
+// channel.
+func (c *controller) Subscribe(filter EventFilter) (<-chan Event, func()) {
+	return c.events.subscribe(filter)
+}
+
+func (c *controller) publishEvent(ev Event) {
+	if c.events == nil {
+		return
+	}
+	c.events.publish(ev)
+}
+
+func (c *controller) publishNetworkEvent(t EventType, n *network) {
+	c.publishEvent(Event{
+		Type:        t,
+		NetworkID:   n.ID(),
+		NetworkName: n.Name(),
+	})
+}
+
+func (c *controller) publishEndpointEvent(t EventType, ep *endpoint, sb *sandbox) {
+	ev := Event{
+		Type:         t,
+		EndpointID:   ep.ID(),
+		EndpointName: ep.Name(),
+	}
+	if n := ep.getNetwork(); n != nil {
+		ev.NetworkID = n.ID()
+		ev.NetworkName = n.Name()
+	}
+	if sb != nil {
+		ev.SandboxID = sb.ID()
+		ev.ContainerID = sb.ContainerID()
+	}
+	c.publishEvent(ev)
+}
+
+func (c *controller) publishSandboxEvent(t EventType, sb *sandbox) {
+	c.publishEvent(Event{
+		Type:        t,
+		SandboxID:   sb.ID(),
+		ContainerID: sb.ContainerID(),
+	})
+}
+
+func (c *controller) publishServiceBindingEvent(t EventType, name, sid string, n Network, eid string, ip net.IP) {
+	c.publishEvent(Event{
+		Type:        t,
+		NetworkID:   n.ID(),
+		NetworkName: n.Name(),
+		EndpointID:  eid,
+		ServiceID:   sid,
+		ServiceName: name,
+		IP:          ip,
+	})
+}
@@ -0,0 +1,74 @@
+package libnetwork
+
+import (
+	"testing"
+)
+
+func TestEventFilter(t *testing.T) {
+	ev := &Event{Type: EventEndpointJoin, NetworkID: "n1", SandboxID: "s1"}
+
+	filters := []struct {
+		filter EventFilter
+		match  bool
+	}{
+		{EventFilter{}, true},
+		{EventFilter{Types: []EventType{EventEndpointJoin}}, true},
+		{EventFilter{Types: []EventType{EventNetworkCreate, EventEndpointJoin}}, true},
+		{EventFilter{Types: []EventType{EventEndpointLeave}}, false},
+		{EventFilter{NetworkID: "n1"}, true},
+		{EventFilter{NetworkID: "n2"}, false},
+		{EventFilter{SandboxID: "s1", Types: []EventType{EventEndpointJoin}}, true},
+		{EventFilter{SandboxID: "s2"}, false},
+	}
+
+	for i, f := range filters {
+		if f.filter.match(ev) != f.match {
+			t.Fatalf("filter %d: expected match %v for %v", i, f.match, f.filter)
+		}
+	}
+}
+
+func TestEventBroker(t *testing.T) {
+	b := newEventBroker()
+
+	all, cancelAll := b.subscribe(EventFilter{})
+	nw, cancelNw := b.subscribe(EventFilter{Types: []EventType{EventNetworkCreate}})
+	defer cancelNw()
+
+	b.publish(Event{Type: EventNetworkCreate, NetworkID: "n1"})
+	b.publish(Event{Type: EventSandboxCreate, SandboxID: "s1"})
+
+	for _, exp := range []EventType{EventNetworkCreate, EventSandboxCreate} {
+		ev := <-all
+		if ev.Type != exp {
+			t.Fatalf("expected event %s, got %s", exp, ev.Type)
+		}
+		if ev.Time.IsZero() {
+			t.Fatalf("event %s has no timestamp", ev.Type)
+		}
+	}
+
+	ev := <-nw
+	if ev.Type != EventNetworkCreate || ev.NetworkID != "n1" {
+		t.Fatalf("unexpected event on filtered subscription: %v", ev)
+	}
+	select {
+	case ev := <-nw:
+		t.Fatalf("unexpected event on filtered subscription: %v", ev)
+	default:
+	}
+
+	cancelAll()
+	cancelAll()
+	if _, ok := <-all; ok {
+		t.Fatalf("expected channel to be closed after cancel")
+	}
+
+	// A full subscriber queue must not block publishers
+	for i := 0; i < eventQueueSize+10; i++ {
+		b.publish(Event{Type: EventNetworkCreate})
+	}
+	if len(nw) != eventQueueSize {
+		t.Fatalf("expected %d queued events, got %d", eventQueueSize, len(nw))
+	}
+}
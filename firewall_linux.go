@@ -0,0 +1,13 @@
+package libnetwork
+
+import "github.com/docker/libnetwork/iptables"
+
+// setFirewallBackend selects the backend programming the iptables rules of
+// the drivers and of the service load balancing. An empty name keeps the
+// default selection.
+func setFirewallBackend(name string) error {
+	if name == "" {
+		return nil
+	}
+	return iptables.SetBackend(name)
+}
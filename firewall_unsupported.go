@@ -0,0 +1,12 @@
+// +build !linux
+
+package libnetwork
+
+import "fmt"
+
+func setFirewallBackend(name string) error {
+	if name != "" {
+		return fmt.Errorf("firewall backend selection is not supported on this platform")
+	}
+	return nil
+}
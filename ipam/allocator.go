@@ -594,3 +594,114 @@ func (a *Allocator) DumpDatabase() string {
 
 	return s
 }
+
+// InspectPools returns the utilization of the pools of the address space, or
+// of all the address spaces when none is specified. The sub-pools are reported
+// along with the pool they are carved from.
+func (a *Allocator) InspectPools(addressSpace string) ([]*ipamapi.PoolInfo, error) {
+	var spaces []string
+	if addressSpace != "" {
+		spaces = append(spaces, addressSpace)
+	} else {
+		a.Lock()
+		for as := range a.addrSpaces {
+			spaces = append(spaces, as)
+		}
+		a.Unlock()
+		sort.Strings(spaces)
+	}
+
+	var pools []*ipamapi.PoolInfo
+	for _, as := range spaces {
+		if err := a.refresh(as); err != nil {
+			return nil, err
+		}
+
+		aSpace, err := a.getAddrSpace(as)
+		if err != nil {
+			return nil, err
+		}
+
+		aSpace.Lock()
+		ordered := make([]string, 0, len(aSpace.subnets))
+		keys := make(map[string]SubnetKey, len(aSpace.subnets))
+		subnets := make(map[SubnetKey]*PoolData, len(aSpace.subnets))
+		for k, p := range aSpace.subnets {
+			ordered = append(ordered, k.String())
+			keys[k.String()] = k
+			subnets[k] = p
+		}
+		aSpace.Unlock()
+
+		sort.Strings(ordered)
+
+		parents := make(map[SubnetKey]*ipamapi.PoolInfo)
+		for _, ks := range ordered {
+			k := keys[ks]
+			p := subnets[k]
+			if p.Range != nil {
+				continue
+			}
+			bm, err := a.retrieveBitmask(k, p.Pool)
+			if err != nil {
+				return nil, err
+			}
+			info := &ipamapi.PoolInfo{AddressSpace: as, Pool: k.Subnet}
+			info.Total, info.Used, info.Allocated = poolUtilization(bm, p.Pool, 0, bm.Bits()-1)
+			parents[k] = info
+			pools = append(pools, info)
+		}
+
+		for _, ks := range ordered {
+			k := keys[ks]
+			p := subnets[k]
+			if p.Range == nil {
+				continue
+			}
+			parent, ok := parents[p.ParentKey]
+			if !ok {
+				continue
+			}
+			bm, err := a.retrieveBitmask(p.ParentKey, subnets[p.ParentKey].Pool)
+			if err != nil {
+				return nil, err
+			}
+			info := &ipamapi.SubPoolInfo{
+				SubPool: k.ChildSubnet,
+				Start:   generateAddress(p.Range.Start, p.Pool).String(),
+				End:     generateAddress(p.Range.End, p.Pool).String(),
+			}
+			info.Total, info.Used, info.Allocated = poolUtilization(bm, p.Pool, p.Range.Start, p.Range.End)
+			parent.SubPools = append(parent.SubPools, info)
+		}
+	}
+
+	return pools, nil
+}
+
+// poolUtilization returns the number of addresses and the allocated addresses
+// of the range of ordinals of the pool bitmask, without the reserved ones
+func poolUtilization(bm *bitseq.Handle, nw *net.IPNet, start, end uint64) (uint64, uint64, []string) {
+	last := bm.Bits() - 1
+	reserved := func(ordinal uint64) bool {
+		return ordinal == 0 || (ordinal == last && getAddressVersion(nw.IP) == v4)
+	}
+
+	total := end - start + 1
+	if start == 0 {
+		total--
+	}
+	if end == last && last != 0 && reserved(last) {
+		total--
+	}
+
+	allocated := []string{}
+	for _, o := range bm.Selected(start, end) {
+		if reserved(o) {
+			continue
+		}
+		allocated = append(allocated, generateAddress(o, nw).String())
+	}
+
+	return total, uint64(len(allocated)), allocated
+}
@@ -695,6 +695,96 @@ func TestRequestReleaseAddressFromSubPool(t *testing.T) {
 	}
 }
 
+func TestInspectPools(t *testing.T) {
+	a, err := getAllocator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.addrSpaces["rosso"] = &addrSpace{
+		id:      dsConfigKey + "/" + "rosso",
+		ds:      a.addrSpaces[localAddressSpace].ds,
+		alloc:   a.addrSpaces[localAddressSpace].alloc,
+		scope:   a.addrSpaces[localAddressSpace].scope,
+		subnets: map[SubnetKey]*PoolData{},
+	}
+
+	poolID, _, _, err := a.RequestPool("rosso", "172.28.0.0/16", "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subPoolID, _, _, err := a.RequestPool("rosso", "172.28.0.0/16", "172.28.30.0/24", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.RequestAddress(poolID, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := a.RequestAddress(subPoolID, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := a.RequestAddress(subPoolID, net.ParseIP("172.28.30.255"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.ReleaseAddress(subPoolID, net.ParseIP("172.28.30.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err := a.InspectPools("rosso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 1 {
+		t.Fatalf("Unexpected pools: %v", pools)
+	}
+	p := pools[0]
+	if p.AddressSpace != "rosso" || p.Pool != "172.28.0.0/16" {
+		t.Fatalf("Unexpected pool: %v", p)
+	}
+	if p.Total != 65534 || p.Used != 4 {
+		t.Fatalf("Unexpected pool utilization: %d/%d", p.Used, p.Total)
+	}
+	expected := []string{"172.28.0.1", "172.28.30.0", "172.28.30.2", "172.28.30.255"}
+	if len(p.Allocated) != len(expected) {
+		t.Fatalf("Unexpected allocated addresses: %v", p.Allocated)
+	}
+	for i, ip := range expected {
+		if p.Allocated[i] != ip {
+			t.Fatalf("Unexpected allocated addresses: %v", p.Allocated)
+		}
+	}
+
+	if len(p.SubPools) != 1 {
+		t.Fatalf("Unexpected sub-pools: %v", p.SubPools)
+	}
+	sp := p.SubPools[0]
+	if sp.SubPool != "172.28.30.0/24" || sp.Start != "172.28.30.0" || sp.End != "172.28.30.255" {
+		t.Fatalf("Unexpected sub-pool: %v", sp)
+	}
+	if sp.Total != 256 || sp.Used != 3 || len(sp.Allocated) != 3 || sp.Allocated[0] != "172.28.30.0" {
+		t.Fatalf("Unexpected sub-pool utilization: %v", sp)
+	}
+
+	if _, err := a.InspectPools("blu"); err == nil {
+		t.Fatalf("Expected failure inspecting an unknown address space")
+	}
+
+	pools, err = a.InspectPools("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, p := range pools {
+		if p.AddressSpace == "rosso" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Pools of all the address spaces not inspected: %v", pools)
+	}
+}
+
 func TestGetAddress(t *testing.T) {
 	input := []string{
 		/*"10.0.0.0/8", "10.0.0.0/9", "10.0.0.0/10",*/ "10.0.0.0/11", "10.0.0.0/12", "10.0.0.0/13", "10.0.0.0/14",
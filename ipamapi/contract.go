@@ -18,6 +18,8 @@ const (
 	DefaultIPAM = "default"
 	// NullIPAM is the name of the built-in null ipam driver
 	NullIPAM = "null"
+	// DHCPIPAM is the name of the built-in dhcp ipam driver
+	DHCPIPAM = "dhcp"
 	// PluginEndpointType represents the Endpoint Type used by Plugin system
 	PluginEndpointType = "IpamDriver"
 	// RequestAddressType represents the Address Type used when requesting an address
@@ -91,3 +93,34 @@ type Capability struct {
 	// request and the address request for current local networks
 	RequiresRequestReplay bool
 }
+
+// PoolInspector is the optional interface of the IPAM drivers which can report
+// the utilization of their pools
+type PoolInspector interface {
+	// InspectPools returns the utilization of the pools of the address space,
+	// or of the pools of all the address spaces when none is specified
+	InspectPools(addressSpace string) ([]*PoolInfo, error)
+}
+
+// PoolInfo represents the utilization of an address pool. The network address
+// of the pool, and the broadcast address of the IPv4 pools, are reserved and
+// not accounted for.
+type PoolInfo struct {
+	AddressSpace string
+	Pool         string
+	Total        uint64
+	Used         uint64
+	Allocated    []string
+	SubPools     []*SubPoolInfo `json:",omitempty"`
+}
+
+// SubPoolInfo represents the utilization of the range of addresses of a pool
+// from which the addresses of a network are allocated
+type SubPoolInfo struct {
+	SubPool   string
+	Start     string
+	End       string
+	Total     uint64
+	Used      uint64
+	Allocated []string
+}
@@ -0,0 +1,161 @@
+package dhcp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+var (
+	// number of times a request is sent before giving up
+	exchangeAttempts = 3
+	// time to wait for a reply before sending the request again
+	exchangeTimeout = 2 * time.Second
+
+	serverAddr = &net.UDPAddr{IP: net.IPv4bcast, Port: 67}
+
+	errNak = errors.New("DHCP server refused the lease")
+)
+
+// client exchanges DHCP messages on an interface on behalf of the endpoints
+// of the networks attached to it. All the messages are broadcast, as the
+// leased addresses are not configured on the interface the client uses, and
+// the replies are dispatched to the pending requests by transaction id.
+type client struct {
+	sync.Mutex
+	iface   string
+	conn    net.PacketConn
+	pending map[uint32]chan *message
+}
+
+func newClient(iface string) (*client, error) {
+	conn, err := listenUDP(iface, 68)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DHCP client socket on %s: %v", iface, err)
+	}
+	c := &client{
+		iface:   iface,
+		conn:    conn,
+		pending: map[uint32]chan *message{},
+	}
+	go c.receive()
+	return c, nil
+}
+
+func (c *client) close() error {
+	return c.conn.Close()
+}
+
+func (c *client) receive() {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
+				continue
+			}
+			return
+		}
+		m, err := unmarshalMessage(buf[:n])
+		if err != nil {
+			logrus.Debugf("Ignoring invalid DHCP message on %s: %v", c.iface, err)
+			continue
+		}
+		if m.op != bootReply {
+			continue
+		}
+
+		c.Lock()
+		ch, ok := c.pending[m.xid]
+		c.Unlock()
+		if ok {
+			select {
+			case ch <- m:
+			default:
+			}
+		}
+	}
+}
+
+// exchange sends the request until a reply of one of the expected types is
+// received from the server
+func (c *client) exchange(req *message, expected ...byte) (*message, error) {
+	ch := make(chan *message, 4)
+	c.Lock()
+	c.pending[req.xid] = ch
+	c.Unlock()
+	defer func() {
+		c.Lock()
+		delete(c.pending, req.xid)
+		c.Unlock()
+	}()
+
+	b := req.marshal()
+	for i := 0; i < exchangeAttempts; i++ {
+		if _, err := c.conn.WriteTo(b, serverAddr); err != nil {
+			return nil, fmt.Errorf("failed to send DHCP request on %s: %v", c.iface, err)
+		}
+		timeout := time.After(exchangeTimeout)
+	wait:
+		for {
+			select {
+			case m := <-ch:
+				if m.chaddr.String() != req.chaddr.String() {
+					continue
+				}
+				for _, t := range expected {
+					if m.messageType() == t {
+						return m, nil
+					}
+				}
+			case <-timeout:
+				break wait
+			}
+		}
+	}
+	return nil, fmt.Errorf("no reply from DHCP server on %s", c.iface)
+}
+
+// discover returns the address offered by a server to the client
+// identified by mac
+func (c *client) discover(mac net.HardwareAddr) (*message, error) {
+	req := newRequestMessage(msgDiscover, rand.Uint32(), mac)
+	req.options[optParamRequest] = []byte{optSubnetMask, optRouter, optLeaseTime, optRenewalTime}
+	return c.exchange(req, msgOffer)
+}
+
+// request requests the lease of the address to the server identified by
+// serverID, after it offered it. Without serverID, it requests the
+// confirmation of a lease obtained earlier, which also extends it.
+func (c *client) request(mac net.HardwareAddr, ip, serverID net.IP) (*message, error) {
+	req := newRequestMessage(msgRequest, rand.Uint32(), mac)
+	req.options[optRequestedIP] = ip.To4()
+	req.options[optParamRequest] = []byte{optSubnetMask, optRouter, optLeaseTime, optRenewalTime}
+	if serverID != nil {
+		req.options[optServerID] = serverID.To4()
+	}
+	ack, err := c.exchange(req, msgAck, msgNak)
+	if err != nil {
+		return nil, err
+	}
+	if ack.messageType() == msgNak {
+		return nil, errNak
+	}
+	return ack, nil
+}
+
+// release relinquishes the lease of the address
+func (c *client) release(mac net.HardwareAddr, ip, serverID net.IP) error {
+	req := newRequestMessage(msgRelease, rand.Uint32(), mac)
+	req.ciaddr = ip
+	if serverID != nil {
+		req.options[optServerID] = serverID.To4()
+	}
+	_, err := c.conn.WriteTo(req.marshal(), serverAddr)
+	return err
+}
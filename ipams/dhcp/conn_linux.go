@@ -0,0 +1,36 @@
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenUDP returns a socket bound to the port on all the addresses of the
+// interface, allowed to send and receive broadcast datagrams even when the
+// interface has no address configured.
+func listenUDP(iface string, port int) (net.PacketConn, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.IPPROTO_UDP)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range []int{syscall.SO_REUSEADDR, syscall.SO_BROADCAST} {
+		if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, opt, 1); err != nil {
+			syscall.Close(fd)
+			return nil, err
+		}
+	}
+	if err := syscall.BindToDevice(fd, iface); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to bind to device %s: %v", iface, err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrInet4{Port: port}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("dhcp-%s", iface))
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
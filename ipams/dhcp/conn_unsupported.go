@@ -0,0 +1,12 @@
+// +build !linux
+
+package dhcp
+
+import (
+	"fmt"
+	"net"
+)
+
+func listenUDP(iface string, port int) (net.PacketConn, error) {
+	return nil, fmt.Errorf("dhcp ipam driver is not supported on this platform")
+}
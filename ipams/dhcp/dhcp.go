@@ -0,0 +1,307 @@
+// Package dhcp implements the dhcp ipam driver, which leases the endpoint
+// addresses from the DHCP server of the network an interface of the host is
+// attached to. It is meant for the macvlan and ipvlan networks sharing the
+// physical network of the host, the interface being the parent of the
+// network.
+package dhcp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/ipamapi"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/netutils"
+	"github.com/docker/libnetwork/types"
+)
+
+const (
+	// InterfaceOpt is the pool option naming the interface the DHCP
+	// requests are sent on
+	InterfaceOpt = "dhcp_interface"
+
+	addressSpace = "dhcp"
+	// default lease duration, when the server does not tell it
+	defaultLeaseTime = time.Hour
+)
+
+var (
+	// time to wait before renewing a lease again after a failure
+	renewRetryInterval = 30 * time.Second
+)
+
+type allocator struct {
+	sync.Mutex
+	pools map[string]*pool
+}
+
+// pool is the subnet of the network attached to an interface
+type pool struct {
+	id      string
+	iface   string
+	subnet  *net.IPNet
+	gateway net.IP
+	client  *client
+	leases  map[string]*lease
+}
+
+// lease is an address leased to an endpoint, renewed until released
+type lease struct {
+	sync.Mutex
+	mac      net.HardwareAddr
+	ip       net.IP
+	serverID net.IP
+	expiry   time.Time
+	timer    *time.Timer
+	released bool
+}
+
+// Init registers the dhcp ipam driver with libnetwork
+func Init(ic ipamapi.Callback, l, g interface{}) error {
+	a := &allocator{pools: map[string]*pool{}}
+	cps := &ipamapi.Capability{RequiresMACAddress: true, RequiresRequestReplay: true}
+	return ic.RegisterIpamDriverWithCapabilities(ipamapi.DHCPIPAM, a, cps)
+}
+
+func (a *allocator) GetDefaultAddressSpaces() (string, string, error) {
+	return addressSpace, addressSpace, nil
+}
+
+// RequestPool returns the subnet of the network attached to the interface,
+// as learnt from the offer of a DHCP server, along with the gateway of the
+// network. The passed pool must match the subnet.
+func (a *allocator) RequestPool(as, requestedPool, subPool string, options map[string]string, v6 bool) (string, *net.IPNet, map[string]string, error) {
+	if as != addressSpace {
+		return "", nil, nil, types.BadRequestErrorf("unknown address space: %s", as)
+	}
+	if subPool != "" {
+		return "", nil, nil, types.BadRequestErrorf("dhcp ipam driver does not handle specific address subpool requests")
+	}
+	if v6 {
+		return "", nil, nil, types.BadRequestErrorf("dhcp ipam driver does not handle IPv6 address pool requests")
+	}
+	iface := options[InterfaceOpt]
+	if iface == "" {
+		return "", nil, nil, types.BadRequestErrorf("dhcp ipam driver requires the %s option", InterfaceOpt)
+	}
+
+	c, err := newClient(iface)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	offer, err := c.discover(netutils.GenerateRandomMAC())
+	if err != nil {
+		c.close()
+		return "", nil, nil, types.NoServiceErrorf("failed to discover the DHCP server: %v", err)
+	}
+	mask := offer.options[optSubnetMask]
+	if len(mask) != 4 {
+		c.close()
+		return "", nil, nil, types.NoServiceErrorf("DHCP server on %s did not provide the subnet mask", iface)
+	}
+	subnet := &net.IPNet{IP: offer.yiaddr.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+
+	if requestedPool != "" {
+		_, nw, err := net.ParseCIDR(requestedPool)
+		if err != nil {
+			c.close()
+			return "", nil, nil, types.BadRequestErrorf("invalid pool %s: %v", requestedPool, err)
+		}
+		if !types.CompareIPNet(nw, subnet) {
+			c.close()
+			return "", nil, nil, types.BadRequestErrorf("pool %s does not match the %s subnet served on %s", requestedPool, subnet, iface)
+		}
+	}
+
+	p := &pool{
+		id:      fmt.Sprintf("%s/%s/%s", addressSpace, iface, subnet),
+		iface:   iface,
+		subnet:  subnet,
+		gateway: offer.ipOption(optRouter),
+		client:  c,
+		leases:  map[string]*lease{},
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if old, ok := a.pools[p.id]; ok {
+		// the request of a pool already in use, which happens when
+		// the requests are replayed
+		c.close()
+		return old.id, old.subnet, nil, nil
+	}
+	a.pools[p.id] = p
+
+	logrus.Debugf("dhcp ipam: pool %s on %s, gateway %v", subnet, iface, p.gateway)
+	return p.id, subnet, nil, nil
+}
+
+func (a *allocator) ReleasePool(poolID string) error {
+	a.Lock()
+	p, ok := a.pools[poolID]
+	if !ok {
+		a.Unlock()
+		return types.NotFoundErrorf("unknown pool id: %s", poolID)
+	}
+	delete(a.pools, poolID)
+	a.Unlock()
+
+	for _, l := range p.leases {
+		p.release(l)
+	}
+	return p.client.close()
+}
+
+func (a *allocator) getPool(poolID string) (*pool, error) {
+	a.Lock()
+	defer a.Unlock()
+	p, ok := a.pools[poolID]
+	if !ok {
+		return nil, types.NotFoundErrorf("unknown pool id: %s", poolID)
+	}
+	return p, nil
+}
+
+// RequestAddress leases an address for the endpoint identified by the MAC
+// address option. When the address is passed, it is requested to the
+// server, which happens when the request is replayed on daemon start.
+func (a *allocator) RequestAddress(poolID string, ip net.IP, opts map[string]string) (*net.IPNet, map[string]string, error) {
+	p, err := a.getPool(poolID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ip != nil && !p.subnet.Contains(ip) {
+		return nil, nil, ipamapi.ErrIPOutOfRange
+	}
+
+	if opts[ipamapi.RequestAddressType] == netlabel.Gateway {
+		if ip == nil {
+			ip = p.gateway
+		}
+		if ip == nil {
+			return nil, nil, types.NoServiceErrorf("DHCP server on %s did not provide a gateway", p.iface)
+		}
+		return &net.IPNet{IP: ip, Mask: p.subnet.Mask}, nil, nil
+	}
+
+	mac, err := net.ParseMAC(opts[netlabel.MacAddress])
+	if err != nil {
+		return nil, nil, types.BadRequestErrorf("dhcp ipam driver requires the endpoint MAC address: %v", err)
+	}
+
+	var serverID net.IP
+	if ip == nil {
+		offer, err := p.client.discover(mac)
+		if err != nil {
+			return nil, nil, types.NoServiceErrorf("failed to lease an address: %v", err)
+		}
+		ip, serverID = offer.yiaddr, offer.ipOption(optServerID)
+		if !p.subnet.Contains(ip) {
+			return nil, nil, types.InternalErrorf("DHCP server offered %s out of the pool %s", ip, p.subnet)
+		}
+	}
+
+	ack, err := p.client.request(mac, ip, serverID)
+	if err != nil {
+		if err == errNak && serverID == nil {
+			return nil, nil, ipamapi.ErrIPAlreadyAllocated
+		}
+		return nil, nil, types.NoServiceErrorf("failed to lease %s: %v", ip, err)
+	}
+
+	l := &lease{mac: mac, ip: ack.yiaddr, serverID: ack.ipOption(optServerID)}
+	l.Lock()
+	p.schedule(l, ack)
+	l.Unlock()
+
+	a.Lock()
+	p.leases[l.ip.String()] = l
+	a.Unlock()
+
+	logrus.Debugf("dhcp ipam: leased %s to %s", l.ip, mac)
+	return &net.IPNet{IP: l.ip, Mask: p.subnet.Mask}, nil, nil
+}
+
+func (a *allocator) ReleaseAddress(poolID string, ip net.IP) error {
+	p, err := a.getPool(poolID)
+	if err != nil {
+		return err
+	}
+
+	a.Lock()
+	l, ok := p.leases[ip.String()]
+	delete(p.leases, ip.String())
+	a.Unlock()
+
+	// the gateway address is not leased
+	if !ok {
+		return nil
+	}
+	return p.release(l)
+}
+
+// schedule programs the renewal of the lease, at the time the server asked
+// for or at half of its duration. It must be called with the lease locked.
+func (p *pool) schedule(l *lease, ack *message) {
+	duration := ack.durationOption(optLeaseTime)
+	if duration == 0 {
+		duration = defaultLeaseTime
+	}
+	renew := ack.durationOption(optRenewalTime)
+	if renew == 0 || renew >= duration {
+		renew = duration / 2
+	}
+	l.expiry = time.Now().Add(duration)
+
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.timer = time.AfterFunc(renew, func() { p.renew(l) })
+}
+
+func (p *pool) renew(l *lease) {
+	ack, err := p.client.request(l.mac, l.ip, nil)
+
+	l.Lock()
+	defer l.Unlock()
+	if l.released {
+		return
+	}
+	if err != nil {
+		if time.Now().After(l.expiry) {
+			logrus.Errorf("dhcp ipam: lease of %s to %s expired: %v", l.ip, l.mac, err)
+		} else {
+			logrus.Warnf("dhcp ipam: failed to renew lease of %s to %s: %v", l.ip, l.mac, err)
+		}
+		l.timer.Reset(renewRetryInterval)
+		return
+	}
+	logrus.Debugf("dhcp ipam: renewed lease of %s to %s", l.ip, l.mac)
+	p.schedule(l, ack)
+}
+
+func (p *pool) release(l *lease) error {
+	l.Lock()
+	l.released = true
+	l.timer.Stop()
+	l.Unlock()
+
+	if err := p.client.release(l.mac, l.ip, l.serverID); err != nil {
+		return types.InternalErrorf("failed to release lease of %s: %v", l.ip, err)
+	}
+	logrus.Debugf("dhcp ipam: released %s", l.ip)
+	return nil
+}
+
+func (a *allocator) DiscoverNew(dType discoverapi.DiscoveryType, data interface{}) error {
+	return nil
+}
+
+func (a *allocator) DiscoverDelete(dType discoverapi.DiscoveryType, data interface{}) error {
+	return nil
+}
@@ -0,0 +1,313 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/plugingetter"
+	"github.com/docker/libnetwork/ipamapi"
+	"github.com/docker/libnetwork/netlabel"
+	"github.com/docker/libnetwork/testutils"
+	"github.com/docker/libnetwork/types"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+const (
+	clientIface = "dhcptest0"
+	serverIface = "dhcptest1"
+)
+
+var (
+	serverIP = net.ParseIP("192.168.57.1").To4()
+	gateway  = net.ParseIP("192.168.57.254").To4()
+)
+
+// testServer is a minimal DHCP server leasing the addresses from .100 of
+// the 192.168.57.0/24 subnet
+type testServer struct {
+	sync.Mutex
+	conn      net.PacketConn
+	leaseTime uint32
+	leases    map[string]net.IP
+	requests  map[string]int
+}
+
+func (s *testServer) lookup(mac net.HardwareAddr, requested net.IP) net.IP {
+	if ip, ok := s.leases[mac.String()]; ok {
+		return ip
+	}
+	if requested != nil {
+		for _, ip := range s.leases {
+			if ip.Equal(requested) {
+				return nil
+			}
+		}
+		return requested
+	}
+	for i := 100; i < 200; i++ {
+		ip := net.IPv4(192, 168, 57, byte(i)).To4()
+		free := true
+		for _, l := range s.leases {
+			if l.Equal(ip) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return ip
+		}
+	}
+	return nil
+}
+
+func (s *testServer) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req, err := unmarshalMessage(buf[:n])
+		if err != nil || req.op != bootRequest {
+			continue
+		}
+
+		s.Lock()
+		reply := &message{op: bootReply, xid: req.xid, flags: req.flags, chaddr: req.chaddr, options: map[byte][]byte{}}
+		reply.options[optServerID] = serverIP
+		switch req.messageType() {
+		case msgDiscover:
+			reply.yiaddr = s.lookup(req.chaddr, nil)
+			reply.options[optMessageType] = []byte{msgOffer}
+		case msgRequest:
+			s.requests[req.chaddr.String()]++
+			reply.options[optMessageType] = []byte{msgNak}
+			if ip := s.lookup(req.chaddr, req.ipOption(optRequestedIP)); ip != nil && ip.Equal(req.ipOption(optRequestedIP)) {
+				s.leases[req.chaddr.String()] = ip
+				reply.yiaddr = ip
+				reply.options[optMessageType] = []byte{msgAck}
+			}
+		case msgRelease:
+			delete(s.leases, req.chaddr.String())
+			reply = nil
+		default:
+			reply = nil
+		}
+		if reply != nil {
+			lt := make([]byte, 4)
+			binary.BigEndian.PutUint32(lt, s.leaseTime)
+			reply.options[optLeaseTime] = lt
+			reply.options[optSubnetMask] = []byte{255, 255, 255, 0}
+			reply.options[optRouter] = gateway
+		}
+		s.Unlock()
+
+		if reply != nil {
+			s.conn.WriteTo(reply.marshal(), &net.UDPAddr{IP: net.IPv4bcast, Port: 68})
+		}
+	}
+}
+
+// setupServer creates the veth pair linking the test namespace to a
+// namespace where the DHCP server listens
+func setupServer(t *testing.T, leaseTime uint32) (*testServer, func()) {
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: clientIface},
+		PeerName:  serverIface,
+	}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Fatal(err)
+	}
+	client, err := netlink.LinkByName(clientIface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, _ := netlink.ParseAddr("192.168.57.2/24")
+	if err := netlink.AddrAdd(client, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.LinkSetUp(client); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server runs in its own namespace, so that its address is not
+	// local to the client
+	origns, err := netns.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer origns.Close()
+	serverns, err := netns.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverns.Close()
+	defer netns.Set(origns)
+
+	nlh, err := netlink.NewHandleAt(origns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nlh.Delete()
+	server, err := nlh.LinkByName(serverIface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nlh.LinkSetNsFd(server, int(serverns)); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err = netlink.LinkByName(serverIface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, _ = netlink.ParseAddr("192.168.57.1/24")
+	if err := netlink.AddrAdd(server, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := netlink.LinkSetUp(server); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := listenUDP(serverIface, 67)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{
+		conn:      conn,
+		leaseTime: leaseTime,
+		leases:    map[string]net.IP{},
+		requests:  map[string]int{},
+	}
+	go s.serve()
+	return s, func() { conn.Close() }
+}
+
+type testCallback struct {
+	driver ipamapi.Ipam
+	caps   *ipamapi.Capability
+}
+
+func (cb *testCallback) GetPluginGetter() plugingetter.PluginGetter {
+	return nil
+}
+
+func (cb *testCallback) RegisterIpamDriver(name string, driver ipamapi.Ipam) error {
+	return cb.RegisterIpamDriverWithCapabilities(name, driver, &ipamapi.Capability{})
+}
+
+func (cb *testCallback) RegisterIpamDriverWithCapabilities(name string, driver ipamapi.Ipam, caps *ipamapi.Capability) error {
+	if name != ipamapi.DHCPIPAM {
+		return types.BadRequestErrorf("unexpected driver name %s", name)
+	}
+	cb.driver, cb.caps = driver, caps
+	return nil
+}
+
+func TestDHCPLeases(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	server, cleanup := setupServer(t, 2)
+	defer cleanup()
+
+	defer func(timeout time.Duration) { exchangeTimeout = timeout }(exchangeTimeout)
+	exchangeTimeout = 500 * time.Millisecond
+
+	cb := &testCallback{}
+	if err := Init(cb, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !cb.caps.RequiresMACAddress {
+		t.Fatalf("dhcp ipam driver must require the endpoint MAC address")
+	}
+	a := cb.driver
+
+	as, _, err := a.GetDefaultAddressSpaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := a.RequestPool(as, "", "", nil, false); err == nil {
+		t.Fatalf("Expected failure requesting a pool without interface")
+	}
+	opts := map[string]string{InterfaceOpt: clientIface}
+	if _, _, _, err := a.RequestPool(as, "10.0.0.0/24", "", opts, false); err == nil {
+		t.Fatalf("Expected failure requesting a pool not served")
+	}
+	poolID, pool, _, err := a.RequestPool(as, "", "", opts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.String() != "192.168.57.0/24" {
+		t.Fatalf("Unexpected pool %s", pool)
+	}
+
+	gw, _, err := a.RequestAddress(poolID, nil, map[string]string{ipamapi.RequestAddressType: netlabel.Gateway})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gw.IP.Equal(gateway) {
+		t.Fatalf("Unexpected gateway %s", gw)
+	}
+
+	if _, _, err := a.RequestAddress(poolID, nil, nil); err == nil {
+		t.Fatalf("Expected failure requesting an address without MAC address")
+	}
+
+	mac1, mac2 := "02:42:c0:a8:39:01", "02:42:c0:a8:39:02"
+	ip1, _, err := a.RequestAddress(poolID, nil, map[string]string{netlabel.MacAddress: mac1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip1.String() != "192.168.57.100/24" {
+		t.Fatalf("Unexpected address %s", ip1)
+	}
+	ip2, _, err := a.RequestAddress(poolID, net.ParseIP("192.168.57.150"), map[string]string{netlabel.MacAddress: mac2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip2.String() != "192.168.57.150/24" {
+		t.Fatalf("Unexpected address %s", ip2)
+	}
+	if _, _, err := a.RequestAddress(poolID, net.ParseIP("192.168.57.100"), map[string]string{netlabel.MacAddress: "02:42:c0:a8:39:03"}); err != ipamapi.ErrIPAlreadyAllocated {
+		t.Fatalf("Expected failure requesting a leased address, got %v", err)
+	}
+
+	// the leases of two seconds are renewed every second
+	time.Sleep(1500 * time.Millisecond)
+	server.Lock()
+	renewals := server.requests[mac1]
+	server.Unlock()
+	if renewals < 2 {
+		t.Fatalf("Lease was not renewed")
+	}
+
+	if err := a.ReleaseAddress(poolID, ip1.IP); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		server.Lock()
+		_, leased := server.leases[mac1]
+		server.Unlock()
+		if !leased {
+			break
+		}
+		if i == 10 {
+			t.Fatalf("Lease was not released")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if err := a.ReleaseAddress(poolID, gw.IP); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.ReleasePool(poolID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.RequestAddress(poolID, nil, map[string]string{netlabel.MacAddress: mac1}); err == nil {
+		t.Fatalf("Expected failure requesting an address from a released pool")
+	}
+}
@@ -0,0 +1,179 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// BOOTP operations
+const (
+	bootRequest = 1
+	bootReply   = 2
+)
+
+// DHCP message types
+const (
+	msgDiscover = 1
+	msgOffer    = 2
+	msgRequest  = 3
+	msgDecline  = 4
+	msgAck      = 5
+	msgNak      = 6
+	msgRelease  = 7
+)
+
+// DHCP options
+const (
+	optPad          = 0
+	optSubnetMask   = 1
+	optRouter       = 3
+	optRequestedIP  = 50
+	optLeaseTime    = 51
+	optMessageType  = 53
+	optServerID     = 54
+	optParamRequest = 55
+	optRenewalTime  = 58
+	optClientID     = 61
+	optEnd          = 255
+)
+
+const (
+	// size of the fixed part of the message, up to the magic cookie
+	headerLen     = 236
+	flagBroadcast = 0x8000
+)
+
+var magicCookie = []byte{99, 130, 83, 99}
+
+// message is a DHCP message as defined by RFC 2131
+type message struct {
+	op      byte
+	xid     uint32
+	flags   uint16
+	ciaddr  net.IP
+	yiaddr  net.IP
+	siaddr  net.IP
+	chaddr  net.HardwareAddr
+	options map[byte][]byte
+}
+
+func newRequestMessage(msgType byte, xid uint32, mac net.HardwareAddr) *message {
+	m := &message{
+		op:      bootRequest,
+		xid:     xid,
+		flags:   flagBroadcast,
+		chaddr:  mac,
+		options: map[byte][]byte{},
+	}
+	m.options[optMessageType] = []byte{msgType}
+	m.options[optClientID] = append([]byte{1}, mac...)
+	return m
+}
+
+func (m *message) messageType() byte {
+	if t := m.options[optMessageType]; len(t) == 1 {
+		return t[0]
+	}
+	return 0
+}
+
+func (m *message) ipOption(opt byte) net.IP {
+	if v := m.options[opt]; len(v) >= 4 {
+		return net.IP(v[:4])
+	}
+	return nil
+}
+
+func (m *message) durationOption(opt byte) time.Duration {
+	if v := m.options[opt]; len(v) == 4 {
+		return time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+	}
+	return 0
+}
+
+func ip4(ip net.IP) []byte {
+	if ip == nil {
+		return make([]byte, 4)
+	}
+	return ip.To4()
+}
+
+func (m *message) marshal() []byte {
+	b := make([]byte, headerLen, headerLen+64)
+	b[0] = m.op
+	b[1] = 1 // ethernet
+	b[2] = byte(len(m.chaddr))
+	binary.BigEndian.PutUint32(b[4:8], m.xid)
+	binary.BigEndian.PutUint16(b[10:12], m.flags)
+	copy(b[12:16], ip4(m.ciaddr))
+	copy(b[16:20], ip4(m.yiaddr))
+	copy(b[20:24], ip4(m.siaddr))
+	copy(b[28:44], m.chaddr)
+	b = append(b, magicCookie...)
+
+	// the message type is conventionally the first option
+	if v, ok := m.options[optMessageType]; ok {
+		b = append(b, optMessageType, byte(len(v)))
+		b = append(b, v...)
+	}
+	for opt := 1; opt < optEnd; opt++ {
+		v, ok := m.options[byte(opt)]
+		if !ok || opt == optMessageType {
+			continue
+		}
+		b = append(b, byte(opt), byte(len(v)))
+		b = append(b, v...)
+	}
+	b = append(b, optEnd)
+
+	// some relays and servers drop messages shorter than a BOOTP message
+	for len(b) < 300 {
+		b = append(b, optPad)
+	}
+	return b
+}
+
+func unmarshalMessage(b []byte) (*message, error) {
+	if len(b) < headerLen+len(magicCookie) {
+		return nil, fmt.Errorf("message too short: %d bytes", len(b))
+	}
+	if string(b[headerLen:headerLen+4]) != string(magicCookie) {
+		return nil, fmt.Errorf("invalid magic cookie")
+	}
+	hlen := int(b[2])
+	if hlen > 16 {
+		return nil, fmt.Errorf("invalid hardware address length %d", hlen)
+	}
+
+	m := &message{
+		op:      b[0],
+		xid:     binary.BigEndian.Uint32(b[4:8]),
+		flags:   binary.BigEndian.Uint16(b[10:12]),
+		ciaddr:  net.IP(append([]byte(nil), b[12:16]...)),
+		yiaddr:  net.IP(append([]byte(nil), b[16:20]...)),
+		siaddr:  net.IP(append([]byte(nil), b[20:24]...)),
+		chaddr:  net.HardwareAddr(append([]byte(nil), b[28:28+hlen]...)),
+		options: map[byte][]byte{},
+	}
+
+	opts := b[headerLen+4:]
+	for len(opts) > 0 {
+		opt := opts[0]
+		if opt == optEnd {
+			break
+		}
+		if opt == optPad {
+			opts = opts[1:]
+			continue
+		}
+		if len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			return nil, fmt.Errorf("truncated option %d", opt)
+		}
+		l := int(opts[1])
+		m.options[opt] = append(m.options[opt], opts[2:2+l]...)
+		opts = opts[2+l:]
+	}
+	return m, nil
+}
@@ -92,3 +92,14 @@ type ReleaseAddressRequest struct {
 type ReleaseAddressResponse struct {
 	Response
 }
+
+// InspectPoolsRequest represents the expected data in a ``inspect pools`` request message
+type InspectPoolsRequest struct {
+	AddressSpace string
+}
+
+// InspectPoolsResponse represents the response message to a ``inspect pools`` request
+type InspectPoolsResponse struct {
+	Response
+	Pools []*ipamapi.PoolInfo
+}
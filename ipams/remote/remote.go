@@ -134,6 +134,16 @@ func (a *allocator) ReleaseAddress(poolID string, address net.IP) error {
 	return a.call("ReleaseAddress", req, res)
 }
 
+// InspectPools returns the utilization of the pools of the address space
+func (a *allocator) InspectPools(addressSpace string) ([]*ipamapi.PoolInfo, error) {
+	req := &api.InspectPoolsRequest{AddressSpace: addressSpace}
+	res := &api.InspectPoolsResponse{}
+	if err := a.call("InspectPools", req, res); err != nil {
+		return nil, err
+	}
+	return res.Pools, nil
+}
+
 // DiscoverNew is a notification for a new discovery event, such as a new global datastore
 func (a *allocator) DiscoverNew(dType discoverapi.DiscoveryType, data interface{}) error {
 	return nil
@@ -290,3 +290,55 @@ func TestRemoteDriver(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestInspectPools(t *testing.T) {
+	var plugin = "test-ipam-driver-inspect-pools"
+
+	mux := http.NewServeMux()
+	defer setupPlugin(t, plugin, mux)()
+
+	handle(t, mux, "InspectPools", func(msg map[string]interface{}) interface{} {
+		if as, ok := msg["AddressSpace"]; !ok || as.(string) != "white" {
+			return map[string]interface{}{"Error": "unknown address space"}
+		}
+		return map[string]interface{}{
+			"Pools": []map[string]interface{}{{
+				"AddressSpace": "white",
+				"Pool":         "172.18.0.0/16",
+				"Total":        65534,
+				"Used":         1,
+				"Allocated":    []string{"172.18.3.1"},
+				"SubPools": []map[string]interface{}{{
+					"SubPool":   "172.18.3.0/24",
+					"Start":     "172.18.3.0",
+					"End":       "172.18.3.255",
+					"Total":     256,
+					"Used":      1,
+					"Allocated": []string{"172.18.3.1"},
+				}},
+			}},
+		}
+	})
+
+	p, err := plugins.Get(plugin, ipamapi.PluginEndpointType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := newAllocator(plugin, p.Client())
+
+	pools, err := d.(ipamapi.PoolInspector).InspectPools("white")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 1 || pools[0].Pool != "172.18.0.0/16" || pools[0].Total != 65534 || pools[0].Used != 1 {
+		t.Fatalf("Unexpected pools: %v", pools)
+	}
+	if len(pools[0].SubPools) != 1 || pools[0].SubPools[0].End != "172.18.3.255" || pools[0].SubPools[0].Allocated[0] != "172.18.3.1" {
+		t.Fatalf("Unexpected sub-pools: %v", pools[0].SubPools)
+	}
+
+	if _, err := d.(ipamapi.PoolInspector).InspectPools("blue"); err == nil {
+		t.Fatalf("Expected failure inspecting an unknown address space")
+	}
+}
@@ -0,0 +1,93 @@
+package iptables
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Backend programs the firewall rules libnetwork describes with the
+// iptables command line syntax.
+type Backend interface {
+	// Name returns the name the backend is selected with.
+	Name() string
+	// Raw runs the iptables command described by args and returns its output.
+	Raw(args ...string) ([]byte, error)
+	// Exists checks if the rule is programmed in the chain of the table.
+	Exists(table Table, chain string, rule ...string) bool
+	// Commit programs the rules of a transaction as a unit.
+	Commit(rules []Rule) error
+}
+
+const (
+	// IptablesBackend programs the rules through the iptables binary, or
+	// through firewalld when it is running.
+	IptablesBackend = "iptables"
+	// NftablesBackend programs the rules in the libnetwork nftables table.
+	NftablesBackend = "nftables"
+
+	// backendEnv carries the selected backend to the re-exec'd processes
+	// which program the rules in the sandbox network namespaces.
+	backendEnv = "LIBNETWORK_FIREWALL_BACKEND"
+)
+
+var (
+	backendMutex sync.Mutex
+	backend      Backend
+)
+
+// SetBackend selects the firewall backend by name. An empty name restores the
+// default selection, which is iptables when the binary is available and
+// nftables otherwise.
+func SetBackend(name string) error {
+	b, err := newBackend(name)
+	if err != nil {
+		return err
+	}
+
+	backendMutex.Lock()
+	backend = b
+	backendMutex.Unlock()
+
+	if name == "" {
+		return os.Unsetenv(backendEnv)
+	}
+	return os.Setenv(backendEnv, name)
+}
+
+// GetBackend returns the firewall backend in use.
+func GetBackend() Backend {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+
+	if backend == nil {
+		b, err := newBackend(os.Getenv(backendEnv))
+		if err != nil {
+			logrus.Warnf("%v, using the default firewall backend", err)
+			b, _ = newBackend("")
+		}
+		backend = b
+	}
+	return backend
+}
+
+func newBackend(name string) (Backend, error) {
+	switch name {
+	case IptablesBackend:
+		return &iptablesBackend{}, nil
+	case NftablesBackend:
+		return newNftablesBackend()
+	case "":
+		if _, err := exec.LookPath("iptables"); err != nil {
+			if b, err := newNftablesBackend(); err == nil {
+				logrus.Infof("iptables binary not found, using the %s firewall backend", NftablesBackend)
+				return b, nil
+			}
+		}
+		return &iptablesBackend{}, nil
+	}
+	return nil, fmt.Errorf("unknown firewall backend %q", name)
+}
@@ -199,51 +199,9 @@ func RemoveExistingChain(name string, table Table) error {
 
 // Forward adds forwarding rule to 'filter' table and corresponding nat rule to 'nat' table.
 func (c *ChainInfo) Forward(action Action, ip net.IP, port int, proto, destAddr string, destPort int, bridgeName string) error {
-	daddr := ip.String()
-	if ip.IsUnspecified() {
-		// iptables interprets "0.0.0.0" as "0.0.0.0/32", whereas we
-		// want "0.0.0.0/0". "0/0" is correctly interpreted as "any
-		// value" by both iptables and ip6tables.
-		daddr = "0/0"
-	}
-
-	args := []string{
-		"-p", proto,
-		"-d", daddr,
-		"--dport", strconv.Itoa(port),
-		"-j", "DNAT",
-		"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort))}
-	if !c.HairpinMode {
-		args = append(args, "!", "-i", bridgeName)
-	}
-	if err := ProgramRule(Nat, c.Name, action, args); err != nil {
-		return err
-	}
-
-	args = []string{
-		"!", "-i", bridgeName,
-		"-o", bridgeName,
-		"-p", proto,
-		"-d", destAddr,
-		"--dport", strconv.Itoa(destPort),
-		"-j", "ACCEPT",
-	}
-	if err := ProgramRule(Filter, c.Name, action, args); err != nil {
-		return err
-	}
-
-	args = []string{
-		"-p", proto,
-		"-s", destAddr,
-		"-d", destAddr,
-		"--dport", strconv.Itoa(destPort),
-		"-j", "MASQUERADE",
-	}
-	if err := ProgramRule(Nat, "POSTROUTING", action, args); err != nil {
-		return err
-	}
-
-	return nil
+	t := NewTransaction()
+	t.Forward(c, action, ip, port, proto, destAddr, destPort, bridgeName)
+	return t.Commit()
 }
 
 // Link adds reciprocal ACCEPT rule for two supplied IP addresses.
@@ -336,9 +294,17 @@ func ExistsNative(table Table, chain string, rule ...string) bool {
 }
 
 func exists(native bool, table Table, chain string, rule ...string) bool {
+	b := GetBackend()
+	if _, ok := b.(*iptablesBackend); !ok || !native {
+		return b.Exists(table, chain, rule...)
+	}
+	return iptablesExists(true, table, chain, rule...)
+}
+
+func iptablesExists(native bool, table Table, chain string, rule ...string) bool {
 	f := Raw
 	if native {
-		f = raw
+		f = execIptables
 	}
 
 	if string(table) == "" {
@@ -365,18 +331,44 @@ func existsRaw(table Table, chain string, rule ...string) bool {
 	return strings.Contains(string(existingRules), ruleString)
 }
 
-// Raw calls 'iptables' system command, passing supplied arguments.
+// Raw calls the firewall backend, passing supplied iptables arguments.
 func Raw(args ...string) ([]byte, error) {
+	return GetBackend().Raw(args...)
+}
+
+// raw behaves as Raw, with the difference that the iptables backend will
+// always invoke the `iptables` binary.
+func raw(args ...string) ([]byte, error) {
+	if _, ok := GetBackend().(*iptablesBackend); ok {
+		return execIptables(args...)
+	}
+	return GetBackend().Raw(args...)
+}
+
+// iptablesBackend programs the rules through firewalld when it is running,
+// and through the 'iptables' system command otherwise.
+type iptablesBackend struct{}
+
+func (b *iptablesBackend) Name() string {
+	return IptablesBackend
+}
+
+func (b *iptablesBackend) Raw(args ...string) ([]byte, error) {
 	if firewalldRunning {
 		output, err := Passthrough(Iptables, args...)
 		if err == nil || !strings.Contains(err.Error(), "was not provided by any .service files") {
 			return output, err
 		}
 	}
-	return raw(args...)
+	return execIptables(args...)
 }
 
-func raw(args ...string) ([]byte, error) {
+func (b *iptablesBackend) Exists(table Table, chain string, rule ...string) bool {
+	return iptablesExists(false, table, chain, rule...)
+}
+
+// execIptables calls 'iptables' system command, passing supplied arguments.
+func execIptables(args ...string) ([]byte, error) {
 	if err := initCheck(); err != nil {
 		return nil, err
 	}
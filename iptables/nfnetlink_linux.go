@@ -0,0 +1,453 @@
+package iptables
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink/nl"
+)
+
+// nfnetlink and nf_tables protocol constants, from
+// include/uapi/linux/netfilter/nfnetlink.h and nf_tables.h
+const (
+	nlaFNested    = 0x8000
+	nlaTypeMask   = 0x3fff
+	nfnetlinkV0   = 0
+	nfprotoIPv4   = 2
+	nfnlSubsysNft = 10
+
+	nfnlMsgBatchBegin = 0x10
+	nfnlMsgBatchEnd   = 0x11
+
+	nftMsgNewTable = 0
+	nftMsgGetTable = 1
+	nftMsgNewChain = 3
+	nftMsgGetChain = 4
+	nftMsgDelChain = 5
+	nftMsgNewRule  = 6
+	nftMsgGetRule  = 7
+	nftMsgDelRule  = 8
+
+	nftaTableName = 1
+
+	nftaChainTable  = 1
+	nftaChainName   = 3
+	nftaChainHook   = 4
+	nftaChainPolicy = 5
+	nftaChainType   = 7
+
+	nftaHookHooknum  = 1
+	nftaHookPriority = 2
+
+	nftaRuleTable       = 1
+	nftaRuleChain       = 2
+	nftaRuleHandle      = 3
+	nftaRuleExpressions = 4
+	nftaRulePosition    = 6
+	nftaRuleUserdata    = 7
+
+	nftaListElem = 1
+	nftaExprName = 1
+	nftaExprData = 2
+
+	nftaDataValue   = 1
+	nftaDataVerdict = 2
+
+	nftaVerdictCode  = 1
+	nftaVerdictChain = 2
+
+	nftaImmediateDreg = 1
+	nftaImmediateData = 2
+
+	nftaCmpSreg = 1
+	nftaCmpOp   = 2
+	nftaCmpData = 3
+
+	nftaBitwiseSreg = 1
+	nftaBitwiseDreg = 2
+	nftaBitwiseLen  = 3
+	nftaBitwiseMask = 4
+	nftaBitwiseXor  = 5
+
+	nftaPayloadDreg   = 1
+	nftaPayloadBase   = 2
+	nftaPayloadOffset = 3
+	nftaPayloadLen    = 4
+
+	nftaMetaDreg = 1
+	nftaMetaKey  = 2
+	nftaMetaSreg = 3
+
+	nftaCtDreg = 1
+	nftaCtKey  = 2
+
+	nftaFibDreg   = 1
+	nftaFibResult = 2
+	nftaFibFlags  = 3
+
+	nftaNatType         = 1
+	nftaNatFamily       = 2
+	nftaNatRegAddrMin   = 3
+	nftaNatRegProtoMin  = 5
+	nftaRedirRegProtMin = 1
+
+	nftaMatchName = 1
+	nftaMatchRev  = 2
+	nftaMatchInfo = 3
+
+	nftRegVerdict = 0
+	nftReg1       = 1
+	nftReg2       = 2
+
+	nftCmpEq  = 0
+	nftCmpNeq = 1
+	nftCmpLte = 3
+	nftCmpGte = 5
+
+	nftPayloadNetworkHeader   = 1
+	nftPayloadTransportHeader = 2
+
+	nftMetaMark    = 3
+	nftMetaIifname = 6
+	nftMetaOifname = 7
+	nftMetaL4proto = 16
+
+	nftCtState = 0
+
+	nftFibResultAddrtype = 3
+	nftFibFSaddr         = 1 << 0
+	nftFibFDaddr         = 1 << 1
+
+	nftNatSnat = 0
+	nftNatDnat = 1
+
+	nfDrop    = 0
+	nfAccept  = 1
+	nftJump   = -3
+	nftReturn = -5
+
+	nfInetPreRouting  = 0
+	nfInetLocalIn     = 1
+	nfInetForward     = 2
+	nfInetLocalOut    = 3
+	nfInetPostRouting = 4
+
+	// libnftnl user data type used by nft to store the rule comments
+	nftnlUdataRuleComment = 0
+)
+
+// nfMsg is an nf_tables netlink message
+type nfMsg struct {
+	typ   uint16
+	flags uint16
+	attrs []*nl.RtAttr
+}
+
+func (m *nfMsg) serialize(seq uint32, family uint8, resID uint16) []byte {
+	native := nl.NativeEndian()
+
+	var payload []byte
+	for _, a := range m.attrs {
+		payload = append(payload, a.Serialize()...)
+	}
+
+	b := make([]byte, syscall.NLMSG_HDRLEN+4+len(payload))
+	native.PutUint32(b[0:4], uint32(len(b)))
+	native.PutUint16(b[4:6], m.typ)
+	native.PutUint16(b[6:8], syscall.NLM_F_REQUEST|m.flags)
+	native.PutUint32(b[8:12], seq)
+	b[16] = family
+	b[17] = nfnetlinkV0
+	binary.BigEndian.PutUint16(b[18:20], resID)
+	copy(b[20:], payload)
+	return b
+}
+
+func newNfMsg(msgType int, flags uint16, attrs ...*nl.RtAttr) nfMsg {
+	return nfMsg{
+		typ:   uint16(nfnlSubsysNft<<8 | msgType),
+		flags: flags,
+		attrs: attrs,
+	}
+}
+
+// nfConn is a netlink socket to the netfilter subsystem of the network
+// namespace the calling thread was in when it was opened
+type nfConn struct {
+	fd  int
+	seq uint32
+}
+
+func newNfConn() (*nfConn, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_NETFILTER)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &nfConn{fd: fd}, nil
+}
+
+func (c *nfConn) close() {
+	syscall.Close(c.fd)
+}
+
+func (c *nfConn) nextSeq() uint32 {
+	c.seq++
+	return c.seq
+}
+
+func (c *nfConn) send(b []byte) error {
+	return syscall.Sendto(c.fd, b, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+func (c *nfConn) receive() ([]syscall.NetlinkMessage, error) {
+	rb := make([]byte, 16*syscall.Getpagesize())
+	nr, _, err := syscall.Recvfrom(c.fd, rb, 0)
+	if err != nil {
+		return nil, err
+	}
+	if nr < syscall.NLMSG_HDRLEN {
+		return nil, fmt.Errorf("short response from netlink")
+	}
+	return syscall.ParseNetlinkMessage(rb[:nr])
+}
+
+func nlmsgErrno(m *syscall.NetlinkMessage) error {
+	if len(m.Data) < 4 {
+		return fmt.Errorf("short netlink error message")
+	}
+	if errno := int32(nl.NativeEndian().Uint32(m.Data[0:4])); errno != 0 {
+		return syscall.Errno(-errno)
+	}
+	return nil
+}
+
+// transact sends the messages to the kernel in a single nfnetlink batch,
+// which the kernel applies atomically: either all of them take effect or
+// none does.
+func (c *nfConn) transact(msgs ...nfMsg) error {
+	begin := nfMsg{typ: nfnlMsgBatchBegin}
+	end := nfMsg{typ: nfnlMsgBatchEnd}
+
+	b := begin.serialize(c.nextSeq(), syscall.AF_UNSPEC, nfnlSubsysNft)
+	var last uint32
+	for _, m := range msgs {
+		m.flags |= syscall.NLM_F_ACK
+		last = c.nextSeq()
+		b = append(b, m.serialize(last, nfprotoIPv4, 0)...)
+	}
+	b = append(b, end.serialize(c.nextSeq(), syscall.AF_UNSPEC, nfnlSubsysNft)...)
+
+	if err := c.send(b); err != nil {
+		return err
+	}
+
+	for {
+		resp, err := c.receive()
+		if err != nil {
+			return err
+		}
+		for i := range resp {
+			if resp[i].Header.Type != syscall.NLMSG_ERROR {
+				continue
+			}
+			if err := nlmsgErrno(&resp[i]); err != nil {
+				return err
+			}
+			if resp[i].Header.Seq == last {
+				return nil
+			}
+		}
+	}
+}
+
+// query sends a get request to the kernel and returns the payload of the
+// reply messages. When dump is set all the objects matching the request are
+// returned.
+func (c *nfConn) query(m nfMsg, dump bool) ([][]byte, error) {
+	if dump {
+		m.flags |= syscall.NLM_F_DUMP
+	} else {
+		m.flags |= syscall.NLM_F_ACK
+	}
+	seq := c.nextSeq()
+	if err := c.send(m.serialize(seq, nfprotoIPv4, 0)); err != nil {
+		return nil, err
+	}
+
+	var res [][]byte
+	for {
+		resp, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		for i := range resp {
+			if resp[i].Header.Seq != seq {
+				continue
+			}
+			switch resp[i].Header.Type {
+			case syscall.NLMSG_DONE:
+				return res, nil
+			case syscall.NLMSG_ERROR:
+				if err := nlmsgErrno(&resp[i]); err != nil {
+					return nil, err
+				}
+				return res, nil
+			}
+			res = append(res, resp[i].Data)
+		}
+	}
+}
+
+// parseNfAttrs returns the attributes of an nf_tables message payload,
+// indexed by their type
+func parseNfAttrs(b []byte) (map[uint16][]byte, error) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[uint16][]byte, len(attrs))
+	for _, a := range attrs {
+		m[a.Attr.Type&nlaTypeMask] = a.Value
+	}
+	return m, nil
+}
+
+func nfUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func nfUint64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func nfNested(attrType int) *nl.RtAttr {
+	return nl.NewRtAttr(attrType|nlaFNested, nil)
+}
+
+// exprList builds the NFTA_RULE_EXPRESSIONS attribute of a rule
+type exprList struct {
+	attr *nl.RtAttr
+}
+
+func newExprList() *exprList {
+	return &exprList{attr: nfNested(nftaRuleExpressions)}
+}
+
+// add appends the named expression to the list and returns its data
+// attribute, to which the expression parameters are added
+func (l *exprList) add(name string) *nl.RtAttr {
+	elem := nl.NewRtAttrChild(l.attr, nftaListElem|nlaFNested, nil)
+	nl.NewRtAttrChild(elem, nftaExprName, nl.ZeroTerminated(name))
+	return nl.NewRtAttrChild(elem, nftaExprData|nlaFNested, nil)
+}
+
+func addDataValue(parent *nl.RtAttr, attrType int, value []byte) {
+	d := nl.NewRtAttrChild(parent, attrType|nlaFNested, nil)
+	nl.NewRtAttrChild(d, nftaDataValue, value)
+}
+
+func (l *exprList) meta(key, dreg uint32) {
+	e := l.add("meta")
+	nl.NewRtAttrChild(e, nftaMetaKey, nfUint32(key))
+	nl.NewRtAttrChild(e, nftaMetaDreg, nfUint32(dreg))
+}
+
+func (l *exprList) metaSet(key, sreg uint32) {
+	e := l.add("meta")
+	nl.NewRtAttrChild(e, nftaMetaKey, nfUint32(key))
+	nl.NewRtAttrChild(e, nftaMetaSreg, nfUint32(sreg))
+}
+
+func (l *exprList) cmp(op, sreg uint32, data []byte) {
+	e := l.add("cmp")
+	nl.NewRtAttrChild(e, nftaCmpSreg, nfUint32(sreg))
+	nl.NewRtAttrChild(e, nftaCmpOp, nfUint32(op))
+	addDataValue(e, nftaCmpData, data)
+}
+
+func (l *exprList) payload(base, offset, length, dreg uint32) {
+	e := l.add("payload")
+	nl.NewRtAttrChild(e, nftaPayloadDreg, nfUint32(dreg))
+	nl.NewRtAttrChild(e, nftaPayloadBase, nfUint32(base))
+	nl.NewRtAttrChild(e, nftaPayloadOffset, nfUint32(offset))
+	nl.NewRtAttrChild(e, nftaPayloadLen, nfUint32(length))
+}
+
+func (l *exprList) bitwise(reg uint32, mask []byte) {
+	e := l.add("bitwise")
+	nl.NewRtAttrChild(e, nftaBitwiseSreg, nfUint32(reg))
+	nl.NewRtAttrChild(e, nftaBitwiseDreg, nfUint32(reg))
+	nl.NewRtAttrChild(e, nftaBitwiseLen, nfUint32(uint32(len(mask))))
+	addDataValue(e, nftaBitwiseMask, mask)
+	addDataValue(e, nftaBitwiseXor, make([]byte, len(mask)))
+}
+
+func (l *exprList) ct(key, dreg uint32) {
+	e := l.add("ct")
+	nl.NewRtAttrChild(e, nftaCtKey, nfUint32(key))
+	nl.NewRtAttrChild(e, nftaCtDreg, nfUint32(dreg))
+}
+
+func (l *exprList) fib(flags, result, dreg uint32) {
+	e := l.add("fib")
+	nl.NewRtAttrChild(e, nftaFibDreg, nfUint32(dreg))
+	nl.NewRtAttrChild(e, nftaFibResult, nfUint32(result))
+	nl.NewRtAttrChild(e, nftaFibFlags, nfUint32(flags))
+}
+
+func (l *exprList) immediate(dreg uint32, data []byte) {
+	e := l.add("immediate")
+	nl.NewRtAttrChild(e, nftaImmediateDreg, nfUint32(dreg))
+	addDataValue(e, nftaImmediateData, data)
+}
+
+func (l *exprList) verdict(code int32, chain string) {
+	e := l.add("immediate")
+	nl.NewRtAttrChild(e, nftaImmediateDreg, nfUint32(nftRegVerdict))
+	d := nl.NewRtAttrChild(e, nftaImmediateData|nlaFNested, nil)
+	v := nl.NewRtAttrChild(d, nftaDataVerdict|nlaFNested, nil)
+	nl.NewRtAttrChild(v, nftaVerdictCode, nfUint32(uint32(code)))
+	if chain != "" {
+		nl.NewRtAttrChild(v, nftaVerdictChain, nl.ZeroTerminated(chain))
+	}
+}
+
+// nat adds a snat or dnat expression. A zero register means the address or
+// the port is not translated.
+func (l *exprList) nat(natType, addrReg, protoReg uint32) {
+	e := l.add("nat")
+	nl.NewRtAttrChild(e, nftaNatType, nfUint32(natType))
+	nl.NewRtAttrChild(e, nftaNatFamily, nfUint32(nfprotoIPv4))
+	if addrReg != 0 {
+		nl.NewRtAttrChild(e, nftaNatRegAddrMin, nfUint32(addrReg))
+	}
+	if protoReg != 0 {
+		nl.NewRtAttrChild(e, nftaNatRegProtoMin, nfUint32(protoReg))
+	}
+}
+
+func (l *exprList) masq() {
+	l.add("masq")
+}
+
+func (l *exprList) redir(protoReg uint32) {
+	e := l.add("redir")
+	nl.NewRtAttrChild(e, nftaRedirRegProtMin, nfUint32(protoReg))
+}
+
+// match adds an xtables match extension through the nft_compat expression
+func (l *exprList) match(name string, rev uint32, info []byte) {
+	e := l.add("match")
+	nl.NewRtAttrChild(e, nftaMatchName, nl.ZeroTerminated(name))
+	nl.NewRtAttrChild(e, nftaMatchRev, nfUint32(rev))
+	nl.NewRtAttrChild(e, nftaMatchInfo, info)
+}
@@ -0,0 +1,908 @@
+package iptables
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// nftTable is the nftables table holding all the libnetwork rules. The
+// iptables tables are flattened into it: chain DOCKER of the nat table is
+// programmed as chain nat-DOCKER, and the builtin chains are base chains
+// registered at the same netfilter hooks and priorities as their iptables
+// counterparts.
+const nftTable = "libnetwork"
+
+// maxCommentLen is the longest rule comment nft user data can carry
+const maxCommentLen = 254
+
+type nftHook struct {
+	num       uint32
+	priority  int32
+	chainType string
+}
+
+var nftBuiltinChains = map[Table]map[string]nftHook{
+	Filter: {
+		"INPUT":   {nfInetLocalIn, 0, "filter"},
+		"FORWARD": {nfInetForward, 0, "filter"},
+		"OUTPUT":  {nfInetLocalOut, 0, "filter"},
+	},
+	Nat: {
+		"PREROUTING":  {nfInetPreRouting, -100, "nat"},
+		"INPUT":       {nfInetLocalIn, 100, "nat"},
+		"OUTPUT":      {nfInetLocalOut, -100, "nat"},
+		"POSTROUTING": {nfInetPostRouting, 100, "nat"},
+	},
+	Mangle: {
+		"PREROUTING":  {nfInetPreRouting, -150, "filter"},
+		"INPUT":       {nfInetLocalIn, -150, "filter"},
+		"FORWARD":     {nfInetForward, -150, "filter"},
+		"OUTPUT":      {nfInetLocalOut, -150, "route"},
+		"POSTROUTING": {nfInetPostRouting, -150, "filter"},
+	},
+}
+
+func nftChainName(table Table, chain string) string {
+	return string(table) + "-" + chain
+}
+
+func isBuiltinChain(table Table, chain string) bool {
+	_, ok := nftBuiltinChains[table][chain]
+	return ok
+}
+
+// nftablesBackend programs the rules natively through the nf_tables netlink
+// interface. Every command is applied to the kernel as a single nfnetlink
+// batch, so that the table, the chain and the rule it needs are created
+// atomically. The iptables rule specification is stored as the rule comment,
+// which is how the rules are looked up for the check and delete commands, and
+// which keeps the backend stateless across daemon restarts and re-exec'd
+// processes.
+type nftablesBackend struct {
+	sync.Mutex
+}
+
+func newNftablesBackend() (Backend, error) {
+	c, err := newNfConn()
+	if err != nil {
+		return nil, fmt.Errorf("nftables not supported: %v", err)
+	}
+	defer c.close()
+
+	if _, err := c.query(newNfMsg(nftMsgGetTable, 0), true); err != nil {
+		return nil, fmt.Errorf("nftables not supported: %v", err)
+	}
+	return &nftablesBackend{}, nil
+}
+
+func (n *nftablesBackend) Name() string {
+	return NftablesBackend
+}
+
+// nftCommand is a parsed iptables command line
+type nftCommand struct {
+	table   Table
+	command string
+	chain   string
+	pos     int
+	rule    []string
+}
+
+var nftCommands = map[string]string{
+	"-A": "-A", "--append": "-A",
+	"-I": "-I", "--insert": "-I",
+	"-D": "-D", "--delete": "-D",
+	"-C": "-C", "--check": "-C",
+	"-N": "-N", "--new-chain": "-N",
+	"-X": "-X", "--delete-chain": "-X",
+	"-F": "-F", "--flush": "-F",
+	"-L": "-L", "--list": "-L",
+	"-S": "-S", "--list-rules": "-S",
+}
+
+func parseCommand(args []string) (*nftCommand, error) {
+	cmd := &nftCommand{table: Filter}
+
+	// the table and the generic options are accepted anywhere
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; a {
+		case "-t", "--table":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option %s requires an argument", a)
+			}
+			i++
+			cmd.table = Table(args[i])
+		case "-w", "--wait", "-n", "--numeric", "-v", "--verbose":
+		default:
+			rest = append(rest, a)
+		}
+	}
+	args = rest
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no command specified")
+	}
+	c, ok := nftCommands[args[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported option %q", args[0])
+	}
+	cmd.command = c
+	if _, ok := nftBuiltinChains[cmd.table]; !ok {
+		return nil, fmt.Errorf("unsupported table %q", cmd.table)
+	}
+
+	i := 1
+	if i < len(args) && !strings.HasPrefix(args[i], "-") {
+		cmd.chain = args[i]
+		i++
+	}
+	if c == "-I" && i < len(args) {
+		if pos, err := strconv.Atoi(args[i]); err == nil {
+			if pos < 1 {
+				return nil, fmt.Errorf("invalid rule number %d", pos)
+			}
+			cmd.pos = pos
+			i++
+		}
+	}
+	cmd.rule = args[i:]
+	return cmd, nil
+}
+
+// Raw executes the iptables command line on the libnetwork nftables table
+func (n *nftablesBackend) Raw(args ...string) ([]byte, error) {
+	n.Lock()
+	defer n.Unlock()
+
+	logrus.Debugf("nftables: %v", args)
+
+	output, err := n.execute(args)
+	if err != nil {
+		return nil, fmt.Errorf("nftables failed: %s: %v", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
+
+// Exists checks if the rule is programmed in the chain
+func (n *nftablesBackend) Exists(table Table, chain string, rule ...string) bool {
+	n.Lock()
+	defer n.Unlock()
+
+	if string(table) == "" {
+		table = Filter
+	}
+	r, err := parseRule(table, rule)
+	if err != nil {
+		logrus.Debugf("nftables: %v", err)
+		return false
+	}
+
+	c, err := newNfConn()
+	if err != nil {
+		return false
+	}
+	defer c.close()
+
+	rules, err := listRules(c, nftChainName(table, chain))
+	if err != nil {
+		return false
+	}
+	for _, lr := range rules {
+		if lr.comment == r.comment {
+			return true
+		}
+	}
+	return false
+}
+
+// Commit programs the rules in a single nfnetlink batch, which the kernel
+// applies atomically
+func (n *nftablesBackend) Commit(rules []Rule) error {
+	n.Lock()
+	defer n.Unlock()
+
+	c, err := newNfConn()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	// queuedRule is a rule of the chain as it will be once the batch is
+	// applied. The rules added by the batch have no handle yet, but the
+	// index of the message adding them.
+	type queuedRule struct {
+		comment string
+		handle  uint64
+		msg     int
+	}
+
+	var (
+		msgs    = []nfMsg{newTableMsg()}
+		chains  = map[string][]queuedRule{}
+		dropped = map[int]bool{}
+	)
+	for _, r := range rules {
+		name := nftChainName(r.Table, r.Chain)
+		queued, ok := chains[name]
+		if !ok {
+			if isBuiltinChain(r.Table, r.Chain) {
+				msgs = append(msgs, ensureChainMsg(r.Table, r.Chain))
+			} else if !chainExists(c, name) {
+				return fmt.Errorf("nftables failed: no chain by the name %s", r.Chain)
+			}
+			listed, err := listRules(c, name)
+			if err != nil {
+				return err
+			}
+			for _, lr := range listed {
+				queued = append(queued, queuedRule{comment: lr.comment, handle: lr.handle, msg: -1})
+			}
+		}
+
+		nr, err := parseRule(r.Table, r.Args)
+		if err != nil {
+			return fmt.Errorf("nftables failed: %s: %v", strings.Join(r.command(), " "), err)
+		}
+		pos := -1
+		for i := range queued {
+			if queued[i].comment == nr.comment {
+				pos = i
+				break
+			}
+		}
+
+		switch {
+		case r.Action == Delete && pos >= 0:
+			if q := queued[pos]; q.msg >= 0 {
+				// added by this batch in the first place
+				dropped[q.msg] = true
+			} else {
+				msgs = append(msgs, delRuleMsg(name, q.handle))
+			}
+			queued = append(queued[:pos], queued[pos+1:]...)
+		case r.Action == Insert && pos < 0:
+			msgs = append(msgs, newRuleMsg(name, nr, 0, 0))
+			queued = append([]queuedRule{{comment: nr.comment, msg: len(msgs) - 1}}, queued...)
+		case r.Action == Append && pos < 0:
+			msgs = append(msgs, newRuleMsg(name, nr, syscall.NLM_F_APPEND, 0))
+			queued = append(queued, queuedRule{comment: nr.comment, msg: len(msgs) - 1})
+		}
+		chains[name] = queued
+	}
+
+	batch := make([]nfMsg, 0, len(msgs))
+	for i, m := range msgs {
+		if !dropped[i] {
+			batch = append(batch, m)
+		}
+	}
+	logrus.Debugf("nftables: committing %d rules in a batch of %d messages", len(rules), len(batch))
+
+	if err := c.transact(batch...); err != nil {
+		return fmt.Errorf("nftables failed: %v", err)
+	}
+	return nil
+}
+
+func (n *nftablesBackend) execute(args []string) ([]byte, error) {
+	cmd, err := parseCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newNfConn()
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	if cmd.command != "-L" && cmd.command != "-S" && cmd.chain == "" {
+		return nil, fmt.Errorf("option %s requires a chain", cmd.command)
+	}
+	name := nftChainName(cmd.table, cmd.chain)
+	builtin := isBuiltinChain(cmd.table, cmd.chain)
+
+	switch cmd.command {
+	case "-N":
+		if builtin || chainExists(c, name) {
+			return nil, fmt.Errorf("chain %s already exists", cmd.chain)
+		}
+		return nil, c.transact(newTableMsg(), newChainMsg(name))
+	case "-X":
+		if builtin {
+			return nil, fmt.Errorf("cannot delete builtin chain %s", cmd.chain)
+		}
+		if !chainExists(c, name) {
+			return nil, fmt.Errorf("no chain by the name %s", cmd.chain)
+		}
+		return nil, c.transact(delChainMsg(name))
+	case "-F":
+		if !builtin && !chainExists(c, name) {
+			return nil, fmt.Errorf("no chain by the name %s", cmd.chain)
+		}
+		return nil, c.transact(newTableMsg(), ensureChainMsg(cmd.table, cmd.chain), flushChainMsg(name))
+	case "-L", "-S":
+		return listChains(c, cmd.table, cmd.chain, cmd.command == "-L")
+	}
+
+	if !builtin && !chainExists(c, name) {
+		return nil, fmt.Errorf("no chain by the name %s", cmd.chain)
+	}
+
+	r, err := parseRule(cmd.table, cmd.rule)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := listRules(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *nftListedRule
+	for i := range rules {
+		if rules[i].comment == r.comment {
+			match = &rules[i]
+			break
+		}
+	}
+
+	switch cmd.command {
+	case "-C":
+		if match == nil {
+			return nil, fmt.Errorf("bad rule (does a matching rule exist in that chain?)")
+		}
+		return nil, nil
+	case "-D":
+		if match == nil {
+			return nil, fmt.Errorf("bad rule (does a matching rule exist in that chain?)")
+		}
+		return nil, c.transact(delRuleMsg(name, match.handle))
+	case "-A":
+		return nil, c.transact(newTableMsg(), ensureChainMsg(cmd.table, cmd.chain), newRuleMsg(name, r, syscall.NLM_F_APPEND, 0))
+	default:
+		// insert before the rule at the requested position, or at the
+		// top of the chain
+		var (
+			flags  uint16
+			before uint64
+		)
+		if pos := cmd.pos; pos > 1 {
+			switch {
+			case pos-1 < len(rules):
+				before = rules[pos-1].handle
+			case pos-1 == len(rules):
+				flags = syscall.NLM_F_APPEND
+			default:
+				return nil, fmt.Errorf("index of insertion too big")
+			}
+		}
+		return nil, c.transact(newTableMsg(), ensureChainMsg(cmd.table, cmd.chain), newRuleMsg(name, r, flags, before))
+	}
+}
+
+func tableAttr(attrType int) *nl.RtAttr {
+	return nl.NewRtAttr(attrType, nl.ZeroTerminated(nftTable))
+}
+
+func newTableMsg() nfMsg {
+	return newNfMsg(nftMsgNewTable, syscall.NLM_F_CREATE, tableAttr(nftaTableName))
+}
+
+func newChainMsg(name string) nfMsg {
+	return newNfMsg(nftMsgNewChain, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL,
+		tableAttr(nftaChainTable),
+		nl.NewRtAttr(nftaChainName, nl.ZeroTerminated(name)))
+}
+
+// ensureChainMsg returns the message creating the base chain backing the
+// passed builtin chain, which is a no-op if it already exists. For other
+// chains it returns a message only ensuring the table exists.
+func ensureChainMsg(table Table, chain string) nfMsg {
+	h, ok := nftBuiltinChains[table][chain]
+	if !ok {
+		return newTableMsg()
+	}
+
+	hook := nfNested(nftaChainHook)
+	nl.NewRtAttrChild(hook, nftaHookHooknum, nfUint32(h.num))
+	nl.NewRtAttrChild(hook, nftaHookPriority, nfUint32(uint32(h.priority)))
+
+	return newNfMsg(nftMsgNewChain, syscall.NLM_F_CREATE,
+		tableAttr(nftaChainTable),
+		nl.NewRtAttr(nftaChainName, nl.ZeroTerminated(nftChainName(table, chain))),
+		hook,
+		nl.NewRtAttr(nftaChainPolicy, nfUint32(nfAccept)),
+		nl.NewRtAttr(nftaChainType, nl.ZeroTerminated(h.chainType)))
+}
+
+func delChainMsg(name string) nfMsg {
+	return newNfMsg(nftMsgDelChain, 0,
+		tableAttr(nftaChainTable),
+		nl.NewRtAttr(nftaChainName, nl.ZeroTerminated(name)))
+}
+
+func flushChainMsg(name string) nfMsg {
+	return newNfMsg(nftMsgDelRule, 0,
+		tableAttr(nftaRuleTable),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(name)))
+}
+
+func delRuleMsg(chain string, handle uint64) nfMsg {
+	return newNfMsg(nftMsgDelRule, 0,
+		tableAttr(nftaRuleTable),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain)),
+		nl.NewRtAttr(nftaRuleHandle, nfUint64(handle)))
+}
+
+func newRuleMsg(chain string, r *nftRule, flags uint16, before uint64) nfMsg {
+	attrs := []*nl.RtAttr{
+		tableAttr(nftaRuleTable),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain)),
+		r.exprs.attr,
+		nl.NewRtAttr(nftaRuleUserdata, commentUserdata(r.comment)),
+	}
+	if before != 0 {
+		attrs = append(attrs, nl.NewRtAttr(nftaRulePosition, nfUint64(before)))
+	}
+	return newNfMsg(nftMsgNewRule, syscall.NLM_F_CREATE|flags, attrs...)
+}
+
+func chainExists(c *nfConn, name string) bool {
+	_, err := c.query(newNfMsg(nftMsgGetChain, 0,
+		tableAttr(nftaChainTable),
+		nl.NewRtAttr(nftaChainName, nl.ZeroTerminated(name))), false)
+	return err == nil
+}
+
+// nftListedRule is a rule read back from the kernel
+type nftListedRule struct {
+	handle  uint64
+	comment string
+}
+
+func listRules(c *nfConn, chain string) ([]nftListedRule, error) {
+	msgs, err := c.query(newNfMsg(nftMsgGetRule, 0,
+		tableAttr(nftaRuleTable),
+		nl.NewRtAttr(nftaRuleChain, nl.ZeroTerminated(chain))), true)
+	if err != nil {
+		if err == syscall.ENOENT {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var rules []nftListedRule
+	for _, m := range msgs {
+		if len(m) < 4 {
+			continue
+		}
+		attrs, err := parseNfAttrs(m[4:])
+		if err != nil {
+			return nil, err
+		}
+		if nl.BytesToString(attrs[nftaRuleTable]) != nftTable || nl.BytesToString(attrs[nftaRuleChain]) != chain {
+			continue
+		}
+		if len(attrs[nftaRuleHandle]) != 8 {
+			continue
+		}
+		rules = append(rules, nftListedRule{
+			handle:  binary.BigEndian.Uint64(attrs[nftaRuleHandle]),
+			comment: parseCommentUserdata(attrs[nftaRuleUserdata]),
+		})
+	}
+	return rules, nil
+}
+
+// listChains returns the chains of the table, or the passed chain only, in
+// the iptables -S format, or in the iptables -L format if tableFormat is set
+func listChains(c *nfConn, table Table, chain string, tableFormat bool) ([]byte, error) {
+	var chains []string
+	if chain != "" {
+		if !isBuiltinChain(table, chain) && !chainExists(c, nftChainName(table, chain)) {
+			return nil, fmt.Errorf("no chain by the name %s", chain)
+		}
+		chains = append(chains, chain)
+	} else {
+		msgs, err := c.query(newNfMsg(nftMsgGetChain, 0), true)
+		if err != nil {
+			return nil, err
+		}
+		for name := range nftBuiltinChains[table] {
+			chains = append(chains, name)
+		}
+		prefix := nftChainName(table, "")
+		for _, m := range msgs {
+			if len(m) < 4 {
+				continue
+			}
+			attrs, err := parseNfAttrs(m[4:])
+			if err != nil {
+				return nil, err
+			}
+			name := nl.BytesToString(attrs[nftaChainName])
+			if nl.BytesToString(attrs[nftaChainTable]) != nftTable || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if name = strings.TrimPrefix(name, prefix); !isBuiltinChain(table, name) {
+				chains = append(chains, name)
+			}
+		}
+		sort.Strings(chains)
+	}
+
+	var out []string
+	if !tableFormat {
+		for _, ch := range chains {
+			if isBuiltinChain(table, ch) {
+				out = append(out, fmt.Sprintf("-P %s ACCEPT", ch))
+			} else {
+				out = append(out, fmt.Sprintf("-N %s", ch))
+			}
+		}
+	}
+	for _, ch := range chains {
+		rules, err := listRules(c, nftChainName(table, ch))
+		if err != nil {
+			return nil, err
+		}
+		if tableFormat {
+			if isBuiltinChain(table, ch) {
+				out = append(out, fmt.Sprintf("Chain %s (policy ACCEPT)", ch))
+			} else {
+				out = append(out, fmt.Sprintf("Chain %s", ch))
+			}
+			out = append(out, fmt.Sprintf("%-10s %-4s %-3s %-20s %-20s", "target", "prot", "opt", "source", "destination"))
+		}
+		for _, r := range rules {
+			if tableFormat {
+				out = append(out, formatRule(r.comment))
+			} else {
+				out = append(out, strings.TrimSpace(fmt.Sprintf("-A %s %s", ch, r.comment)))
+			}
+		}
+		if tableFormat {
+			out = append(out, "")
+		}
+	}
+	return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// formatRule returns the rule in the iptables -L format
+func formatRule(comment string) string {
+	s, err := parseRuleSpec(strings.Fields(comment))
+	if err != nil {
+		return comment
+	}
+
+	addr := func(n *net.IPNet, neg bool) string {
+		a := "0.0.0.0/0"
+		if n != nil {
+			a = n.String()
+			if ones, _ := n.Mask.Size(); ones == 32 {
+				a = n.IP.String()
+			}
+		}
+		if neg {
+			a = "!" + a
+		}
+		return a
+	}
+	prot := "all"
+	if s.proto != "" {
+		prot = s.proto
+		if s.protoNeg {
+			prot = "!" + prot
+		}
+	}
+
+	var extra []string
+	if s.srcType != "" || s.dstType != "" {
+		m := "ADDRTYPE match"
+		if s.srcType != "" {
+			m += " src-type " + s.srcType
+		}
+		if s.dstType != "" {
+			m += " dst-type " + s.dstType
+		}
+		extra = append(extra, m)
+	}
+	if len(s.ctState) > 0 {
+		extra = append(extra, "ctstate "+strings.Join(s.ctState, ","))
+	}
+	if s.ipvs {
+		extra = append(extra, "ipvs")
+	}
+	if s.u32 != nil {
+		extra = append(extra, "u32 \""+s.u32.String()+"\"")
+	}
+	for _, p := range []struct {
+		name, ports string
+		neg         bool
+	}{{"spt", s.sport, s.sportNeg}, {"dpt", s.dport, s.dportNeg}} {
+		if p.ports == "" {
+			continue
+		}
+		name := p.name
+		if strings.Contains(p.ports, ":") {
+			name += "s"
+		}
+		if p.neg {
+			name += ":!"
+		} else {
+			name += ":"
+		}
+		extra = append(extra, s.proto+" "+name+p.ports)
+	}
+	switch s.target {
+	case "DNAT", "SNAT":
+		extra = append(extra, "to:"+s.to)
+	case "REDIRECT":
+		extra = append(extra, "redir ports "+s.toPorts)
+	case "MARK":
+		extra = append(extra, fmt.Sprintf("MARK set 0x%x", s.mark))
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%-10s %-4s %-3s %-20s %-20s %s",
+		s.target, prot, "--", addr(s.src, s.srcNeg), addr(s.dst, s.dstNeg), strings.Join(extra, " ")))
+}
+
+// commentUserdata encodes the comment as the nft rule user data, so that
+// it is also displayed by `nft list ruleset`
+func commentUserdata(comment string) []byte {
+	b := []byte{nftnlUdataRuleComment, byte(len(comment) + 1)}
+	return append(b, nl.ZeroTerminated(comment)...)
+}
+
+func parseCommentUserdata(b []byte) string {
+	for len(b) >= 2 {
+		t, l := b[0], int(b[1])
+		if len(b) < 2+l {
+			break
+		}
+		if t == nftnlUdataRuleComment {
+			return nl.BytesToString(b[2 : 2+l])
+		}
+		b = b[2+l:]
+	}
+	return ""
+}
+
+// nftRule is an iptables rule translated to nf_tables expressions
+type nftRule struct {
+	// comment identifies the rule. It is its canonical iptables
+	// specification, or a digest of it if it is too long.
+	comment string
+	exprs   *exprList
+}
+
+// parseRule translates the iptables rule specification to the nf_tables
+// expressions implementing it
+func parseRule(table Table, args []string) (*nftRule, error) {
+	s, err := parseRuleSpec(args)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &nftRule{comment: s.String(), exprs: newExprList()}
+	if len(r.comment) > maxCommentLen {
+		sum := sha256.Sum256([]byte(r.comment))
+		r.comment = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	l := r.exprs
+	native := nl.NativeEndian()
+
+	if s.in != "" {
+		l.meta(nftMetaIifname, nftReg1)
+		l.cmp(cmpOp(s.inNeg), nftReg1, ifnameData(s.in))
+	}
+	if s.out != "" {
+		l.meta(nftMetaOifname, nftReg1)
+		l.cmp(cmpOp(s.outNeg), nftReg1, ifnameData(s.out))
+	}
+	if s.proto != "" && s.proto != "all" {
+		p, _ := protocolNumber(s.proto)
+		l.meta(nftMetaL4proto, nftReg1)
+		l.cmp(cmpOp(s.protoNeg), nftReg1, []byte{p})
+	}
+	if s.src != nil {
+		addrMatch(l, 12, s.src, s.srcNeg)
+	}
+	if s.dst != nil {
+		addrMatch(l, 16, s.dst, s.dstNeg)
+	}
+	for _, m := range []struct {
+		flags    uint32
+		addrType string
+	}{{nftFibFSaddr, s.srcType}, {nftFibFDaddr, s.dstType}} {
+		if m.addrType == "" {
+			continue
+		}
+		t := make([]byte, 4)
+		native.PutUint32(t, addrTypes[m.addrType])
+		l.fib(m.flags, nftFibResultAddrtype, nftReg1)
+		l.cmp(nftCmpEq, nftReg1, t)
+	}
+	if len(s.ctState) > 0 {
+		var bits uint32
+		for _, st := range s.ctState {
+			bits |= ctStates[st]
+		}
+		mask := make([]byte, 4)
+		native.PutUint32(mask, bits)
+		l.ct(nftCtState, nftReg1)
+		l.bitwise(nftReg1, mask)
+		l.cmp(nftCmpNeq, nftReg1, make([]byte, 4))
+	}
+	if s.ipvs {
+		// struct xt_ipvs_mtinfo with the XT_IPVS_IPVS_PROPERTY bit set
+		info := make([]byte, 40)
+		info[39] = 1
+		l.match("ipvs", 0, info)
+	}
+	if s.u32 != nil {
+		l.payload(nftPayloadTransportHeader, s.u32.offset, 4, nftReg1)
+		l.bitwise(nftReg1, nfUint32(s.u32.mask))
+		l.cmp(nftCmpEq, nftReg1, nfUint32(s.u32.value&s.u32.mask))
+	}
+	if s.sport != "" {
+		if err := portMatch(l, 0, s.sport, s.sportNeg); err != nil {
+			return nil, err
+		}
+	}
+	if s.dport != "" {
+		if err := portMatch(l, 2, s.dport, s.dportNeg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := addTarget(l, table, s); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func cmpOp(neg bool) uint32 {
+	if neg {
+		return nftCmpNeq
+	}
+	return nftCmpEq
+}
+
+// ifnameData returns the bytes compared against the interface name. The
+// iptables "+" wildcard suffix is a prefix match, otherwise the terminating
+// null byte is compared as well.
+func ifnameData(name string) []byte {
+	if strings.HasSuffix(name, "+") {
+		return []byte(strings.TrimSuffix(name, "+"))
+	}
+	return nl.ZeroTerminated(name)
+}
+
+func addrMatch(l *exprList, offset uint32, n *net.IPNet, neg bool) {
+	ones, _ := n.Mask.Size()
+	if ones == 0 {
+		return
+	}
+	l.payload(nftPayloadNetworkHeader, offset, 4, nftReg1)
+	if ones < 32 {
+		l.bitwise(nftReg1, []byte(n.Mask))
+	}
+	l.cmp(cmpOp(neg), nftReg1, []byte(n.IP.To4()))
+}
+
+func parsePort(s string) ([]byte, error) {
+	p, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", s)
+	}
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, uint16(p))
+	return b, nil
+}
+
+func portMatch(l *exprList, offset uint32, ports string, neg bool) error {
+	if i := strings.Index(ports, ":"); i >= 0 {
+		if neg {
+			return fmt.Errorf("negated port ranges are not supported")
+		}
+		min, err := parsePort(ports[:i])
+		if err != nil {
+			return err
+		}
+		max, err := parsePort(ports[i+1:])
+		if err != nil {
+			return err
+		}
+		l.payload(nftPayloadTransportHeader, offset, 2, nftReg1)
+		l.cmp(nftCmpGte, nftReg1, min)
+		l.cmp(nftCmpLte, nftReg1, max)
+		return nil
+	}
+
+	p, err := parsePort(ports)
+	if err != nil {
+		return err
+	}
+	l.payload(nftPayloadTransportHeader, offset, 2, nftReg1)
+	l.cmp(cmpOp(neg), nftReg1, p)
+	return nil
+}
+
+func addTarget(l *exprList, table Table, s *ruleSpec) error {
+	switch s.target {
+	case "":
+	case "ACCEPT":
+		l.verdict(nfAccept, "")
+	case "DROP":
+		l.verdict(nfDrop, "")
+	case "RETURN":
+		l.verdict(nftReturn, "")
+	case "MASQUERADE":
+		if s.toPorts != "" {
+			return fmt.Errorf("MASQUERADE --to-ports is not supported")
+		}
+		l.masq()
+	case "DNAT", "SNAT":
+		host, port := s.to, ""
+		if i := strings.LastIndex(s.to, ":"); i >= 0 {
+			host, port = s.to[:i], s.to[i+1:]
+		}
+		var addrReg, protoReg uint32
+		if host != "" {
+			ip := net.ParseIP(host).To4()
+			if ip == nil {
+				return fmt.Errorf("invalid %s address %q", s.target, s.to)
+			}
+			l.immediate(nftReg1, []byte(ip))
+			addrReg = nftReg1
+		}
+		if port != "" {
+			p, err := parsePort(port)
+			if err != nil {
+				return err
+			}
+			l.immediate(nftReg2, p)
+			protoReg = nftReg2
+		}
+		if addrReg == 0 && protoReg == 0 {
+			return fmt.Errorf("missing %s translation", s.target)
+		}
+		natType := uint32(nftNatDnat)
+		if s.target == "SNAT" {
+			natType = nftNatSnat
+		}
+		l.nat(natType, addrReg, protoReg)
+	case "REDIRECT":
+		p, err := parsePort(s.toPorts)
+		if err != nil {
+			return err
+		}
+		l.immediate(nftReg1, p)
+		l.redir(nftReg1)
+	case "MARK":
+		m := make([]byte, 4)
+		nl.NativeEndian().PutUint32(m, s.mark)
+		l.immediate(nftReg1, m)
+		l.metaSet(nftMetaMark, nftReg1)
+	default:
+		if isBuiltinChain(table, s.target) {
+			return fmt.Errorf("cannot jump to builtin chain %s", s.target)
+		}
+		l.verdict(nftJump, nftChainName(table, s.target))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package iptables
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/docker/libnetwork/testutils"
+)
+
+func setupNftables(t *testing.T) func() {
+	cleanup := testutils.SetupTestOSContext(t)
+	if err := SetBackend(NftablesBackend); err != nil {
+		cleanup()
+		t.Skipf("nftables not available: %v", err)
+	}
+	return func() {
+		SetBackend("")
+		cleanup()
+	}
+}
+
+func TestNftablesChains(t *testing.T) {
+	defer setupNftables(t)()
+
+	nat, err := NewChain(chainName, Nat, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ProgramChain(nat, "lo", false, true); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := NewChain(chainName, Filter, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ProgramChain(filter, "lo", false, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !ExistChain(chainName, Nat) || !ExistChain(chainName, Filter) {
+		t.Fatalf("Chains were not created")
+	}
+	if _, err := NewChain(chainName, Filter, false); err != nil {
+		t.Fatalf("NewChain must be idempotent: %v", err)
+	}
+	if err := RawCombinedOutput("-N", chainName); err == nil {
+		t.Fatalf("Expected failure creating an existing chain")
+	}
+
+	if !Exists(Nat, "PREROUTING", "-m", "addrtype", "--dst-type", "LOCAL", "-j", chainName) {
+		t.Fatalf("PREROUTING jump rule does not exist")
+	}
+	if !Exists(Filter, "FORWARD", "-o", "lo", "-j", chainName) {
+		t.Fatalf("FORWARD jump rule does not exist")
+	}
+
+	ip := net.ParseIP("192.168.1.1")
+	if err := nat.Forward(Insert, ip, 1234, "tcp", "172.17.0.1", 4321, "lo"); err != nil {
+		t.Fatal(err)
+	}
+	dnatRule := []string{
+		"-d", ip.String(),
+		"-p", "tcp",
+		"--dport", "1234",
+		"-j", "DNAT",
+		"--to-destination", "172.17.0.1:4321",
+		"!", "-i", "lo",
+	}
+	if !Exists(Nat, chainName, dnatRule...) {
+		t.Fatalf("DNAT rule does not exist")
+	}
+	if !Exists(Nat, "POSTROUTING", "-p", "tcp", "-s", "172.17.0.1", "-d", "172.17.0.1", "--dport", "4321", "-j", "MASQUERADE") {
+		t.Fatalf("MASQUERADE rule does not exist")
+	}
+
+	if err := filter.Link(Append, net.ParseIP("172.17.0.2"), net.ParseIP("172.17.0.3"), 80, "tcp", "lo"); err != nil {
+		t.Fatal(err)
+	}
+	if !Exists(Filter, chainName, "-i", "lo", "-o", "lo", "-p", "tcp", "-s", "172.17.0.3", "-d", "172.17.0.2", "--sport", "80", "-j", "ACCEPT") {
+		t.Fatalf("Reverse link rule does not exist")
+	}
+
+	// Rules must be inserted at the top of the chain
+	if err := RawCombinedOutput("-I", chainName, "-i", "lo", "-j", "DROP"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Raw("-S", chainName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 5 || lines[0] != "-N "+chainName || lines[1] != "-A "+chainName+" -i lo -j DROP" {
+		t.Fatalf("Unexpected chain listing:\n%s", out)
+	}
+
+	if err := nat.Forward(Delete, ip, 1234, "tcp", "172.17.0.1", 4321, "lo"); err != nil {
+		t.Fatal(err)
+	}
+	if Exists(Nat, chainName, dnatRule...) {
+		t.Fatalf("DNAT rule was not removed")
+	}
+	if err := RawCombinedOutput(append([]string{"-t", "nat", "-D", chainName}, dnatRule...)...); err == nil {
+		t.Fatalf("Expected failure deleting a missing rule")
+	}
+
+	if err := nat.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ProgramChain(filter, "lo", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := filter.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if ExistChain(chainName, Nat) || ExistChain(chainName, Filter) {
+		t.Fatalf("Chains were not removed")
+	}
+}
+
+func TestNftablesTargets(t *testing.T) {
+	defer setupNftables(t)()
+
+	for _, rule := range [][]string{
+		{"-t", "mangle", "-A", "OUTPUT", "-p", "udp", "--dport", "4789", "-m", "u32", "--u32", "0>>22&0x3C@12&0xFFFFFF00=256", "-j", "MARK", "--set-mark", "13681891"},
+		{"-t", "nat", "-A", "PREROUTING", "-d", "10.0.0.2", "-p", "tcp", "--dport", "80", "-j", "REDIRECT", "--to-port", "8080"},
+		{"-t", "nat", "-I", "POSTROUTING", "-s", "127.0.0.11", "-p", "udp", "--sport", "4000", "-j", "SNAT", "--to-source", ":53"},
+		{"-I", "INPUT", "-d", "10.0.0.2", "-p", "tcp", "--dport", "8000:8080", "-m", "state", "--state", "NEW,ESTABLISHED", "-j", "ACCEPT"},
+		{"-I", "INPUT", "2", "-d", "10.0.0.2", "-p", "udp", "-j", "DROP"},
+	} {
+		if err := RawCombinedOutputNative(rule...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !ExistsNative(Mangle, "OUTPUT", "-p", "udp", "--dport", "4789", "-m", "u32", "--u32", "0>>22&0x3C@12&0xFFFFFF00=256", "-j", "MARK", "--set-mark", "0xd0c4e3") {
+		t.Fatalf("Mark rule does not exist")
+	}
+
+	out, err := Raw("-S", "INPUT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], "-p udp -j DROP") {
+		t.Fatalf("Unexpected chain listing:\n%s", out)
+	}
+
+	if err := RawCombinedOutput("-A", "INPUT", "-j", "NOSUCHCHAIN"); err == nil {
+		t.Fatalf("Expected failure jumping to a missing chain")
+	}
+}
+
+func TestNftablesTransaction(t *testing.T) {
+	defer setupNftables(t)()
+
+	if _, err := NewChain(chainName, Filter, false); err != nil {
+		t.Fatal(err)
+	}
+
+	accept := []string{"-i", "lo", "-p", "tcp", "--dport", "80", "-j", "ACCEPT"}
+	drop := []string{"-i", "lo", "-j", "DROP"}
+	transient := []string{"-i", "lo", "-p", "udp", "-j", "ACCEPT"}
+
+	tx := NewTransaction()
+	tx.ProgramRule(Filter, chainName, Append, drop)
+	tx.ProgramRule(Filter, chainName, Insert, accept)
+	tx.ProgramRule(Filter, chainName, Insert, accept)
+	tx.ProgramRule(Filter, chainName, Append, transient)
+	tx.ProgramRule(Filter, chainName, Delete, transient)
+	tx.ProgramRule(Filter, "FORWARD", Insert, []string{"-o", "lo", "-j", chainName})
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Len() != 0 {
+		t.Fatalf("Transaction was not emptied on commit")
+	}
+
+	out, err := Raw("-S", chainName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "-N " + chainName + "\n" +
+		"-A " + chainName + " -i lo -p tcp --dport 80 -j ACCEPT\n" +
+		"-A " + chainName + " -i lo -j DROP\n"
+	if string(out) != expected {
+		t.Fatalf("Unexpected chain listing:\n%s", out)
+	}
+	if !Exists(Filter, "FORWARD", "-o", "lo", "-j", chainName) {
+		t.Fatalf("FORWARD jump rule does not exist")
+	}
+
+	// A failing transaction must not program any rule
+	tx.ProgramRule(Filter, chainName, Delete, drop)
+	tx.ProgramRule(Filter, chainName, Append, transient)
+	tx.ProgramRule(Filter, chainName, Append, []string{"-j", "NOSUCHCHAIN"})
+	if err := tx.Commit(); err == nil {
+		t.Fatalf("Expected failure jumping to a missing chain")
+	}
+	if !Exists(Filter, chainName, drop...) || Exists(Filter, chainName, transient...) {
+		t.Fatalf("Failed transaction was partially applied")
+	}
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package iptables
+
+import "fmt"
+
+func newNftablesBackend() (Backend, error) {
+	return nil, fmt.Errorf("the %s firewall backend is only supported on linux", NftablesBackend)
+}
@@ -0,0 +1,256 @@
+package iptables
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+var (
+	restorePath         string
+	savePath            string
+	supportsRestoreWait bool
+	restoreOnce         sync.Once
+	// serializes the transactions from the time the current rules are
+	// saved to the time the changes are restored
+	restoreLock sync.Mutex
+
+	restoreErrorRe = regexp.MustCompile(`line (\d+) failed`)
+)
+
+func initRestore() {
+	if path, err := exec.LookPath("iptables-restore"); err == nil {
+		restorePath = path
+	}
+	if path, err := exec.LookPath("iptables-save"); err == nil {
+		savePath = path
+	}
+	if restorePath != "" {
+		out, _ := exec.Command(restorePath, "--help").CombinedOutput()
+		supportsRestoreWait = strings.Contains(string(out), "--wait")
+	}
+}
+
+// Commit programs the rules with a single iptables-restore invocation, which
+// applies the changes to each table atomically. If the changes to a table
+// fail, the changes already committed to the other tables are reverted. The
+// rules are programmed one at a time when firewalld is running, when the
+// iptables-save and iptables-restore commands are missing, or when a rule is
+// not understood well enough to be looked up in the iptables-save output.
+func (b *iptablesBackend) Commit(rules []Rule) error {
+	if err := initCheck(); err != nil {
+		return err
+	}
+	restoreOnce.Do(initRestore)
+	if firewalldRunning || restorePath == "" || savePath == "" {
+		return commitRules(b, rules)
+	}
+
+	specs := make([]string, len(rules))
+	for i, r := range rules {
+		s, err := parseRuleSpec(r.Args)
+		if err != nil {
+			logrus.Debugf("Programming the iptables rules one at a time: %v", err)
+			return commitRules(b, rules)
+		}
+		specs[i] = s.String()
+	}
+
+	restoreLock.Lock()
+	defer restoreLock.Unlock()
+
+	current, err := saveRules()
+	if err != nil {
+		return err
+	}
+
+	var changes, undo restoreScript
+	for i, r := range rules {
+		chain := current.chain(r.Table, r.Chain)
+		pos := -1
+		for j, spec := range *chain {
+			if spec == specs[i] {
+				pos = j
+				break
+			}
+		}
+
+		switch r.Action {
+		case Delete:
+			if pos < 0 {
+				continue
+			}
+			*chain = append((*chain)[:pos], (*chain)[pos+1:]...)
+			undo.prepend(r.Table, append([]string{string(Insert), r.Chain, strconv.Itoa(pos + 1)}, r.Args...))
+		case Insert:
+			if pos >= 0 {
+				continue
+			}
+			*chain = append([]string{specs[i]}, *chain...)
+			undo.prepend(r.Table, append([]string{string(Delete), r.Chain}, r.Args...))
+		default:
+			if pos >= 0 {
+				continue
+			}
+			*chain = append(*chain, specs[i])
+			undo.prepend(r.Table, append([]string{string(Delete), r.Chain}, r.Args...))
+		}
+		changes.append(r.Table, append([]string{string(r.Action), r.Chain}, r.Args...))
+	}
+
+	committed, err := changes.restore()
+	if err != nil && committed > 0 {
+		undo.tables = undo.tables[len(undo.tables)-committed:]
+		if _, uerr := undo.restore(); uerr != nil {
+			logrus.Warnf("Failed to roll back the iptables transaction: %v", uerr)
+		}
+	}
+	return err
+}
+
+// savedRules are the canonical specifications of the rules of each chain, as
+// reported by iptables-save
+type savedRules map[Table]map[string]*[]string
+
+func (s savedRules) chain(table Table, chain string) *[]string {
+	if s[table] == nil {
+		s[table] = map[string]*[]string{}
+	}
+	if s[table][chain] == nil {
+		s[table][chain] = &[]string{}
+	}
+	return s[table][chain]
+}
+
+func saveRules() (savedRules, error) {
+	output, err := exec.Command(savePath).Output()
+	if err != nil {
+		return nil, fmt.Errorf("iptables-save failed: %v", err)
+	}
+
+	var (
+		rules = savedRules{}
+		table Table
+	)
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = Table(line[1:])
+		case strings.HasPrefix(line, "-A "):
+			f := strings.Fields(line)
+			if len(f) < 2 {
+				continue
+			}
+			chain := rules.chain(table, f[1])
+			spec := strings.Join(f[2:], " ")
+			// The rules libnetwork does not program may not be
+			// understood, they can be compared verbatim
+			if s, err := parseRuleSpec(f[2:]); err == nil {
+				spec = s.String()
+			}
+			*chain = append(*chain, spec)
+		}
+	}
+	return rules, nil
+}
+
+// restoreScript is the input of iptables-restore, with the commands of each
+// table in the order the tables are committed
+type restoreScript struct {
+	tables   []Table
+	commands map[Table][][]string
+}
+
+func (s *restoreScript) add(table Table, command []string, prepend bool) {
+	if s.commands == nil {
+		s.commands = map[Table][][]string{}
+	}
+	if _, ok := s.commands[table]; !ok {
+		if prepend {
+			s.tables = append([]Table{table}, s.tables...)
+		} else {
+			s.tables = append(s.tables, table)
+		}
+	}
+	if prepend {
+		s.commands[table] = append([][]string{command}, s.commands[table]...)
+	} else {
+		s.commands[table] = append(s.commands[table], command)
+	}
+}
+
+func (s *restoreScript) append(table Table, command []string) {
+	s.add(table, command, false)
+}
+
+func (s *restoreScript) prepend(table Table, command []string) {
+	s.add(table, command, true)
+}
+
+// restore runs iptables-restore on the script, without flushing the tables.
+// It returns the number of tables committed, which is less than the number of
+// tables of the script on failure.
+func (s *restoreScript) restore() (int, error) {
+	if len(s.tables) == 0 {
+		return 0, nil
+	}
+
+	var (
+		input  bytes.Buffer
+		line   int
+		commit []int
+	)
+	for _, t := range s.tables {
+		fmt.Fprintf(&input, "*%s\n", t)
+		line++
+		for _, c := range s.commands[t] {
+			quoted := make([]string, len(c))
+			for i, a := range c {
+				quoted[i] = quoteRestoreArg(a)
+			}
+			fmt.Fprintln(&input, strings.Join(quoted, " "))
+			line++
+		}
+		fmt.Fprintln(&input, "COMMIT")
+		line++
+		commit = append(commit, line)
+	}
+
+	args := []string{"--noflush"}
+	if supportsRestoreWait {
+		args = append(args, "--wait")
+	}
+	logrus.Debugf("%s %v:\n%s", restorePath, args, input.String())
+
+	cmd := exec.Command(restorePath, args...)
+	cmd.Stdin = &input
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return len(s.tables), nil
+	}
+
+	// The tables are committed in order, up to the line which failed
+	committed := 0
+	if m := restoreErrorRe.FindSubmatch(output); m != nil {
+		failed, _ := strconv.Atoi(string(m[1]))
+		for _, l := range commit {
+			if l < failed {
+				committed++
+			}
+		}
+	}
+	return committed, fmt.Errorf("iptables-restore failed: %s (%v)", strings.TrimSpace(string(output)), err)
+}
+
+func quoteRestoreArg(a string) string {
+	if a != "" && !strings.ContainsAny(a, " \t\"'") {
+		return a
+	}
+	return `"` + strings.Replace(a, `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,295 @@
+package iptables
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ruleSpec is a parsed iptables rule specification. Only the matches and
+// targets libnetwork uses are supported.
+type ruleSpec struct {
+	src, dst           *net.IPNet
+	srcNeg, dstNeg     bool
+	in, out            string
+	inNeg, outNeg      bool
+	proto              string
+	protoNeg           bool
+	srcType, dstType   string
+	ctState            []string
+	ipvs               bool
+	u32                *u32Match
+	sport, dport       string
+	sportNeg, dportNeg bool
+	target             string
+	to                 string
+	toPorts            string
+	mark               uint32
+}
+
+var (
+	protocolNumbers = map[string]byte{"icmp": 1, "tcp": 6, "udp": 17, "sctp": 132}
+
+	addrTypes = map[string]uint32{
+		"UNSPEC": 0, "UNICAST": 1, "LOCAL": 2, "BROADCAST": 3, "ANYCAST": 4,
+		"MULTICAST": 5, "BLACKHOLE": 6, "UNREACHABLE": 7, "PROHIBIT": 8,
+	}
+
+	ctStates = map[string]uint32{
+		"INVALID": 1, "ESTABLISHED": 2, "RELATED": 4, "NEW": 8, "UNTRACKED": 64,
+	}
+
+	// The u32 expression matching a 32 bits word of the transport header,
+	// as used to match the vxlan network identifier. iptables-save reports
+	// all its numbers in hexadecimal.
+	u32TransportRe = regexp.MustCompile(`^(?:0|0x0)>>(?:22|0x16)&0x3[cC]@(0x[0-9a-fA-F]+|\d+)&(0x[0-9a-fA-F]+)=(0x[0-9a-fA-F]+|\d+)$`)
+)
+
+// u32Match is a u32 expression comparing the masked 32 bits word at the
+// offset of the transport header with value
+type u32Match struct {
+	offset, mask, value uint32
+}
+
+func parseU32Match(expr string) (*u32Match, error) {
+	m := u32TransportRe.FindStringSubmatch(strings.Trim(expr, "\""))
+	if m == nil {
+		return nil, fmt.Errorf("unsupported u32 expression %q", expr)
+	}
+	var v [3]uint64
+	for i := range v {
+		n, err := strconv.ParseUint(m[i+1], 0, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid u32 expression %q", expr)
+		}
+		v[i] = n
+	}
+	return &u32Match{offset: uint32(v[0]), mask: uint32(v[1]), value: uint32(v[2])}, nil
+}
+
+// String returns the expression in the iptables-save format
+func (m *u32Match) String() string {
+	return fmt.Sprintf("0x0>>0x16&0x3c@0x%x&0x%x=0x%x", m.offset, m.mask, m.value)
+}
+
+func parseRuleSpec(args []string) (*ruleSpec, error) {
+	s := &ruleSpec{}
+	for i := 0; i < len(args); i++ {
+		neg := false
+		if args[i] == "!" {
+			neg = true
+			if i++; i >= len(args) {
+				return nil, fmt.Errorf("missing option after \"!\"")
+			}
+		}
+		opt := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("option %s requires an argument", opt)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var (
+			v   string
+			err error
+		)
+		switch opt {
+		case "--ipvs":
+			if neg {
+				return nil, fmt.Errorf("negated %s is not supported", opt)
+			}
+			s.ipvs = true
+			continue
+		}
+		if v, err = value(); err != nil {
+			return nil, err
+		}
+
+		switch opt {
+		case "-s", "--source", "--src":
+			s.srcNeg = neg
+			s.src, err = parseRuleAddr(v)
+		case "-d", "--destination", "--dst":
+			s.dstNeg = neg
+			s.dst, err = parseRuleAddr(v)
+		case "-i", "--in-interface":
+			s.in, s.inNeg = v, neg
+		case "-o", "--out-interface":
+			s.out, s.outNeg = v, neg
+		case "-p", "--protocol":
+			s.proto, s.protoNeg = strings.ToLower(v), neg
+			if _, err = protocolNumber(s.proto); err != nil {
+				return nil, err
+			}
+		case "--sport", "--source-port":
+			s.sport, s.sportNeg = v, neg
+		case "--dport", "--destination-port":
+			s.dport, s.dportNeg = v, neg
+		case "-m", "--match":
+			switch v {
+			case "addrtype", "conntrack", "state", "ipvs", "u32", "tcp", "udp", "sctp":
+			default:
+				err = fmt.Errorf("unsupported match %q", v)
+			}
+		case "--src-type", "--dst-type":
+			if _, ok := addrTypes[v]; !ok || neg {
+				return nil, fmt.Errorf("unsupported address type %q", v)
+			}
+			if opt == "--src-type" {
+				s.srcType = v
+			} else {
+				s.dstType = v
+			}
+		case "--ctstate", "--state":
+			if neg {
+				return nil, fmt.Errorf("negated %s is not supported", opt)
+			}
+			for _, st := range strings.Split(v, ",") {
+				if _, ok := ctStates[st]; !ok {
+					return nil, fmt.Errorf("unsupported connection state %q", st)
+				}
+				s.ctState = append(s.ctState, st)
+			}
+			sort.Strings(s.ctState)
+		case "--u32":
+			if neg {
+				return nil, fmt.Errorf("negated %s is not supported", opt)
+			}
+			s.u32, err = parseU32Match(v)
+		case "-j", "--jump":
+			s.target = v
+		case "--to-destination", "--to-source":
+			s.to = v
+		case "--to-ports", "--to-port":
+			s.toPorts = v
+		case "--set-mark", "--set-xmark":
+			// iptables-save reports --set-mark as --set-xmark with a
+			// full mask, which is the only mask supported
+			if opt == "--set-xmark" {
+				if !strings.HasSuffix(v, "/0xffffffff") {
+					return nil, fmt.Errorf("unsupported mark %q", v)
+				}
+				v = strings.TrimSuffix(v, "/0xffffffff")
+			}
+			var m uint64
+			if m, err = strconv.ParseUint(v, 0, 32); err == nil {
+				s.mark = uint32(m)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported option %q", opt)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (s.sport != "" || s.dport != "") && s.proto != "tcp" && s.proto != "udp" && s.proto != "sctp" {
+		return nil, fmt.Errorf("port matches require a tcp, udp or sctp protocol match")
+	}
+	return s, nil
+}
+
+func parseRuleAddr(s string) (*net.IPNet, error) {
+	if s == "0/0" {
+		s = "0.0.0.0/0"
+	}
+	if !strings.Contains(s, "/") {
+		s = s + "/32"
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil || n.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid IPv4 address %q", s)
+	}
+	return n, nil
+}
+
+// isAnyAddr returns whether the address match matches any address, which
+// iptables does not report
+func isAnyAddr(n *net.IPNet, neg bool) bool {
+	ones, _ := n.Mask.Size()
+	return ones == 0 && !neg
+}
+
+func protocolNumber(proto string) (byte, error) {
+	if p, ok := protocolNumbers[proto]; ok {
+		return p, nil
+	}
+	p, err := strconv.ParseUint(proto, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported protocol %q", proto)
+	}
+	return byte(p), nil
+}
+
+func negOpt(neg bool, opt, value string) []string {
+	if neg {
+		return []string{"!", opt, value}
+	}
+	return []string{opt, value}
+}
+
+// String returns the canonical form of the rule specification, in the order
+// iptables -S reports it
+func (s *ruleSpec) String() string {
+	var a []string
+	if s.src != nil && !isAnyAddr(s.src, s.srcNeg) {
+		a = append(a, negOpt(s.srcNeg, "-s", s.src.String())...)
+	}
+	if s.dst != nil && !isAnyAddr(s.dst, s.dstNeg) {
+		a = append(a, negOpt(s.dstNeg, "-d", s.dst.String())...)
+	}
+	if s.in != "" {
+		a = append(a, negOpt(s.inNeg, "-i", s.in)...)
+	}
+	if s.out != "" {
+		a = append(a, negOpt(s.outNeg, "-o", s.out)...)
+	}
+	if s.proto != "" {
+		a = append(a, negOpt(s.protoNeg, "-p", s.proto)...)
+	}
+	if s.srcType != "" || s.dstType != "" {
+		a = append(a, "-m", "addrtype")
+		if s.srcType != "" {
+			a = append(a, "--src-type", s.srcType)
+		}
+		if s.dstType != "" {
+			a = append(a, "--dst-type", s.dstType)
+		}
+	}
+	if len(s.ctState) > 0 {
+		a = append(a, "-m", "conntrack", "--ctstate", strings.Join(s.ctState, ","))
+	}
+	if s.ipvs {
+		a = append(a, "-m", "ipvs", "--ipvs")
+	}
+	if s.u32 != nil {
+		a = append(a, "-m", "u32", "--u32", s.u32.String())
+	}
+	if s.sport != "" {
+		a = append(a, negOpt(s.sportNeg, "--sport", s.sport)...)
+	}
+	if s.dport != "" {
+		a = append(a, negOpt(s.dportNeg, "--dport", s.dport)...)
+	}
+	if s.target != "" {
+		a = append(a, "-j", s.target)
+	}
+	switch s.target {
+	case "DNAT":
+		a = append(a, "--to-destination", s.to)
+	case "SNAT":
+		a = append(a, "--to-source", s.to)
+	case "REDIRECT", "MASQUERADE":
+		if s.toPorts != "" {
+			a = append(a, "--to-ports", s.toPorts)
+		}
+	case "MARK":
+		a = append(a, "--set-mark", fmt.Sprintf("0x%x", s.mark))
+	}
+	return strings.Join(a, " ")
+}
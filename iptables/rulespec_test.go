@@ -0,0 +1,64 @@
+package iptables
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleSpec(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		spec string
+	}{
+		{
+			[]string{"-p", "tcp", "-d", "0/0", "--dport", "80", "-j", "DNAT", "--to-destination", "172.17.0.2:8080", "!", "-i", "docker0"},
+			"! -i docker0 -p tcp --dport 80 -j DNAT --to-destination 172.17.0.2:8080",
+		},
+		{
+			[]string{"-o", "docker0", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+			"-o docker0 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT",
+		},
+		{
+			[]string{"-m", "addrtype", "--dst-type", "LOCAL", "!", "--dst", "127.0.0.0/8", "-j", "DOCKER"},
+			"! -d 127.0.0.0/8 -m addrtype --dst-type LOCAL -j DOCKER",
+		},
+		{
+			[]string{"-s", "172.17.0.1/16", "!", "-o", "docker0", "-j", "MASQUERADE"},
+			"-s 172.17.0.0/16 ! -o docker0 -j MASQUERADE",
+		},
+		{
+			[]string{"-p", "udp", "--dport", "4789", "-m", "u32", "--u32", "0>>22&0x3C@12&0xFFFFFF00=256", "-j", "MARK", "--set-mark", "13681891"},
+			"-p udp -m u32 --u32 0x0>>0x16&0x3c@0xc&0xffffff00=0x100 --dport 4789 -j MARK --set-mark 0xd0c4e3",
+		},
+		// iptables-save format
+		{
+			strings.Fields(`-d 10.0.0.2/32 -p tcp -m tcp --dport 80 -m state --state NEW,ESTABLISHED -j ACCEPT`),
+			"-d 10.0.0.2/32 -p tcp -m conntrack --ctstate ESTABLISHED,NEW --dport 80 -j ACCEPT",
+		},
+		{
+			strings.Fields(`-p udp -m udp --dport 4789 -m u32 --u32 "0x0>>0x16&0x3c@0xc&0xffffff00=0x100" -j MARK --set-xmark 0xd0c4e3/0xffffffff`),
+			"-p udp -m u32 --u32 0x0>>0x16&0x3c@0xc&0xffffff00=0x100 --dport 4789 -j MARK --set-mark 0xd0c4e3",
+		},
+	} {
+		s, err := parseRuleSpec(tc.args)
+		if err != nil {
+			t.Fatalf("Failed to parse %v: %v", tc.args, err)
+		}
+		if s.String() != tc.spec {
+			t.Fatalf("Unexpected canonical rule for %v.\nExpected: %s\nGot:      %s", tc.args, tc.spec, s.String())
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--dport", "80", "-j", "ACCEPT"},
+		{"-m", "physdev", "--physdev-in", "eth0"},
+		{"-s", "2001:db8::1", "-j", "DROP"},
+		{"-p", "udp", "-m", "u32", "--u32", "0&0xFF=0x1"},
+		{"-j"},
+		{"-j", "MARK", "--set-xmark", "0x1/0xff"},
+	} {
+		if _, err := parseRuleSpec(args); err == nil {
+			t.Fatalf("Expected failure parsing %v", args)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package iptables
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Rule is a rule programmed by a Transaction.
+type Rule struct {
+	Table  Table
+	Chain  string
+	Action Action
+	Args   []string
+}
+
+func (r Rule) command() []string {
+	return append([]string{"-t", string(r.Table), string(r.Action), r.Chain}, r.Args...)
+}
+
+// reverse returns the rule undoing the programming of r. The rules removed
+// are restored at the end of their chain.
+func (r Rule) reverse() Rule {
+	if r.Action == Delete {
+		r.Action = Append
+	} else {
+		r.Action = Delete
+	}
+	return r
+}
+
+// Transaction collects rule insertions and deletions which are programmed as
+// a unit: either all the rules are programmed, or none is. As with
+// ProgramRule, a rule is only added if it is not already present in its
+// chain, and only removed if present.
+type Transaction struct {
+	rules []Rule
+}
+
+// NewTransaction returns a new empty transaction.
+func NewTransaction() *Transaction {
+	return &Transaction{}
+}
+
+// ProgramRule queues the addition or the removal of the rule specified by args.
+func (t *Transaction) ProgramRule(table Table, chain string, action Action, args []string) {
+	if string(table) == "" {
+		table = Filter
+	}
+	t.rules = append(t.rules, Rule{Table: table, Chain: chain, Action: action, Args: args})
+}
+
+// Len returns the number of rules queued in the transaction.
+func (t *Transaction) Len() int {
+	return len(t.rules)
+}
+
+// Commit programs the queued rules through the firewall backend in use. On
+// failure the rules already programmed are rolled back. The transaction is
+// emptied in any case.
+func (t *Transaction) Commit() error {
+	rules := t.rules
+	t.rules = nil
+	if len(rules) == 0 {
+		return nil
+	}
+	return GetBackend().Commit(rules)
+}
+
+// Forward queues the forwarding rule of the 'filter' table and the
+// corresponding rules of the 'nat' table mapping the port to destAddr.
+func (t *Transaction) Forward(c *ChainInfo, action Action, ip net.IP, port int, proto, destAddr string, destPort int, bridgeName string) {
+	daddr := ip.String()
+	if ip.IsUnspecified() {
+		// iptables interprets "0.0.0.0" as "0.0.0.0/32", whereas we
+		// want "0.0.0.0/0". "0/0" is correctly interpreted as "any
+		// value" by both iptables and ip6tables.
+		daddr = "0/0"
+	}
+
+	args := []string{
+		"-p", proto,
+		"-d", daddr,
+		"--dport", strconv.Itoa(port),
+		"-j", "DNAT",
+		"--to-destination", net.JoinHostPort(destAddr, strconv.Itoa(destPort))}
+	if !c.HairpinMode {
+		args = append(args, "!", "-i", bridgeName)
+	}
+	t.ProgramRule(Nat, c.Name, action, args)
+
+	t.ProgramRule(Filter, c.Name, action, []string{
+		"!", "-i", bridgeName,
+		"-o", bridgeName,
+		"-p", proto,
+		"-d", destAddr,
+		"--dport", strconv.Itoa(destPort),
+		"-j", "ACCEPT",
+	})
+
+	t.ProgramRule(Nat, "POSTROUTING", action, []string{
+		"-p", proto,
+		"-s", destAddr,
+		"-d", destAddr,
+		"--dport", strconv.Itoa(destPort),
+		"-j", "MASQUERADE",
+	})
+}
+
+// commitRules programs the rules one at a time through the backend, and
+// removes the ones already programmed if one fails. It is used by the
+// backends which cannot program the rules atomically.
+func commitRules(b Backend, rules []Rule) error {
+	var undo []Rule
+	for _, r := range rules {
+		if b.Exists(r.Table, r.Chain, r.Args...) != (r.Action == Delete) {
+			continue
+		}
+		if output, err := b.Raw(r.command()...); err != nil || len(output) != 0 {
+			rollbackRules(b, undo)
+			return fmt.Errorf("%s (%v)", string(output), err)
+		}
+		undo = append(undo, r.reverse())
+	}
+	return nil
+}
+
+func rollbackRules(b Backend, undo []Rule) {
+	for i := len(undo) - 1; i >= 0; i-- {
+		if output, err := b.Raw(undo[i].command()...); err != nil || len(output) != 0 {
+			logrus.Warnf("Failed to roll back iptables rule %v: %s (%v)", undo[i].command(), string(output), err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package iptables
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeBackend keeps the rules of each chain in memory, and fails to program
+// the rules jumping to the FAIL target
+type fakeBackend struct {
+	rules map[string][]string
+}
+
+func (b *fakeBackend) Name() string {
+	return "fake"
+}
+
+func (b *fakeBackend) Raw(args ...string) ([]byte, error) {
+	table, action, chain := args[1], args[2], args[3]
+	key := table + "/" + chain
+	rule := strings.Join(args[4:], " ")
+	if strings.HasSuffix(rule, "-j FAIL") {
+		return nil, fmt.Errorf("failed to program %s", rule)
+	}
+	switch Action(action) {
+	case Append:
+		b.rules[key] = append(b.rules[key], rule)
+	case Insert:
+		b.rules[key] = append([]string{rule}, b.rules[key]...)
+	case Delete:
+		for i, r := range b.rules[key] {
+			if r == rule {
+				b.rules[key] = append(b.rules[key][:i], b.rules[key][i+1:]...)
+				break
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (b *fakeBackend) Exists(table Table, chain string, rule ...string) bool {
+	for _, r := range b.rules[string(table)+"/"+chain] {
+		if r == strings.Join(rule, " ") {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *fakeBackend) Commit(rules []Rule) error {
+	return commitRules(b, rules)
+}
+
+func TestCommitRules(t *testing.T) {
+	b := &fakeBackend{rules: map[string][]string{
+		"filter/FORWARD": {"-j DOCKER", "-j ACCEPT"},
+	}}
+
+	rules := []Rule{
+		{Filter, "FORWARD", Delete, []string{"-j", "ACCEPT"}},
+		{Filter, "FORWARD", Insert, []string{"-j", "DOCKER"}},
+		{Nat, "POSTROUTING", Append, []string{"-j", "MASQUERADE"}},
+		{Filter, "FORWARD", Delete, []string{"-j", "DROP"}},
+	}
+	if err := commitRules(b, rules); err != nil {
+		t.Fatal(err)
+	}
+	if f := b.rules["filter/FORWARD"]; len(f) != 1 || f[0] != "-j DOCKER" {
+		t.Fatalf("Unexpected FORWARD rules: %v", f)
+	}
+	if p := b.rules["nat/POSTROUTING"]; len(p) != 1 || p[0] != "-j MASQUERADE" {
+		t.Fatalf("Unexpected POSTROUTING rules: %v", p)
+	}
+
+	rules = []Rule{
+		{Nat, "POSTROUTING", Delete, []string{"-j", "MASQUERADE"}},
+		{Filter, "FORWARD", Append, []string{"-j", "ACCEPT"}},
+		{Filter, "FORWARD", Append, []string{"-j", "FAIL"}},
+	}
+	if err := commitRules(b, rules); err == nil {
+		t.Fatalf("Expected failure programming the rules")
+	}
+	if f := b.rules["filter/FORWARD"]; len(f) != 1 || f[0] != "-j DOCKER" {
+		t.Fatalf("Failed transaction was not rolled back: %v", f)
+	}
+	if p := b.rules["nat/POSTROUTING"]; len(p) != 1 || p[0] != "-j MASQUERADE" {
+		t.Fatalf("Failed transaction was not rolled back: %v", p)
+	}
+}
@@ -69,6 +69,7 @@ const (
 	ipvsSvcAttrNetmask
 	ipvsSvcAttrStats
 	ipvsSvcAttrPEName
+	ipvsSvcAttrStats64
 )
 
 // Attributes used to describe a destination (real server). Used
@@ -85,6 +86,27 @@ const (
 	ipvsDestAttrInactiveConnections
 	ipvsDestAttrPersistentConnections
 	ipvsDestAttrStats
+	ipvsDestAttrAddressFamily
+	ipvsDestAttrStats64
+)
+
+// Attributes used to describe the statistics of a service or a
+// destination. Used inside nested attributes ipvsSvcAttrStats and
+// ipvsDestAttrStats, where the counters are 32 bits but the bytes
+// ones, and ipvsSvcAttrStats64 and ipvsDestAttrStats64, where they
+// all are 64 bits.
+const (
+	ipvsStatsUnspec int = iota
+	ipvsStatsConns
+	ipvsStatsPktsIn
+	ipvsStatsPktsOut
+	ipvsStatsBytesIn
+	ipvsStatsBytesOut
+	ipvsStatsCPS
+	ipvsStatsPPSIn
+	ipvsStatsPPSOut
+	ipvsStatsBPSIn
+	ipvsStatsBPSOut
 )
 
 // Destination forwarding methods
@@ -114,6 +136,10 @@ const (
 	// real servers.
 	RoundRobin = "rr"
 
+	// WeightedRoundRobin distributes jobs amongst the available
+	// real servers in proportion to their weight.
+	WeightedRoundRobin = "wrr"
+
 	// LeastConnection assigns more jobs to real servers with
 	// fewer active jobs.
 	LeastConnection = "lc"
@@ -127,4 +153,30 @@ const (
 	// a statically assigned hash table by their source IP
 	// addresses.
 	SourceHashing = "sh"
+
+	// MaglevHashing assigns jobs to servers through looking up a
+	// consistent hash table by their source IP addresses, which
+	// changes minimally when real servers are added or removed.
+	MaglevHashing = "mh"
+)
+
+// Virtual service flags
+const (
+	// SvcFlagPersistent directs the jobs of a client to the same real
+	// server until the service timeout expires.
+	SvcFlagPersistent = 0x0001
+
+	// SvcFlagHashed indicates the service is hashed.
+	SvcFlagHashed = 0x0002
+
+	// SvcFlagOnePacket schedules each UDP datagram independently.
+	SvcFlagOnePacket = 0x0004
+
+	// SvcFlagSchedFallback makes the hashing schedulers assign the
+	// job to another real server when the hashed one is unavailable.
+	SvcFlagSchedFallback = 0x0008
+
+	// SvcFlagSchedPort makes the hashing schedulers hash the source
+	// port along with the source address.
+	SvcFlagSchedPort = 0x0010
 )
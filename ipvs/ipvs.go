@@ -25,6 +25,10 @@ type Service struct {
 	Netmask       uint32
 	AddressFamily uint16
 	PEName        string
+
+	// Statistics of the service, only set in the services read
+	// back from the kernel.
+	Stats Stats
 }
 
 // Destination defines an IPVS destination (real server) in its
@@ -37,6 +41,29 @@ type Destination struct {
 	AddressFamily   uint16
 	UpperThreshold  uint32
 	LowerThreshold  uint32
+
+	// Connection counters and statistics of the destination, only
+	// set in the destinations read back from the kernel.
+	ActiveConnections     uint32
+	InactiveConnections   uint32
+	PersistentConnections uint32
+	Stats                 Stats
+}
+
+// Stats defines the statistics of an IPVS service or destination: the
+// counters since its creation and the rates estimated by the kernel,
+// per second.
+type Stats struct {
+	Connections uint64
+	PacketsIn   uint64
+	PacketsOut  uint64
+	BytesIn     uint64
+	BytesOut    uint64
+	CPS         uint64
+	PPSIn       uint64
+	PPSOut      uint64
+	BPSIn       uint64
+	BPSOut      uint64
 }
 
 // Handle provides a namespace specific ipvs handle to program ipvs
@@ -111,3 +138,30 @@ func (i *Handle) UpdateDestination(s *Service, d *Destination) error {
 func (i *Handle) DelDestination(s *Service, d *Destination) error {
 	return i.doCmd(s, d, ipvsCmdDelDest)
 }
+
+// GetServices returns all the ipvs services in the passed handle,
+// along with their statistics.
+func (i *Handle) GetServices() ([]*Service, error) {
+	return i.doGetServicesCmd(nil)
+}
+
+// GetService returns the ipvs service matching the passed one, by
+// address, protocol and port or by firewall mark, along with its
+// statistics.
+func (i *Handle) GetService(s *Service) (*Service, error) {
+	res, err := i.doGetServicesCmd(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, syscall.ESRCH
+	}
+	return res[0], nil
+}
+
+// GetDestinations returns all the real servers of the passed ipvs
+// service, which should already be existing in the passed handle,
+// along with their statistics.
+func (i *Handle) GetDestinations(s *Service) ([]*Destination, error) {
+	return i.doGetDestinationsCmd(s)
+}
@@ -20,6 +20,7 @@ import (
 var (
 	schedMethods = []string{
 		RoundRobin,
+		WeightedRoundRobin,
 		LeastConnection,
 		DestinationHashing,
 		SourceHashing,
@@ -184,6 +185,37 @@ func TestService(t *testing.T) {
 
 }
 
+func TestServicePersistence(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skipf("Skipping as not supported on CIRCLE CI kernel")
+	}
+
+	defer testutils.SetupTestOSContext(t)()
+
+	i, err := New("")
+	require.NoError(t, err)
+
+	s := Service{
+		AddressFamily: nl.FAMILY_V4,
+		FWMark:        1234,
+		SchedName:     RoundRobin,
+		Flags:         SvcFlagPersistent,
+		Timeout:       300,
+		Netmask:       0xFFFFFFFF,
+	}
+
+	err = i.NewService(&s)
+	require.NoError(t, err)
+	checkService(t, true, "FWM", RoundRobin, "1234")
+
+	out, err := exec.Command("ipvsadm", "-Ln").CombinedOutput()
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "persistent 300")
+
+	err = i.DelService(&s)
+	assert.NoError(t, err)
+}
+
 func createDummyInterface(t *testing.T) {
 	if testutils.RunningOnCircleCI() {
 		t.Skipf("Skipping as not supported on CIRCLE CI kernel")
@@ -319,3 +351,141 @@ func TestDestination(t *testing.T) {
 		}
 	}
 }
+
+func TestGetServices(t *testing.T) {
+	if testutils.RunningOnCircleCI() {
+		t.Skipf("Skipping as not supported on CIRCLE CI kernel")
+	}
+
+	defer testutils.SetupTestOSContext(t)()
+
+	createDummyInterface(t)
+	i, err := New("")
+	require.NoError(t, err)
+
+	s := Service{
+		AddressFamily: nl.FAMILY_V4,
+		Protocol:      syscall.IPPROTO_TCP,
+		Address:       net.ParseIP("1.2.3.4"),
+		Port:          80,
+		SchedName:     WeightedRoundRobin,
+		Netmask:       0xFFFFFFFF,
+	}
+	err = i.NewService(&s)
+	require.NoError(t, err)
+
+	fs := Service{
+		AddressFamily: nl.FAMILY_V4,
+		FWMark:        1234,
+		SchedName:     RoundRobin,
+		Flags:         SvcFlagPersistent,
+		Timeout:       300,
+		Netmask:       0xFFFFFFFF,
+	}
+	err = i.NewService(&fs)
+	require.NoError(t, err)
+
+	services, err := i.GetServices()
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+	for _, svc := range services {
+		if svc.FWMark != 0 {
+			assert.Equal(t, uint32(1234), svc.FWMark)
+			assert.Equal(t, RoundRobin, svc.SchedName)
+			assert.Equal(t, uint32(SvcFlagPersistent), svc.Flags&SvcFlagPersistent)
+			assert.Equal(t, uint32(300), svc.Timeout)
+			continue
+		}
+		assert.Equal(t, "1.2.3.4", svc.Address.String())
+		assert.Equal(t, uint16(80), svc.Port)
+		assert.Equal(t, uint16(syscall.IPPROTO_TCP), svc.Protocol)
+		assert.Equal(t, WeightedRoundRobin, svc.SchedName)
+	}
+
+	svc, err := i.GetService(&s)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3.4", svc.Address.String())
+
+	s.SchedName = ""
+	d := Destination{
+		AddressFamily: nl.FAMILY_V4,
+		Address:       net.ParseIP("10.1.1.2"),
+		Port:          5000,
+		Weight:        3,
+	}
+	err = i.NewDestination(&s, &d)
+	require.NoError(t, err)
+
+	dests, err := i.GetDestinations(&s)
+	require.NoError(t, err)
+	require.Len(t, dests, 1)
+	assert.Equal(t, "10.1.1.2", dests[0].Address.String())
+	assert.Equal(t, uint16(5000), dests[0].Port)
+	assert.Equal(t, 3, dests[0].Weight)
+	assert.Equal(t, uint32(0), dests[0].ActiveConnections)
+
+	require.NoError(t, i.DelService(&s))
+	require.NoError(t, i.DelService(&fs))
+
+	services, err = i.GetServices()
+	require.NoError(t, err)
+	assert.Len(t, services, 0)
+}
+
+func TestParseService(t *testing.T) {
+	s := &Service{
+		AddressFamily: nl.FAMILY_V4,
+		Protocol:      syscall.IPPROTO_TCP,
+		Address:       net.ParseIP("1.2.3.4").To4(),
+		Port:          8080,
+		SchedName:     SourceHashing,
+		Flags:         SvcFlagPersistent,
+		Timeout:       600,
+		Netmask:       0xFFFFFFFF,
+	}
+
+	attr := fillService(s).(*nl.RtAttr)
+	stats := nl.NewRtAttrChild(attr, ipvsSvcAttrStats, nil)
+	nl.NewRtAttrChild(stats, ipvsStatsConns, nl.Uint32Attr(7))
+	nl.NewRtAttrChild(stats, ipvsStatsPktsIn, nl.Uint32Attr(70))
+	bytesIn := make([]byte, 8)
+	native.PutUint64(bytesIn, 1<<40)
+	nl.NewRtAttrChild(stats, ipvsStatsBytesIn, bytesIn)
+
+	attrs, err := nl.ParseRouteAttr(attr.Serialize())
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+
+	svc, err := parseService(attrs[0].Value)
+	require.NoError(t, err)
+	s.Stats = Stats{Connections: 7, PacketsIn: 70, BytesIn: 1 << 40}
+	assert.Equal(t, s, svc)
+
+	d := &Destination{
+		AddressFamily:   nl.FAMILY_V4,
+		Address:         net.ParseIP("10.1.1.2").To4(),
+		Port:            5000,
+		Weight:          2,
+		ConnectionFlags: ConnectionFlagTunnel,
+		UpperThreshold:  100,
+	}
+
+	attr = fillDestinaton(d).(*nl.RtAttr)
+	nl.NewRtAttrChild(attr, ipvsDestAttrActiveConnections, nl.Uint32Attr(4))
+	nl.NewRtAttrChild(attr, ipvsDestAttrInactiveConnections, nl.Uint32Attr(1))
+	stats = nl.NewRtAttrChild(attr, ipvsDestAttrStats64, nil)
+	connections := make([]byte, 8)
+	native.PutUint64(connections, 5)
+	nl.NewRtAttrChild(stats, ipvsStatsConns, connections)
+
+	attrs, err = nl.ParseRouteAttr(attr.Serialize())
+	require.NoError(t, err)
+	require.Len(t, attrs, 1)
+
+	dest, err := parseDestination(attrs[0].Value, nl.FAMILY_V4)
+	require.NoError(t, err)
+	d.ActiveConnections = 4
+	d.InactiveConnections = 1
+	d.Stats = Stats{Connections: 5}
+	assert.Equal(t, d, dest)
+}
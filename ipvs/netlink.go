@@ -18,6 +18,10 @@ import (
 	"github.com/vishvananda/netns"
 )
 
+// Mask of the type of a netlink attribute, without the nested and the
+// byte order flags
+const nlaTypeMask = 0x3fff
+
 var (
 	native     = nl.NativeEndian()
 	ipvsFamily int
@@ -131,6 +135,243 @@ func (i *Handle) doCmd(s *Service, d *Destination, cmd uint8) error {
 	return nil
 }
 
+func (i *Handle) doGetServicesCmd(s *Service) ([]*Service, error) {
+	req := newIPVSRequest(ipvsCmdGetService)
+	if s == nil {
+		req.Flags |= syscall.NLM_F_DUMP
+	} else {
+		req.AddData(fillService(s))
+	}
+
+	msgs, err := execute(i.sock, req, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*Service
+	for _, m := range msgs {
+		attrs, err := parseGenlMsg(m)
+		if err != nil {
+			return nil, err
+		}
+		for _, attr := range attrs {
+			if int(attr.Attr.Type&nlaTypeMask) != ipvsCmdAttrService {
+				continue
+			}
+			svc, err := parseService(attr.Value)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, svc)
+		}
+	}
+
+	return res, nil
+}
+
+func (i *Handle) doGetDestinationsCmd(s *Service) ([]*Destination, error) {
+	req := newIPVSRequest(ipvsCmdGetDest)
+	req.Flags |= syscall.NLM_F_DUMP
+	req.AddData(fillService(s))
+
+	msgs, err := execute(i.sock, req, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*Destination
+	for _, m := range msgs {
+		attrs, err := parseGenlMsg(m)
+		if err != nil {
+			return nil, err
+		}
+		for _, attr := range attrs {
+			if int(attr.Attr.Type&nlaTypeMask) != ipvsCmdAttrDest {
+				continue
+			}
+			d, err := parseDestination(attr.Value, s.AddressFamily)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, d)
+		}
+	}
+
+	return res, nil
+}
+
+func parseGenlMsg(m []byte) ([]syscall.NetlinkRouteAttr, error) {
+	var hdr *genlMsgHdr
+	if len(m) < hdr.Len() {
+		return nil, fmt.Errorf("short generic netlink message of %d bytes", len(m))
+	}
+	return nl.ParseRouteAttr(m[hdr.Len():])
+}
+
+// parseService decodes the attributes nested in an ipvsCmdAttrService
+// attribute.
+func parseService(b []byte) (*Service, error) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{}
+	var addr []byte
+	for _, attr := range attrs {
+		v := attr.Value
+		switch int(attr.Attr.Type & nlaTypeMask) {
+		case ipvsSvcAttrAddressFamily:
+			s.AddressFamily = native.Uint16(v)
+		case ipvsSvcAttrProtocol:
+			s.Protocol = native.Uint16(v)
+		case ipvsSvcAttrAddress:
+			addr = v
+		case ipvsSvcAttrPort:
+			s.Port = binary.BigEndian.Uint16(v)
+		case ipvsSvcAttrFWMark:
+			s.FWMark = native.Uint32(v)
+		case ipvsSvcAttrSchedName:
+			s.SchedName = nl.BytesToString(v)
+		case ipvsSvcAttrFlags:
+			s.Flags = native.Uint32(v)
+		case ipvsSvcAttrTimeout:
+			s.Timeout = native.Uint32(v)
+		case ipvsSvcAttrNetmask:
+			s.Netmask = native.Uint32(v)
+		case ipvsSvcAttrPEName:
+			s.PEName = nl.BytesToString(v)
+		case ipvsSvcAttrStats:
+			// The 64 bits statistics take precedence
+			if s.Stats != (Stats{}) {
+				continue
+			}
+			fallthrough
+		case ipvsSvcAttrStats64:
+			if s.Stats, err = parseStats(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if addr != nil {
+		s.Address = parseIP(addr, s.AddressFamily)
+	}
+
+	return s, nil
+}
+
+// parseDestination decodes the attributes nested in an
+// ipvsCmdAttrDest attribute. The address family of the destination is
+// the one of its service, unless the kernel tells it.
+func parseDestination(b []byte, family uint16) (*Destination, error) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Destination{AddressFamily: family}
+	var addr []byte
+	for _, attr := range attrs {
+		v := attr.Value
+		switch int(attr.Attr.Type & nlaTypeMask) {
+		case ipvsDestAttrAddressFamily:
+			d.AddressFamily = native.Uint16(v)
+		case ipvsDestAttrAddress:
+			addr = v
+		case ipvsDestAttrPort:
+			d.Port = binary.BigEndian.Uint16(v)
+		case ipvsDestAttrForwardingMethod:
+			d.ConnectionFlags = native.Uint32(v)
+		case ipvsDestAttrWeight:
+			d.Weight = int(native.Uint32(v))
+		case ipvsDestAttrUpperThreshold:
+			d.UpperThreshold = native.Uint32(v)
+		case ipvsDestAttrLowerThreshold:
+			d.LowerThreshold = native.Uint32(v)
+		case ipvsDestAttrActiveConnections:
+			d.ActiveConnections = native.Uint32(v)
+		case ipvsDestAttrInactiveConnections:
+			d.InactiveConnections = native.Uint32(v)
+		case ipvsDestAttrPersistentConnections:
+			d.PersistentConnections = native.Uint32(v)
+		case ipvsDestAttrStats:
+			// The 64 bits statistics take precedence
+			if d.Stats != (Stats{}) {
+				continue
+			}
+			fallthrough
+		case ipvsDestAttrStats64:
+			if d.Stats, err = parseStats(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if addr != nil {
+		d.Address = parseIP(addr, d.AddressFamily)
+	}
+
+	return d, nil
+}
+
+// parseStats decodes the attributes nested in the statistics
+// attributes, the size of each counter is the one of its attribute.
+func parseStats(b []byte) (Stats, error) {
+	var st Stats
+
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return st, err
+	}
+
+	for _, attr := range attrs {
+		var v uint64
+		switch len(attr.Value) {
+		case 4:
+			v = uint64(native.Uint32(attr.Value))
+		case 8:
+			v = native.Uint64(attr.Value)
+		default:
+			continue
+		}
+
+		switch int(attr.Attr.Type & nlaTypeMask) {
+		case ipvsStatsConns:
+			st.Connections = v
+		case ipvsStatsPktsIn:
+			st.PacketsIn = v
+		case ipvsStatsPktsOut:
+			st.PacketsOut = v
+		case ipvsStatsBytesIn:
+			st.BytesIn = v
+		case ipvsStatsBytesOut:
+			st.BytesOut = v
+		case ipvsStatsCPS:
+			st.CPS = v
+		case ipvsStatsPPSIn:
+			st.PPSIn = v
+		case ipvsStatsPPSOut:
+			st.PPSOut = v
+		case ipvsStatsBPSIn:
+			st.BPSIn = v
+		case ipvsStatsBPSOut:
+			st.BPSOut = v
+		}
+	}
+
+	return st, nil
+}
+
+// parseIP decodes an address of the passed family, the kernel always
+// sends the 16 bytes of its union of the IPv4 and IPv6 addresses.
+func parseIP(b []byte, family uint16) net.IP {
+	if family == nl.FAMILY_V4 && len(b) >= net.IPv4len {
+		return net.IP(append([]byte(nil), b[:net.IPv4len]...))
+	}
+	return net.IP(append([]byte(nil), b...))
+}
+
 func getIPVSFamily() (int, error) {
 	sock, err := nl.GetNetlinkSocketAt(netns.None(), netns.None(), syscall.NETLINK_GENERIC)
 	if err != nil {
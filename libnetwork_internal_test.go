@@ -14,6 +14,8 @@ import (
 	"github.com/docker/libnetwork/netlabel"
 	"github.com/docker/libnetwork/testutils"
 	"github.com/docker/libnetwork/types"
+	"github.com/gogo/protobuf/proto"
+	"github.com/miekg/dns"
 )
 
 func TestNetworkMarshalling(t *testing.T) {
@@ -192,6 +194,12 @@ func TestEndpointMarshalling(t *testing.T) {
 			v4PoolID:  "poolpool",
 			v6PoolID:  "poolv6",
 		},
+		lbConfig: lbConfig{
+			scheduler:   "wrr",
+			persistence: 300,
+			healthCheck: lbHealthCheck{protocol: "http", port: 8080, path: "/health", interval: 10 * time.Second, timeout: time.Second},
+		},
+		lbWeight: 3,
 	}
 
 	b, err := json.Marshal(e)
@@ -205,11 +213,151 @@ func TestEndpointMarshalling(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if e.name != ee.name || e.id != ee.id || e.sandboxID != ee.sandboxID || !compareEndpointInterface(e.iface, ee.iface) || e.anonymous != ee.anonymous {
+	if e.name != ee.name || e.id != ee.id || e.sandboxID != ee.sandboxID || !compareEndpointInterface(e.iface, ee.iface) || e.anonymous != ee.anonymous ||
+		e.lbConfig != ee.lbConfig || e.lbWeight != ee.lbWeight {
 		t.Fatalf("JSON marsh/unmarsh failed.\nOriginal:\n%#v\nDecoded:\n%#v\nOriginal iface: %#v\nDecodediface:\n%#v", e, ee, e.iface, ee.iface)
 	}
 }
 
+func TestEndpointRecordLoadBalancing(t *testing.T) {
+	rec := &EndpointRecord{
+		Name:                 "svc.1",
+		ServiceName:          "svc",
+		ServiceID:            "svcid",
+		VirtualIP:            "10.0.0.2",
+		EndpointIP:           "10.0.0.3",
+		LBScheduler:          "sh",
+		LBPersistenceTimeout: 600,
+		LBWeight:             5,
+	}
+
+	b, err := proto.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dec EndpointRecord
+	if err := proto.Unmarshal(b, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.LBScheduler != "sh" || dec.LBPersistenceTimeout != 600 || dec.LBWeight != 5 || dec.EndpointIP != rec.EndpointIP {
+		t.Fatalf("Unexpected endpoint record: %s", dec.String())
+	}
+
+	if err := (lbConfig{scheduler: "wlc"}).validate(); err == nil {
+		t.Fatalf("Expected failure validating an unsupported scheduler")
+	}
+
+	ep := &endpoint{}
+	CreateOptionService("svc", "svcid", net.ParseIP("10.0.0.2"), nil, nil,
+		ServiceOptionScheduler("sh", 600), ServiceOptionWeight(5))(ep)
+	if ep.svcName != "svc" || ep.lbConfig.scheduler != "sh" || ep.lbConfig.persistence != 600 || ep.lbWeight != 5 {
+		t.Fatalf("Unexpected service options: %v %d", ep.lbConfig, ep.lbWeight)
+	}
+}
+
+func TestLBHealthCheck(t *testing.T) {
+	rec := &EndpointRecord{
+		Name:                  "svc.1",
+		EndpointIP:            "10.0.0.3",
+		LBHealthCheckProtocol: "http",
+		LBHealthCheckPort:     8080,
+		LBHealthCheckPath:     "/health",
+		LBHealthCheckInterval: int64(10 * time.Second),
+		LBHealthCheckTimeout:  int64(time.Second),
+	}
+
+	b, err := proto.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dec EndpointRecord
+	if err := proto.Unmarshal(b, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.LBHealthCheckProtocol != "http" || dec.LBHealthCheckPort != 8080 || dec.LBHealthCheckPath != "/health" ||
+		time.Duration(dec.LBHealthCheckInterval) != 10*time.Second || time.Duration(dec.LBHealthCheckTimeout) != time.Second {
+		t.Fatalf("Unexpected endpoint record: %s", dec.String())
+	}
+
+	for _, hc := range []lbHealthCheck{
+		{},
+		{protocol: "tcp", port: 80},
+		{protocol: "http", port: 80, path: "/", interval: time.Second, timeout: time.Second},
+	} {
+		if err := (lbConfig{healthCheck: hc}).validate(); err != nil {
+			t.Fatalf("Unexpected failure validating health check %#v: %v", hc, err)
+		}
+	}
+	for _, hc := range []lbHealthCheck{
+		{protocol: "udp", port: 53},
+		{protocol: "tcp"},
+		{protocol: "tcp", port: 65536},
+		{protocol: "http", port: 80, path: "health"},
+		{protocol: "tcp", port: 80, interval: -time.Second},
+	} {
+		if err := (lbConfig{healthCheck: hc}).validate(); err == nil {
+			t.Fatalf("Expected failure validating health check %#v", hc)
+		}
+	}
+
+	for _, tc := range []struct {
+		be     lbBackend
+		weight int
+	}{
+		{lbBackend{}, 1},
+		{lbBackend{weight: 5}, 5},
+		{lbBackend{weight: 5, unhealthy: true}, 0},
+	} {
+		if w := tc.be.ipvsWeight(); w != tc.weight {
+			t.Fatalf("Unexpected weight %d of backend %#v, expected %d", w, tc.be, tc.weight)
+		}
+	}
+}
+
+func TestLBHealthCheckDNSRR(t *testing.T) {
+	c := &controller{svcRecords: make(map[string]svcInfo)}
+	n := &network{id: "n1", ctrlr: c}
+	s := &service{
+		name:     "web",
+		aliases:  []string{"www"},
+		lbConfig: lbConfig{healthCheck: lbHealthCheck{protocol: "tcp", port: 80, interval: time.Hour}},
+	}
+	lb := &loadBalancer{backEnds: make(map[string]lbBackend), service: s}
+
+	ip := net.ParseIP("10.0.0.2")
+	for _, name := range []string{"web", "tasks.web", "www", "tasks.www"} {
+		n.addSvcRecords(name, ip, nil, false)
+	}
+
+	s.Lock()
+	lb.startHealthCheck(n)
+	if lb.health == nil {
+		t.Fatal("Expected the backends of a DNS round robin service to be health checked")
+	}
+	be := lbBackend{ip: ip, unhealthy: true}
+	lb.backEnds["ep1"] = be
+	lb.updateBackend(n, be)
+	s.Unlock()
+
+	for _, name := range []string{"web", "tasks.web", "www", "tasks.www"} {
+		if ips := c.svcRecords[n.id].svcMap[name]; len(ips) != 0 {
+			t.Fatalf("Expected the unhealthy backend to be removed from %s, got %v", name, ips)
+		}
+	}
+
+	s.Lock()
+	lb.stopHealthCheck(n)
+	s.Unlock()
+
+	for _, name := range []string{"web", "tasks.web", "www", "tasks.www"} {
+		if ips := c.svcRecords[n.id].svcMap[name]; len(ips) != 1 || !ips[0].Equal(ip) {
+			t.Fatalf("Expected the backend to be restored in %s, got %v", name, ips)
+		}
+	}
+}
+
 func compareEndpointInterface(a, b *endpointInterface) bool {
 	if a == b {
 		return true
@@ -417,6 +565,163 @@ func TestSRVServiceQuery(t *testing.T) {
 	}
 }
 
+func TestDNSCache(t *testing.T) {
+	servers := []string{"10.1.1.1", "10.1.1.2"}
+	c := getDNSCache(servers)
+	defer putDNSCache(c)
+	if other := getDNSCache(servers); other != c {
+		t.Fatal("Expected the resolvers with the same servers to share the cache")
+	} else {
+		putDNSCache(other)
+	}
+
+	query := new(dns.Msg)
+	query.SetQuestion("www.example.com.", dns.TypeA)
+	if c.get(query) != nil {
+		t.Fatal("Unexpected cached answer")
+	}
+
+	resp := new(dns.Msg)
+	resp.SetReply(query)
+	rr, err := dns.NewRR("www.example.com. 300 IN A 10.2.2.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Answer = append(resp.Answer, rr)
+	c.add(query, resp)
+
+	query.Id++
+	query.Question[0].Name = "WWW.example.com."
+	cached := c.get(query)
+	if cached == nil {
+		t.Fatal("Expected a cached answer")
+	}
+	if cached.Id != query.Id || len(cached.Answer) != 1 || cached.Answer[0].Header().Ttl > 300 {
+		t.Fatalf("Unexpected cached answer: %v", cached)
+	}
+
+	// The answers to the DNSSEC queries are cached apart
+	dnssecQuery := query.Copy()
+	dnssecQuery.SetEdns0(4096, true)
+	if c.get(dnssecQuery) != nil {
+		t.Fatal("Unexpected cached answer to a query with the DO bit")
+	}
+	cdQuery := query.Copy()
+	cdQuery.CheckingDisabled = true
+	if c.get(cdQuery) != nil {
+		t.Fatal("Unexpected cached answer to a query with the CD flag")
+	}
+
+	// Negative answer, cached for the minimum of the SOA record
+	nxQuery := new(dns.Msg)
+	nxQuery.SetQuestion("nx.example.com.", dns.TypeA)
+	nxResp := new(dns.Msg)
+	nxResp.SetRcode(nxQuery, dns.RcodeNameError)
+	soa, err := dns.NewRR("example.com. 3600 IN SOA ns.example.com. admin.example.com. 1 7200 3600 1209600 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nxResp.Ns = append(nxResp.Ns, soa)
+	c.add(nxQuery, nxResp)
+	if c.get(nxQuery) != nil {
+		t.Fatal("Expected the negative answer with no minimum TTL not to be cached")
+	}
+	soa.(*dns.SOA).Minttl = 60
+	c.add(nxQuery, nxResp)
+	if cached := c.get(nxQuery); cached == nil || cached.Rcode != dns.RcodeNameError {
+		t.Fatalf("Expected a cached negative answer, got %v", cached)
+	}
+
+	st := c.statistics()
+	if st.Entries != 2 || st.Hits != 2 || st.Misses != 4 {
+		t.Fatalf("Unexpected cache statistics: %v", st)
+	}
+}
+
+func TestExtDNSRules(t *testing.T) {
+	rules, err := parseExtDNSRules("*.corp.example=10.1.1.53,10.1.1.54; lab.corp.example=10.2.2.53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Domain != "*.corp.example" || len(rules[0].Servers) != 2 {
+		t.Fatalf("Unexpected rules: %v", rules)
+	}
+	for _, invalid := range []string{"corp.example", "=10.1.1.53", "corp.example=10.1.1", "corp.example="} {
+		if _, err := parseExtDNSRules(invalid); err == nil {
+			t.Fatalf("Expected failure parsing %q", invalid)
+		}
+	}
+
+	r := NewResolver(resolverIPSandbox, true, "", nil).(*resolver)
+	r.SetExtServers(append(rules, ExtDNSRule{Domain: "Corp.Example", Servers: []string{"10.3.3.53"}}, ExtDNSRule{Servers: []string{"8.8.8.8"}}))
+
+	for name, server := range map[string]string{
+		"www.corp.example.":     "10.1.1.53",
+		"CORP.example.":         "10.1.1.53",
+		"www.lab.corp.example.": "10.2.2.53",
+		"www.example.":          "8.8.8.8",
+		"notcorp.example.":      "8.8.8.8",
+	} {
+		g := r.extDNSGroup(name)
+		if g == nil || g.list[0].ipStr != server {
+			t.Fatalf("Expected %s to be forwarded to %s, got %v", name, server, g)
+		}
+	}
+
+	r.SetExtServers([]ExtDNSRule{{Servers: []string{"8.8.4.4"}}})
+	if g := r.extDNSGroup("www.corp.example."); g == nil || g.list[0].ipStr != "8.8.4.4" {
+		t.Fatalf("Expected the removed rules not to be used, got %v", g)
+	}
+}
+
+type tstDNSWriter struct{}
+
+func (w *tstDNSWriter) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("127.0.0.11"), Port: 53}
+}
+
+func (w *tstDNSWriter) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 33333}
+}
+
+func (w *tstDNSWriter) WriteMsg(*dns.Msg) error   { return nil }
+func (w *tstDNSWriter) Write([]byte) (int, error) { return 0, nil }
+func (w *tstDNSWriter) Close() error              { return nil }
+func (w *tstDNSWriter) TsigStatus() error         { return nil }
+func (w *tstDNSWriter) TsigTimersOnly(bool)       {}
+func (w *tstDNSWriter) Hijack()                   {}
+
+func TestDNSQueryStatistics(t *testing.T) {
+	r := NewResolver(resolverIPSandbox, true, "/var/run/docker/netns/c0ffee", nil).(*resolver)
+	r.SetQueryLog(true)
+
+	query := new(dns.Msg)
+	query.SetQuestion("www.example.com.", dns.TypeA)
+	resp := new(dns.Msg)
+	resp.SetRcode(query, dns.RcodeNameError)
+
+	w := &tstDNSWriter{}
+	r.recordQuery(w, query, createRespMsg(query), queryLocal, "", time.Millisecond)
+	r.recordQuery(w, query, resp, queryCached, "", time.Millisecond)
+	r.recordQuery(w, query, resp, queryForwarded, "10.1.1.1", 3*time.Millisecond)
+	r.recordQuery(w, query, nil, queryFailed, "", time.Millisecond)
+	r.recordQuery(w, query, nil, queryDropped, "", 0)
+
+	expected := types.DNSQueryStatistics{
+		Queries:   5,
+		Local:     1,
+		Cached:    1,
+		Forwarded: 1,
+		Failed:    1,
+		Dropped:   1,
+		NXDomain:  2,
+		Latency:   6 * time.Millisecond,
+	}
+	if st := r.QueryStatistics(); *st != expected {
+		t.Fatalf("Unexpected query statistics.\nExpected: %s\nGot: %s", &expected, st)
+	}
+}
+
 func TestIpamReleaseOnNetDriverFailures(t *testing.T) {
 	if !testutils.IsRunningInContainer() {
 		defer testutils.SetupTestOSContext(t)()
@@ -542,3 +847,37 @@ func (b *badDriver) NetworkFree(id string) error {
 
 func (b *badDriver) EventNotify(etype driverapi.EventType, nid, tableName, key string, value []byte) {
 }
+
+func TestNetworkPolicyUpdates(t *testing.T) {
+	web := types.NetworkPolicy{Name: "web", Selector: map[string]string{"app": "web"}}
+	db := types.NetworkPolicy{Name: "db", Selector: map[string]string{"app": "db"}}
+
+	policies, err := setNetworkPolicy(web)(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policies, err = setNetworkPolicy(db)(policies); err != nil {
+		t.Fatal(err)
+	}
+	db.Isolation = []string{types.PolicyIngress}
+	if policies, err = setNetworkPolicy(db)(policies); err != nil {
+		t.Fatal(err)
+	}
+	if len(policies) != 2 || len(policies[1].Isolation) != 1 {
+		t.Fatalf("Expected the db policy to be replaced: %v", policies)
+	}
+
+	if policies, err = deleteNetworkPolicy("none")(policies); err != nil || len(policies) != 2 {
+		t.Fatalf("Expected no policy to be removed: %v, %v", policies, err)
+	}
+	if policies, err = deleteNetworkPolicy("web")(policies); err != nil || len(policies) != 1 || policies[0].Name != "db" {
+		t.Fatalf("Expected the web policy to be removed: %v, %v", policies, err)
+	}
+
+	n := &network{name: "n1", id: "n1", enableIPv6: true}
+	if err := n.AddNetworkPolicy(web); err == nil {
+		t.Fatal("Expected the network policy to be rejected on an IPv6 network")
+	} else if _, ok := err.(types.ForbiddenError); !ok {
+		t.Fatalf("Unexpected error type %T: %v", err, err)
+	}
+}
@@ -823,6 +823,18 @@ func (f *fakeSandbox) Statistics() (map[string]*types.InterfaceStatistics, error
 	return nil, nil
 }
 
+func (f *fakeSandbox) ResolverStatistics() []*types.ExtDNSStatistics {
+	return nil
+}
+
+func (f *fakeSandbox) ResolverCacheStatistics() []*types.DNSCacheStatistics {
+	return nil
+}
+
+func (f *fakeSandbox) ResolverQueryStatistics() *types.DNSQueryStatistics {
+	return nil
+}
+
 func (f *fakeSandbox) Refresh(opts ...libnetwork.SandboxOption) error {
 	return nil
 }
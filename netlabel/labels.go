@@ -1,6 +1,7 @@
 package netlabel
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -30,6 +31,14 @@ const (
 	// DNSServers A list of DNS servers associated with the endpoint
 	DNSServers = Prefix + ".endpoint.dnsservers"
 
+	// QosPolicy constant represents the bandwidth limits of the endpoint
+	QosPolicy = Prefix + ".endpoint.qospolicy"
+
+	// EndpointLabels constant represents the labels of the endpoint, as a
+	// map[string]string, matched by the connectivity rules and by the
+	// network policies of the networks
+	EndpointLabels = Prefix + ".endpoint.labels"
+
 	//EnableIPv6 constant represents enabling IPV6 at network level
 	EnableIPv6 = Prefix + ".enable_ipv6"
 
@@ -45,11 +54,31 @@ const (
 	// OverlayVxlanIDList constant represents a list of VXLAN Ids as csv
 	OverlayVxlanIDList = DriverPrefix + ".overlay.vxlanid_list"
 
+	// OverlayEncap constant represents the encapsulation of the overlay network, vxlan or geneve
+	OverlayEncap = DriverPrefix + ".overlay.encap"
+
+	// OverlayEncapPort constant represents the UDP port of the geneve encapsulation
+	OverlayEncapPort = DriverPrefix + ".overlay.encap_port"
+
+	// OverlayGeneveOptions constant represents the comma separated class:type:data options added to the geneve header
+	OverlayGeneveOptions = DriverPrefix + ".overlay.geneve_options"
+
+	// OverlayMulticast constant represents the forwarding of the broadcast and multicast frames of the overlay network, replication or underlay
+	OverlayMulticast = DriverPrefix + ".overlay.multicast"
+
+	// OverlayMulticastGroup constant represents the underlay multicast group of the overlay network
+	OverlayMulticastGroup = DriverPrefix + ".overlay.multicast_group"
+
 	// Gateway represents the gateway for the network
 	Gateway = Prefix + ".gateway"
 
 	// Internal constant represents that the network is internal which disables default gateway service
 	Internal = Prefix + ".internal"
+
+	// DNSForward constant represents the conditional forwarding rules of the
+	// embedded DNS server for the containers connected to the network, as
+	// domain=server[,server] separated by semicolons
+	DNSForward = Prefix + ".dns.forward"
 )
 
 var (
@@ -124,3 +153,29 @@ func KeyValue(label string) (key string, value string) {
 	}
 	return
 }
+
+// GetEndpointLabels returns the endpoint labels carried by the generic
+// options, if any
+func GetEndpointLabels(options map[string]interface{}) (map[string]string, error) {
+	opt, ok := options[EndpointLabels]
+	if !ok {
+		return nil, nil
+	}
+
+	switch labels := opt.(type) {
+	case map[string]string:
+		return labels, nil
+	case map[string]interface{}:
+		// The labels went through a json round trip
+		l := make(map[string]string, len(labels))
+		for k, v := range labels {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid value %v of endpoint label %s", v, k)
+			}
+			l[k] = s
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("invalid endpoint labels %v", opt)
+}
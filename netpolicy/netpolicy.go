@@ -0,0 +1,155 @@
+// Package netpolicy translates the network policies of a network into the
+// iptables rules enforcing them on the endpoints of the network.
+package netpolicy
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/docker/libnetwork/types"
+)
+
+// Endpoint is an endpoint of the network the policies are enforced on
+type Endpoint struct {
+	IP     net.IP
+	Labels map[string]string
+	// Remote is set for the endpoints on other hosts, which are only
+	// selected as peers, the policies are enforced on them by their host
+	Remote bool
+}
+
+// Rules returns the iptables rule specifications enforcing the policies on
+// the endpoints, for the chain filtering the traffic to the endpoints and for
+// the chain filtering the traffic from the endpoints. The rules of an
+// isolated endpoint return from the chain the traffic the policies allow and
+// drop the rest, the rules are meant to restrict the traffic only.
+// When the interface name is set the rules only match the traffic forwarded
+// through the interface.
+func Rules(policies []types.NetworkPolicy, endpoints []Endpoint, ifName string) (ingress, egress [][]string) {
+	eps := make([]Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if ep.IP.To4() != nil {
+			eps = append(eps, ep)
+		}
+	}
+	// Keep the rules in a stable order
+	sort.Sort(byIP(eps))
+
+	for _, ep := range eps {
+		if ep.Remote {
+			continue
+		}
+		var in, out []string
+		if ifName != "" {
+			in = []string{"-o", ifName}
+			out = []string{"-i", ifName}
+		}
+		addr := ep.IP.String() + "/32"
+		ingress = append(ingress, endpointRules(policies, eps, ep, types.PolicyIngress, append(in, "-d", addr), "-s")...)
+		egress = append(egress, endpointRules(policies, eps, ep, types.PolicyEgress, append(out, "-s", addr), "-d")...)
+	}
+
+	return ingress, egress
+}
+
+// endpointRules returns the rules enforcing the policies on the endpoint in
+// the direction, matching the endpoint with base and the peers with peerOpt
+func endpointRules(policies []types.NetworkPolicy, eps []Endpoint, ep Endpoint, direction string, base []string, peerOpt string) [][]string {
+	var (
+		rules [][]string
+		seen  = make(map[string]bool)
+	)
+	add := func(args ...string) {
+		rule := append(append([]string(nil), base...), args...)
+		k := fmt.Sprint(rule)
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		rules = append(rules, rule)
+	}
+
+	isolated := false
+	for _, p := range policies {
+		if !p.Isolates(direction) || !MatchLabels(ep.Labels, p.Selector) {
+			continue
+		}
+		if !isolated {
+			add("-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN")
+			isolated = true
+		}
+
+		prs := p.Ingress
+		if direction == types.PolicyEgress {
+			prs = p.Egress
+		}
+		for _, r := range prs {
+			ports := r.Ports
+			if len(ports) == 0 {
+				ports = []types.TransportPort{{}}
+			}
+			for _, peer := range selectPeers(r.Peers, eps) {
+				for _, port := range ports {
+					var args []string
+					if peer != "" {
+						args = append(args, peerOpt, peer)
+					}
+					if port.Proto != 0 {
+						args = append(args, "-p", port.Proto.String())
+						if port.Port != 0 {
+							args = append(args, "--dport", strconv.Itoa(int(port.Port)))
+						}
+					}
+					add(append(args, "-j", "RETURN")...)
+				}
+			}
+		}
+	}
+	if isolated {
+		add("-j", "DROP")
+	}
+
+	return rules
+}
+
+// selectPeers returns the address ranges of the peers. An empty peer list
+// matches all, reported as a single empty range.
+func selectPeers(peers []types.PolicyPeer, eps []Endpoint) []string {
+	if len(peers) == 0 {
+		return []string{""}
+	}
+
+	var addrs []string
+	for _, peer := range peers {
+		if peer.CIDR != "" {
+			addrs = append(addrs, peer.CIDR)
+			continue
+		}
+		for _, ep := range eps {
+			if MatchLabels(ep.Labels, peer.Labels) {
+				addrs = append(addrs, ep.IP.String()+"/32")
+			}
+		}
+	}
+	return addrs
+}
+
+// MatchLabels returns whether the labels carry all the labels of the
+// selector. An empty selector matches all.
+func MatchLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+type byIP []Endpoint
+
+func (b byIP) Len() int           { return len(b) }
+func (b byIP) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byIP) Less(i, j int) bool { return bytes.Compare(b[i].IP.To4(), b[j].IP.To4()) < 0 }
@@ -0,0 +1,134 @@
+package netpolicy
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docker/libnetwork/types"
+)
+
+func testEndpoints() []Endpoint {
+	return []Endpoint{
+		{IP: net.ParseIP("10.0.0.3"), Labels: map[string]string{"app": "db"}},
+		{IP: net.ParseIP("10.0.0.2"), Labels: map[string]string{"app": "web"}},
+		{IP: net.ParseIP("10.0.0.4")},
+		{IP: net.ParseIP("fe80::4"), Labels: map[string]string{"app": "db"}},
+	}
+}
+
+func joinRules(rules [][]string) []string {
+	var l []string
+	for _, r := range rules {
+		l = append(l, strings.Join(r, " "))
+	}
+	return l
+}
+
+func TestRulesIngress(t *testing.T) {
+	policies := []types.NetworkPolicy{{
+		Name:     "db",
+		Selector: map[string]string{"app": "db"},
+		Ingress: []types.PolicyRule{{
+			Peers: []types.PolicyPeer{{Labels: map[string]string{"app": "web"}}, {CIDR: "192.168.0.0/16"}},
+			Ports: []types.TransportPort{{Proto: types.TCP, Port: 5432}},
+		}},
+	}}
+
+	ingress, egress := Rules(policies, testEndpoints(), "br0")
+	expected := []string{
+		"-o br0 -d 10.0.0.3/32 -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-o br0 -d 10.0.0.3/32 -s 10.0.0.2/32 -p tcp --dport 5432 -j RETURN",
+		"-o br0 -d 10.0.0.3/32 -s 192.168.0.0/16 -p tcp --dport 5432 -j RETURN",
+		"-o br0 -d 10.0.0.3/32 -j DROP",
+	}
+	if got := joinRules(ingress); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Unexpected ingress rules.\nExpected: %v\nGot: %v", expected, got)
+	}
+	if len(egress) != 0 {
+		t.Fatalf("Unexpected egress rules: %v", egress)
+	}
+}
+
+func TestRulesEgress(t *testing.T) {
+	policies := []types.NetworkPolicy{
+		{
+			Name:   "deny-all",
+			Egress: []types.PolicyRule{{Ports: []types.TransportPort{{Proto: types.UDP, Port: 53}}}},
+		},
+		{
+			Name:      "web",
+			Selector:  map[string]string{"app": "web"},
+			Egress:    []types.PolicyRule{{Peers: []types.PolicyPeer{{Labels: map[string]string{"app": "db"}}}}},
+			Isolation: []string{types.PolicyEgress},
+		},
+	}
+
+	_, egress := Rules(policies, testEndpoints(), "")
+	expected := []string{
+		"-s 10.0.0.2/32 -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-s 10.0.0.2/32 -p udp --dport 53 -j RETURN",
+		"-s 10.0.0.2/32 -d 10.0.0.3/32 -j RETURN",
+		"-s 10.0.0.2/32 -j DROP",
+		"-s 10.0.0.3/32 -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-s 10.0.0.3/32 -p udp --dport 53 -j RETURN",
+		"-s 10.0.0.3/32 -j DROP",
+		"-s 10.0.0.4/32 -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-s 10.0.0.4/32 -p udp --dport 53 -j RETURN",
+		"-s 10.0.0.4/32 -j DROP",
+	}
+	if got := joinRules(egress); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Unexpected egress rules.\nExpected: %v\nGot: %v", expected, got)
+	}
+}
+
+func TestRulesNoMatchingPeer(t *testing.T) {
+	policies := []types.NetworkPolicy{{
+		Name:    "isolated",
+		Ingress: []types.PolicyRule{{Peers: []types.PolicyPeer{{Labels: map[string]string{"app": "none"}}}}},
+	}}
+
+	ingress, _ := Rules(policies, testEndpoints()[:1], "")
+	expected := []string{
+		"-d 10.0.0.3/32 -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-d 10.0.0.3/32 -j DROP",
+	}
+	if got := joinRules(ingress); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Unexpected ingress rules.\nExpected: %v\nGot: %v", expected, got)
+	}
+}
+
+func TestRulesRemotePeer(t *testing.T) {
+	policies := []types.NetworkPolicy{{
+		Name:    "web",
+		Ingress: []types.PolicyRule{{Peers: []types.PolicyPeer{{Labels: map[string]string{"app": "web"}}}}},
+	}}
+
+	eps := append(testEndpoints()[:1], Endpoint{IP: net.ParseIP("10.0.1.2"), Labels: map[string]string{"app": "web"}, Remote: true})
+	ingress, _ := Rules(policies, eps, "")
+	expected := []string{
+		"-d 10.0.0.3/32 -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-d 10.0.0.3/32 -s 10.0.1.2/32 -j RETURN",
+		"-d 10.0.0.3/32 -j DROP",
+	}
+	if got := joinRules(ingress); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Unexpected ingress rules.\nExpected: %v\nGot: %v", expected, got)
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"app": "web", "tier": "front"}
+	if !MatchLabels(labels, nil) {
+		t.Fatal("Expected the empty selector to match")
+	}
+	if !MatchLabels(labels, map[string]string{"app": "web"}) {
+		t.Fatal("Expected the selector to match")
+	}
+	if MatchLabels(labels, map[string]string{"app": "web", "tier": "back"}) {
+		t.Fatal("Expected the selector not to match")
+	}
+	if MatchLabels(nil, map[string]string{"app": "web"}) {
+		t.Fatal("Expected the selector not to match the endpoint without labels")
+	}
+}
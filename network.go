@@ -55,6 +55,38 @@ type Network interface {
 
 	// Return certain operational data belonging to this network
 	Info() NetworkInfo
+
+	// AddDNSRecord adds a user-defined static record to the names the
+	// embedded DNS server answers to the containers of the network.
+	AddDNSRecord(rec DNSRecord) error
+
+	// UpdateDNSRecord replaces the values of the user-defined static DNS
+	// record with the same name and type.
+	UpdateDNSRecord(rec DNSRecord) error
+
+	// DeleteDNSRecord removes the user-defined static DNS record.
+	DeleteDNSRecord(name, recType string) error
+
+	// DNSRecords returns the user-defined static DNS records of the network.
+	DNSRecords() []DNSRecord
+
+	// SetConnectivityRules replaces the rules allowing the traffic from
+	// other networks, or between the endpoints of the network, through
+	// the isolation enforced by the driver.
+	SetConnectivityRules(rules []types.ConnectivityRule) error
+
+	// ConnectivityRules returns the connectivity rules of the network.
+	ConnectivityRules() []types.ConnectivityRule
+
+	// AddNetworkPolicy adds a policy restricting the traffic of the
+	// endpoints of the network it selects.
+	AddNetworkPolicy(policy types.NetworkPolicy) error
+
+	// RemoveNetworkPolicy removes the network policy with the name.
+	RemoveNetworkPolicy(name string) error
+
+	// NetworkPolicies returns the network policies of the network.
+	NetworkPolicies() []types.NetworkPolicy
 }
 
 // NetworkInfo returns some configuration and operational information about the network
@@ -198,6 +230,9 @@ type network struct {
 	internal     bool
 	inDelete     bool
 	ingress      bool
+	dnsRecords   map[string]*DNSRecord
+	connRules    []types.ConnectivityRule
+	policies     []types.NetworkPolicy
 	driverTables []string
 	dynamic      bool
 	sync.Mutex
@@ -390,6 +425,23 @@ func (n *network) CopyTo(o datastore.KVObject) error {
 		dstN.ipamV6Info = append(dstN.ipamV6Info, dstV6Info)
 	}
 
+	if n.dnsRecords != nil {
+		dstN.dnsRecords = make(map[string]*DNSRecord, len(n.dnsRecords))
+		for k, v := range n.dnsRecords {
+			dstN.dnsRecords[k] = v
+		}
+	}
+
+	dstN.connRules = nil
+	for _, r := range n.connRules {
+		dstN.connRules = append(dstN.connRules, r.GetCopy())
+	}
+
+	dstN.policies = nil
+	for _, p := range n.policies {
+		dstN.policies = append(dstN.policies, p.GetCopy())
+	}
+
 	dstN.generic = options.Generic{}
 	for k, v := range n.generic {
 		dstN.generic[k] = v
@@ -455,6 +507,27 @@ func (n *network) MarshalJSON() ([]byte, error) {
 		}
 		netMap["ipamV6Info"] = string(iis)
 	}
+	if len(n.dnsRecords) > 0 {
+		recs, err := json.Marshal(n.dnsRecords)
+		if err != nil {
+			return nil, err
+		}
+		netMap["dnsRecords"] = string(recs)
+	}
+	if len(n.connRules) > 0 {
+		rules, err := json.Marshal(n.connRules)
+		if err != nil {
+			return nil, err
+		}
+		netMap["connectivityRules"] = string(rules)
+	}
+	if len(n.policies) > 0 {
+		policies, err := json.Marshal(n.policies)
+		if err != nil {
+			return nil, err
+		}
+		netMap["policies"] = string(policies)
+	}
 	netMap["internal"] = n.internal
 	netMap["inDelete"] = n.inDelete
 	netMap["ingress"] = n.ingress
@@ -547,6 +620,21 @@ func (n *network) UnmarshalJSON(b []byte) (err error) {
 			return err
 		}
 	}
+	if v, ok := netMap["dnsRecords"]; ok {
+		if err := json.Unmarshal([]byte(v.(string)), &n.dnsRecords); err != nil {
+			return err
+		}
+	}
+	if v, ok := netMap["connectivityRules"]; ok {
+		if err := json.Unmarshal([]byte(v.(string)), &n.connRules); err != nil {
+			return err
+		}
+	}
+	if v, ok := netMap["policies"]; ok {
+		if err := json.Unmarshal([]byte(v.(string)), &n.policies); err != nil {
+			return err
+		}
+	}
 	if v, ok := netMap["internal"]; ok {
 		n.internal = v.(bool)
 	}
@@ -665,6 +753,17 @@ func NetworkOptionLabels(labels map[string]string) NetworkOption {
 	}
 }
 
+// NetworkOptionDNSRecords function returns an option setter for the user-defined static DNS records of a network
+func NetworkOptionDNSRecords(recs []DNSRecord) NetworkOption {
+	return func(n *network) {
+		n.dnsRecords = make(map[string]*DNSRecord, len(recs))
+		for i := range recs {
+			rec := recs[i]
+			n.dnsRecords[rec.key()] = &rec
+		}
+	}
+}
+
 // NetworkOptionDynamic function returns an option setter for dynamic option for a network
 func NetworkOptionDynamic() NetworkOption {
 	return func(n *network) {
@@ -726,6 +825,15 @@ func (n *network) driverScope() string {
 	return cap.DataScope
 }
 
+func (n *network) driverAllocatesIPv4() bool {
+	_, cap, err := n.resolveDriver(n.networkType, true)
+	if err != nil {
+		return false
+	}
+
+	return cap.AllocatesIPv4
+}
+
 func (n *network) driver(load bool) (driverapi.Driver, error) {
 	d, cap, err := n.resolveDriver(n.networkType, load)
 	if err != nil {
@@ -800,6 +908,10 @@ func (n *network) delete(force bool) error {
 
 	c.cleanupServiceBindings(n.ID())
 
+	c.Lock()
+	delete(c.dnsRecords, n.ID())
+	c.Unlock()
+
 	// deleteFromStore performs an atomic delete operation and the
 	// network.epCnt will help prevent any possible
 	// race between endpoint join and network delete
@@ -814,6 +926,8 @@ func (n *network) delete(force bool) error {
 		return fmt.Errorf("error deleting network from store: %v", err)
 	}
 
+	c.publishNetworkEvent(EventNetworkDelete, n)
+
 	return nil
 }
 
@@ -887,6 +1001,10 @@ func (n *network) CreateEndpoint(name string, options ...EndpointOption) (Endpoi
 		}
 	}
 
+	if err = ep.lbConfig.validate(); err != nil {
+		return nil, err
+	}
+
 	if opt, ok := ep.generic[netlabel.MacAddress]; ok {
 		if mac, ok := opt.(net.HardwareAddr); ok {
 			ep.iface.mac = mac
@@ -908,7 +1026,11 @@ func (n *network) CreateEndpoint(name string, options ...EndpointOption) (Endpoi
 		ep.ipamOptions[netlabel.MacAddress] = ep.iface.mac.String()
 	}
 
-	if err = ep.assignAddress(ipam, true, n.enableIPv6 && !n.postIPv6); err != nil {
+	// The drivers allocating the IPv4 addresses set the address of the
+	// endpoint on creation, it is reserved in the IPAM driver afterwards
+	postIPv4 := ep.prefAddress == nil && n.driverAllocatesIPv4()
+
+	if err = ep.assignAddress(ipam, !postIPv4, n.enableIPv6 && !n.postIPv6); err != nil {
 		return nil, err
 	}
 	defer func() {
@@ -928,7 +1050,7 @@ func (n *network) CreateEndpoint(name string, options ...EndpointOption) (Endpoi
 		}
 	}()
 
-	if err = ep.assignAddress(ipam, false, n.enableIPv6 && n.postIPv6); err != nil {
+	if err = ep.assignAddress(ipam, postIPv4, n.enableIPv6 && n.postIPv6); err != nil {
 		return nil, err
 	}
 
@@ -956,6 +1078,8 @@ func (n *network) CreateEndpoint(name string, options ...EndpointOption) (Endpoi
 		return nil, err
 	}
 
+	n.getController().publishEndpointEvent(EventEndpointCreate, ep, nil)
+
 	return ep, nil
 }
 
@@ -1589,6 +1713,45 @@ func (n *network) TableEventRegister(tableName string) error {
 	return nil
 }
 
+func (n *network) UpdateTableEntry(tableName, key string, value []byte) error {
+	c := n.getController()
+	c.Lock()
+	sbs := make([]*sandbox, 0, len(c.sandboxes))
+	for _, sb := range c.sandboxes {
+		sbs = append(sbs, sb)
+	}
+	c.Unlock()
+
+	// The entries are kept with the endpoints to be gossiped again when
+	// the endpoints are added back to the cluster
+	found := false
+	for _, sb := range sbs {
+		for _, ep := range sb.getConnectedEndpoints() {
+			if ep.getNetwork().ID() != n.ID() {
+				continue
+			}
+			ep.Lock()
+			if ep.joinInfo != nil {
+				for _, te := range ep.joinInfo.driverTableEntries {
+					if te.tableName == tableName && te.key == key {
+						te.value = value
+						found = true
+					}
+				}
+			}
+			ep.Unlock()
+		}
+	}
+	if !found {
+		return types.NotFoundErrorf("no entry %s in table %s of network %s", key, tableName, n.Name())
+	}
+
+	if !n.isClusterEligible() {
+		return nil
+	}
+	return c.agent.networkDB.UpdateEntry(tableName, n.ID(), key, value)
+}
+
 // Special drivers are ones which do not need to perform any network plumbing
 func (n *network) hasSpecialDriver() bool {
 	return n.Type() == "host" || n.Type() == "null"
@@ -1603,7 +1766,7 @@ func (n *network) ResolveName(req string, ipType int) ([]net.IP, bool) {
 	c.Unlock()
 
 	if !ok {
-		return nil, false
+		return n.resolveStaticName(req, ipType)
 	}
 
 	req = strings.TrimSuffix(req, ".")
@@ -1627,6 +1790,12 @@ func (n *network) ResolveName(req string, ipType int) ([]net.IP, bool) {
 		return ip, false
 	}
 
+	// The names of the containers take precedence over the user-defined
+	// records
+	if !ipv6Miss {
+		return n.resolveStaticName(req, ipType)
+	}
+
 	return nil, ipv6Miss
 }
 
@@ -1666,6 +1835,7 @@ func (n *network) ResolveService(name string) ([]*net.SRV, []net.IP) {
 	// There are DNS implementaions that allow SRV queries for names not in
 	// the format defined by RFC 2782. Hence specific validations checks are
 	// not done
+	name = strings.TrimSuffix(name, ".")
 	parts := strings.Split(name, ".")
 	if len(parts) < 3 {
 		return nil, nil
@@ -1680,12 +1850,12 @@ func (n *network) ResolveService(name string) ([]*net.SRV, []net.IP) {
 	c.Unlock()
 
 	if !ok {
-		return nil, nil
+		return n.resolveStaticService(name)
 	}
 
 	svcs, ok := sr.service[svcName]
 	if !ok {
-		return nil, nil
+		return n.resolveStaticService(name)
 	}
 
 	for _, svc := range svcs {
@@ -1706,6 +1876,12 @@ func (n *network) ResolveService(name string) ([]*net.SRV, []net.IP) {
 		}
 	}
 
+	// As for the names, the services of the containers take precedence
+	// over the user-defined records
+	if len(srv) == 0 {
+		return n.resolveStaticService(name)
+	}
+
 	return srv, ip
 }
 
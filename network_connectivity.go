@@ -0,0 +1,134 @@
+package libnetwork
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/types"
+)
+
+func (n *network) SetConnectivityRules(rules []types.ConnectivityRule) error {
+	c := n.getController()
+
+	c.networkLocker.Lock(n.ID())
+	defer c.networkLocker.Unlock(n.ID())
+
+	d, cap, err := n.resolveDriver(n.networkType, true)
+	if err != nil {
+		return err
+	}
+	if !cap.ConnectivityRules {
+		return types.NotImplementedErrorf("%s driver does not support connectivity rules", n.Type())
+	}
+
+	cr := make([]types.ConnectivityRule, 0, len(rules))
+	for _, r := range rules {
+		r = r.GetCopy()
+		if err := n.validateConnectivityRule(&r); err != nil {
+			return err
+		}
+		cr = append(cr, r)
+	}
+
+	if err := pushConnectivityRules(d, n.ID(), cr); err != nil {
+		return err
+	}
+
+	n.Lock()
+	old := n.connRules
+	n.connRules = cr
+	n.Unlock()
+
+	if err := c.updateToStore(n); err != nil {
+		n.Lock()
+		n.connRules = old
+		n.Unlock()
+		if e := pushConnectivityRules(d, n.ID(), old); e != nil {
+			log.Warnf("Failed to restore the connectivity rules of network %s: %v", n.Name(), e)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (n *network) ConnectivityRules() []types.ConnectivityRule {
+	n.Lock()
+	defer n.Unlock()
+
+	rules := make([]types.ConnectivityRule, 0, len(n.connRules))
+	for _, r := range n.connRules {
+		rules = append(rules, r.GetCopy())
+	}
+	return rules
+}
+
+// validateConnectivityRule checks the rule and resolves the name of its
+// peer network to the network ID
+func (n *network) validateConnectivityRule(r *types.ConnectivityRule) error {
+	if r.Network != "" {
+		c := n.getController()
+		peer, err := c.NetworkByName(r.Network)
+		if err != nil {
+			if peer, err = c.NetworkByID(r.Network); err != nil {
+				return types.NotFoundErrorf("peer network %s of the connectivity rule not found", r.Network)
+			}
+		}
+		if peer.Type() != n.Type() {
+			return types.BadRequestErrorf("peer network %s is not a %s network", peer.Name(), n.Type())
+		}
+		r.Network = peer.ID()
+		if r.Network == n.ID() {
+			r.Network = ""
+		}
+	}
+
+	for _, labels := range []map[string]string{r.From, r.To} {
+		for k := range labels {
+			if k == "" {
+				return types.BadRequestErrorf("empty label in connectivity rule selector")
+			}
+		}
+	}
+
+	for _, p := range r.Ports {
+		switch p.Proto {
+		case types.TCP, types.UDP:
+		case types.ICMP:
+			if p.Port != 0 {
+				return types.BadRequestErrorf("invalid port %d for protocol icmp in connectivity rule", p.Port)
+			}
+		default:
+			return types.BadRequestErrorf("invalid protocol %d in connectivity rule", p.Proto)
+		}
+	}
+
+	return nil
+}
+
+func pushConnectivityRules(d driverapi.Driver, nid string, rules []types.ConnectivityRule) error {
+	return d.DiscoverNew(discoverapi.ConnectivityConfig, discoverapi.ConnectivityConfigData{
+		NetworkID: nid,
+		Rules:     rules,
+	})
+}
+
+// restoreConnectivityRules programs again in the driver the connectivity
+// rules of the networks restored from the store
+var restoreConnectivityRules NetworkWalker = func(nw Network) bool {
+	n := nw.(*network)
+	rules := n.ConnectivityRules()
+	if len(rules) == 0 {
+		return false
+	}
+
+	d, _, err := n.resolveDriver(n.networkType, true)
+	if err != nil {
+		log.Warnf("Failed to restore the connectivity rules of network %s: %v", n.Name(), err)
+		return false
+	}
+	if err := pushConnectivityRules(d, n.ID(), rules); err != nil {
+		log.Warnf("Failed to restore the connectivity rules of network %s: %v", n.Name(), err)
+	}
+	return false
+}
@@ -0,0 +1,499 @@
+package libnetwork
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-events"
+	"github.com/docker/libnetwork/networkdb"
+	"github.com/docker/libnetwork/types"
+	"github.com/miekg/dns"
+)
+
+// Types of the user-defined static DNS records
+const (
+	DNSRecordA     = "A"
+	DNSRecordAAAA  = "AAAA"
+	DNSRecordCNAME = "CNAME"
+	DNSRecordTXT   = "TXT"
+	DNSRecordSRV   = "SRV"
+)
+
+// networkdb table gossiping the DNS records added to the swarm networks
+const dnsRecordTable = "dns_record_table"
+
+// DNSRecord is a user-defined static DNS record, answered by the embedded
+// DNS server to the containers connected to the network.
+type DNSRecord struct {
+	// Name is the name the record is answered for, such as db.internal
+	Name string `json:"name"`
+	// Type is one of A, AAAA, CNAME, TXT and SRV
+	Type string `json:"type"`
+	// Values are the addresses of A and AAAA records, the canonical name
+	// of a CNAME record, the texts of TXT records and the
+	// "priority weight port target" of SRV records
+	Values []string `json:"values"`
+}
+
+func (rec *DNSRecord) key() string {
+	return dnsRecordKey(rec.Name, rec.Type)
+}
+
+func dnsRecordKey(name, recType string) string {
+	return strings.ToUpper(recType) + "/" + strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// normalize validates the record and returns it in its canonical form
+func (rec DNSRecord) normalize() (DNSRecord, error) {
+	rec.Name = strings.ToLower(strings.TrimSuffix(rec.Name, "."))
+	rec.Type = strings.ToUpper(rec.Type)
+	if _, ok := dns.IsDomainName(rec.Name); !ok || rec.Name == "" {
+		return rec, types.BadRequestErrorf("invalid DNS record name %q", rec.Name)
+	}
+	if len(rec.Values) == 0 {
+		return rec, types.BadRequestErrorf("no value for DNS record %s %s", rec.Type, rec.Name)
+	}
+	rec.Values = append([]string(nil), rec.Values...)
+
+	for i, v := range rec.Values {
+		switch rec.Type {
+		case DNSRecordA, DNSRecordAAAA:
+			ip := net.ParseIP(v)
+			if ip == nil || (ip.To4() != nil) != (rec.Type == DNSRecordA) {
+				return rec, types.BadRequestErrorf("invalid address %q for DNS record %s %s", v, rec.Type, rec.Name)
+			}
+			rec.Values[i] = ip.String()
+		case DNSRecordCNAME:
+			if len(rec.Values) != 1 {
+				return rec, types.BadRequestErrorf("DNS record CNAME %s must have a single value", rec.Name)
+			}
+			if _, ok := dns.IsDomainName(v); !ok {
+				return rec, types.BadRequestErrorf("invalid canonical name %q for DNS record CNAME %s", v, rec.Name)
+			}
+			rec.Values[i] = strings.ToLower(strings.TrimSuffix(v, "."))
+		case DNSRecordTXT:
+			if len(v) > 255 {
+				return rec, types.BadRequestErrorf("text longer than 255 characters for DNS record TXT %s", rec.Name)
+			}
+		case DNSRecordSRV:
+			// The names of the services are _service._proto.name
+			if len(strings.Split(rec.Name, ".")) < 3 {
+				return rec, types.BadRequestErrorf("DNS record SRV %s is not a _service._proto.name name", rec.Name)
+			}
+			if _, err := parseSRVValue(v); err != nil {
+				return rec, types.BadRequestErrorf("invalid value %q for DNS record SRV %s: %v", v, rec.Name, err)
+			}
+		default:
+			return rec, types.BadRequestErrorf("unsupported DNS record type %q", rec.Type)
+		}
+	}
+
+	return rec, nil
+}
+
+// parseSRVValue parses the "priority weight port target" value of a SRV record
+func parseSRVValue(v string) (*net.SRV, error) {
+	f := strings.Fields(v)
+	if len(f) != 4 {
+		return nil, types.BadRequestErrorf("expected priority weight port target")
+	}
+
+	var n [3]uint16
+	for i := range n {
+		u, err := strconv.ParseUint(f[i], 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = uint16(u)
+	}
+	if _, ok := dns.IsDomainName(f[3]); !ok {
+		return nil, types.BadRequestErrorf("invalid target %q", f[3])
+	}
+
+	return &net.SRV{
+		Priority: n[0],
+		Weight:   n[1],
+		Port:     n[2],
+		Target:   dns.Fqdn(strings.ToLower(f[3])),
+	}, nil
+}
+
+func (n *network) AddDNSRecord(rec DNSRecord) error {
+	return n.setDNSRecord(rec, true)
+}
+
+func (n *network) UpdateDNSRecord(rec DNSRecord) error {
+	return n.setDNSRecord(rec, false)
+}
+
+func (n *network) setDNSRecord(rec DNSRecord, create bool) error {
+	rec, err := rec.normalize()
+	if err != nil {
+		return err
+	}
+	k := rec.key()
+
+	c := n.getController()
+	c.networkLocker.Lock(n.ID())
+	defer c.networkLocker.Unlock(n.ID())
+
+	if n.isClusterEligible() {
+		return n.gossipDNSRecord(k, &rec, create)
+	}
+
+	n.Lock()
+	prev, exists := n.dnsRecords[k]
+	if create && exists {
+		n.Unlock()
+		return types.ForbiddenErrorf("DNS record %s %s already exists in network %s", rec.Type, rec.Name, n.Name())
+	}
+	if !create && !exists {
+		n.Unlock()
+		return types.NotFoundErrorf("DNS record %s %s not found in network %s", rec.Type, rec.Name, n.Name())
+	}
+	if n.dnsRecords == nil {
+		n.dnsRecords = make(map[string]*DNSRecord)
+	}
+	n.dnsRecords[k] = &rec
+	n.Unlock()
+
+	// The update fails if another host changed the network in the meantime
+	if err := c.updateToStore(n); err != nil {
+		n.Lock()
+		if prev != nil {
+			n.dnsRecords[k] = prev
+		} else {
+			delete(n.dnsRecords, k)
+		}
+		n.Unlock()
+		return err
+	}
+
+	n.loadStaticRecords()
+	c.setDNSRecord(n.ID(), &rec)
+	return nil
+}
+
+func (n *network) DeleteDNSRecord(name, recType string) error {
+	k := dnsRecordKey(name, recType)
+
+	c := n.getController()
+	c.networkLocker.Lock(n.ID())
+	defer c.networkLocker.Unlock(n.ID())
+
+	if n.isClusterEligible() {
+		return n.gossipDNSRecord(k, nil, false)
+	}
+
+	n.Lock()
+	rec, ok := n.dnsRecords[k]
+	if !ok {
+		n.Unlock()
+		return types.NotFoundErrorf("DNS record %s %s not found in network %s", recType, name, n.Name())
+	}
+	delete(n.dnsRecords, k)
+	n.Unlock()
+
+	if err := c.updateToStore(n); err != nil {
+		n.Lock()
+		n.dnsRecords[k] = rec
+		n.Unlock()
+		return err
+	}
+
+	n.loadStaticRecords()
+	c.delDNSRecord(n.ID(), k)
+	return nil
+}
+
+// gossipDNSRecord adds, updates or deletes, when rec is nil, the record of
+// the swarm network in the DNS record table. The records defined with the
+// swarm network cannot be changed.
+func (n *network) gossipDNSRecord(k string, rec *DNSRecord, create bool) error {
+	recType, name := splitDNSRecordKey(k)
+
+	n.Lock()
+	_, defined := n.dnsRecords[k]
+	n.Unlock()
+	if defined {
+		if create {
+			return types.ForbiddenErrorf("DNS record %s %s already exists in network %s", recType, name, n.Name())
+		}
+		return types.ForbiddenErrorf("DNS record %s %s is defined with swarm network %s", recType, name, n.Name())
+	}
+
+	c := n.getController()
+	nDB := c.agent.networkDB
+
+	_, err := nDB.GetEntry(dnsRecordTable, n.ID(), k)
+	exists := err == nil
+	if create && exists {
+		return types.ForbiddenErrorf("DNS record %s %s already exists in network %s", recType, name, n.Name())
+	}
+	if !create && !exists {
+		return types.NotFoundErrorf("DNS record %s %s not found in network %s", recType, name, n.Name())
+	}
+
+	if rec == nil {
+		if err := nDB.DeleteEntry(dnsRecordTable, n.ID(), k); err != nil {
+			return err
+		}
+		n.loadStaticRecords()
+		c.delDNSRecord(n.ID(), k)
+		return nil
+	}
+
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if create {
+		err = nDB.CreateEntry(dnsRecordTable, n.ID(), k, buf)
+	} else {
+		err = nDB.UpdateEntry(dnsRecordTable, n.ID(), k, buf)
+	}
+	if err != nil {
+		return err
+	}
+
+	n.loadStaticRecords()
+	c.setDNSRecord(n.ID(), rec)
+	return nil
+}
+
+func splitDNSRecordKey(k string) (string, string) {
+	i := strings.Index(k, "/")
+	if i < 0 {
+		return "", k
+	}
+	return k[:i], k[i+1:]
+}
+
+func (n *network) DNSRecords() []DNSRecord {
+	recs := n.staticRecords()
+	keys := make([]string, 0, len(recs))
+	for k := range recs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	l := make([]DNSRecord, 0, len(keys))
+	for _, k := range keys {
+		rec := *recs[k]
+		rec.Values = append([]string(nil), rec.Values...)
+		l = append(l, rec)
+	}
+	return l
+}
+
+// loadStaticRecords makes sure the controller holds the user-defined DNS
+// records of the network, which are stored with the network. The records
+// added to the swarm networks are then gossiped, the records of the
+// networks of a distributed store are refreshed by the network watch.
+func (n *network) loadStaticRecords() {
+	c := n.getController()
+	c.Lock()
+	_, ok := c.dnsRecords[n.ID()]
+	c.Unlock()
+	if ok {
+		return
+	}
+
+	n.Lock()
+	recs := make(map[string]*DNSRecord, len(n.dnsRecords))
+	for k, rec := range n.dnsRecords {
+		recs[k] = rec
+	}
+	n.Unlock()
+
+	c.Lock()
+	if _, ok := c.dnsRecords[n.ID()]; !ok {
+		c.dnsRecords[n.ID()] = recs
+	}
+	c.Unlock()
+}
+
+// staticRecords returns a copy of the user-defined DNS records of the
+// network.
+func (n *network) staticRecords() map[string]*DNSRecord {
+	n.loadStaticRecords()
+
+	c := n.getController()
+	c.Lock()
+	defer c.Unlock()
+
+	recs := make(map[string]*DNSRecord, len(c.dnsRecords[n.ID()]))
+	for k, rec := range c.dnsRecords[n.ID()] {
+		recs[k] = rec
+	}
+	return recs
+}
+
+func (n *network) staticRecord(name, recType string) *DNSRecord {
+	n.loadStaticRecords()
+
+	c := n.getController()
+	c.Lock()
+	defer c.Unlock()
+
+	return c.dnsRecords[n.ID()][dnsRecordKey(name, recType)]
+}
+
+// resolveStaticName resolves the name to the addresses of its user-defined
+// A or AAAA record. The second return value is true if the name only has
+// an A record for an IPv6 query.
+func (n *network) resolveStaticName(name string, ipType int) ([]net.IP, bool) {
+	recType := DNSRecordA
+	if ipType == types.IPv6 {
+		recType = DNSRecordAAAA
+	}
+
+	rec := n.staticRecord(name, recType)
+	if rec == nil {
+		return nil, ipType == types.IPv6 && n.staticRecord(name, DNSRecordA) != nil
+	}
+
+	ips := make([]net.IP, 0, len(rec.Values))
+	for _, v := range rec.Values {
+		ips = append(ips, net.ParseIP(v))
+	}
+	return ips, false
+}
+
+// resolveStaticService returns the targets of the user-defined SRV record
+// of the name, with their address when the network can resolve them.
+func (n *network) resolveStaticService(name string) ([]*net.SRV, []net.IP) {
+	rec := n.staticRecord(name, DNSRecordSRV)
+	if rec == nil {
+		return nil, nil
+	}
+
+	var (
+		srv []*net.SRV
+		ip  []net.IP
+	)
+	for _, v := range rec.Values {
+		s, err := parseSRVValue(v)
+		if err != nil {
+			continue
+		}
+		var addr net.IP
+		if addrs, _ := n.ResolveName(s.Target, types.IPv4); len(addrs) > 0 {
+			addr = addrs[0]
+		}
+		srv = append(srv, s)
+		ip = append(ip, addr)
+	}
+	return srv, ip
+}
+
+// ResolveCNAME returns the canonical name of the user-defined CNAME record
+// of the name.
+func (n *network) ResolveCNAME(name string) string {
+	rec := n.staticRecord(name, DNSRecordCNAME)
+	if rec == nil {
+		return ""
+	}
+	return rec.Values[0]
+}
+
+// ResolveTXT returns the texts of the user-defined TXT record of the name.
+func (n *network) ResolveTXT(name string) []string {
+	rec := n.staticRecord(name, DNSRecordTXT)
+	if rec == nil {
+		return nil
+	}
+	return append([]string(nil), rec.Values...)
+}
+
+func (c *controller) setDNSRecord(nid string, rec *DNSRecord) {
+	c.Lock()
+	defer c.Unlock()
+
+	recs, ok := c.dnsRecords[nid]
+	if !ok {
+		recs = make(map[string]*DNSRecord)
+		c.dnsRecords[nid] = recs
+	}
+	recs[rec.key()] = rec
+}
+
+func (c *controller) delDNSRecord(nid, key string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.dnsRecords[nid], key)
+}
+
+// refreshDNSRecords replaces the user-defined DNS records of the network
+// with the ones of its latest copy in the store
+func (c *controller) refreshDNSRecords(n *network) {
+	n.Lock()
+	recs := make(map[string]*DNSRecord, len(n.dnsRecords))
+	for k, rec := range n.dnsRecords {
+		recs[k] = rec
+	}
+	n.Unlock()
+
+	c.Lock()
+	c.dnsRecords[n.ID()] = recs
+	c.Unlock()
+}
+
+// handleDNSRecordTableEvent applies the DNS records gossiped for the swarm
+// networks
+func (c *controller) handleDNSRecordTableEvent(ev events.Event) {
+	var (
+		nid   string
+		key   string
+		value []byte
+		isAdd bool
+	)
+
+	switch event := ev.(type) {
+	case networkdb.CreateEvent:
+		nid = event.NetworkID
+		key = event.Key
+		value = event.Value
+		isAdd = true
+	case networkdb.UpdateEvent:
+		nid = event.NetworkID
+		key = event.Key
+		value = event.Value
+		isAdd = true
+	case networkdb.DeleteEvent:
+		nid = event.NetworkID
+		key = event.Key
+	}
+
+	nw, err := c.NetworkByID(nid)
+	if err != nil {
+		log.Errorf("Could not find network %s while handling DNS record table event: %v", nid, err)
+		return
+	}
+	// The records defined with the network must not be lost to the ones
+	// gossiped first
+	nw.(*network).loadStaticRecords()
+
+	if !isAdd {
+		c.delDNSRecord(nid, key)
+		return
+	}
+
+	var rec DNSRecord
+	if err := json.Unmarshal(value, &rec); err != nil {
+		log.Errorf("Failed to unmarshal DNS record table value: %v", err)
+		return
+	}
+	rec, err = rec.normalize()
+	if err != nil || rec.key() != key {
+		log.Errorf("Invalid DNS record received while handling DNS record table event %s: %v", value, err)
+		return
+	}
+
+	c.setDNSRecord(nid, &rec)
+}
@@ -0,0 +1,358 @@
+package libnetwork
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/go-events"
+	"github.com/docker/libnetwork/discoverapi"
+	"github.com/docker/libnetwork/driverapi"
+	"github.com/docker/libnetwork/networkdb"
+	"github.com/docker/libnetwork/types"
+)
+
+// networkdb table gossiping the network policies of the swarm networks, keyed
+// by policy name
+const networkPolicyTable = "network_policy_table"
+
+func (n *network) AddNetworkPolicy(policy types.NetworkPolicy) error {
+	policy = policy.GetCopy()
+	if err := validateNetworkPolicy(&policy); err != nil {
+		return err
+	}
+
+	// The policies are only translated into iptables rules
+	n.Lock()
+	ipv6 := n.enableIPv6
+	n.Unlock()
+	if ipv6 {
+		return types.ForbiddenErrorf("network policies are not supported on IPv6 network %s", n.Name())
+	}
+
+	err := n.updateNetworkPolicies(func(policies []types.NetworkPolicy) ([]types.NetworkPolicy, error) {
+		for _, p := range policies {
+			if p.Name == policy.Name {
+				return nil, types.ForbiddenErrorf("network policy %s already exists in network %s", policy.Name, n.Name())
+			}
+		}
+		return append(policies, policy), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := n.gossipNetworkPolicy(policy, true); err != nil {
+		if e := n.updateNetworkPolicies(deleteNetworkPolicy(policy.Name)); e != nil {
+			log.Warnf("Failed to remove network policy %s of network %s: %v", policy.Name, n.Name(), e)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (n *network) RemoveNetworkPolicy(name string) error {
+	var removed types.NetworkPolicy
+	err := n.updateNetworkPolicies(func(policies []types.NetworkPolicy) ([]types.NetworkPolicy, error) {
+		for i, p := range policies {
+			if p.Name == name {
+				removed = p
+				return append(policies[:i], policies[i+1:]...), nil
+			}
+		}
+		return nil, types.NotFoundErrorf("network policy %s not found in network %s", name, n.Name())
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := n.gossipNetworkPolicy(removed, false); err != nil {
+		if e := n.updateNetworkPolicies(setNetworkPolicy(removed)); e != nil {
+			log.Warnf("Failed to restore network policy %s of network %s: %v", name, n.Name(), e)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// setNetworkPolicy returns the update adding the policy to the policies of
+// the network, or replacing the policy with the same name
+func setNetworkPolicy(policy types.NetworkPolicy) func([]types.NetworkPolicy) ([]types.NetworkPolicy, error) {
+	return func(policies []types.NetworkPolicy) ([]types.NetworkPolicy, error) {
+		for i, p := range policies {
+			if p.Name == policy.Name {
+				policies[i] = policy
+				return policies, nil
+			}
+		}
+		return append(policies, policy), nil
+	}
+}
+
+// deleteNetworkPolicy returns the update removing the policy from the
+// policies of the network, if present
+func deleteNetworkPolicy(name string) func([]types.NetworkPolicy) ([]types.NetworkPolicy, error) {
+	return func(policies []types.NetworkPolicy) ([]types.NetworkPolicy, error) {
+		for i, p := range policies {
+			if p.Name == name {
+				return append(policies[:i], policies[i+1:]...), nil
+			}
+		}
+		return policies, nil
+	}
+}
+
+// gossipNetworkPolicy distributes the policy added to or removed from a swarm
+// network to the other nodes of the network
+func (n *network) gossipNetworkPolicy(policy types.NetworkPolicy, add bool) error {
+	if !n.isClusterEligible() {
+		return nil
+	}
+
+	c := n.getController()
+	if !add {
+		return c.agent.networkDB.DeleteEntry(networkPolicyTable, n.ID(), policy.Name)
+	}
+
+	buf, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return c.agent.networkDB.CreateEntry(networkPolicyTable, n.ID(), policy.Name, buf)
+}
+
+func (n *network) NetworkPolicies() []types.NetworkPolicy {
+	n.Lock()
+	defer n.Unlock()
+
+	policies := make([]types.NetworkPolicy, 0, len(n.policies))
+	for _, p := range n.policies {
+		policies = append(policies, p.GetCopy())
+	}
+	return policies
+}
+
+// updateNetworkPolicies applies the update to the policies of the network,
+// enforces the updated policies in the driver and saves them to the store
+func (n *network) updateNetworkPolicies(update func([]types.NetworkPolicy) ([]types.NetworkPolicy, error)) error {
+	c := n.getController()
+
+	c.networkLocker.Lock(n.ID())
+	defer c.networkLocker.Unlock(n.ID())
+
+	d, cap, err := n.resolveDriver(n.networkType, true)
+	if err != nil {
+		return err
+	}
+	if !cap.NetworkPolicies {
+		return types.NotImplementedErrorf("%s driver does not support network policies", n.Type())
+	}
+
+	old := n.NetworkPolicies()
+	policies, err := update(n.NetworkPolicies())
+	if err != nil {
+		return err
+	}
+	if equalNetworkPolicies(old, policies) {
+		return nil
+	}
+
+	if err := pushNetworkPolicies(d, n.ID(), policies); err != nil {
+		return err
+	}
+
+	n.Lock()
+	n.policies = policies
+	n.Unlock()
+
+	if err := c.updateToStore(n); err != nil {
+		n.Lock()
+		n.policies = old
+		n.Unlock()
+		if e := pushNetworkPolicies(d, n.ID(), old); e != nil {
+			log.Warnf("Failed to restore the network policies of network %s: %v", n.Name(), e)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// validateNetworkPolicy checks the policy and completes its isolation
+// directions with their defaults
+func validateNetworkPolicy(p *types.NetworkPolicy) error {
+	if p.Name == "" {
+		return types.BadRequestErrorf("network policy has no name")
+	}
+	if err := validateSelector(p.Selector); err != nil {
+		return err
+	}
+
+	if len(p.Isolation) == 0 {
+		for _, d := range []string{types.PolicyIngress, types.PolicyEgress} {
+			if p.Isolates(d) {
+				p.Isolation = append(p.Isolation, d)
+			}
+		}
+	}
+	for _, d := range p.Isolation {
+		if d != types.PolicyIngress && d != types.PolicyEgress {
+			return types.BadRequestErrorf("invalid isolation direction %q in network policy %s", d, p.Name)
+		}
+	}
+
+	for _, rules := range [][]types.PolicyRule{p.Ingress, p.Egress} {
+		for _, r := range rules {
+			for _, peer := range r.Peers {
+				if err := validateSelector(peer.Labels); err != nil {
+					return err
+				}
+				if peer.CIDR == "" {
+					continue
+				}
+				if len(peer.Labels) > 0 {
+					return types.BadRequestErrorf("network policy %s peer selects both labels and addresses", p.Name)
+				}
+				ip, _, err := net.ParseCIDR(peer.CIDR)
+				if err != nil || ip.To4() == nil {
+					return types.BadRequestErrorf("invalid IPv4 range %q in network policy %s", peer.CIDR, p.Name)
+				}
+			}
+			if err := validatePolicyPorts(r.Ports); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateSelector(labels map[string]string) error {
+	for k := range labels {
+		if k == "" {
+			return types.BadRequestErrorf("empty label in selector")
+		}
+	}
+	return nil
+}
+
+func validatePolicyPorts(ports []types.TransportPort) error {
+	for _, p := range ports {
+		switch p.Proto {
+		case types.TCP, types.UDP:
+		case types.ICMP:
+			if p.Port != 0 {
+				return types.BadRequestErrorf("invalid port %d for protocol icmp", p.Port)
+			}
+		default:
+			return types.BadRequestErrorf("invalid protocol %d", p.Proto)
+		}
+	}
+	return nil
+}
+
+func pushNetworkPolicies(d driverapi.Driver, nid string, policies []types.NetworkPolicy) error {
+	return d.DiscoverNew(discoverapi.NetworkPolicyConfig, discoverapi.NetworkPolicyConfigData{
+		NetworkID: nid,
+		Policies:  policies,
+	})
+}
+
+// restoreNetworkPolicies enforces again in the driver the network policies
+// of the networks restored from the store
+var restoreNetworkPolicies NetworkWalker = func(nw Network) bool {
+	n := nw.(*network)
+	policies := n.NetworkPolicies()
+	if len(policies) == 0 {
+		return false
+	}
+
+	d, _, err := n.resolveDriver(n.networkType, true)
+	if err != nil {
+		log.Warnf("Failed to restore the network policies of network %s: %v", n.Name(), err)
+		return false
+	}
+	if err := pushNetworkPolicies(d, n.ID(), policies); err != nil {
+		log.Warnf("Failed to restore the network policies of network %s: %v", n.Name(), err)
+	}
+	return false
+}
+
+func equalNetworkPolicies(a, b []types.NetworkPolicy) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+// handleNetworkPolicyTableEvent applies the policies gossiped by the other
+// nodes, and by this node, to the local copy of the swarm network
+func (c *controller) handleNetworkPolicyTableEvent(ev events.Event) {
+	var (
+		nid   string
+		name  string
+		value []byte
+		isAdd bool
+	)
+
+	switch event := ev.(type) {
+	case networkdb.CreateEvent:
+		nid = event.NetworkID
+		name = event.Key
+		value = event.Value
+		isAdd = true
+	case networkdb.UpdateEvent:
+		nid = event.NetworkID
+		name = event.Key
+		value = event.Value
+		isAdd = true
+	case networkdb.DeleteEvent:
+		nid = event.NetworkID
+		name = event.Key
+	}
+
+	nw, err := c.NetworkByID(nid)
+	if err != nil {
+		log.Errorf("Could not find network %s while handling network policy table event: %v", nid, err)
+		return
+	}
+	n := nw.(*network)
+
+	update := deleteNetworkPolicy(name)
+	if isAdd {
+		var policy types.NetworkPolicy
+		if err := json.Unmarshal(value, &policy); err != nil {
+			log.Errorf("Failed to unmarshal network policy table value: %v", err)
+			return
+		}
+		if err := validateNetworkPolicy(&policy); err != nil || policy.Name != name {
+			log.Errorf("Invalid network policy received while handling network policy table event %s: %v", value, err)
+			return
+		}
+		update = setNetworkPolicy(policy)
+	}
+
+	if err := n.updateNetworkPolicies(update); err != nil {
+		log.Warnf("Failed to update the network policies of network %s: %v", n.Name(), err)
+	}
+}
+
+// enforceWatchedPolicies enforces the policies of the latest copy of the
+// network in the store when they differ from the enforced ones, and returns
+// the policies now enforced
+func (n *network) enforceWatchedPolicies(enforced, policies []types.NetworkPolicy) []types.NetworkPolicy {
+	if equalNetworkPolicies(enforced, policies) {
+		return enforced
+	}
+
+	d, _, err := n.resolveDriver(n.networkType, true)
+	if err != nil {
+		log.Warnf("Failed to enforce the network policies of network %s: %v", n.Name(), err)
+		return enforced
+	}
+	if err := pushNetworkPolicies(d, n.ID(), policies); err != nil {
+		log.Warnf("Failed to enforce the network policies of network %s: %v", n.Name(), err)
+		return enforced
+	}
+	return policies
+}
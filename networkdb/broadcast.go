@@ -125,6 +125,7 @@ func (nDB *NetworkDB) sendTableEvent(event TableEvent_Type, nid string, tname st
 		TableName: tname,
 		Key:       key,
 		Value:     entry.value,
+		CreatedAt: entry.createdAt,
 	}
 
 	raw, err := encodeMessage(MessageTypeTableEvent, &tEvent)
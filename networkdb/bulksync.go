@@ -0,0 +1,131 @@
+package networkdb
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	rnd "math/rand"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/memberlist"
+)
+
+// Capabilities advertised to the other nodes in the first byte of the
+// node metadata. The nodes which do not advertise them get their bulk
+// syncs in a single uncompressed message.
+const (
+	capChunkedBulkSync byte = 1 << iota
+	capCompressedBulkSync
+)
+
+// hasCapability returns whether the node advertised the capability in
+// its metadata
+func (n *node) hasCapability(c byte) bool {
+	return len(n.Meta) > 0 && n.Meta[0]&c != 0
+}
+
+// bulkSyncChunkReceived records the reception of a chunk of a chunked
+// bulk sync and returns whether all its chunks were received
+func (nDB *NetworkDB) bulkSyncChunkReceived(bsm *BulkSyncMessage) bool {
+	nDB.Lock()
+	defer nDB.Unlock()
+
+	id := bulkSyncID{node: bsm.NodeName, id: bsm.SyncId}
+	s, ok := nDB.bulkSyncChunks[id]
+	if !ok {
+		s = &bulkSyncChunks{reapTime: bulkSyncTimeout}
+		nDB.bulkSyncChunks[id] = s
+	}
+
+	s.received++
+	if bsm.LastChunk {
+		s.chunks = bsm.Chunk + 1
+	}
+	if s.chunks == 0 || s.received < s.chunks {
+		return false
+	}
+
+	delete(nDB.bulkSyncChunks, id)
+	return true
+}
+
+// sendBulkSync sends the table events to the node. The events of a
+// chunked bulk sync are split in messages carrying up to
+// BulkSyncChunkSize bytes of events, a single message is sent
+// otherwise.
+func (nDB *NetworkDB) sendBulkSync(mnode *memberlist.Node, bsm *BulkSyncMessage, events []*TableEvent, compress bool) error {
+	send := func(msgs [][]byte, last bool) error {
+		bsm.Payload = makeCompoundMessage(msgs)
+		bsm.LastChunk = last && bsm.SyncId != 0
+		if compress {
+			payload, err := compressPayload(bsm.Payload)
+			if err != nil {
+				return fmt.Errorf("failed to compress bulk sync payload: %v", err)
+			}
+			bsm.Payload = payload
+			bsm.Compressed = true
+		}
+
+		buf, err := encodeMessage(MessageTypeBulkSync, bsm)
+		if err != nil {
+			return fmt.Errorf("failed to encode bulk sync message: %v", err)
+		}
+
+		if err := nDB.memberlist.SendToTCP(mnode, buf); err != nil {
+			return fmt.Errorf("failed to send a TCP message during bulk sync: %v", err)
+		}
+		nDB.metrics.messageSent(MetricsBulkSync, 1)
+
+		bsm.Chunk++
+		return nil
+	}
+
+	var (
+		msgs [][]byte
+		size int
+	)
+	for _, tEvent := range events {
+		msg, err := encodeMessage(MessageTypeTableEvent, tEvent)
+		if err != nil {
+			logrus.Errorf("Encode failure during bulk sync: %#v", tEvent)
+			continue
+		}
+
+		if bsm.SyncId != 0 && len(msgs) > 0 && size+len(msg) > nDB.config.BulkSyncChunkSize {
+			if err := send(msgs, false); err != nil {
+				return err
+			}
+			msgs, size = nil, 0
+		}
+
+		msgs = append(msgs, msg)
+		size += len(msg)
+	}
+
+	return send(msgs, true)
+}
+
+// newBulkSyncID returns a random non-zero identifier for a chunked bulk
+// sync
+func newBulkSyncID() uint64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return uint64(rnd.Int63()) | 1
+	}
+	return binary.BigEndian.Uint64(b[:]) | 1
+}
+
+// reapBulkSyncs gives up the chunked bulk syncs which did not complete
+// in time
+func (nDB *NetworkDB) reapBulkSyncs() {
+	nDB.Lock()
+	for id, s := range nDB.bulkSyncChunks {
+		if s.reapTime <= 0 {
+			logrus.Debugf("%s: giving up bulk sync from node %s, received %d chunks", nDB.config.NodeName, id.node, s.received)
+			delete(nDB.bulkSyncChunks, id)
+			continue
+		}
+		s.reapTime -= reapPeriod
+	}
+	nDB.Unlock()
+}
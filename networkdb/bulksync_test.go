@@ -0,0 +1,114 @@
+package networkdb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressPayload(t *testing.T) {
+	payload := bytes.Repeat([]byte("networkdb bulk sync payload "), 1000)
+
+	compressed, err := compressPayload(payload)
+	require.NoError(t, err)
+	assert.Equal(t, true, len(compressed) < len(payload))
+
+	decompressed, err := decompressPayload(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decompressed)
+
+	_, err = decompressPayload(payload)
+	assert.Error(t, err)
+}
+
+func TestBulkSyncChunks(t *testing.T) {
+	nDB := &NetworkDB{bulkSyncChunks: make(map[bulkSyncID]*bulkSyncChunks)}
+
+	// The chunks can be handled in any order
+	for i, c := range []struct {
+		chunk    uint32
+		last     bool
+		complete bool
+	}{
+		{2, true, false},
+		{0, false, false},
+		{1, false, true},
+	} {
+		bsm := &BulkSyncMessage{NodeName: "node1", SyncId: 1, Chunk: c.chunk, LastChunk: c.last}
+		assert.Equal(t, c.complete, nDB.bulkSyncChunkReceived(bsm), "chunk %d", i)
+	}
+	assert.Len(t, nDB.bulkSyncChunks, 0)
+
+	// An incomplete bulk sync is given up
+	nDB.config = &Config{NodeName: "node2"}
+	assert.Equal(t, false, nDB.bulkSyncChunkReceived(&BulkSyncMessage{NodeName: "node1", SyncId: 2}))
+	assert.Equal(t, false, nDB.bulkSyncChunkReceived(&BulkSyncMessage{NodeName: "node3", SyncId: 2, LastChunk: true, Chunk: 1}))
+	assert.Len(t, nDB.bulkSyncChunks, 2)
+	for i := 0; i <= int(bulkSyncTimeout/reapPeriod); i++ {
+		nDB.reapBulkSyncs()
+	}
+	assert.Len(t, nDB.bulkSyncChunks, 0)
+}
+
+func testBulkSync(t *testing.T, c *Config, legacy bool) Metrics {
+	const keys = 200
+	dbs := createNetworkDBInstancesWithConfig(t, 2, "node", c)
+
+	if legacy {
+		// Nodes which do not advertise any capability
+		dbs[0].verifyNodeExistence(t, "node2", true)
+		dbs[1].verifyNodeExistence(t, "node1", true)
+		for _, db := range dbs {
+			db.Lock()
+			for _, n := range db.nodes {
+				n.Meta = nil
+			}
+			db.Unlock()
+		}
+	}
+
+	require.NoError(t, dbs[0].JoinNetwork("network1"))
+	for i := 0; i < keys; i++ {
+		require.NoError(t, dbs[0].CreateEntry("test_table", "network1", fmt.Sprintf("key%d", i), bytes.Repeat([]byte("v"), 100)))
+	}
+	dbs[1].verifyNetworkExistence(t, "node1", "network1", true)
+
+	before := dbs[1].Metrics().MessagesReceived[MetricsBulkSync]
+	require.NoError(t, dbs[1].JoinNetwork("network1"))
+	for i := 0; i < keys; i++ {
+		dbs[1].verifyEntryExistence(t, "test_table", "network1", fmt.Sprintf("key%d", i), string(bytes.Repeat([]byte("v"), 100)), true)
+	}
+
+	m := dbs[1].Metrics()
+	m.MessagesReceived[MetricsBulkSync] -= before
+	assert.Equal(t, uint64(0), m.BulkSyncFailures)
+	dbs[1].RLock()
+	assert.Len(t, dbs[1].bulkSyncChunks, 0)
+	dbs[1].RUnlock()
+
+	closeNetworkDBInstances(dbs)
+	return m
+}
+
+func TestBulkSyncChunked(t *testing.T) {
+	// Push-pulls would add bulk syncs
+	c := &Config{
+		ReapEntryInterval: 10 * time.Minute,
+		BulkSyncInterval:  5 * time.Minute,
+		BulkSyncChunkSize: 4096,
+	}
+
+	m := testBulkSync(t, c, false)
+	assert.Equal(t, true, m.MessagesReceived[MetricsBulkSync] > 5, "expected several chunks, got %d", m.MessagesReceived[MetricsBulkSync])
+
+	c.BulkSyncCompression = true
+	m = testBulkSync(t, c, false)
+	assert.Equal(t, true, m.MessagesReceived[MetricsBulkSync] > 1, "expected several chunks, got %d", m.MessagesReceived[MetricsBulkSync])
+
+	m = testBulkSync(t, c, true)
+	assert.Equal(t, uint64(1), m.MessagesReceived[MetricsBulkSync])
+}
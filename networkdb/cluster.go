@@ -16,11 +16,10 @@ import (
 )
 
 const (
-	reapInterval     = 60 * time.Second
-	reapPeriod       = 5 * time.Second
-	retryInterval    = 1 * time.Second
-	nodeReapInterval = 24 * time.Hour
-	nodeReapPeriod   = 2 * time.Hour
+	reapPeriod      = 5 * time.Second
+	retryInterval   = 1 * time.Second
+	nodeReapPeriod  = 2 * time.Hour
+	bulkSyncTimeout = 30 * time.Second
 )
 
 type logWriter struct{}
@@ -86,8 +85,11 @@ func (nDB *NetworkDB) RemoveKey(key []byte) {
 }
 
 func (nDB *NetworkDB) clusterInit() error {
-	config := memberlist.DefaultLANConfig()
+	config := profiles[nDB.config.Profile].memberlist()
 	config.Name = nDB.config.NodeName
+	config.GossipInterval = nDB.config.GossipInterval
+	config.PushPullInterval = nDB.config.BulkSyncInterval
+	config.RetransmitMult = nDB.config.RetransmitMult
 	config.BindAddr = nDB.config.BindAddr
 	config.AdvertiseAddr = nDB.config.AdvertiseAddr
 
@@ -150,6 +152,7 @@ func (nDB *NetworkDB) clusterInit() error {
 		{config.PushPullInterval, nDB.bulkSyncTables},
 		{retryInterval, nDB.reconnectNode},
 		{nodeReapPeriod, nDB.reapDeadNode},
+		{snapshotPeriod, nDB.saveSnapshot},
 	} {
 		t := time.NewTicker(trigger.interval)
 		go nDB.triggerFunc(trigger.interval, t.C, nDB.stopCh, trigger.fn)
@@ -242,7 +245,7 @@ func (nDB *NetworkDB) reapDeadNode() {
 	defer nDB.Unlock()
 	for id, n := range nDB.failedNodes {
 		if n.reapTime > 0 {
-			n.reapTime -= reapPeriod
+			n.reapTime -= nodeReapPeriod
 			continue
 		}
 		logrus.Debugf("Removing failed node %v from gossip cluster", n.Name)
@@ -288,18 +291,30 @@ func (nDB *NetworkDB) reconnectNode() {
 
 // For timing the entry deletion in the repaer APIs that doesn't use monotonic clock
 // source (time.Now, Sub etc.) should be avoided. Hence we use reapTime in every
-// entry which is set initially to reapInterval and decremented by reapPeriod every time
+// entry which is set initially to ReapEntryInterval and decremented by reapPeriod every time
 // the reaper runs. NOTE nDB.reapTableEntries updates the reapTime with a readlock. This
 // is safe as long as no other concurrent path touches the reapTime field.
 func (nDB *NetworkDB) reapState() {
 	nDB.reapNetworks()
 	nDB.reapTableEntries()
+	nDB.reapBulkSyncs()
 }
 
 func (nDB *NetworkDB) reapNetworks() {
 	nDB.Lock()
 	for name, nn := range nDB.networks {
 		for id, n := range nn {
+			// Remove the restored attachments the cluster did
+			// not confirm in time
+			if n.restored {
+				if n.restoreTime <= 0 {
+					delete(nn, id)
+					nDB.deleteNetworkNode(id, name)
+					continue
+				}
+				n.restoreTime -= reapPeriod
+			}
+
 			if n.leaving {
 				if n.reapTime <= 0 {
 					delete(nn, id)
@@ -314,7 +329,7 @@ func (nDB *NetworkDB) reapNetworks() {
 }
 
 func (nDB *NetworkDB) reapTableEntries() {
-	var paths []string
+	var paths, expired []string
 
 	nDB.RLock()
 	nDB.indexes[byTable].Walk(func(path string, v interface{}) bool {
@@ -324,6 +339,13 @@ func (nDB *NetworkDB) reapTableEntries() {
 		}
 
 		if !entry.deleting {
+			if entry.restored {
+				if entry.restoreTime > 0 {
+					entry.restoreTime -= reapPeriod
+					return false
+				}
+				expired = append(expired, path)
+			}
 			return false
 		}
 		if entry.reapTime > 0 {
@@ -350,6 +372,22 @@ func (nDB *NetworkDB) reapTableEntries() {
 			logrus.Errorf("Could not delete entry in network %s with table name %s and key %s as it does not exist", nid, tname, key)
 		}
 	}
+
+	// Purge the restored entries the cluster did not confirm in time
+	for _, path := range expired {
+		v, ok := nDB.indexes[byTable].Get(path)
+		if !ok {
+			continue
+		}
+
+		entry := v.(*entry)
+		if !entry.restored || entry.deleting {
+			continue
+		}
+
+		params := strings.Split(path[1:], "/")
+		nDB.purgeRestoredEntry(params[0], params[1], params[2], entry)
+	}
 	nDB.Unlock()
 }
 
@@ -405,7 +443,9 @@ func (nDB *NetworkDB) gossip() {
 			// Send the compound message
 			if err := nDB.memberlist.SendToUDP(&mnode.Node, compound); err != nil {
 				logrus.Errorf("Failed to send gossip to %s: %s", mnode.Addr, err)
+				continue
 			}
+			nDB.metrics.messageSent(MetricsTable, len(msgs))
 		}
 	}
 }
@@ -501,7 +541,7 @@ func (nDB *NetworkDB) bulkSync(nodes []string, all bool) ([]string, error) {
 // single peer node. It can be unsolicited or can be in response to an
 // unsolicited bulk sync
 func (nDB *NetworkDB) bulkSyncNode(networks []string, node string, unsolicited bool) error {
-	var msgs [][]byte
+	var events []*TableEvent
 
 	var unsolMsg string
 	if unsolicited {
@@ -524,13 +564,18 @@ func (nDB *NetworkDB) bulkSyncNode(networks []string, node string, unsolicited b
 				return false
 			}
 
+			// Restored entries are only propagated once confirmed
+			if entry.restored {
+				return false
+			}
+
 			eType := TableEventTypeCreate
 			if entry.deleting {
 				eType = TableEventTypeDelete
 			}
 
 			params := strings.Split(path[1:], "/")
-			tEvent := TableEvent{
+			events = append(events, &TableEvent{
 				Type:      eType,
 				LTime:     entry.ltime,
 				NodeName:  entry.node,
@@ -538,59 +583,55 @@ func (nDB *NetworkDB) bulkSyncNode(networks []string, node string, unsolicited b
 				TableName: params[1],
 				Key:       params[2],
 				Value:     entry.value,
-			}
-
-			msg, err := encodeMessage(MessageTypeTableEvent, &tEvent)
-			if err != nil {
-				logrus.Errorf("Encode failure during bulk sync: %#v", tEvent)
-				return false
-			}
-
-			msgs = append(msgs, msg)
+				CreatedAt: entry.createdAt,
+			})
 			return false
 		})
 	}
 	nDB.RUnlock()
 
-	// Create a compound message
-	compound := makeCompoundMessage(msgs)
-
-	bsm := BulkSyncMessage{
+	bsm := &BulkSyncMessage{
 		LTime:       nDB.tableClock.Time(),
 		Unsolicited: unsolicited,
 		NodeName:    nDB.config.NodeName,
 		Networks:    networks,
-		Payload:     compound,
 	}
 
-	buf, err := encodeMessage(MessageTypeBulkSync, &bsm)
-	if err != nil {
-		return fmt.Errorf("failed to encode bulk sync message: %v", err)
+	// The nodes supporting it get the bulk sync in chunks of bounded
+	// size, the others in a single message.
+	if mnode.hasCapability(capChunkedBulkSync) {
+		bsm.SyncId = newBulkSyncID()
 	}
+	compress := nDB.config.BulkSyncCompression && mnode.hasCapability(capCompressedBulkSync)
 
 	nDB.Lock()
 	ch := make(chan struct{})
 	nDB.bulkSyncAckTbl[node] = ch
 	nDB.Unlock()
 
-	err = nDB.memberlist.SendToTCP(&mnode.Node, buf)
-	if err != nil {
+	startTime := time.Now()
+	if err := nDB.sendBulkSync(&mnode.Node, bsm, events, compress); err != nil {
 		nDB.Lock()
 		delete(nDB.bulkSyncAckTbl, node)
 		nDB.Unlock()
 
-		return fmt.Errorf("failed to send a TCP message during bulk sync: %v", err)
+		if unsolicited {
+			nDB.metrics.bulkSyncDone(0, err)
+		}
+		return err
 	}
 
 	// Wait on a response only if it is unsolicited.
 	if unsolicited {
-		startTime := time.Now()
-		t := time.NewTimer(30 * time.Second)
+		t := time.NewTimer(bulkSyncTimeout)
 		select {
 		case <-t.C:
 			logrus.Errorf("Bulk sync to node %s timed out", node)
+			nDB.metrics.bulkSyncDone(0, fmt.Errorf("bulk sync to node %s timed out", node))
 		case <-ch:
-			logrus.Debugf("%s: Bulk sync to node %s took %s", nDB.config.NodeName, node, time.Now().Sub(startTime))
+			d := time.Now().Sub(startTime)
+			logrus.Debugf("%s: Bulk sync to node %s took %s", nDB.config.NodeName, node, d)
+			nDB.metrics.bulkSyncDone(d, nil)
 		}
 		t.Stop()
 	}
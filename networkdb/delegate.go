@@ -14,7 +14,7 @@ type delegate struct {
 }
 
 func (d *delegate) NodeMeta(limit int) []byte {
-	return []byte{}
+	return []byte{capChunkedBulkSync | capCompressedBulkSync}
 }
 
 func (nDB *NetworkDB) checkAndGetNode(nEvent *NodeEvent) *node {
@@ -114,13 +114,19 @@ func (nDB *NetworkDB) handleNetworkEvent(nEvent *NetworkEvent) bool {
 		// We have the latest state. Ignore the event
 		// since it is stale.
 		if n.ltime >= nEvent.LTime {
+			// The cluster confirms an attachment restored
+			// from a snapshot
+			if n.restored && n.ltime == nEvent.LTime {
+				n.restored = false
+			}
 			return false
 		}
 
 		n.ltime = nEvent.LTime
+		n.restored = false
 		n.leaving = nEvent.Type == NetworkEventTypeLeave
 		if n.leaving {
-			n.reapTime = reapInterval
+			n.reapTime = nDB.config.ReapEntryInterval
 		}
 
 		nDB.addNetworkNode(nEvent.NetworkID, nEvent.NodeName)
@@ -141,7 +147,7 @@ func (nDB *NetworkDB) handleNetworkEvent(nEvent *NetworkEvent) bool {
 	return true
 }
 
-func (nDB *NetworkDB) handleTableEvent(tEvent *TableEvent) bool {
+func (nDB *NetworkDB) handleTableEvent(tEvent *TableEvent, isBulkSync bool) bool {
 	// Update our local clock if the received messages has newer
 	// time.
 	nDB.tableClock.Witness(tEvent.LTime)
@@ -162,22 +168,33 @@ func (nDB *NetworkDB) handleTableEvent(tEvent *TableEvent) bool {
 	}
 
 	if err == nil {
-		// We have the latest state. Ignore the event
-		// since it is stale.
-		if e.ltime >= tEvent.LTime {
+		if e.restored && e.ltime == tEvent.LTime {
+			// The cluster confirms an entry restored from a
+			// snapshot, unless it was deleted in the meantime
+			// or this node purged it too early.
+			if e.deleting == (tEvent.Type == TableEventTypeDelete) {
+				nDB.Lock()
+				e.restored = false
+				nDB.Unlock()
+				return false
+			}
+		} else if e.ltime >= tEvent.LTime {
+			// We have the latest state. Ignore the event
+			// since it is stale.
 			return false
 		}
 	}
 
 	e = &entry{
-		ltime:    tEvent.LTime,
-		node:     tEvent.NodeName,
-		value:    tEvent.Value,
-		deleting: tEvent.Type == TableEventTypeDelete,
+		ltime:     tEvent.LTime,
+		node:      tEvent.NodeName,
+		value:     tEvent.Value,
+		deleting:  tEvent.Type == TableEventTypeDelete,
+		createdAt: tEvent.CreatedAt,
 	}
 
 	if e.deleting {
-		e.reapTime = reapInterval
+		e.reapTime = nDB.config.ReapEntryInterval
 	}
 
 	nDB.Lock()
@@ -185,6 +202,12 @@ func (nDB *NetworkDB) handleTableEvent(tEvent *TableEvent) bool {
 	nDB.indexes[byNetwork].Insert(fmt.Sprintf("/%s/%s/%s", tEvent.NetworkID, tEvent.TableName, tEvent.Key), e)
 	nDB.Unlock()
 
+	// The entries of a bulk sync are only late because the node was
+	// not there, they do not measure how fast the gossip converges.
+	if !e.deleting && !isBulkSync {
+		nDB.metrics.entryVisible(e.createdAt)
+	}
+
 	var op opType
 	switch tEvent.Type {
 	case TableEventTypeCreate:
@@ -226,7 +249,7 @@ func (nDB *NetworkDB) handleTableMessage(buf []byte, isBulkSync bool) {
 	}
 
 	// Do not rebroadcast a bulk sync
-	if rebroadcast := nDB.handleTableEvent(&tEvent); rebroadcast && !isBulkSync {
+	if rebroadcast := nDB.handleTableEvent(&tEvent, isBulkSync); rebroadcast && !isBulkSync {
 		var err error
 		buf, err = encodeRawMessage(MessageTypeTableEvent, buf)
 		if err != nil {
@@ -313,7 +336,24 @@ func (nDB *NetworkDB) handleBulkSync(buf []byte) {
 		nDB.tableClock.Witness(bsm.LTime)
 	}
 
-	nDB.handleMessage(bsm.Payload, true)
+	payload := bsm.Payload
+	if bsm.Compressed {
+		var err error
+		if payload, err = decompressPayload(payload); err != nil {
+			logrus.Errorf("Error decompressing bulk sync payload from %s: %v", bsm.NodeName, err)
+			return
+		}
+	}
+
+	nDB.handleMessage(payload, true)
+
+	// A chunked bulk sync is complete once all its chunks, which can
+	// be handled in any order, are received
+	if bsm.SyncId != 0 && !nDB.bulkSyncChunkReceived(&bsm) {
+		return
+	}
+
+	nDB.purgeUnconfirmedEntries(bsm.Networks)
 
 	// Don't respond to a bulk sync which was not unsolicited
 	if !bsm.Unsolicited {
@@ -347,6 +387,12 @@ func (nDB *NetworkDB) handleMessage(buf []byte, isBulkSync bool) {
 		return
 	}
 
+	// The messages carried by a bulk sync are accounted for by the bulk
+	// sync itself
+	if !isBulkSync {
+		nDB.metrics.messageReceived(mType)
+	}
+
 	switch mType {
 	case MessageTypeNodeEvent:
 		nDB.handleNodeMessage(data)
@@ -373,7 +419,10 @@ func (d *delegate) NotifyMsg(buf []byte) {
 
 func (d *delegate) GetBroadcasts(overhead, limit int) [][]byte {
 	msgs := d.nDB.networkBroadcasts.GetBroadcasts(overhead, limit)
-	msgs = append(msgs, d.nDB.nodeBroadcasts.GetBroadcasts(overhead, limit)...)
+	d.nDB.metrics.messageSent(MetricsNetwork, len(msgs))
+	nodeMsgs := d.nDB.nodeBroadcasts.GetBroadcasts(overhead, limit)
+	d.nDB.metrics.messageSent(MetricsNode, len(nodeMsgs))
+	msgs = append(msgs, nodeMsgs...)
 	return msgs
 }
 
@@ -397,6 +446,11 @@ func (d *delegate) LocalState(join bool) []byte {
 
 	for name, nn := range d.nDB.networks {
 		for _, n := range nn {
+			// Restored attachments are only propagated once
+			// confirmed
+			if n.restored {
+				continue
+			}
 			pp.Networks = append(pp.Networks, &NetworkEntry{
 				LTime:     n.ltime,
 				NetworkID: n.id,
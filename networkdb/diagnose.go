@@ -0,0 +1,461 @@
+package networkdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/types"
+)
+
+// The diagnostic server exposes the state of the NetworkDB instance as
+// seen by the local node, and lets the operator drive it to reproduce
+// convergence problems. It only listens on the loopback interface, and
+// the endpoints modifying the state are only enabled by DiagnosticWrite.
+// They take a JSON body, which a browser cannot send to another origin
+// without a preflight request, and the Host of the requests must be the
+// listen address to defeat DNS rebinding.
+const (
+	nodeActive = "active"
+	nodeFailed = "failed"
+	nodeLeft   = "left"
+)
+
+// DiagnosticNode is the state of a cluster member
+type DiagnosticNode struct {
+	Name     string `json:"name"`
+	Addr     string `json:"addr"`
+	Port     uint16 `json:"port"`
+	State    string `json:"state"`
+	Self     bool   `json:"self"`
+	LTime    uint64 `json:"ltime"`
+	ReapTime string `json:"reap_time,omitempty"`
+}
+
+// DiagnosticAttachment is the attachment of a node to a network
+type DiagnosticAttachment struct {
+	Node     string `json:"node"`
+	LTime    uint64 `json:"ltime"`
+	Leaving  bool   `json:"leaving"`
+	ReapTime string `json:"reap_time,omitempty"`
+	Restored bool   `json:"restored"`
+}
+
+// DiagnosticNetwork lists the nodes participating in a network
+type DiagnosticNetwork struct {
+	ID          string                 `json:"id"`
+	Nodes       []string               `json:"nodes"`
+	Attachments []DiagnosticAttachment `json:"attachments"`
+}
+
+// DiagnosticEntry is a table entry
+type DiagnosticEntry struct {
+	Table    string `json:"table"`
+	Network  string `json:"network"`
+	Key      string `json:"key"`
+	Value    []byte `json:"value"`
+	Owner    string `json:"owner"`
+	LTime    uint64 `json:"ltime"`
+	Deleting bool   `json:"deleting"`
+	ReapTime string `json:"reap_time,omitempty"`
+	Restored bool   `json:"restored"`
+}
+
+// DiagnosticRequest is the body of the requests modifying the state
+type DiagnosticRequest struct {
+	Table   string `json:"tname"`
+	Network string `json:"nid"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+}
+
+// DiagnosticQueues reports the number of messages waiting in the
+// broadcast queues
+type DiagnosticQueues struct {
+	Node    int            `json:"node"`
+	Network int            `json:"network"`
+	Tables  map[string]int `json:"tables"`
+}
+
+type diagnosticHandler func(nDB *NetworkDB, w http.ResponseWriter, r *http.Request)
+
+var diagnosticRoutes = map[string]struct {
+	method  string
+	write   bool
+	handler diagnosticHandler
+}{
+	"/members":       {"GET", false, diagnoseMembers},
+	"/networks":      {"GET", false, diagnoseNetworks},
+	"/table":         {"GET", false, diagnoseTable},
+	"/queues":        {"GET", false, diagnoseQueues},
+	"/metrics":       {"GET", false, diagnoseMetrics},
+	"/entry/create":  {"POST", true, diagnoseCreateEntry},
+	"/entry/update":  {"POST", true, diagnoseUpdateEntry},
+	"/entry/delete":  {"POST", true, diagnoseDeleteEntry},
+	"/network/join":  {"POST", true, diagnoseJoinNetwork},
+	"/network/leave": {"POST", true, diagnoseLeaveNetwork},
+}
+
+// validateDiagnosticAddr makes sure the diagnostic server is only
+// reachable from the local host
+func validateDiagnosticAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid diagnostic address %q: %v", addr, err)
+	}
+	switch host {
+	case "":
+		host = "127.0.0.1"
+	case "localhost":
+	default:
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return "", fmt.Errorf("diagnostic address %q is not a loopback address", addr)
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// diagnosticMux returns the handler of the diagnostic server, serving the
+// requests addressed to one of the hosts
+func (nDB *NetworkDB) diagnosticMux(hosts ...string) *http.ServeMux {
+	mux := http.NewServeMux()
+	for path, route := range diagnosticRoutes {
+		route := route
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if !validDiagnosticHost(r.Host, hosts) {
+				http.Error(w, fmt.Sprintf("invalid host %q", r.Host), http.StatusForbidden)
+				return
+			}
+			if r.Method != route.method {
+				w.Header().Set("Allow", route.method)
+				http.Error(w, fmt.Sprintf("%s only supports %s", r.URL.Path, route.method), http.StatusMethodNotAllowed)
+				return
+			}
+			if route.write {
+				if !nDB.config.DiagnosticWrite {
+					http.Error(w, fmt.Sprintf("%s is disabled", r.URL.Path), http.StatusForbidden)
+					return
+				}
+				if ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || ct != "application/json" {
+					http.Error(w, fmt.Sprintf("%s only accepts application/json", r.URL.Path), http.StatusUnsupportedMediaType)
+					return
+				}
+			}
+			route.handler(nDB, w, r)
+		})
+	}
+	return mux
+}
+
+func validDiagnosticHost(host string, hosts []string) bool {
+	for _, h := range hosts {
+		if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+func (nDB *NetworkDB) diagnosticInit() error {
+	if nDB.config.DiagnosticAddr == "" {
+		return nil
+	}
+
+	addr, err := validateDiagnosticAddr(nDB.config.DiagnosticAddr)
+	if err != nil {
+		return err
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start the networkdb diagnostic server: %v", err)
+	}
+	nDB.diagnosticListener = l
+
+	// The port is only known once listening when it is left to zero
+	host, _, _ := net.SplitHostPort(addr)
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	mux := nDB.diagnosticMux(net.JoinHostPort(host, port), l.Addr().String())
+
+	logrus.Infof("NetworkDB %s: diagnostic server listening on %s", nDB.config.NodeName, l.Addr())
+	go func() {
+		if err := http.Serve(l, mux); err != nil {
+			logrus.Debugf("NetworkDB %s: diagnostic server stopped: %v", nDB.config.NodeName, err)
+		}
+	}()
+
+	return nil
+}
+
+func (nDB *NetworkDB) diagnosticStop() {
+	if nDB.diagnosticListener != nil {
+		nDB.diagnosticListener.Close()
+	}
+}
+
+func writeDiagnostic(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("Failed to encode networkdb diagnostic: %v", err)
+	}
+}
+
+func diagnosticError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch err.(type) {
+	case types.BadRequestError:
+		status = http.StatusBadRequest
+	case types.NotFoundError:
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// diagnosticParams returns the values of the mandatory query parameters
+func diagnosticParams(r *http.Request, names ...string) ([]string, error) {
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			return nil, types.BadRequestErrorf("missing %s parameter", name)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// diagnosticRequest decodes the body of a request modifying the state, the
+// table and key are mandatory for the entry requests
+func diagnosticRequest(r *http.Request, entry bool) (*DiagnosticRequest, error) {
+	var req DiagnosticRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, types.BadRequestErrorf("invalid request body: %v", err)
+	}
+	if req.Network == "" {
+		return nil, types.BadRequestErrorf("missing nid field")
+	}
+	if entry && req.Table == "" {
+		return nil, types.BadRequestErrorf("missing tname field")
+	}
+	if entry && req.Key == "" {
+		return nil, types.BadRequestErrorf("missing key field")
+	}
+	return &req, nil
+}
+
+func diagnoseMembers(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	nDB.RLock()
+	members := make([]DiagnosticNode, 0, len(nDB.nodes)+len(nDB.failedNodes)+len(nDB.leftNodes))
+	for state, nodes := range map[string]map[string]*node{
+		nodeActive: nDB.nodes,
+		nodeFailed: nDB.failedNodes,
+		nodeLeft:   nDB.leftNodes,
+	} {
+		for _, n := range nodes {
+			m := DiagnosticNode{
+				Name:  n.Name,
+				Addr:  n.Addr.String(),
+				Port:  n.Port,
+				State: state,
+				Self:  n.Name == nDB.config.NodeName,
+				LTime: uint64(n.ltime),
+			}
+			if state != nodeActive {
+				m.ReapTime = n.reapTime.String()
+			}
+			members = append(members, m)
+		}
+	}
+	nDB.RUnlock()
+
+	sort.Sort(byNodeName(members))
+	writeDiagnostic(w, members)
+}
+
+func diagnoseNetworks(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	nDB.RLock()
+	ids := map[string]bool{}
+	for nid := range nDB.networkNodes {
+		ids[nid] = true
+	}
+	for _, nodeNetworks := range nDB.networks {
+		for nid := range nodeNetworks {
+			ids[nid] = true
+		}
+	}
+
+	networks := make([]DiagnosticNetwork, 0, len(ids))
+	for nid := range ids {
+		dn := DiagnosticNetwork{
+			ID:          nid,
+			Nodes:       append([]string{}, nDB.networkNodes[nid]...),
+			Attachments: []DiagnosticAttachment{},
+		}
+		for nodeName, nodeNetworks := range nDB.networks {
+			n, ok := nodeNetworks[nid]
+			if !ok {
+				continue
+			}
+			a := DiagnosticAttachment{
+				Node:     nodeName,
+				LTime:    uint64(n.ltime),
+				Leaving:  n.leaving,
+				Restored: n.restored,
+			}
+			if n.leaving {
+				a.ReapTime = n.reapTime.String()
+			}
+			dn.Attachments = append(dn.Attachments, a)
+		}
+		sort.Strings(dn.Nodes)
+		sort.Sort(byAttachmentNode(dn.Attachments))
+		networks = append(networks, dn)
+	}
+	nDB.RUnlock()
+
+	sort.Sort(byNetworkID(networks))
+	writeDiagnostic(w, networks)
+}
+
+func diagnoseTable(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	params, err := diagnosticParams(r, "tname")
+	if err != nil {
+		diagnosticError(w, err)
+		return
+	}
+	tname := params[0]
+
+	prefix := fmt.Sprintf("/%s/", tname)
+	if nid := r.URL.Query().Get("nid"); nid != "" {
+		prefix = fmt.Sprintf("/%s/%s/", tname, nid)
+	}
+
+	entries := []DiagnosticEntry{}
+	nDB.RLock()
+	nDB.indexes[byTable].WalkPrefix(prefix, func(path string, v interface{}) bool {
+		e := v.(*entry)
+		params := strings.SplitN(path[1:], "/", 3)
+		de := DiagnosticEntry{
+			Table:    params[0],
+			Network:  params[1],
+			Key:      params[2],
+			Value:    e.value,
+			Owner:    e.node,
+			LTime:    uint64(e.ltime),
+			Deleting: e.deleting,
+			Restored: e.restored,
+		}
+		if e.deleting {
+			de.ReapTime = e.reapTime.String()
+		}
+		entries = append(entries, de)
+		return false
+	})
+	nDB.RUnlock()
+
+	writeDiagnostic(w, entries)
+}
+
+func diagnoseQueues(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	queues := DiagnosticQueues{
+		Node:    nDB.nodeBroadcasts.NumQueued(),
+		Network: nDB.networkBroadcasts.NumQueued(),
+		Tables:  map[string]int{},
+	}
+
+	nDB.RLock()
+	for nid, n := range nDB.networks[nDB.config.NodeName] {
+		if n.tableBroadcasts != nil {
+			queues.Tables[nid] = n.tableBroadcasts.NumQueued()
+		}
+	}
+	nDB.RUnlock()
+
+	writeDiagnostic(w, queues)
+}
+
+func diagnoseMetrics(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	m := nDB.Metrics()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := m.WritePrometheus(w); err != nil {
+		logrus.Warnf("Failed to write networkdb metrics: %v", err)
+	}
+}
+
+func diagnoseCreateEntry(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	req, err := diagnosticRequest(r, true)
+	if err != nil {
+		diagnosticError(w, err)
+		return
+	}
+	if err := nDB.CreateEntry(req.Table, req.Network, req.Key, []byte(req.Value)); err != nil {
+		diagnosticError(w, err)
+	}
+}
+
+func diagnoseUpdateEntry(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	req, err := diagnosticRequest(r, true)
+	if err != nil {
+		diagnosticError(w, err)
+		return
+	}
+	if err := nDB.UpdateEntry(req.Table, req.Network, req.Key, []byte(req.Value)); err != nil {
+		diagnosticError(w, err)
+	}
+}
+
+func diagnoseDeleteEntry(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	req, err := diagnosticRequest(r, true)
+	if err != nil {
+		diagnosticError(w, err)
+		return
+	}
+	if err := nDB.DeleteEntry(req.Table, req.Network, req.Key); err != nil {
+		diagnosticError(w, err)
+	}
+}
+
+func diagnoseJoinNetwork(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	req, err := diagnosticRequest(r, false)
+	if err != nil {
+		diagnosticError(w, err)
+		return
+	}
+	if err := nDB.JoinNetwork(req.Network); err != nil {
+		diagnosticError(w, err)
+	}
+}
+
+func diagnoseLeaveNetwork(nDB *NetworkDB, w http.ResponseWriter, r *http.Request) {
+	req, err := diagnosticRequest(r, false)
+	if err != nil {
+		diagnosticError(w, err)
+		return
+	}
+	if err := nDB.LeaveNetwork(req.Network); err != nil {
+		diagnosticError(w, err)
+	}
+}
+
+type byNodeName []DiagnosticNode
+
+func (s byNodeName) Len() int           { return len(s) }
+func (s byNodeName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byNodeName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+type byAttachmentNode []DiagnosticAttachment
+
+func (s byAttachmentNode) Len() int           { return len(s) }
+func (s byAttachmentNode) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byAttachmentNode) Less(i, j int) bool { return s[i].Node < s[j].Node }
+
+type byNetworkID []DiagnosticNetwork
+
+func (s byNetworkID) Len() int           { return len(s) }
+func (s byNetworkID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byNetworkID) Less(i, j int) bool { return s[i].ID < s[j].ID }
@@ -0,0 +1,183 @@
+package networkdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diagnosticTestHost = "127.0.0.1:2000"
+
+func serveDiagnostic(db *NetworkDB, req *http.Request) *httptest.ResponseRecorder {
+	req.Host = diagnosticTestHost
+	rec := httptest.NewRecorder()
+	db.diagnosticMux(diagnosticTestHost).ServeHTTP(rec, req)
+	return rec
+}
+
+func diagnose(t *testing.T, db *NetworkDB, method, url string, v interface{}) int {
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+
+	rec := serveDiagnostic(db, req)
+	if v != nil && rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	return rec.Code
+}
+
+func diagnoseWrite(t *testing.T, db *NetworkDB, url string, dr DiagnosticRequest) int {
+	b, err := json.Marshal(dr)
+	require.NoError(t, err)
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	return serveDiagnostic(db, req).Code
+}
+
+func TestDiagnosticAddr(t *testing.T) {
+	for addr, expected := range map[string]string{
+		":2000":           "127.0.0.1:2000",
+		"127.0.0.1:2000":  "127.0.0.1:2000",
+		"localhost:2000":  "localhost:2000",
+		"[::1]:2000":      "[::1]:2000",
+		"0.0.0.0:2000":    "",
+		"10.0.0.1:2000":   "",
+		"127.0.0.1":       "",
+		"somehost:2000":   "",
+		"[::]:2000":       "",
+		"127.0.0.2:20000": "127.0.0.2:20000",
+	} {
+		a, err := validateDiagnosticAddr(addr)
+		if expected == "" {
+			assert.Error(t, err, "expected failure for %s", addr)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, expected, a)
+	}
+}
+
+func TestDiagnosticServer(t *testing.T) {
+	db, err := New(&Config{
+		NodeName:       "node1",
+		BindPort:       int(atomic.AddInt32(&dbPort, 1)),
+		DiagnosticAddr: "127.0.0.1:0",
+	})
+	require.NoError(t, err)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/members", db.diagnosticListener.Addr()))
+	require.NoError(t, err)
+	var members []DiagnosticNode
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&members))
+	resp.Body.Close()
+	require.Len(t, members, 1)
+	assert.Equal(t, "node1", members[0].Name)
+	assert.Equal(t, true, members[0].Self)
+
+	addr := db.diagnosticListener.Addr().String()
+	db.Close()
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+}
+
+func TestDiagnosticState(t *testing.T) {
+	dbs := createNetworkDBInstancesWithConfig(t, 2, "node", &Config{DiagnosticWrite: true})
+
+	assert.Equal(t, http.StatusOK, diagnoseWrite(t, dbs[0], "/network/join", DiagnosticRequest{Network: "network1"}))
+	dbs[1].verifyNetworkExistence(t, "node1", "network1", true)
+	assert.Equal(t, http.StatusOK, diagnoseWrite(t, dbs[1], "/network/join", DiagnosticRequest{Network: "network1"}))
+	dbs[0].verifyNetworkExistence(t, "node2", "network1", true)
+
+	entry := DiagnosticRequest{Table: "test_table", Network: "network1", Key: "test_key", Value: "test_value"}
+	assert.Equal(t, http.StatusOK, diagnoseWrite(t, dbs[0], "/entry/create", entry))
+	dbs[1].verifyEntryExistence(t, "test_table", "network1", "test_key", "test_value", true)
+
+	var members []DiagnosticNode
+	assert.Equal(t, http.StatusOK, diagnose(t, dbs[1], "GET", "/members", &members))
+	require.Len(t, members, 2)
+	assert.Equal(t, "node1", members[0].Name)
+	assert.Equal(t, nodeActive, members[0].State)
+	assert.Equal(t, false, members[0].Self)
+	assert.Equal(t, true, members[1].Self)
+
+	var networks []DiagnosticNetwork
+	assert.Equal(t, http.StatusOK, diagnose(t, dbs[1], "GET", "/networks", &networks))
+	require.Len(t, networks, 1)
+	assert.Equal(t, "network1", networks[0].ID)
+	assert.Equal(t, []string{"node1", "node2"}, networks[0].Nodes)
+	require.Len(t, networks[0].Attachments, 2)
+
+	var entries []DiagnosticEntry
+	assert.Equal(t, http.StatusOK, diagnose(t, dbs[1], "GET", "/table?tname=test_table&nid=network1", &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "test_key", entries[0].Key)
+	assert.Equal(t, "test_value", string(entries[0].Value))
+	assert.Equal(t, "node1", entries[0].Owner)
+	assert.Equal(t, false, entries[0].Deleting)
+
+	var queues DiagnosticQueues
+	assert.Equal(t, http.StatusOK, diagnose(t, dbs[0], "GET", "/queues", &queues))
+	_, ok := queues.Tables["network1"]
+	assert.Equal(t, true, ok)
+
+	assert.Equal(t, http.StatusOK, diagnoseWrite(t, dbs[0], "/entry/delete", entry))
+	dbs[1].verifyEntryExistence(t, "test_table", "network1", "test_key", "", false)
+	entries = nil
+	assert.Equal(t, http.StatusOK, diagnose(t, dbs[0], "GET", "/table?tname=test_table", &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, true, entries[0].Deleting)
+	assert.NotEmpty(t, entries[0].ReapTime)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, diagnose(t, dbs[0], "GET", "/entry/create?tname=test_table&nid=network1&key=test_key", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, diagnose(t, dbs[0], "POST", "/members", nil))
+	assert.Equal(t, http.StatusBadRequest, diagnose(t, dbs[0], "GET", "/table", nil))
+	assert.Equal(t, http.StatusBadRequest, diagnoseWrite(t, dbs[0], "/entry/create", DiagnosticRequest{Table: "test_table", Network: "network1"}))
+
+	assert.Equal(t, http.StatusOK, diagnoseWrite(t, dbs[0], "/network/leave", DiagnosticRequest{Network: "network1"}))
+	dbs[1].verifyNetworkExistence(t, "node1", "network1", false)
+
+	closeNetworkDBInstances(dbs)
+}
+
+func TestDiagnosticWriteProtection(t *testing.T) {
+	dbs := createNetworkDBInstancesWithConfig(t, 1, "node", &Config{DiagnosticWrite: true})
+	db := dbs[0]
+
+	// A form post a browser could send from any origin
+	req, err := http.NewRequest("POST", "/network/join", strings.NewReader("nid=network1"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusUnsupportedMediaType, serveDiagnostic(db, req).Code)
+
+	req, err = http.NewRequest("POST", "/network/join?nid=network1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnsupportedMediaType, serveDiagnostic(db, req).Code)
+
+	// A request addressed to another host, as after a DNS rebinding
+	req, err = http.NewRequest("GET", "/members", nil)
+	require.NoError(t, err)
+	req.Host = "attacker.example.com:2000"
+	rec := httptest.NewRecorder()
+	db.diagnosticMux(diagnosticTestHost).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	_, ok := db.networks[db.config.NodeName]["network1"]
+	assert.Equal(t, false, ok)
+
+	db.config.DiagnosticWrite = false
+	assert.Equal(t, http.StatusForbidden, diagnoseWrite(t, db, "/network/join", DiagnosticRequest{Network: "network1"}))
+	assert.Equal(t, http.StatusOK, diagnose(t, db, "GET", "/members", nil))
+
+	closeNetworkDBInstances(dbs)
+}
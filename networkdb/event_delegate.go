@@ -30,7 +30,7 @@ func (e *eventDelegate) NotifyLeave(mn *memberlist.Node) {
 	if n, ok := e.nDB.nodes[mn.Name]; ok {
 		delete(e.nDB.nodes, mn.Name)
 
-		n.reapTime = reapInterval
+		n.reapTime = e.nDB.config.ReapNodeInterval
 		e.nDB.failedNodes[mn.Name] = n
 	}
 	e.nDB.Unlock()
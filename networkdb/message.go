@@ -1,6 +1,12 @@
 package networkdb
 
-import "github.com/gogo/protobuf/proto"
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/gogo/protobuf/proto"
+)
 
 const (
 	// Max udp message size chosen to avoid network packet
@@ -100,3 +106,30 @@ func decodeCompoundMessage(buf []byte) ([][]byte, error) {
 
 	return parts, nil
 }
+
+// compressPayload compresses a bulk sync payload with gzip
+func compressPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressPayload decompresses a bulk sync payload compressed with
+// compressPayload
+func decompressPayload(payload []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,328 @@
+package networkdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the message types in the metrics
+const (
+	MetricsNode     = "node"
+	MetricsNetwork  = "network"
+	MetricsTable    = "table"
+	MetricsBulkSync = "bulk_sync"
+)
+
+var metricsTypes = map[MessageType]string{
+	MessageTypeNodeEvent:    MetricsNode,
+	MessageTypeNetworkEvent: MetricsNetwork,
+	MessageTypeTableEvent:   MetricsTable,
+	MessageTypeBulkSync:     MetricsBulkSync,
+}
+
+// Upper bounds of the histogram buckets, gossip and bulk syncs converge
+// within seconds on a healthy cluster
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// Bucket is a histogram bucket, it counts the observations less than or
+// equal to its upper bound
+type Bucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// Histogram is the distribution of a duration
+type Histogram struct {
+	// Cumulative buckets, the observations above the last bound are
+	// only reflected by Count
+	Buckets []Bucket
+	Count   uint64
+	Sum     time.Duration
+}
+
+// Metrics is a snapshot of the counters and gauges of a NetworkDB
+// instance.
+type Metrics struct {
+	// Messages sent and received by message type. The table events
+	// carried by bulk syncs are not counted as table messages.
+	MessagesSent     map[string]uint64
+	MessagesReceived map[string]uint64
+
+	// Number of messages waiting in the node and network event
+	// broadcast queues
+	NodeQueueDepth    int
+	NetworkQueueDepth int
+	// Number of messages waiting in the table event broadcast queues
+	// by network
+	TableQueueDepth map[string]int
+
+	// Bulk syncs initiated by this node, failures included
+	BulkSyncs        uint64
+	BulkSyncFailures uint64
+	// Time taken by the bulk syncs initiated by this node to be
+	// acknowledged by the remote node
+	BulkSyncDuration Histogram
+
+	// Number of live entries by table and network
+	Entries map[string]map[string]int
+
+	// Time from the creation or update of the entries on their owner
+	// node to their visibility on this node. It relies on the clocks
+	// of the nodes being synchronized.
+	ConvergenceLatency Histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+	for i, bound := range latencyBuckets {
+		if d <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += d
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Buckets: make([]Bucket, len(latencyBuckets)),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+	for i, bound := range latencyBuckets {
+		s.Buckets[i].UpperBound = bound
+		if h.counts != nil {
+			s.Buckets[i].Count = h.counts[i]
+		}
+	}
+	return s
+}
+
+// metrics holds the counters updated along the gossip and bulk sync
+// paths, the gauges are computed when taking a snapshot
+type metrics struct {
+	sync.Mutex
+	sent             map[string]uint64
+	received         map[string]uint64
+	bulkSyncs        uint64
+	bulkSyncFailures uint64
+	bulkSyncDuration histogram
+	convergence      histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		sent:     make(map[string]uint64),
+		received: make(map[string]uint64),
+	}
+}
+
+func (m *metrics) messageSent(mType string, count int) {
+	m.Lock()
+	m.sent[mType] += uint64(count)
+	m.Unlock()
+}
+
+func (m *metrics) messageReceived(mType MessageType) {
+	name, ok := metricsTypes[mType]
+	if !ok {
+		return
+	}
+	m.Lock()
+	m.received[name]++
+	m.Unlock()
+}
+
+func (m *metrics) bulkSyncDone(d time.Duration, err error) {
+	m.Lock()
+	m.bulkSyncs++
+	if err != nil {
+		m.bulkSyncFailures++
+	} else {
+		m.bulkSyncDuration.observe(d)
+	}
+	m.Unlock()
+}
+
+// entryVisible records the convergence latency of an entry created at
+// the given time, in nanoseconds since the epoch
+func (m *metrics) entryVisible(createdAt int64) {
+	if createdAt == 0 {
+		return
+	}
+	d := time.Duration(time.Now().UnixNano() - createdAt)
+	if d < 0 {
+		d = 0
+	}
+	m.Lock()
+	m.convergence.observe(d)
+	m.Unlock()
+}
+
+// Metrics returns a snapshot of the metrics of the NetworkDB instance.
+func (nDB *NetworkDB) Metrics() Metrics {
+	m := Metrics{
+		MessagesSent:      make(map[string]uint64),
+		MessagesReceived:  make(map[string]uint64),
+		NodeQueueDepth:    nDB.nodeBroadcasts.NumQueued(),
+		NetworkQueueDepth: nDB.networkBroadcasts.NumQueued(),
+		TableQueueDepth:   make(map[string]int),
+		Entries:           make(map[string]map[string]int),
+	}
+
+	for _, name := range metricsTypes {
+		m.MessagesSent[name] = 0
+		m.MessagesReceived[name] = 0
+	}
+
+	nDB.metrics.Lock()
+	for name, count := range nDB.metrics.sent {
+		m.MessagesSent[name] = count
+	}
+	for name, count := range nDB.metrics.received {
+		m.MessagesReceived[name] = count
+	}
+	m.BulkSyncs = nDB.metrics.bulkSyncs
+	m.BulkSyncFailures = nDB.metrics.bulkSyncFailures
+	m.BulkSyncDuration = nDB.metrics.bulkSyncDuration.snapshot()
+	m.ConvergenceLatency = nDB.metrics.convergence.snapshot()
+	nDB.metrics.Unlock()
+
+	nDB.RLock()
+	for nid, n := range nDB.networks[nDB.config.NodeName] {
+		if n.tableBroadcasts != nil {
+			m.TableQueueDepth[nid] = n.tableBroadcasts.NumQueued()
+		}
+	}
+	nDB.indexes[byTable].Walk(func(path string, v interface{}) bool {
+		if v.(*entry).deleting {
+			return false
+		}
+		params := strings.Split(path[1:], "/")
+		tname, nid := params[0], params[1]
+		if m.Entries[tname] == nil {
+			m.Entries[tname] = make(map[string]int)
+		}
+		m.Entries[tname][nid]++
+		return false
+	})
+	nDB.RUnlock()
+
+	return m
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	writeHeader := func(name, help, mType string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, mType)
+	}
+
+	writeHeader("networkdb_messages_sent_total", "Gossip messages sent by message type.", "counter")
+	for _, name := range sortedKeys(m.MessagesSent) {
+		fmt.Fprintf(bw, "networkdb_messages_sent_total{type=%s} %d\n", quoteLabel(name), m.MessagesSent[name])
+	}
+
+	writeHeader("networkdb_messages_received_total", "Gossip messages received by message type.", "counter")
+	for _, name := range sortedKeys(m.MessagesReceived) {
+		fmt.Fprintf(bw, "networkdb_messages_received_total{type=%s} %d\n", quoteLabel(name), m.MessagesReceived[name])
+	}
+
+	writeHeader("networkdb_queue_depth", "Messages waiting in the node and network event broadcast queues.", "gauge")
+	fmt.Fprintf(bw, "networkdb_queue_depth{queue=%s} %d\n", quoteLabel(MetricsNode), m.NodeQueueDepth)
+	fmt.Fprintf(bw, "networkdb_queue_depth{queue=%s} %d\n", quoteLabel(MetricsNetwork), m.NetworkQueueDepth)
+
+	writeHeader("networkdb_table_queue_depth", "Messages waiting in the table event broadcast queues by network.", "gauge")
+	nids := make([]string, 0, len(m.TableQueueDepth))
+	for nid := range m.TableQueueDepth {
+		nids = append(nids, nid)
+	}
+	sort.Strings(nids)
+	for _, nid := range nids {
+		fmt.Fprintf(bw, "networkdb_table_queue_depth{network=%s} %d\n", quoteLabel(nid), m.TableQueueDepth[nid])
+	}
+
+	writeHeader("networkdb_bulk_syncs_total", "Bulk syncs initiated by this node.", "counter")
+	fmt.Fprintf(bw, "networkdb_bulk_syncs_total %d\n", m.BulkSyncs)
+	writeHeader("networkdb_bulk_sync_failures_total", "Bulk syncs initiated by this node which failed.", "counter")
+	fmt.Fprintf(bw, "networkdb_bulk_sync_failures_total %d\n", m.BulkSyncFailures)
+	writeHeader("networkdb_bulk_sync_duration_seconds", "Time for the bulk syncs initiated by this node to be acknowledged.", "histogram")
+	writeHistogram(bw, "networkdb_bulk_sync_duration_seconds", m.BulkSyncDuration)
+
+	writeHeader("networkdb_entries", "Live table entries by table and network.", "gauge")
+	tables := make([]string, 0, len(m.Entries))
+	for tname := range m.Entries {
+		tables = append(tables, tname)
+	}
+	sort.Strings(tables)
+	for _, tname := range tables {
+		nids := make([]string, 0, len(m.Entries[tname]))
+		for nid := range m.Entries[tname] {
+			nids = append(nids, nid)
+		}
+		sort.Strings(nids)
+		for _, nid := range nids {
+			fmt.Fprintf(bw, "networkdb_entries{table=%s,network=%s} %d\n", quoteLabel(tname), quoteLabel(nid), m.Entries[tname][nid])
+		}
+	}
+
+	writeHeader("networkdb_convergence_latency_seconds", "Time from the creation or update of the entries on their owner node to their visibility on this node.", "histogram")
+	writeHistogram(bw, "networkdb_convergence_latency_seconds", m.ConvergenceLatency)
+
+	return bw.Flush()
+}
+
+func writeHistogram(w io.Writer, name string, h Histogram) {
+	for _, b := range h.Buckets {
+		fmt.Fprintf(w, "%s_bucket{le=%s} %d\n", name, quoteLabel(formatSeconds(b.UpperBound)), b.Count)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, formatSeconds(h.Sum))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.Count)
+}
+
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%g", d.Seconds())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel quotes a label value the way the exposition format expects
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
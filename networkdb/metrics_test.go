@@ -0,0 +1,148 @@
+package networkdb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHistogram(t *testing.T) {
+	var h histogram
+	for _, d := range []time.Duration{5 * time.Millisecond, 200 * time.Millisecond, time.Minute} {
+		h.observe(d)
+	}
+
+	s := h.snapshot()
+	assert.Equal(t, uint64(3), s.Count)
+	assert.Equal(t, time.Minute+205*time.Millisecond, s.Sum)
+	require.Len(t, s.Buckets, len(latencyBuckets))
+	for _, b := range s.Buckets {
+		switch {
+		case b.UpperBound < 200*time.Millisecond:
+			assert.Equal(t, uint64(1), b.Count, "bucket %s", b.UpperBound)
+		default:
+			assert.Equal(t, uint64(2), b.Count, "bucket %s", b.UpperBound)
+		}
+	}
+}
+
+func TestMetricsPrometheus(t *testing.T) {
+	var h histogram
+	h.observe(20 * time.Millisecond)
+
+	m := Metrics{
+		MessagesSent:      map[string]uint64{MetricsTable: 4, MetricsNode: 1},
+		MessagesReceived:  map[string]uint64{MetricsTable: 2},
+		NodeQueueDepth:    3,
+		NetworkQueueDepth: 0,
+		TableQueueDepth:   map[string]int{"network1": 5},
+		BulkSyncs:         2,
+		BulkSyncFailures:  1,
+		BulkSyncDuration:  h.snapshot(),
+		Entries:           map[string]map[string]int{"test_table": {"net\"work": 7}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, m.WritePrometheus(&buf))
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE networkdb_messages_sent_total counter",
+		`networkdb_messages_sent_total{type="node"} 1`,
+		`networkdb_messages_sent_total{type="table"} 4`,
+		`networkdb_messages_received_total{type="table"} 2`,
+		`networkdb_queue_depth{queue="node"} 3`,
+		`networkdb_queue_depth{queue="network"} 0`,
+		`networkdb_table_queue_depth{network="network1"} 5`,
+		"networkdb_bulk_syncs_total 2",
+		"networkdb_bulk_sync_failures_total 1",
+		"# TYPE networkdb_bulk_sync_duration_seconds histogram",
+		`networkdb_bulk_sync_duration_seconds_bucket{le="0.01"} 0`,
+		`networkdb_bulk_sync_duration_seconds_bucket{le="0.05"} 1`,
+		`networkdb_bulk_sync_duration_seconds_bucket{le="+Inf"} 1`,
+		"networkdb_bulk_sync_duration_seconds_sum 0.02",
+		"networkdb_bulk_sync_duration_seconds_count 1",
+		`networkdb_entries{table="test_table",network="net\"work"} 7`,
+		`networkdb_convergence_latency_seconds_bucket{le="+Inf"} 0`,
+		"networkdb_convergence_latency_seconds_count 0",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		assert.Equal(t, 2, len(strings.SplitN(line, " ", 3)), "malformed sample %q", line)
+	}
+}
+
+func TestNetworkDBMetrics(t *testing.T) {
+	dbs := createNetworkDBInstances(t, 2, "node")
+
+	require.NoError(t, dbs[0].JoinNetwork("network1"))
+	dbs[1].verifyNetworkExistence(t, "node1", "network1", true)
+	require.NoError(t, dbs[1].JoinNetwork("network1"))
+	dbs[0].verifyNetworkExistence(t, "node2", "network1", true)
+
+	require.NoError(t, dbs[0].CreateEntry("test_table", "network1", "test_key", []byte("test_value")))
+	dbs[1].verifyEntryExistence(t, "test_table", "network1", "test_key", "test_value", true)
+
+	m := dbs[1].Metrics()
+	assert.Equal(t, 1, m.Entries["test_table"]["network1"])
+	assert.Equal(t, uint64(1), m.ConvergenceLatency.Count)
+	assert.NotEqual(t, uint64(0), m.MessagesReceived[MetricsNetwork])
+	assert.NotEqual(t, uint64(0), m.BulkSyncs)
+	assert.Equal(t, m.BulkSyncs, m.BulkSyncDuration.Count+m.BulkSyncFailures)
+	_, ok := m.TableQueueDepth["network1"]
+	assert.Equal(t, true, ok)
+
+	m = dbs[0].Metrics()
+	assert.Equal(t, 1, m.Entries["test_table"]["network1"])
+	assert.NotEqual(t, uint64(0), m.MessagesSent[MetricsNetwork])
+	assert.NotEqual(t, uint64(0), m.MessagesSent[MetricsBulkSync])
+	assert.NotEqual(t, uint64(0), m.MessagesReceived[MetricsBulkSync])
+
+	require.NoError(t, dbs[0].DeleteEntry("test_table", "network1", "test_key"))
+	dbs[1].verifyEntryExistence(t, "test_table", "network1", "test_key", "", false)
+	m = dbs[1].Metrics()
+	assert.Equal(t, 0, m.Entries["test_table"]["network1"])
+	assert.Equal(t, uint64(1), m.ConvergenceLatency.Count)
+
+	var buf bytes.Buffer
+	require.NoError(t, m.WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), "networkdb_convergence_latency_seconds_count 1\n")
+
+	closeNetworkDBInstances(dbs)
+}
+
+func TestBulkSyncConvergence(t *testing.T) {
+	dbs := createNetworkDBInstances(t, 1, "node")
+	db := dbs[0]
+	require.NoError(t, db.JoinNetwork("network1"))
+
+	tEvent := &TableEvent{
+		Type:      TableEventTypeCreate,
+		LTime:     1,
+		NodeName:  "node2",
+		NetworkID: "network1",
+		TableName: "test_table",
+		Key:       "test_key",
+		Value:     []byte("test_value"),
+		CreatedAt: time.Now().Add(-time.Minute).UnixNano(),
+	}
+	db.handleTableEvent(tEvent, true)
+	assert.Equal(t, 1, db.Metrics().Entries["test_table"]["network1"])
+	assert.Equal(t, uint64(0), db.Metrics().ConvergenceLatency.Count)
+
+	tEvent.Type = TableEventTypeUpdate
+	tEvent.LTime = 2
+	db.handleTableEvent(tEvent, false)
+	assert.Equal(t, uint64(1), db.Metrics().ConvergenceLatency.Count)
+
+	closeNetworkDBInstances(dbs)
+}
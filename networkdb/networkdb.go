@@ -4,6 +4,7 @@ package networkdb
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/armon/go-radix"
 	"github.com/docker/go-events"
+	"github.com/docker/libnetwork/datastore"
 	"github.com/docker/libnetwork/types"
 	"github.com/hashicorp/memberlist"
 	"github.com/hashicorp/serf/serf"
@@ -64,6 +66,9 @@ type NetworkDB struct {
 	// waiting for an ack.
 	bulkSyncAckTbl map[string]chan struct{}
 
+	// The chunked bulk syncs being received.
+	bulkSyncChunks map[bulkSyncID]*bulkSyncChunks
+
 	// Global lamport clock for node network attach events.
 	networkClock serf.LamportClock
 
@@ -90,6 +95,31 @@ type NetworkDB struct {
 
 	// Reference to the memberlist's keyring to add & remove keys
 	keyring *memberlist.Keyring
+
+	// Listener of the diagnostic server, if enabled.
+	diagnosticListener net.Listener
+
+	// Counters of the gossip and bulk sync activity.
+	metrics *metrics
+}
+
+// bulkSyncID identifies a chunked bulk sync
+type bulkSyncID struct {
+	node string
+	id   uint64
+}
+
+// bulkSyncChunks tracks the chunks received for a chunked bulk sync
+type bulkSyncChunks struct {
+	received uint32
+
+	// Number of chunks of the bulk sync, known once the last chunk
+	// is received
+	chunks uint32
+
+	// Time left to receive the missing chunks before the bulk sync
+	// is given up
+	reapTime time.Duration
 }
 
 // PeerInfo represents the peer (gossip cluster) nodes of a network
@@ -120,6 +150,14 @@ type network struct {
 	// removed from networkDB
 	reapTime time.Duration
 
+	// The attachment was restored from a snapshot and has not been
+	// confirmed by the cluster yet.
+	restored bool
+
+	// Time left for the cluster to confirm a restored attachment
+	// before it gets removed from networkDB
+	restoreTime time.Duration
+
 	// The broadcast queue for table event gossip. This is only
 	// initialized for this node's network attachment entries.
 	tableBroadcasts *memberlist.TransmitLimitedQueue
@@ -146,6 +184,55 @@ type Config struct {
 	// Keys to be added to the Keyring of the memberlist. Key at index
 	// 0 is the primary key
 	Keys [][]byte
+
+	// DiagnosticAddr is the loopback address on which the diagnostic
+	// server listens. The server is disabled if it is empty.
+	DiagnosticAddr string
+
+	// DiagnosticWrite enables the endpoints of the diagnostic server
+	// which modify the state of NetworkDB, read only if false.
+	DiagnosticWrite bool
+
+	// Store is the local datastore in which NetworkDB periodically
+	// snapshots its state to rejoin the cluster faster after a
+	// restart. Snapshots are disabled if it is nil.
+	Store datastore.DataStore
+
+	// Profile is the preset of the timing settings, ProfileLAN if
+	// empty. The timing settings below take the value of the
+	// profile when left to zero.
+	Profile string
+
+	// ReapEntryInterval is the time the deleted table entries and
+	// the network attachments being left linger in the cluster.
+	ReapEntryInterval time.Duration
+
+	// ReapNodeInterval is the time a failed node is tried to be
+	// reconnected before it is removed.
+	ReapNodeInterval time.Duration
+
+	// GossipInterval is the period of the gossip of the node,
+	// network and table events.
+	GossipInterval time.Duration
+
+	// BulkSyncInterval is the period of the push-pull of the
+	// cluster state and of the bulk syncs of the tables with a
+	// random node.
+	BulkSyncInterval time.Duration
+
+	// RetransmitMult is the multiplier of the number of
+	// retransmissions of the gossiped events, which is
+	// RetransmitMult * log(N+1) for a cluster of N nodes.
+	RetransmitMult int
+
+	// BulkSyncChunkSize bounds the size of the table events sent in
+	// a single message by a bulk sync, 512KB if zero. The nodes which
+	// do not support chunked bulk syncs get a single message.
+	BulkSyncChunkSize int
+
+	// BulkSyncCompression enables the compression of the bulk syncs
+	// sent to the nodes which support it.
+	BulkSyncCompression bool
 }
 
 // entry defines a table entry
@@ -166,13 +253,31 @@ type entry struct {
 	// Number of seconds still left before a deleted table entry gets
 	// removed from networkDB
 	reapTime time.Duration
+
+	// Wall clock time in nanoseconds when the entry was created or
+	// updated on the node owning it
+	createdAt int64
+
+	// The entry was restored from a snapshot and has not been
+	// confirmed by the cluster yet. Restored entries are not
+	// propagated to the other nodes.
+	restored bool
+
+	// Time left for the cluster to confirm a restored entry before
+	// it gets purged
+	restoreTime time.Duration
 }
 
 // New creates a new instance of NetworkDB using the Config passed by
 // the caller.
 func New(c *Config) (*NetworkDB, error) {
+	config, err := c.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
 	nDB := &NetworkDB{
-		config:         c,
+		config:         config,
 		indexes:        make(map[int]*radix.Tree),
 		networks:       make(map[string]map[string]*network),
 		nodes:          make(map[string]*node),
@@ -180,16 +285,27 @@ func New(c *Config) (*NetworkDB, error) {
 		leftNodes:      make(map[string]*node),
 		networkNodes:   make(map[string][]string),
 		bulkSyncAckTbl: make(map[string]chan struct{}),
+		bulkSyncChunks: make(map[bulkSyncID]*bulkSyncChunks),
 		broadcaster:    events.NewBroadcaster(),
+		metrics:        newMetrics(),
 	}
 
 	nDB.indexes[byTable] = radix.New()
 	nDB.indexes[byNetwork] = radix.New()
 
+	if err := nDB.restoreSnapshot(); err != nil {
+		logrus.Errorf("%s: failed to restore networkdb snapshot: %v", config.NodeName, err)
+	}
+
 	if err := nDB.clusterInit(); err != nil {
 		return nil, err
 	}
 
+	if err := nDB.diagnosticInit(); err != nil {
+		nDB.Close()
+		return nil, err
+	}
+
 	return nDB, nil
 }
 
@@ -202,6 +318,8 @@ func (nDB *NetworkDB) Join(members []string) error {
 // Close destroys this NetworkDB instance by leave the cluster,
 // stopping timers, canceling goroutines etc.
 func (nDB *NetworkDB) Close() {
+	nDB.diagnosticStop()
+	nDB.saveSnapshot()
 	if err := nDB.clusterLeave(); err != nil {
 		logrus.Errorf("Could not close DB %s: %v", nDB.config.NodeName, err)
 	}
@@ -213,9 +331,15 @@ func (nDB *NetworkDB) Peers(nid string) []PeerInfo {
 	defer nDB.RUnlock()
 	peers := make([]PeerInfo, 0, len(nDB.networkNodes[nid]))
 	for _, nodeName := range nDB.networkNodes[nid] {
+		// The attachments restored from a snapshot can refer to
+		// nodes which did not rejoin yet
+		node, ok := nDB.nodes[nodeName]
+		if !ok {
+			continue
+		}
 		peers = append(peers, PeerInfo{
-			Name: nDB.nodes[nodeName].Name,
-			IP:   nDB.nodes[nodeName].Addr.String(),
+			Name: node.Name,
+			IP:   node.Addr.String(),
 		})
 	}
 	return peers
@@ -261,9 +385,10 @@ func (nDB *NetworkDB) CreateEntry(tname, nid, key string, value []byte) error {
 	}
 
 	entry := &entry{
-		ltime: nDB.tableClock.Increment(),
-		node:  nDB.config.NodeName,
-		value: value,
+		ltime:     nDB.tableClock.Increment(),
+		node:      nDB.config.NodeName,
+		value:     value,
+		createdAt: time.Now().UnixNano(),
 	}
 
 	if err := nDB.sendTableEvent(TableEventTypeCreate, nid, tname, key, entry); err != nil {
@@ -289,9 +414,10 @@ func (nDB *NetworkDB) UpdateEntry(tname, nid, key string, value []byte) error {
 	}
 
 	entry := &entry{
-		ltime: nDB.tableClock.Increment(),
-		node:  nDB.config.NodeName,
-		value: value,
+		ltime:     nDB.tableClock.Increment(),
+		node:      nDB.config.NodeName,
+		value:     value,
+		createdAt: time.Now().UnixNano(),
 	}
 
 	if err := nDB.sendTableEvent(TableEventTypeUpdate, nid, tname, key, entry); err != nil {
@@ -321,7 +447,7 @@ func (nDB *NetworkDB) DeleteEntry(tname, nid, key string) error {
 		node:     nDB.config.NodeName,
 		value:    value,
 		deleting: true,
-		reapTime: reapInterval,
+		reapTime: nDB.config.ReapEntryInterval,
 	}
 
 	if err := nDB.sendTableEvent(TableEventTypeDelete, nid, tname, key, entry); err != nil {
@@ -374,7 +500,7 @@ func (nDB *NetworkDB) deleteNodeTableEntries(node string) {
 			node:     node,
 			value:    oldEntry.value,
 			deleting: true,
-			reapTime: reapInterval,
+			reapTime: nDB.config.ReapEntryInterval,
 		}
 
 		nDB.indexes[byTable].Insert(fmt.Sprintf("/%s/%s/%s", tname, nid, key), entry)
@@ -431,7 +557,7 @@ func (nDB *NetworkDB) JoinNetwork(nid string) error {
 			nDB.RUnlock()
 			return num
 		},
-		RetransmitMult: 4,
+		RetransmitMult: nDB.config.RetransmitMult,
 	}
 	nDB.networkNodes[nid] = append(nDB.networkNodes[nid], nDB.config.NodeName)
 	networkNodes := nDB.networkNodes[nid]
@@ -276,6 +276,9 @@ type TableEvent struct {
 	Key string `protobuf:"bytes,6,opt,name=key,proto3" json:"key,omitempty"`
 	// Entry value.
 	Value []byte `protobuf:"bytes,7,opt,name=value,proto3" json:"value,omitempty"`
+	// Wall clock time in nanoseconds since the epoch when the entry
+	// was created or updated on the node owning it.
+	CreatedAt int64 `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
 }
 
 func (m *TableEvent) Reset()                    { *m = TableEvent{} }
@@ -296,6 +299,15 @@ type BulkSyncMessage struct {
 	Networks []string `protobuf:"bytes,4,rep,name=networks" json:"networks,omitempty"`
 	// Bulksync payload
 	Payload []byte `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
+	// Indicates if the payload is compressed with gzip.
+	Compressed bool `protobuf:"varint,6,opt,name=compressed,proto3" json:"compressed,omitempty"`
+	// Identifier of a bulk sync sent in multiple chunks. Zero if
+	// the bulk sync is sent in a single message.
+	SyncId uint64 `protobuf:"varint,7,opt,name=sync_id,json=syncId,proto3" json:"sync_id,omitempty"`
+	// Index of the chunk in a chunked bulk sync.
+	Chunk uint32 `protobuf:"varint,8,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	// Indicates if this is the last chunk of a chunked bulk sync.
+	LastChunk bool `protobuf:"varint,9,opt,name=last_chunk,json=lastChunk,proto3" json:"last_chunk,omitempty"`
 }
 
 func (m *BulkSyncMessage) Reset()                    { *m = BulkSyncMessage{} }
@@ -413,7 +425,7 @@ func (this *TableEvent) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 11)
+	s := make([]string, 0, 12)
 	s = append(s, "&networkdb.TableEvent{")
 	s = append(s, "Type: "+fmt.Sprintf("%#v", this.Type)+",\n")
 	s = append(s, "LTime: "+fmt.Sprintf("%#v", this.LTime)+",\n")
@@ -422,6 +434,7 @@ func (this *TableEvent) GoString() string {
 	s = append(s, "TableName: "+fmt.Sprintf("%#v", this.TableName)+",\n")
 	s = append(s, "Key: "+fmt.Sprintf("%#v", this.Key)+",\n")
 	s = append(s, "Value: "+fmt.Sprintf("%#v", this.Value)+",\n")
+	s = append(s, "CreatedAt: "+fmt.Sprintf("%#v", this.CreatedAt)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -429,13 +442,17 @@ func (this *BulkSyncMessage) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 9)
+	s := make([]string, 0, 13)
 	s = append(s, "&networkdb.BulkSyncMessage{")
 	s = append(s, "LTime: "+fmt.Sprintf("%#v", this.LTime)+",\n")
 	s = append(s, "Unsolicited: "+fmt.Sprintf("%#v", this.Unsolicited)+",\n")
 	s = append(s, "NodeName: "+fmt.Sprintf("%#v", this.NodeName)+",\n")
 	s = append(s, "Networks: "+fmt.Sprintf("%#v", this.Networks)+",\n")
 	s = append(s, "Payload: "+fmt.Sprintf("%#v", this.Payload)+",\n")
+	s = append(s, "Compressed: "+fmt.Sprintf("%#v", this.Compressed)+",\n")
+	s = append(s, "SyncId: "+fmt.Sprintf("%#v", this.SyncId)+",\n")
+	s = append(s, "Chunk: "+fmt.Sprintf("%#v", this.Chunk)+",\n")
+	s = append(s, "LastChunk: "+fmt.Sprintf("%#v", this.LastChunk)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -730,6 +747,11 @@ func (m *TableEvent) MarshalTo(data []byte) (int, error) {
 		i = encodeVarintNetworkdb(data, i, uint64(len(m.Value)))
 		i += copy(data[i:], m.Value)
 	}
+	if m.CreatedAt != 0 {
+		data[i] = 0x40
+		i++
+		i = encodeVarintNetworkdb(data, i, uint64(m.CreatedAt))
+	}
 	return i, nil
 }
 
@@ -790,6 +812,36 @@ func (m *BulkSyncMessage) MarshalTo(data []byte) (int, error) {
 		i = encodeVarintNetworkdb(data, i, uint64(len(m.Payload)))
 		i += copy(data[i:], m.Payload)
 	}
+	if m.Compressed {
+		data[i] = 0x30
+		i++
+		if m.Compressed {
+			data[i] = 1
+		} else {
+			data[i] = 0
+		}
+		i++
+	}
+	if m.SyncId != 0 {
+		data[i] = 0x38
+		i++
+		i = encodeVarintNetworkdb(data, i, uint64(m.SyncId))
+	}
+	if m.Chunk != 0 {
+		data[i] = 0x40
+		i++
+		i = encodeVarintNetworkdb(data, i, uint64(m.Chunk))
+	}
+	if m.LastChunk {
+		data[i] = 0x48
+		i++
+		if m.LastChunk {
+			data[i] = 1
+		} else {
+			data[i] = 0
+		}
+		i++
+	}
 	return i, nil
 }
 
@@ -991,6 +1043,9 @@ func (m *TableEvent) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovNetworkdb(uint64(l))
 	}
+	if m.CreatedAt != 0 {
+		n += 1 + sovNetworkdb(uint64(m.CreatedAt))
+	}
 	return n
 }
 
@@ -1017,6 +1072,18 @@ func (m *BulkSyncMessage) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovNetworkdb(uint64(l))
 	}
+	if m.Compressed {
+		n += 2
+	}
+	if m.SyncId != 0 {
+		n += 1 + sovNetworkdb(uint64(m.SyncId))
+	}
+	if m.Chunk != 0 {
+		n += 1 + sovNetworkdb(uint64(m.Chunk))
+	}
+	if m.LastChunk {
+		n += 2
+	}
 	return n
 }
 
@@ -1128,6 +1195,7 @@ func (this *TableEvent) String() string {
 		`TableName:` + fmt.Sprintf("%v", this.TableName) + `,`,
 		`Key:` + fmt.Sprintf("%v", this.Key) + `,`,
 		`Value:` + fmt.Sprintf("%v", this.Value) + `,`,
+		`CreatedAt:` + fmt.Sprintf("%v", this.CreatedAt) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1142,6 +1210,10 @@ func (this *BulkSyncMessage) String() string {
 		`NodeName:` + fmt.Sprintf("%v", this.NodeName) + `,`,
 		`Networks:` + fmt.Sprintf("%v", this.Networks) + `,`,
 		`Payload:` + fmt.Sprintf("%v", this.Payload) + `,`,
+		`Compressed:` + fmt.Sprintf("%v", this.Compressed) + `,`,
+		`SyncId:` + fmt.Sprintf("%v", this.SyncId) + `,`,
+		`Chunk:` + fmt.Sprintf("%v", this.Chunk) + `,`,
+		`LastChunk:` + fmt.Sprintf("%v", this.LastChunk) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -2027,6 +2099,25 @@ func (m *TableEvent) Unmarshal(data []byte) error {
 				m.Value = []byte{}
 			}
 			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CreatedAt", wireType)
+			}
+			m.CreatedAt = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNetworkdb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.CreatedAt |= (int64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipNetworkdb(data[iNdEx:])
@@ -2205,6 +2296,84 @@ func (m *BulkSyncMessage) Unmarshal(data []byte) error {
 				m.Payload = []byte{}
 			}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compressed", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNetworkdb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compressed = bool(v != 0)
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SyncId", wireType)
+			}
+			m.SyncId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNetworkdb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.SyncId |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunk", wireType)
+			}
+			m.Chunk = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNetworkdb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				m.Chunk |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LastChunk", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNetworkdb
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := data[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.LastChunk = bool(v != 0)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipNetworkdb(data[iNdEx:])
@@ -2494,61 +2663,66 @@ var (
 )
 
 var fileDescriptorNetworkdb = []byte{
-	// 887 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xcc, 0x96, 0xc1, 0x6e, 0xe3, 0x44,
-	0x18, 0xc7, 0xeb, 0xc4, 0x49, 0xe3, 0xaf, 0x0d, 0x1b, 0xbc, 0xdd, 0xad, 0xd7, 0x0b, 0x49, 0x31,
-	0xcb, 0x2a, 0x44, 0xe0, 0xa2, 0xee, 0x13, 0x24, 0xb1, 0x05, 0xd9, 0xf5, 0x3a, 0x91, 0x93, 0x14,
-	0x71, 0x8a, 0x9c, 0x78, 0x48, 0xac, 0x3a, 0xb6, 0x15, 0x3b, 0x45, 0x39, 0x81, 0x38, 0xad, 0x78,
-	0x07, 0x4e, 0xcb, 0x99, 0x07, 0xe0, 0xc0, 0x89, 0xc3, 0x8a, 0x13, 0xdc, 0x10, 0x87, 0x8a, 0xee,
-	0x13, 0xf0, 0x08, 0x8c, 0xc7, 0x76, 0x32, 0x4e, 0xa3, 0x5e, 0x40, 0xc0, 0xc1, 0xad, 0x67, 0xe6,
-	0xe7, 0xcf, 0xdf, 0xf7, 0x9f, 0xff, 0xe7, 0x09, 0xdc, 0x71, 0x51, 0xf8, 0x85, 0xb7, 0xb8, 0xb0,
-	0xc6, 0xb2, 0xbf, 0xf0, 0x42, 0x8f, 0xe7, 0xd6, 0x13, 0xe2, 0xd1, 0xd4, 0x9b, 0x7a, 0x64, 0xf6,
-	0x34, 0xba, 0x8b, 0x01, 0xa9, 0x0b, 0xe5, 0x8f, 0xbd, 0x20, 0xb0, 0xfd, 0xe7, 0x28, 0x08, 0xcc,
-	0x29, 0xe2, 0x1b, 0xc0, 0x86, 0x2b, 0x1f, 0x09, 0xcc, 0x09, 0x53, 0x7f, 0xe3, 0xec, 0xbe, 0xbc,
-	0x89, 0x98, 0x10, 0x03, 0xbc, 0x6a, 0x10, 0x86, 0xe7, 0x81, 0xb5, 0xcc, 0xd0, 0x14, 0x72, 0x98,
-	0x3d, 0x34, 0xc8, 0xbd, 0xf4, 0x32, 0x07, 0x9c, 0xee, 0x59, 0x48, 0xbd, 0x44, 0x6e, 0xc8, 0x7f,
-	0x98, 0x89, 0xf6, 0x80, 0x8a, 0xb6, 0x66, 0x64, 0x2a, 0x60, 0x07, 0x8a, 0xce, 0x28, 0xb4, 0xe7,
-	0x88, 0x84, 0x64, 0x5b, 0x67, 0xaf, 0xae, 0x6a, 0x7b, 0xbf, 0x5f, 0xd5, 0x1a, 0x53, 0x3b, 0x9c,
-	0x2d, 0xc7, 0xf2, 0xc4, 0x9b, 0x9f, 0xce, 0xcc, 0x60, 0x66, 0x4f, 0xbc, 0x85, 0x7f, 0x1a, 0xa0,
-	0xc5, 0xe7, 0xe4, 0x8f, 0xac, 0x99, 0x73, 0xdf, 0x5b, 0x84, 0x03, 0xfc, 0xa4, 0x51, 0x70, 0xa2,
-	0x7f, 0xfc, 0x43, 0xe0, 0x5c, 0xfc, 0x8a, 0x91, 0x6b, 0xe2, 0x68, 0x79, 0x1c, 0x8d, 0x33, 0x4a,
-	0xd1, 0x84, 0x8e, 0xc7, 0xd2, 0x97, 0xc0, 0x46, 0x6f, 0xe5, 0xdf, 0x83, 0xfd, 0x8e, 0x7e, 0xde,
-	0xd4, 0x3a, 0x4a, 0x65, 0x4f, 0x14, 0xbe, 0xf9, 0xf6, 0xe4, 0x68, 0x9d, 0x56, 0xb4, 0xde, 0x71,
-	0x2f, 0x4d, 0xc7, 0xb6, 0xf8, 0x1a, 0xb0, 0x4f, 0xbb, 0x1d, 0xbd, 0xc2, 0x88, 0xf7, 0x30, 0xf3,
-	0x66, 0x86, 0x79, 0xea, 0xd9, 0x2e, 0xff, 0x0e, 0x14, 0x34, 0xb5, 0x79, 0xae, 0x56, 0x72, 0xe2,
-	0x7d, 0x4c, 0xf0, 0x19, 0x42, 0x43, 0xe6, 0x25, 0x12, 0x0f, 0x5f, 0xbc, 0xac, 0xee, 0xfd, 0xf0,
-	0x5d, 0x95, 0xbc, 0x58, 0xba, 0xce, 0xc1, 0xa1, 0x1e, 0x6b, 0x11, 0x0b, 0xf5, 0x51, 0x46, 0xa8,
-	0xb7, 0x68, 0xa1, 0x28, 0xec, 0x3f, 0xd0, 0x8a, 0xff, 0x00, 0x20, 0x49, 0x66, 0x64, 0x5b, 0x02,
-	0x1b, 0xad, 0xb6, 0xca, 0xaf, 0xaf, 0x6a, 0x5c, 0x92, 0x58, 0x47, 0x31, 0x52, 0x97, 0x75, 0x2c,
-	0xe9, 0x05, 0x93, 0x48, 0x5b, 0xa7, 0xa5, 0x7d, 0x88, 0x45, 0x39, 0xa6, 0x0b, 0xa1, 0xd5, 0x95,
-	0xd6, 0xea, 0xc6, 0x3b, 0xb0, 0x85, 0x11, 0x81, 0x1f, 0x6d, 0x04, 0x7e, 0x80, 0xa1, 0x7b, 0xdb,
-	0xd0, 0x2e, 0x8d, 0x7f, 0x64, 0x36, 0x1a, 0xbb, 0xe1, 0x62, 0xb5, 0x55, 0x09, 0x73, 0x7b, 0x25,
-	0xff, 0x9a, 0xbe, 0x02, 0xec, 0x3b, 0x38, 0x7b, 0xdb, 0x9d, 0x12, 0x71, 0x4b, 0x46, 0x3a, 0x94,
-	0xbe, 0x67, 0xe0, 0x4e, 0x92, 0x5a, 0x6f, 0x19, 0xcc, 0x7a, 0x4b, 0xc7, 0xa1, 0xb2, 0x62, 0xfe,
-	0x6e, 0x56, 0x4f, 0xa0, 0x94, 0x54, 0x1b, 0xe0, 0x12, 0xf3, 0xf5, 0x83, 0xb3, 0xe3, 0x1d, 0xb6,
-	0x8b, 0x94, 0x33, 0xd6, 0xe0, 0xed, 0x6d, 0xf5, 0x73, 0x1e, 0x60, 0x60, 0x8e, 0x9d, 0xa4, 0xf9,
-	0xe5, 0x8c, 0xa7, 0x45, 0x2a, 0xf8, 0x06, 0xfa, 0xdf, 0x3b, 0x9a, 0x7f, 0x1b, 0x20, 0x8c, 0xd2,
-	0x8d, 0x63, 0x15, 0x48, 0x2c, 0x8e, 0xcc, 0x90, 0x60, 0x15, 0xc8, 0x5f, 0xa0, 0x95, 0x50, 0x24,
-	0xf3, 0xd1, 0x2d, 0x7f, 0x04, 0x05, 0x6c, 0xec, 0x25, 0x12, 0xf6, 0xc9, 0x67, 0x31, 0x1e, 0x44,
-	0x9b, 0x19, 0x37, 0xc6, 0x63, 0xba, 0x31, 0x88, 0x99, 0x37, 0x6a, 0xd0, 0x6d, 0xf1, 0x08, 0x8a,
-	0x6d, 0x43, 0x6d, 0x0e, 0xd4, 0xb4, 0x31, 0xb2, 0x58, 0x7b, 0x81, 0xcc, 0x10, 0x45, 0xd4, 0xb0,
-	0xa7, 0x44, 0x54, 0x6e, 0x17, 0x35, 0xf4, 0xad, 0x84, 0x52, 0x54, 0x4d, 0xc5, 0x54, 0x7e, 0x17,
-	0xa5, 0x20, 0x07, 0x85, 0xdb, 0xed, 0xf3, 0x2b, 0x76, 0x5f, 0x6b, 0xe9, 0x5c, 0xf4, 0x57, 0xee,
-	0x24, 0x3d, 0x1c, 0xfe, 0x41, 0xf7, 0x9d, 0xc0, 0xc1, 0xd2, 0x0d, 0x3c, 0xc7, 0x9e, 0xd8, 0x21,
-	0xb2, 0xc8, 0x8e, 0x97, 0x0c, 0x7a, 0xea, 0xf6, 0x3d, 0x14, 0x29, 0xf3, 0xb2, 0xd8, 0xbc, 0x1c,
-	0xe5, 0x51, 0xdc, 0x51, 0xbe, 0xb9, 0x72, 0x3c, 0xd3, 0x22, 0xdb, 0x75, 0x68, 0xa4, 0x43, 0xe9,
-	0x6b, 0x5c, 0x53, 0xdb, 0xc3, 0xb9, 0x2c, 0x5d, 0x2b, 0xad, 0x49, 0x81, 0xd2, 0x3c, 0xbe, 0x0d,
-	0x70, 0x55, 0x51, 0x1b, 0xd4, 0x29, 0xa7, 0x6e, 0xd1, 0x72, 0xdf, 0x9e, 0xfb, 0x0e, 0x4a, 0x46,
-	0xc6, 0xfa, 0x49, 0xf1, 0x7d, 0x28, 0x67, 0x96, 0xa2, 0x24, 0x7a, 0x49, 0x12, 0x4c, 0x26, 0x89,
-	0xc6, 0x4f, 0x39, 0x38, 0xa0, 0xce, 0x52, 0xfe, 0x5d, 0xda, 0x10, 0xe4, 0xf8, 0xa0, 0x56, 0x53,
-	0x37, 0xc8, 0x50, 0xd6, 0xd5, 0xc1, 0xa7, 0x5d, 0xe3, 0xd9, 0x48, 0x3d, 0x57, 0xf5, 0x01, 0x36,
-	0x05, 0xf9, 0xa8, 0x52, 0x68, 0xe6, 0x3c, 0x69, 0xc0, 0xc1, 0xa0, 0xd9, 0xd2, 0xd4, 0x84, 0x4e,
-	0x3e, 0x9b, 0x14, 0x4d, 0xf5, 0xe9, 0x63, 0xe0, 0x7a, 0xc3, 0xfe, 0x27, 0xa3, 0xde, 0x50, 0xd3,
-	0xb0, 0x41, 0x8e, 0x31, 0x79, 0x97, 0x22, 0xd7, 0xdf, 0x1e, 0xcc, 0xb5, 0x86, 0xda, 0xb3, 0x51,
-	0xff, 0x33, 0xbd, 0x5d, 0x61, 0x6f, 0x70, 0xa9, 0x59, 0xf0, 0xa9, 0x5a, 0x6a, 0x77, 0x9f, 0xf7,
-	0xba, 0x43, 0x5d, 0xa9, 0x14, 0x6e, 0x60, 0xa9, 0xa2, 0xf8, 0x84, 0x00, 0xbd, 0xab, 0xa4, 0x19,
-	0x16, 0x63, 0x63, 0xd2, 0xf5, 0xa4, 0x87, 0xa8, 0x78, 0x37, 0x31, 0x26, 0x2d, 0x5b, 0x4b, 0xf8,
-	0xed, 0xba, 0xba, 0xf7, 0xe7, 0x75, 0x95, 0xf9, 0xea, 0x75, 0x95, 0x79, 0x85, 0xaf, 0x5f, 0xf0,
-	0xf5, 0x07, 0xbe, 0xc6, 0x45, 0xf2, 0xd3, 0xe6, 0xc9, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x21,
-	0x78, 0x72, 0xc3, 0x0e, 0x09, 0x00, 0x00,
+	// 972 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x56, 0x4d, 0x6f, 0xe3, 0x44,
+	0x18, 0xee, 0xe4, 0xdb, 0x6f, 0x1b, 0x1a, 0x66, 0xbb, 0x5b, 0xaf, 0x17, 0x52, 0x13, 0x76, 0xab,
+	0x50, 0x41, 0x8a, 0xba, 0xbf, 0x20, 0x1f, 0x16, 0x64, 0x37, 0xeb, 0x44, 0x6e, 0x52, 0xc4, 0x29,
+	0x72, 0xe3, 0x21, 0xb1, 0xea, 0x2f, 0xc5, 0x4e, 0x50, 0x4e, 0x20, 0x4e, 0xab, 0xfc, 0x87, 0x9c,
+	0x96, 0x33, 0x3f, 0x80, 0x03, 0x5c, 0x38, 0xec, 0x91, 0x23, 0xe2, 0x50, 0xd1, 0xdc, 0xb8, 0xf1,
+	0x13, 0xd0, 0x8c, 0xed, 0x64, 0x92, 0xad, 0x7a, 0x01, 0xc1, 0x5e, 0xda, 0x99, 0x77, 0x9e, 0x3e,
+	0x7e, 0xdf, 0xe7, 0x7d, 0xde, 0x99, 0xc2, 0xbe, 0x43, 0x82, 0xaf, 0xdd, 0xf1, 0x95, 0x71, 0x59,
+	0xf1, 0xc6, 0x6e, 0xe0, 0x62, 0x61, 0x15, 0x90, 0x0e, 0x86, 0xee, 0xd0, 0x65, 0xd1, 0x53, 0xba,
+	0x0a, 0x01, 0xa5, 0x36, 0xe4, 0x3f, 0x73, 0x7d, 0xdf, 0xf4, 0x5e, 0x10, 0xdf, 0xd7, 0x87, 0x04,
+	0x9f, 0x40, 0x2a, 0x98, 0x79, 0x44, 0x44, 0x32, 0x2a, 0xbf, 0x73, 0xf6, 0xa0, 0xb2, 0x66, 0x8c,
+	0x10, 0xdd, 0x99, 0x47, 0x34, 0x86, 0xc1, 0x18, 0x52, 0x86, 0x1e, 0xe8, 0x62, 0x42, 0x46, 0xe5,
+	0x3d, 0x8d, 0xad, 0x4b, 0xaf, 0x12, 0x20, 0xa8, 0xae, 0x41, 0x94, 0x29, 0x71, 0x02, 0xfc, 0xc9,
+	0x06, 0xdb, 0x43, 0x8e, 0x6d, 0x85, 0xa9, 0x70, 0x84, 0x4d, 0xc8, 0x58, 0xfd, 0xc0, 0xb4, 0x09,
+	0xa3, 0x4c, 0xd5, 0xce, 0x5e, 0x5f, 0x1f, 0xed, 0xfc, 0x7e, 0x7d, 0x74, 0x32, 0x34, 0x83, 0xd1,
+	0xe4, 0xb2, 0x32, 0x70, 0xed, 0xd3, 0x91, 0xee, 0x8f, 0xcc, 0x81, 0x3b, 0xf6, 0x4e, 0x7d, 0x32,
+	0xfe, 0x8a, 0xfd, 0xa8, 0xb4, 0x74, 0xdb, 0x73, 0xc7, 0x41, 0xd7, 0xb4, 0x89, 0x96, 0xb6, 0xe8,
+	0x2f, 0xfc, 0x08, 0x04, 0xc7, 0x35, 0x48, 0xdf, 0xd1, 0x6d, 0x22, 0x26, 0x65, 0x54, 0x16, 0xb4,
+	0x1c, 0x0d, 0xa8, 0xba, 0x4d, 0x4a, 0xdf, 0x40, 0x8a, 0x7e, 0x15, 0x3f, 0x81, 0x6c, 0x53, 0xbd,
+	0xa8, 0xb6, 0x9a, 0x8d, 0xc2, 0x8e, 0x24, 0xce, 0x17, 0xf2, 0xc1, 0x2a, 0x2d, 0x7a, 0xde, 0x74,
+	0xa6, 0xba, 0x65, 0x1a, 0xf8, 0x08, 0x52, 0xcf, 0xda, 0x4d, 0xb5, 0x80, 0xa4, 0xfb, 0xf3, 0x85,
+	0xfc, 0xee, 0x06, 0xe6, 0x99, 0x6b, 0x3a, 0xf8, 0x03, 0x48, 0xb7, 0x94, 0xea, 0x85, 0x52, 0x48,
+	0x48, 0x0f, 0xe6, 0x0b, 0x19, 0x6f, 0x20, 0x5a, 0x44, 0x9f, 0x12, 0x69, 0xef, 0xe5, 0xab, 0xe2,
+	0xce, 0x8f, 0xdf, 0x17, 0xd9, 0x87, 0x4b, 0x37, 0x09, 0xd8, 0x53, 0x43, 0x2d, 0x42, 0xa1, 0x3e,
+	0xdd, 0x10, 0xea, 0x3d, 0x5e, 0x28, 0x0e, 0xf6, 0x3f, 0x68, 0x85, 0x3f, 0x06, 0x88, 0x92, 0xe9,
+	0x9b, 0x86, 0x98, 0xa2, 0xa7, 0xb5, 0xfc, 0xf2, 0xfa, 0x48, 0x88, 0x12, 0x6b, 0x36, 0xb4, 0xd8,
+	0x65, 0x4d, 0xa3, 0xf4, 0x12, 0x45, 0xd2, 0x96, 0x79, 0x69, 0x1f, 0xcd, 0x17, 0xf2, 0x21, 0x5f,
+	0x08, 0xaf, 0x6e, 0x69, 0xa5, 0x6e, 0xd8, 0x81, 0x2d, 0x18, 0x13, 0xf8, 0xf1, 0x5a, 0xe0, 0x87,
+	0xf3, 0x85, 0x7c, 0x7f, 0x1b, 0x74, 0x9b, 0xc6, 0x3f, 0xa1, 0xb5, 0xc6, 0x4e, 0x30, 0x9e, 0x6d,
+	0x55, 0x82, 0xee, 0xae, 0xe4, 0x3f, 0xd3, 0x57, 0x84, 0xac, 0x45, 0xf4, 0xa9, 0xe9, 0x0c, 0x99,
+	0xb8, 0x39, 0x2d, 0xde, 0x96, 0x7e, 0x40, 0xb0, 0x1f, 0xa5, 0xd6, 0x99, 0xf8, 0xa3, 0xce, 0xc4,
+	0xb2, 0xb8, 0xac, 0xd0, 0x3f, 0xcd, 0xea, 0x29, 0xe4, 0xa2, 0x6a, 0x7d, 0x31, 0x21, 0x27, 0xcb,
+	0xbb, 0x67, 0x87, 0xb7, 0xd8, 0x8e, 0x2a, 0xa7, 0xad, 0x80, 0x77, 0x8f, 0xd5, 0x9f, 0x49, 0x80,
+	0xae, 0x7e, 0x69, 0x45, 0xc3, 0x5f, 0xd9, 0xf0, 0xb4, 0xc4, 0x91, 0xaf, 0x41, 0x6f, 0xbd, 0xa3,
+	0xf1, 0xfb, 0x00, 0x01, 0x4d, 0x37, 0xe4, 0x4a, 0x33, 0x2e, 0x81, 0x45, 0x18, 0x59, 0x01, 0x92,
+	0x57, 0x64, 0x26, 0x66, 0x58, 0x9c, 0x2e, 0xf1, 0x01, 0xa4, 0xa7, 0xba, 0x35, 0x21, 0x62, 0x96,
+	0x5d, 0x8b, 0xe1, 0x86, 0xd2, 0x0c, 0xc6, 0x44, 0x0f, 0x88, 0xd1, 0xd7, 0x03, 0x31, 0x27, 0xa3,
+	0x72, 0x52, 0x13, 0xa2, 0x48, 0x35, 0xa0, 0xbd, 0x0e, 0xe7, 0xe6, 0x98, 0x9f, 0x1b, 0xe6, 0xf5,
+	0xb5, 0x58, 0xfc, 0xd4, 0x3c, 0x86, 0x4c, 0x5d, 0x53, 0xaa, 0x5d, 0x25, 0x9e, 0x9b, 0x4d, 0x58,
+	0x9d, 0x31, 0x53, 0x54, 0xaf, 0xd3, 0xa0, 0xa8, 0xc4, 0x6d, 0xa8, 0x9e, 0x67, 0x44, 0xa8, 0x86,
+	0xd2, 0x52, 0xba, 0x4a, 0x21, 0x79, 0x1b, 0xaa, 0x41, 0x2c, 0x12, 0x6c, 0x4f, 0xd7, 0xcf, 0x09,
+	0xd8, 0xaf, 0x4d, 0xac, 0xab, 0xf3, 0x99, 0x33, 0x88, 0xdf, 0x8e, 0x7f, 0xd1, 0x9c, 0x32, 0xec,
+	0x4e, 0x1c, 0xdf, 0xb5, 0xcc, 0x81, 0x19, 0x10, 0x83, 0x19, 0x22, 0xa7, 0xf1, 0xa1, 0xbb, 0x5b,
+	0x2c, 0x71, 0xde, 0x4e, 0xc9, 0x49, 0x76, 0x16, 0x5b, 0x58, 0x84, 0xac, 0xa7, 0xcf, 0x2c, 0x57,
+	0x37, 0x58, 0x37, 0xf7, 0xb4, 0x78, 0x8b, 0x8b, 0x00, 0x03, 0xd7, 0xf6, 0xc6, 0xc4, 0xf7, 0x89,
+	0xc1, 0x5a, 0x9a, 0xd3, 0xb8, 0x08, 0x3e, 0x84, 0xac, 0x3f, 0x73, 0x06, 0xd4, 0x35, 0xb4, 0xb7,
+	0x29, 0x2d, 0x43, 0xb7, 0x4d, 0x83, 0xb6, 0x7c, 0x30, 0x9a, 0x38, 0x57, 0xac, 0xaf, 0x79, 0x2d,
+	0xdc, 0xd0, 0x96, 0x5b, 0xba, 0x1f, 0xf4, 0xc3, 0x23, 0x81, 0xd1, 0x09, 0x34, 0x52, 0xa7, 0x81,
+	0xd2, 0x77, 0x08, 0xf6, 0xeb, 0xae, 0xed, 0xb9, 0x13, 0xc7, 0x88, 0x15, 0x6c, 0x40, 0xce, 0x0e,
+	0x97, 0xbe, 0x88, 0xd8, 0x4c, 0x96, 0xb9, 0xb1, 0xd9, 0x42, 0x57, 0xce, 0x4d, 0xdb, 0xb3, 0x48,
+	0xb4, 0xd3, 0x56, 0x7f, 0x29, 0x7d, 0x04, 0xf9, 0x8d, 0x23, 0x5a, 0x72, 0x27, 0x2a, 0x19, 0x6d,
+	0x94, 0x7c, 0xf2, 0x4b, 0x02, 0x76, 0xb9, 0x87, 0x1d, 0x7f, 0xc8, 0xdb, 0x8f, 0xbd, 0x65, 0xdc,
+	0x69, 0xec, 0xbd, 0x0a, 0xe4, 0x55, 0xa5, 0xfb, 0x45, 0x5b, 0x7b, 0xde, 0x57, 0x2e, 0x14, 0xb5,
+	0x5b, 0x40, 0xe1, 0x0d, 0xcf, 0x41, 0x37, 0x1e, 0xb7, 0x13, 0xd8, 0xed, 0x56, 0x6b, 0x2d, 0x25,
+	0x42, 0x47, 0x77, 0x38, 0x87, 0xe6, 0x2e, 0x8d, 0x63, 0x10, 0x3a, 0xbd, 0xf3, 0xcf, 0xfb, 0x9d,
+	0x5e, 0xab, 0x55, 0x48, 0x4a, 0x87, 0xf3, 0x85, 0x7c, 0x8f, 0x43, 0xae, 0x2e, 0xc2, 0x63, 0x10,
+	0x6a, 0xbd, 0xd6, 0xf3, 0xfe, 0xf9, 0x97, 0x6a, 0xbd, 0x90, 0x7a, 0x03, 0x17, 0x5b, 0x13, 0x3f,
+	0x81, 0x5c, 0xbd, 0xfd, 0xa2, 0xd3, 0xee, 0xa9, 0x8d, 0x42, 0xfa, 0x0d, 0x58, 0xac, 0x28, 0x2e,
+	0x03, 0xa8, 0xed, 0x46, 0x9c, 0x61, 0x26, 0x1c, 0x03, 0xbe, 0x9e, 0xf8, 0x45, 0x97, 0xee, 0x45,
+	0x63, 0xc0, 0xcb, 0x56, 0x13, 0x7f, 0xbb, 0x29, 0xee, 0xfc, 0x75, 0x53, 0x44, 0xdf, 0x2e, 0x8b,
+	0xe8, 0xf5, 0xb2, 0x88, 0x7e, 0x5d, 0x16, 0xd1, 0x1f, 0xcb, 0x22, 0xba, 0xcc, 0xb0, 0xff, 0xb3,
+	0x9e, 0xfe, 0x3d, 0x00, 0xd5, 0x96, 0xa3, 0x52, 0x9b, 0x09, 0x00, 0x00,
 }
@@ -26,12 +26,16 @@ func TestMain(m *testing.M) {
 }
 
 func createNetworkDBInstances(t *testing.T, num int, namePrefix string) []*NetworkDB {
+	return createNetworkDBInstancesWithConfig(t, num, namePrefix, &Config{})
+}
+
+func createNetworkDBInstancesWithConfig(t *testing.T, num int, namePrefix string, c *Config) []*NetworkDB {
 	var dbs []*NetworkDB
 	for i := 0; i < num; i++ {
-		db, err := New(&Config{
-			NodeName: fmt.Sprintf("%s%d", namePrefix, i+1),
-			BindPort: int(atomic.AddInt32(&dbPort, 1)),
-		})
+		config := *c
+		config.NodeName = fmt.Sprintf("%s%d", namePrefix, i+1)
+		config.BindPort = int(atomic.AddInt32(&dbPort, 1))
+		db, err := New(&config)
 		require.NoError(t, err)
 
 		if i != 0 {
@@ -0,0 +1,116 @@
+package networkdb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+)
+
+// Names of the presets of the NetworkDB timing settings
+const (
+	// ProfileLAN suits small clusters on a local network. It is the
+	// default profile.
+	ProfileLAN = "lan"
+	// ProfileWAN suits large clusters and clusters spanning a wide
+	// area network. It trades convergence time for less gossip
+	// traffic and more tolerance to latency.
+	ProfileWAN = "wan"
+)
+
+// Default bound of the size of the table events sent in a single bulk
+// sync message, which is not part of the profiles
+const defaultBulkSyncChunkSize = 512 * 1024
+
+// profile is a preset of the timing settings. The gossip and bulk sync
+// periods and the retransmit multiplier come from the memberlist
+// configuration.
+type profile struct {
+	memberlist        func() *memberlist.Config
+	reapEntryInterval time.Duration
+	reapNodeInterval  time.Duration
+}
+
+var profiles = map[string]profile{
+	ProfileLAN: {
+		memberlist:        memberlist.DefaultLANConfig,
+		reapEntryInterval: 60 * time.Second,
+		reapNodeInterval:  24 * time.Hour,
+	},
+	ProfileWAN: {
+		memberlist:        memberlist.DefaultWANConfig,
+		reapEntryInterval: 5 * time.Minute,
+		reapNodeInterval:  24 * time.Hour,
+	},
+}
+
+// withDefaults returns a copy of the configuration where the timing
+// settings left unset take the value of the profile.
+func (c *Config) withDefaults() (*Config, error) {
+	cfg := *c
+	if cfg.Profile == "" {
+		cfg.Profile = ProfileLAN
+	}
+
+	p, ok := profiles[cfg.Profile]
+	if !ok {
+		return nil, fmt.Errorf("unknown networkdb profile %q", cfg.Profile)
+	}
+
+	for _, s := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"reap entry interval", cfg.ReapEntryInterval},
+		{"reap node interval", cfg.ReapNodeInterval},
+		{"gossip interval", cfg.GossipInterval},
+		{"bulk sync interval", cfg.BulkSyncInterval},
+	} {
+		if s.d < 0 {
+			return nil, fmt.Errorf("invalid networkdb %s %s", s.name, s.d)
+		}
+	}
+	if cfg.RetransmitMult < 0 {
+		return nil, fmt.Errorf("invalid networkdb retransmit multiplier %d", cfg.RetransmitMult)
+	}
+	if cfg.BulkSyncChunkSize < 0 {
+		return nil, fmt.Errorf("invalid networkdb bulk sync chunk size %d", cfg.BulkSyncChunkSize)
+	}
+
+	mConfig := p.memberlist()
+	if cfg.ReapEntryInterval == 0 {
+		cfg.ReapEntryInterval = p.reapEntryInterval
+	}
+	if cfg.ReapNodeInterval == 0 {
+		cfg.ReapNodeInterval = p.reapNodeInterval
+	}
+	if cfg.GossipInterval == 0 {
+		cfg.GossipInterval = mConfig.GossipInterval
+	}
+	if cfg.BulkSyncInterval == 0 {
+		cfg.BulkSyncInterval = mConfig.PushPullInterval
+	}
+	if cfg.RetransmitMult == 0 {
+		cfg.RetransmitMult = mConfig.RetransmitMult
+	}
+	if cfg.BulkSyncChunkSize == 0 {
+		cfg.BulkSyncChunkSize = defaultBulkSyncChunkSize
+	}
+
+	if cfg.ReapEntryInterval < reapPeriod {
+		return nil, fmt.Errorf("networkdb reap entry interval %s is shorter than the reap period %s", cfg.ReapEntryInterval, reapPeriod)
+	}
+	if cfg.ReapNodeInterval < nodeReapPeriod {
+		return nil, fmt.Errorf("networkdb reap node interval %s is shorter than the node reap period %s", cfg.ReapNodeInterval, nodeReapPeriod)
+	}
+	// The deleted entries must outlive a bulk sync to reach the
+	// nodes which missed the deletion
+	if cfg.ReapEntryInterval <= cfg.BulkSyncInterval {
+		return nil, fmt.Errorf("networkdb reap entry interval %s must be longer than the bulk sync interval %s", cfg.ReapEntryInterval, cfg.BulkSyncInterval)
+	}
+	if cfg.GossipInterval >= cfg.BulkSyncInterval {
+		return nil, fmt.Errorf("networkdb gossip interval %s must be shorter than the bulk sync interval %s", cfg.GossipInterval, cfg.BulkSyncInterval)
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,127 @@
+package networkdb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	c, err := (&Config{NodeName: "node1"}).withDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, "node1", c.NodeName)
+	assert.Equal(t, ProfileLAN, c.Profile)
+	assert.Equal(t, 60*time.Second, c.ReapEntryInterval)
+	assert.Equal(t, 24*time.Hour, c.ReapNodeInterval)
+	assert.Equal(t, 200*time.Millisecond, c.GossipInterval)
+	assert.Equal(t, 30*time.Second, c.BulkSyncInterval)
+	assert.Equal(t, 4, c.RetransmitMult)
+
+	c, err = (&Config{Profile: ProfileWAN}).withDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, c.ReapEntryInterval)
+	assert.Equal(t, 500*time.Millisecond, c.GossipInterval)
+	assert.Equal(t, 60*time.Second, c.BulkSyncInterval)
+
+	orig := &Config{
+		Profile:           ProfileWAN,
+		ReapEntryInterval: 2 * time.Minute,
+		GossipInterval:    time.Second,
+		RetransmitMult:    6,
+	}
+	c, err = orig.withDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, c.ReapEntryInterval)
+	assert.Equal(t, time.Second, c.GossipInterval)
+	assert.Equal(t, 60*time.Second, c.BulkSyncInterval)
+	assert.Equal(t, 6, c.RetransmitMult)
+	assert.Equal(t, time.Duration(0), orig.BulkSyncInterval, "the caller configuration must not be modified")
+
+	for _, c := range []*Config{
+		{Profile: "datacenter"},
+		{ReapEntryInterval: -time.Second},
+		{GossipInterval: -time.Second},
+		{RetransmitMult: -1},
+		{ReapEntryInterval: time.Second},
+		{ReapEntryInterval: 30 * time.Second},
+		{ReapNodeInterval: time.Hour},
+		{GossipInterval: 30 * time.Second},
+		{Profile: ProfileWAN, ReapEntryInterval: 60 * time.Second},
+	} {
+		_, err := c.withDefaults()
+		assert.Error(t, err, "expected failure for %+v", c)
+	}
+
+	_, err = New(&Config{NodeName: "node1", Profile: "datacenter"})
+	assert.Error(t, err)
+}
+
+func (db *NetworkDB) hasEntry(tname, nid, key, value string) bool {
+	e, err := db.getEntry(tname, nid, key)
+	return err == nil && !e.deleting && string(e.value) == value
+}
+
+func verifyConvergence(t *testing.T, dbs []*NetworkDB, keys int, present bool) {
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		missing := 0
+		for _, db := range dbs {
+			for _, owner := range dbs {
+				for i := 0; i < keys; i++ {
+					key := fmt.Sprintf("%s_key%d", owner.config.NodeName, i)
+					if db.hasEntry("test_table", "network1", key, "test_value") != present {
+						missing++
+					}
+				}
+			}
+		}
+		if missing == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			assert.Fail(t, fmt.Sprintf("%d entries did not converge", missing))
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func testProfileConvergence(t *testing.T, profile string) {
+	const (
+		num  = 10
+		keys = 5
+	)
+	dbs := createNetworkDBInstancesWithConfig(t, num, profile, &Config{Profile: profile})
+
+	for _, db := range dbs {
+		require.NoError(t, db.JoinNetwork("network1"))
+	}
+	for _, db := range dbs {
+		for i := 0; i < keys; i++ {
+			key := fmt.Sprintf("%s_key%d", db.config.NodeName, i)
+			require.NoError(t, db.CreateEntry("test_table", "network1", key, []byte("test_value")))
+		}
+	}
+	verifyConvergence(t, dbs, keys, true)
+
+	for _, db := range dbs {
+		for i := 0; i < keys; i++ {
+			key := fmt.Sprintf("%s_key%d", db.config.NodeName, i)
+			require.NoError(t, db.DeleteEntry("test_table", "network1", key))
+		}
+	}
+	verifyConvergence(t, dbs, keys, false)
+
+	closeNetworkDBInstances(dbs)
+}
+
+func TestLANProfileConvergence(t *testing.T) {
+	testProfileConvergence(t, ProfileLAN)
+}
+
+func TestWANProfileConvergence(t *testing.T) {
+	testProfileConvergence(t, ProfileWAN)
+}
@@ -0,0 +1,295 @@
+package networkdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libnetwork/datastore"
+	"github.com/hashicorp/serf/serf"
+)
+
+const (
+	snapshotPrefix = "networkdb"
+	snapshotPeriod = 30 * time.Second
+
+	// Time given to the cluster to confirm the state restored from
+	// a snapshot before it is purged.
+	restoreInterval = 5 * time.Minute
+)
+
+// snapshot is the state of a NetworkDB instance persisted in the
+// local datastore. Only the state learned from the other nodes is
+// persisted, the application recreates the state of this node after
+// a restart.
+type snapshot struct {
+	NodeName     string
+	Time         int64
+	NetworkClock serf.LamportTime
+	TableClock   serf.LamportTime
+	Networks     []*snapshotNetwork
+	Entries      []*snapshotEntry
+	dbIndex      uint64
+	dbExists     bool
+}
+
+// snapshotNetwork is the attachment of a node to a network
+type snapshotNetwork struct {
+	Node  string
+	ID    string
+	LTime serf.LamportTime
+}
+
+// snapshotEntry is a live table entry
+type snapshotEntry struct {
+	Table     string
+	Network   string
+	Key       string
+	Value     []byte
+	Owner     string
+	LTime     serf.LamportTime
+	CreatedAt int64
+}
+
+func (s *snapshot) Key() []string {
+	return []string{snapshotPrefix, "snapshot"}
+}
+
+func (s *snapshot) KeyPrefix() []string {
+	return []string{snapshotPrefix}
+}
+
+func (s *snapshot) Value() []byte {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func (s *snapshot) SetValue(value []byte) error {
+	return json.Unmarshal(value, s)
+}
+
+func (s *snapshot) Index() uint64 {
+	return s.dbIndex
+}
+
+func (s *snapshot) SetIndex(index uint64) {
+	s.dbIndex = index
+	s.dbExists = true
+}
+
+func (s *snapshot) Exists() bool {
+	return s.dbExists
+}
+
+func (s *snapshot) Skip() bool {
+	return false
+}
+
+func (s *snapshot) DataScope() string {
+	return datastore.LocalScope
+}
+
+func (s *snapshot) New() datastore.KVObject {
+	return &snapshot{}
+}
+
+func (s *snapshot) CopyTo(o datastore.KVObject) error {
+	dstS := o.(*snapshot)
+	*dstS = *s
+	return nil
+}
+
+// saveSnapshot persists the state of the NetworkDB instance in the
+// local datastore, if any.
+func (nDB *NetworkDB) saveSnapshot() {
+	if nDB.config.Store == nil {
+		return
+	}
+
+	s := &snapshot{
+		NodeName:     nDB.config.NodeName,
+		Time:         time.Now().UnixNano(),
+		NetworkClock: nDB.networkClock.Time(),
+		TableClock:   nDB.tableClock.Time(),
+	}
+
+	nDB.RLock()
+	for name, nn := range nDB.networks {
+		if name == nDB.config.NodeName {
+			continue
+		}
+		for _, n := range nn {
+			if n.leaving {
+				continue
+			}
+			s.Networks = append(s.Networks, &snapshotNetwork{
+				Node:  name,
+				ID:    n.id,
+				LTime: n.ltime,
+			})
+		}
+	}
+	nDB.indexes[byTable].Walk(func(path string, v interface{}) bool {
+		entry := v.(*entry)
+		if entry.deleting || entry.node == nDB.config.NodeName {
+			return false
+		}
+
+		params := strings.Split(path[1:], "/")
+		s.Entries = append(s.Entries, &snapshotEntry{
+			Table:     params[0],
+			Network:   params[1],
+			Key:       params[2],
+			Value:     entry.value,
+			Owner:     entry.node,
+			LTime:     entry.ltime,
+			CreatedAt: entry.createdAt,
+		})
+		return false
+	})
+	nDB.RUnlock()
+
+	if err := nDB.config.Store.PutObject(s); err != nil {
+		logrus.Errorf("%s: failed to save networkdb snapshot: %v", nDB.config.NodeName, err)
+	}
+}
+
+// restoreSnapshot loads the state persisted by a previous instance
+// from the local datastore. The restored state is only trusted once
+// the cluster confirms it, until then it is not propagated to the
+// other nodes and it is purged if the cluster does not know about it.
+func (nDB *NetworkDB) restoreSnapshot() error {
+	if nDB.config.Store == nil {
+		return nil
+	}
+
+	s := &snapshot{}
+	if err := nDB.config.Store.GetObject(datastore.Key(s.Key()...), s); err != nil {
+		if err == datastore.ErrKeyNotFound {
+			return nil
+		}
+		return fmt.Errorf("could not get networkdb snapshot: %v", err)
+	}
+
+	// The nodes the snapshot refers to may have been reaped by the
+	// cluster in the meantime.
+	if age := time.Since(time.Unix(0, s.Time)); age > nDB.config.ReapNodeInterval {
+		logrus.Infof("%s: ignoring networkdb snapshot taken %s ago", nDB.config.NodeName, age)
+		return nil
+	}
+
+	// The clocks must not go backwards, the events generated by this
+	// node would be discarded as stale otherwise.
+	nDB.networkClock.Witness(s.NetworkClock)
+	nDB.tableClock.Witness(s.TableClock)
+
+	nDB.Lock()
+	defer nDB.Unlock()
+
+	var networks, entries int
+	for _, sn := range s.Networks {
+		if sn.Node == s.NodeName || sn.Node == nDB.config.NodeName {
+			continue
+		}
+
+		nodeNetworks, ok := nDB.networks[sn.Node]
+		if !ok {
+			nodeNetworks = make(map[string]*network)
+			nDB.networks[sn.Node] = nodeNetworks
+		}
+		nodeNetworks[sn.ID] = &network{
+			id:          sn.ID,
+			ltime:       sn.LTime,
+			restored:    true,
+			restoreTime: restoreInterval,
+		}
+		nDB.addNetworkNode(sn.ID, sn.Node)
+		networks++
+	}
+
+	for _, se := range s.Entries {
+		// The entries of the previous instance of this node are
+		// recreated by the application.
+		if se.Owner == s.NodeName || se.Owner == nDB.config.NodeName {
+			continue
+		}
+
+		e := &entry{
+			ltime:       se.LTime,
+			node:        se.Owner,
+			value:       se.Value,
+			createdAt:   se.CreatedAt,
+			restored:    true,
+			restoreTime: restoreInterval,
+		}
+		nDB.indexes[byTable].Insert(fmt.Sprintf("/%s/%s/%s", se.Table, se.Network, se.Key), e)
+		nDB.indexes[byNetwork].Insert(fmt.Sprintf("/%s/%s/%s", se.Network, se.Table, se.Key), e)
+		entries++
+	}
+
+	logrus.Infof("%s: restored %d network attachments and %d table entries from the snapshot of %s",
+		nDB.config.NodeName, networks, entries, s.NodeName)
+	return nil
+}
+
+// purgeRestoredEntry turns a restored entry which the cluster did not
+// confirm into a tombstone and notifies the watchers. The tombstone
+// is not propagated to the other nodes. Caller should hold the
+// NetworkDB lock while calling this
+func (nDB *NetworkDB) purgeRestoredEntry(tname, nid, key string, e *entry) {
+	tombstone := &entry{
+		ltime:    e.ltime,
+		node:     e.node,
+		value:    e.value,
+		deleting: true,
+		reapTime: nDB.config.ReapEntryInterval,
+		restored: true,
+	}
+
+	nDB.indexes[byTable].Insert(fmt.Sprintf("/%s/%s/%s", tname, nid, key), tombstone)
+	nDB.indexes[byNetwork].Insert(fmt.Sprintf("/%s/%s/%s", nid, tname, key), tombstone)
+
+	nDB.broadcaster.Write(makeEvent(opDelete, tname, nid, key, e.value))
+}
+
+// purgeUnconfirmedEntries purges the restored entries of the passed
+// networks after a bulk sync. A bulk sync carries the whole state of
+// its networks, the restored entries it did not confirm are stale.
+func (nDB *NetworkDB) purgeUnconfirmedEntries(networks []string) {
+	nDB.Lock()
+	defer nDB.Unlock()
+
+	for _, nid := range networks {
+		// The bulk sync was ignored if this node is not attached
+		if n, ok := nDB.networks[nDB.config.NodeName][nid]; !ok || n.leaving {
+			continue
+		}
+
+		var (
+			paths   []string
+			entries []*entry
+		)
+		nDB.indexes[byNetwork].WalkPrefix(fmt.Sprintf("/%s/", nid), func(path string, v interface{}) bool {
+			entry := v.(*entry)
+			if entry.restored && !entry.deleting {
+				paths = append(paths, path)
+				entries = append(entries, entry)
+			}
+			return false
+		})
+
+		for i, path := range paths {
+			params := strings.Split(path[1:], "/")
+			nDB.purgeRestoredEntry(params[1], nid, params[2], entries[i])
+		}
+
+		if len(paths) > 0 {
+			logrus.Debugf("%s: purged %d stale restored entries of network %s", nDB.config.NodeName, len(paths), nid)
+		}
+	}
+}
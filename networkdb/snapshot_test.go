@@ -0,0 +1,227 @@
+package networkdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/libkv/store"
+	"github.com/docker/libkv/store/boltdb"
+	"github.com/docker/libnetwork/datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	boltdb.Register()
+}
+
+func newSnapshotStore(t *testing.T) (datastore.DataStore, func()) {
+	dir, err := ioutil.TempDir("", "networkdb-")
+	require.NoError(t, err)
+
+	ds, err := datastore.NewDataStore(datastore.LocalScope, &datastore.ScopeCfg{
+		Client: datastore.ScopeClientCfg{
+			Provider: "boltdb",
+			Address:  filepath.Join(dir, "local-kv.db"),
+			Config: &store.Config{
+				Bucket:            "libnetwork",
+				ConnectionTimeout: 3 * time.Second,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return ds, func() {
+		ds.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newSnapshotInstance(t *testing.T, name string, ds datastore.DataStore) *NetworkDB {
+	db, err := New(&Config{
+		NodeName: name,
+		BindPort: int(atomic.AddInt32(&dbPort, 1)),
+		Store:    ds,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func (db *NetworkDB) verifyEntryConfirmed(t *testing.T, tname, nid, key string) {
+	for i := 0; i < 80; i++ {
+		db.RLock()
+		v, ok := db.indexes[byTable].Get(fmt.Sprintf("/%s/%s/%s", tname, nid, key))
+		confirmed := ok && !v.(*entry).restored
+		db.RUnlock()
+		if confirmed {
+			return
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	assert.Fail(t, fmt.Sprintf("%s: entry %s was not confirmed", db.config.NodeName, key))
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	ds, cleanup := newSnapshotStore(t)
+	defer cleanup()
+
+	node1 := newSnapshotInstance(t, "node1", nil)
+	node2 := newSnapshotInstance(t, "node2", ds)
+	require.NoError(t, node2.Join([]string{fmt.Sprintf("localhost:%d", node1.config.BindPort)}))
+
+	require.NoError(t, node1.JoinNetwork("network1"))
+	node2.verifyNetworkExistence(t, "node1", "network1", true)
+	require.NoError(t, node2.JoinNetwork("network1"))
+	node1.verifyNetworkExistence(t, "node2", "network1", true)
+
+	require.NoError(t, node1.CreateEntry("test_table", "network1", "key1", []byte("value1")))
+	require.NoError(t, node1.CreateEntry("test_table", "network1", "key2", []byte("value2")))
+	require.NoError(t, node2.CreateEntry("test_table", "network1", "key3", []byte("value3")))
+	node2.verifyEntryExistence(t, "test_table", "network1", "key1", "value1", true)
+	node2.verifyEntryExistence(t, "test_table", "network1", "key2", "value2", true)
+	node1.verifyEntryExistence(t, "test_table", "network1", "key3", "value3", true)
+
+	tableTime := node2.tableClock.Time()
+	node2.Close()
+	node1.verifyEntryExistence(t, "test_table", "network1", "key3", "", false)
+
+	// Changes missed while the node is down
+	require.NoError(t, node1.DeleteEntry("test_table", "network1", "key2"))
+	require.NoError(t, node1.CreateEntry("test_table", "network1", "key4", []byte("value4")))
+
+	node3 := newSnapshotInstance(t, "node3", ds)
+	assert.Equal(t, true, node3.tableClock.Time() > tableTime)
+
+	e, err := node3.getEntry("test_table", "network1", "key1")
+	require.NoError(t, err)
+	assert.Equal(t, true, e.restored)
+	assert.Equal(t, "value1", string(e.value))
+	_, err = node3.getEntry("test_table", "network1", "key2")
+	assert.NoError(t, err)
+	_, err = node3.getEntry("test_table", "network1", "key3")
+	assert.Error(t, err, "the entries of the previous instance must not be restored")
+	assert.Equal(t, true, node3.networks["node1"]["network1"].restored)
+	_, ok := node3.networks["node2"]
+	assert.Equal(t, false, ok)
+
+	require.NoError(t, node3.Join([]string{fmt.Sprintf("localhost:%d", node1.config.BindPort)}))
+	require.NoError(t, node3.JoinNetwork("network1"))
+	node1.verifyNetworkExistence(t, "node3", "network1", true)
+
+	node3.verifyEntryConfirmed(t, "test_table", "network1", "key1")
+	node3.verifyEntryExistence(t, "test_table", "network1", "key2", "", false)
+	node3.verifyEntryExistence(t, "test_table", "network1", "key4", "value4", true)
+
+	e, err = node1.getEntry("test_table", "network1", "key3")
+	require.NoError(t, err)
+	assert.Equal(t, true, e.deleting)
+
+	closeNetworkDBInstances([]*NetworkDB{node1, node3})
+}
+
+func TestSnapshotPurge(t *testing.T) {
+	ds, cleanup := newSnapshotStore(t)
+	defer cleanup()
+
+	require.NoError(t, ds.PutObject(&snapshot{
+		NodeName: "node0",
+		Time:     time.Now().UnixNano(),
+		Networks: []*snapshotNetwork{
+			{Node: "ghost", ID: "network1", LTime: 1},
+		},
+		Entries: []*snapshotEntry{
+			{Table: "test_table", Network: "network1", Key: "ghost_key", Value: []byte("ghost_value"), Owner: "ghost", LTime: 1},
+		},
+	}))
+
+	node1 := newSnapshotInstance(t, "node1", nil)
+	require.NoError(t, node1.JoinNetwork("network1"))
+	require.NoError(t, node1.CreateEntry("test_table", "network1", "key1", []byte("value1")))
+
+	node2 := newSnapshotInstance(t, "node2", ds)
+	node2.verifyEntryExistence(t, "test_table", "network1", "ghost_key", "ghost_value", true)
+	assert.Equal(t, []string{"ghost"}, node2.networkNodes["network1"])
+	assert.Len(t, node2.Peers("network1"), 0)
+
+	require.NoError(t, node2.Join([]string{fmt.Sprintf("localhost:%d", node1.config.BindPort)}))
+	require.NoError(t, node2.JoinNetwork("network1"))
+
+	node2.verifyEntryExistence(t, "test_table", "network1", "key1", "value1", true)
+	node2.verifyEntryExistence(t, "test_table", "network1", "ghost_key", "", false)
+	_, err := node1.getEntry("test_table", "network1", "ghost_key")
+	assert.Error(t, err, "restored entries must not be propagated")
+	assert.Len(t, node2.Peers("network1"), 2)
+
+	// The restored attachment is never confirmed
+	node2.Lock()
+	node2.networks["ghost"]["network1"].restoreTime = 0
+	node2.Unlock()
+	node2.reapNetworks()
+	_, ok := node2.networks["ghost"]["network1"]
+	assert.Equal(t, false, ok)
+	assert.Len(t, node2.networkNodes["network1"], 2)
+
+	node1.verifyNetworkExistence(t, "node2", "network1", true)
+	_, ok = node1.networks["ghost"]
+	assert.Equal(t, false, ok)
+
+	closeNetworkDBInstances([]*NetworkDB{node1, node2})
+}
+
+func TestSnapshotExpire(t *testing.T) {
+	ds, cleanup := newSnapshotStore(t)
+	defer cleanup()
+
+	s := &snapshot{
+		NodeName: "node0",
+		Time:     time.Now().Add(-48 * time.Hour).UnixNano(),
+		Entries: []*snapshotEntry{
+			{Table: "test_table", Network: "network1", Key: "key1", Value: []byte("value1"), Owner: "node1", LTime: 1},
+		},
+	}
+	require.NoError(t, ds.PutObject(s))
+
+	db := newSnapshotInstance(t, "node2", ds)
+	_, err := db.getEntry("test_table", "network1", "key1")
+	assert.Error(t, err, "stale snapshots must be ignored")
+	db.Close()
+
+	s = &snapshot{
+		NodeName: "node0",
+		Time:     time.Now().UnixNano(),
+		Entries:  s.Entries,
+	}
+	require.NoError(t, ds.PutObject(s))
+
+	db = newSnapshotInstance(t, "node2", ds)
+	ch, cancel := db.Watch("test_table", "", "")
+	defer cancel()
+
+	e, err := db.getEntry("test_table", "network1", "key1")
+	require.NoError(t, err)
+	db.Lock()
+	e.restoreTime = 0
+	db.Unlock()
+	db.reapTableEntries()
+
+	e, err = db.getEntry("test_table", "network1", "key1")
+	require.NoError(t, err)
+	assert.Equal(t, true, e.deleting)
+
+	select {
+	case ev := <-ch:
+		_, ok := ev.(DeleteEvent)
+		assert.Equal(t, true, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "no delete event for the purged entry")
+	}
+
+	db.Close()
+}
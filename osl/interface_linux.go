@@ -28,6 +28,7 @@ type nwIface struct {
 	addressIPv6 *net.IPNet
 	llAddrs     []*net.IPNet
 	routes      []*net.IPNet
+	qos         *types.QosPolicy
 	bridge      bool
 	ns          *networkNamespace
 	sync.Mutex
@@ -328,6 +329,20 @@ func (n *networkNamespace) AddInterface(srcName, dstPrefix string, options ...If
 		return fmt.Errorf("error setting interface %q routes to %q: %v", iface.Attrs().Name, i.Routes(), err)
 	}
 
+	// Program the rate limits in the namespace the interface now lives in.
+	if i.qos != nil {
+		nsh := netns.None()
+		if !isDefault {
+			if nsh, err = netns.GetFromPath(path); err != nil {
+				return fmt.Errorf("failed get network namespace %q: %v", path, err)
+			}
+			defer nsh.Close()
+		}
+		if err := setInterfaceQos(nlh, nsh, iface, i); err != nil {
+			return fmt.Errorf("error setting interface %q QoS policy: %v", iface.Attrs().Name, err)
+		}
+	}
+
 	n.Lock()
 	n.iFaces = append(n.iFaces, i)
 	n.Unlock()
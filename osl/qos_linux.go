@@ -0,0 +1,135 @@
+package osl
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/docker/libnetwork/types"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+)
+
+const (
+	// Minimum burst allowed by the rate limiters, in bytes
+	qosMinBurst = 16 * 1024
+	// Amount of traffic, in milliseconds at the configured rate, the egress
+	// shaper is allowed to queue before dropping
+	qosLatencyMs = 50
+	// Priority of the ingress policer filter
+	qosPolicerPrio = 1
+)
+
+// qosBurst returns the burst size in bytes used for the passed rate in bytes
+// per second. It allows 10ms worth of traffic at the configured rate.
+func qosBurst(rate uint64) uint32 {
+	burst := rate / 100
+	if burst < qosMinBurst {
+		burst = qosMinBurst
+	}
+	return uint32(burst)
+}
+
+func validateQosPolicy(qos *types.QosPolicy) error {
+	if qos.MaxEgressBandwidth > 0xffffffff || qos.MaxIngressBandwidth > 0xffffffff {
+		return fmt.Errorf("bandwidth limits greater than %d bytes/s are not supported", uint32(0xffffffff))
+	}
+	return nil
+}
+
+// setInterfaceQos programs the rate limits of the interface QoS policy. The
+// egress traffic is shaped by a token bucket filter root qdisc, while the
+// ingress traffic exceeding the limit is dropped by a policer attached to the
+// ingress qdisc. The handle and the namespace are the ones of the namespace
+// the interface lives in.
+func setInterfaceQos(nlh *netlink.Handle, nsh netns.NsHandle, iface netlink.Link, i *nwIface) error {
+	if err := validateQosPolicy(i.qos); err != nil {
+		return err
+	}
+
+	if rate := i.qos.MaxEgressBandwidth; rate != 0 {
+		burst := qosBurst(rate)
+		tbf := &netlink.Tbf{
+			QdiscAttrs: netlink.QdiscAttrs{
+				LinkIndex: iface.Attrs().Index,
+				Handle:    netlink.MakeHandle(1, 0),
+				Parent:    netlink.HANDLE_ROOT,
+			},
+			Rate:   rate,
+			Limit:  uint32(rate*qosLatencyMs/1000) + burst,
+			Buffer: uint32(netlink.Xmittime(rate, burst)),
+		}
+		if err := nlh.QdiscReplace(tbf); err != nil {
+			return fmt.Errorf("failed to set egress rate limit: %v", err)
+		}
+	}
+
+	if rate := i.qos.MaxIngressBandwidth; rate != 0 {
+		ingress := &netlink.Ingress{
+			QdiscAttrs: netlink.QdiscAttrs{
+				LinkIndex: iface.Attrs().Index,
+				Handle:    netlink.MakeHandle(0xffff, 0),
+				Parent:    netlink.HANDLE_INGRESS,
+			},
+		}
+		if err := nlh.QdiscReplace(ingress); err != nil {
+			return fmt.Errorf("failed to set ingress qdisc: %v", err)
+		}
+		if err := addIngressPolicer(nsh, iface.Attrs().Index, rate); err != nil {
+			return fmt.Errorf("failed to set ingress rate limit: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// addIngressPolicer attaches to the ingress qdisc of the link a match-all u32
+// filter with a policer dropping the traffic exceeding the passed rate. This
+// is the netlink equivalent of:
+//
+//	tc filter add dev <link> parent ffff: protocol all prio 1 u32 match u32 0 0 \
+//	   police rate <rate> burst <burst> drop
+//
+// The netlink package has no police action, so the request is sent on a
+// socket opened in the namespace of the interface.
+func addIngressPolicer(nsh netns.NsHandle, linkIndex int, rate uint64) error {
+	burst := qosBurst(rate)
+
+	police := nl.TcPolice{Action: int32(netlink.TC_POLICE_SHOT)}
+	police.Rate.Rate = uint32(rate)
+	police.Burst = uint32(netlink.Xmittime(rate, burst))
+
+	var rtab [256]uint32
+	cellLog := netlink.CalcRtable(&police.Rate, rtab, -1, 0, nl.LINKLAYER_ETHERNET)
+	for i := range rtab {
+		rtab[i] = uint32(netlink.Xmittime(rate, uint32((i+1)<<uint(cellLog))))
+	}
+
+	sock, err := nl.GetNetlinkSocketAt(nsh, netns.None(), syscall.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	defer sock.Close()
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWTFILTER, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	req.Sockets = map[int]*nl.SocketHandle{syscall.NETLINK_ROUTE: {Socket: sock}}
+	req.AddData(&nl.TcMsg{
+		Family:  nl.FAMILY_ALL,
+		Ifindex: int32(linkIndex),
+		Parent:  netlink.HANDLE_INGRESS,
+		Info:    netlink.MakeHandle(qosPolicerPrio, nl.Swap16(syscall.ETH_P_ALL)),
+	})
+	req.AddData(nl.NewRtAttr(nl.TCA_KIND, nl.ZeroTerminated("u32")))
+
+	options := nl.NewRtAttr(nl.TCA_OPTIONS, nil)
+	sel := nl.TcU32Sel{Nkeys: 1, Flags: nl.TC_U32_TERMINAL}
+	sel.Keys = append(sel.Keys, nl.TcU32Key{})
+	nl.NewRtAttrChild(options, nl.TCA_U32_SEL, sel.Serialize())
+	policeAttr := nl.NewRtAttrChild(options, nl.TCA_U32_POLICE, nil)
+	nl.NewRtAttrChild(policeAttr, nl.TCA_POLICE_TBF, police.Serialize())
+	nl.NewRtAttrChild(policeAttr, nl.TCA_POLICE_RATE, netlink.SerializeRtab(rtab))
+	req.AddData(options)
+
+	_, err = req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
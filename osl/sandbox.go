@@ -98,6 +98,9 @@ type IfaceOptionSetter interface {
 
 	// Address returns an option setter to set interface routes.
 	Routes([]*net.IPNet) IfaceOption
+
+	// QosPolicy returns an option setter to set the interface bandwidth limits.
+	QosPolicy(*types.QosPolicy) IfaceOption
 }
 
 // Info represents all possible information that
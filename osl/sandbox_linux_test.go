@@ -7,10 +7,12 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/docker/libnetwork/ns"
 	"github.com/docker/libnetwork/testutils"
 	"github.com/docker/libnetwork/types"
 	"github.com/vishvananda/netlink"
@@ -225,3 +227,97 @@ func TestDisableIPv6DAD(t *testing.T) {
 		t.Fatalf("Unexpected interface flags: 0x%x. Expected to contain 0x%x", addrList[0].Flags, syscall.IFA_F_NODAD)
 	}
 }
+
+func TestInterfaceQosPolicy(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	key, err := newKey(t)
+	if err != nil {
+		t.Fatalf("Failed to obtain a key: %v", err)
+	}
+
+	s, err := NewSandbox(key, true, false)
+	if err != nil {
+		t.Fatalf("Failed to create a new sandbox: %v", err)
+	}
+	defer func() {
+		s.Destroy()
+		GC()
+	}()
+
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: "qosA"},
+		PeerName:  "qosB",
+	}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(veth)
+
+	qos := &types.QosPolicy{MaxEgressBandwidth: 1 << 20}
+	if err := s.AddInterface("qosB", sboxIfaceName, s.InterfaceOptions().QosPolicy(qos)); err != nil {
+		t.Fatalf("Failed to add interface to sandbox: %v", err)
+	}
+
+	n := s.(*networkNamespace)
+	link, err := n.nlHandle.LinkByName(sboxIfaceName + "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qdiscs, err := n.nlHandle.QdiscList(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range qdiscs {
+		if tbf, ok := q.(*netlink.Tbf); ok {
+			if tbf.Rate != qos.MaxEgressBandwidth {
+				t.Fatalf("Unexpected egress rate. Expected %d. Got %d", qos.MaxEgressBandwidth, tbf.Rate)
+			}
+			return
+		}
+	}
+	t.Fatalf("Missing egress rate limiter on sandbox interface: %v", qdiscs)
+}
+
+func TestIngressPolicer(t *testing.T) {
+	defer testutils.SetupTestOSContext(t)()
+
+	veth := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{Name: "polA"},
+		PeerName:  "polB",
+	}
+	if err := netlink.LinkAdd(veth); err != nil {
+		t.Fatal(err)
+	}
+	defer netlink.LinkDel(veth)
+
+	link, err := netlink.LinkByName("polA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := &nwIface{dstName: "polA", qos: &types.QosPolicy{MaxIngressBandwidth: 1 << 20}}
+	if err := setInterfaceQos(ns.NlHandle(), netns.None(), link, i); err != nil {
+		if strings.Contains(err.Error(), syscall.ENOENT.Error()) {
+			t.Skipf("Skipping as the kernel does not support the police action")
+		}
+		t.Fatal(err)
+	}
+
+	filters, err := netlink.FilterList(link, netlink.HANDLE_INGRESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filters) == 0 {
+		t.Fatalf("Missing ingress policer on interface")
+	}
+}
+
+func TestValidateQosPolicy(t *testing.T) {
+	if err := validateQosPolicy(&types.QosPolicy{MaxEgressBandwidth: 1 << 30}); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateQosPolicy(&types.QosPolicy{MaxIngressBandwidth: 1 << 33}); err == nil {
+		t.Fatalf("Expected failure for out of range bandwidth")
+	}
+}
@@ -138,7 +138,9 @@ func (pm *PortMapper) MapRange(container net.Addr, hostIP net.IP, hostPortStart,
 
 	containerIP, containerPort := getIPAndPort(m.container)
 	if hostIP.To4() != nil {
-		if err := pm.forward(iptables.Append, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort); err != nil {
+		t := iptables.NewTransaction()
+		pm.forward(t, iptables.Append, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
+		if err := t.Commit(); err != nil {
 			return nil, err
 		}
 	}
@@ -147,7 +149,9 @@ func (pm *PortMapper) MapRange(container net.Addr, hostIP net.IP, hostPortStart,
 		// need to undo the iptables rules before we return
 		m.userlandProxy.Stop()
 		if hostIP.To4() != nil {
-			pm.forward(iptables.Delete, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
+			t := iptables.NewTransaction()
+			pm.forward(t, iptables.Delete, m.proto, hostIP, allocatedHostPort, containerIP.String(), containerPort)
+			t.Commit()
 			if err := pm.Allocator.ReleasePort(hostIP, m.proto, allocatedHostPort); err != nil {
 				return err
 			}
@@ -186,7 +190,9 @@ func (pm *PortMapper) Unmap(host net.Addr) error {
 
 	containerIP, containerPort := getIPAndPort(data.container)
 	hostIP, hostPort := getIPAndPort(data.host)
-	if err := pm.forward(iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
+	t := iptables.NewTransaction()
+	pm.forward(t, iptables.Delete, data.proto, hostIP, hostPort, containerIP.String(), containerPort)
+	if err := t.Commit(); err != nil {
 		logrus.Errorf("Error on iptables delete: %s", err)
 	}
 
@@ -204,12 +210,14 @@ func (pm *PortMapper) ReMapAll() {
 	pm.lock.Lock()
 	defer pm.lock.Unlock()
 	logrus.Debugln("Re-applying all port mappings.")
+	t := iptables.NewTransaction()
 	for _, data := range pm.currentMappings {
 		containerIP, containerPort := getIPAndPort(data.container)
 		hostIP, hostPort := getIPAndPort(data.host)
-		if err := pm.forward(iptables.Append, data.proto, hostIP, hostPort, containerIP.String(), containerPort); err != nil {
-			logrus.Errorf("Error on iptables add: %s", err)
-		}
+		pm.forward(t, iptables.Append, data.proto, hostIP, hostPort, containerIP.String(), containerPort)
+	}
+	if err := t.Commit(); err != nil {
+		logrus.Errorf("Error on iptables add: %s", err)
 	}
 }
 
@@ -233,9 +241,11 @@ func getIPAndPort(a net.Addr) (net.IP, int) {
 	return nil, 0
 }
 
-func (pm *PortMapper) forward(action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) error {
+// forward queues in the transaction the rules forwarding the port to the
+// container, if the iptables chain is set
+func (pm *PortMapper) forward(t *iptables.Transaction, action iptables.Action, proto string, sourceIP net.IP, sourcePort int, containerIP string, containerPort int) {
 	if pm.chain == nil {
-		return nil
+		return
 	}
-	return pm.chain.Forward(action, sourceIP, sourcePort, proto, containerIP, containerPort, pm.bridgeName)
+	t.Forward(pm.chain, action, sourceIP, sourcePort, proto, containerIP, containerPort, pm.bridgeName)
 }
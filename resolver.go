@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,11 +29,30 @@ type Resolver interface {
 	// NameServer() returns the IP of the DNS resolver for the
 	// containers.
 	NameServer() string
-	// SetExtServers configures the external nameservers the resolver
-	// should use to forward queries
-	SetExtServers([]string)
+	// SetExtServers configures the rules selecting the external
+	// nameservers the resolver should use to forward queries
+	SetExtServers([]ExtDNSRule)
 	// ResolverOptions returns resolv.conf options that should be set
 	ResolverOptions() []string
+	// Statistics returns the health and the counters of the external
+	// nameservers the queries are forwarded to
+	Statistics() []*types.ExtDNSStatistics
+	// CacheStatistics returns the counters of the caches of the answers
+	// of the external nameservers
+	CacheStatistics() []*types.DNSCacheStatistics
+	// SetQueryLog enables or disables the logging of the queries
+	SetQueryLog(bool)
+	// QueryStatistics returns the counters of the queries answered by
+	// the resolver
+	QueryStatistics() *types.DNSQueryStatistics
+}
+
+// ExtDNSRule forwards the queries for the names in Domain, and in its
+// subdomains, to the Servers. The rule with an empty Domain forwards the
+// queries for all the other names.
+type ExtDNSRule struct {
+	Domain  string
+	Servers []string
 }
 
 // DNSBackend represents a backend DNS resolver used for DNS name
@@ -49,6 +70,12 @@ type DNSBackend interface {
 	// ResolveService returns all the backend details about the containers or hosts
 	// backing a service. Its purpose is to satisfy an SRV query
 	ResolveService(name string) ([]*net.SRV, []net.IP)
+	// ResolveCNAME returns the canonical name of the user-defined CNAME
+	// record of the name
+	ResolveCNAME(name string) string
+	// ResolveTXT returns the texts of the user-defined TXT record of the
+	// name
+	ResolveTXT(name string) []string
 	// ExecFunc allows a function to be executed in the context of the backend
 	// on behalf of the resolver.
 	ExecFunc(f func()) error
@@ -62,20 +89,69 @@ const (
 	ptrIPv6domain   = ".ip6.arpa."
 	respTTL         = 600
 	maxExtDNS       = 3 //max number of external servers to try
-	extIOTimeout    = 4 * time.Second
 	defaultRespSize = 512
 	maxConcurrent   = 100
 	logInterval     = 2 * time.Second
+	raceExtDNS      = 2 // number of external servers queried in parallel
+
+	// backoff of the external servers which failed to answer, doubled
+	// on each consecutive failure
+	extDNSMinBackoff = time.Second
+	extDNSMaxBackoff = 2 * time.Minute
+)
+
+// The port and the I/O timeout of the external servers, which the tests
+// change to query fake servers
+var (
+	extDNSPort   = dnsPort
+	extIOTimeout = 4 * time.Second
+)
+
+// How a query was answered, as logged in the query log
+const (
+	queryLocal     = "local"
+	queryCached    = "cache"
+	queryForwarded = "forward"
+	queryFailed    = "fail"
+	queryDropped   = "drop"
 )
 
 type extDNSEntry struct {
 	ipStr string
+
+	// The server is tried after the healthy ones until retryAt,
+	// after failing to answer
+	failures int
+	retryAt  time.Time
+
+	queries      uint64
+	errors       uint64
+	tcpFallbacks uint64
+	latency      time.Duration
+}
+
+// extDNSGroup is the list of external servers the queries for the names
+// in domain are forwarded to
+type extDNSGroup struct {
+	domain string
+	list   [maxExtDNS]extDNSEntry
+	cache  *dnsCache
+}
+
+type byDomain []*extDNSGroup
+
+func (g byDomain) Len() int      { return len(g) }
+func (g byDomain) Swap(i, j int) { g[i], g[j] = g[j], g[i] }
+
+// The most specific domains first, the default rule last
+func (g byDomain) Less(i, j int) bool {
+	return dns.CountLabel(g[i].domain) > dns.CountLabel(g[j].domain)
 }
 
 // resolver implements the Resolver interface
 type resolver struct {
 	backend       DNSBackend
-	extDNSList    [maxExtDNS]extDNSEntry
+	extDNS        []*extDNSGroup
 	server        *dns.Server
 	conn          *net.UDPConn
 	tcpServer     *dns.Server
@@ -84,6 +160,11 @@ type resolver struct {
 	count         int32
 	tStamp        time.Time
 	queryLock     sync.Mutex
+	extDNSLock    sync.Mutex
+	caching       bool
+	statsLock     sync.Mutex
+	stats         types.DNSQueryStatistics
+	queryLog      bool
 	listenAddress string
 	proxyDNS      bool
 	resolverKey   string
@@ -145,6 +226,13 @@ func (r *resolver) Start() error {
 		return fmt.Errorf("setting up IP table rules failed: %v", err)
 	}
 
+	r.extDNSLock.Lock()
+	r.caching = true
+	for _, g := range r.extDNS {
+		g.cache = getDNSCache(g.servers())
+	}
+	r.extDNSLock.Unlock()
+
 	s := &dns.Server{Handler: r, PacketConn: r.conn}
 	r.server = s
 	go func() {
@@ -172,16 +260,242 @@ func (r *resolver) Stop() {
 	r.tStamp = time.Time{}
 	r.count = 0
 	r.queryLock = sync.Mutex{}
+
+	r.extDNSLock.Lock()
+	r.caching = false
+	for _, g := range r.extDNS {
+		putDNSCache(g.cache)
+		g.cache = nil
+	}
+	r.extDNSLock.Unlock()
+}
+
+func (r *resolver) SetExtServers(rules []ExtDNSRule) {
+	r.extDNSLock.Lock()
+	defer r.extDNSLock.Unlock()
+
+	old := make(map[string]*extDNSGroup, len(r.extDNS))
+	for _, g := range r.extDNS {
+		old[g.domain] = g
+	}
+
+	var groups []*extDNSGroup
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		domain := ""
+		if rule.Domain != "" {
+			domain = dns.Fqdn(strings.ToLower(strings.TrimPrefix(rule.Domain, "*.")))
+		}
+		// The first rule of a domain wins
+		if seen[domain] || (domain != "" && len(rule.Servers) == 0) {
+			continue
+		}
+		seen[domain] = true
+
+		g, ok := old[domain]
+		if ok {
+			delete(old, domain)
+		} else {
+			g = &extDNSGroup{domain: domain}
+		}
+
+		l := len(rule.Servers)
+		if l > maxExtDNS {
+			l = maxExtDNS
+		}
+		changed := false
+		for i := 0; i < maxExtDNS; i++ {
+			ipStr := ""
+			if i < l {
+				ipStr = rule.Servers[i]
+			}
+			if g.list[i].ipStr != ipStr {
+				g.list[i] = extDNSEntry{ipStr: ipStr}
+				changed = true
+			}
+		}
+
+		// The cached answers of the previous servers are not valid anymore
+		if r.caching && (changed || g.cache == nil) {
+			putDNSCache(g.cache)
+			g.cache = getDNSCache(g.servers())
+		}
+		groups = append(groups, g)
+	}
+
+	for _, g := range old {
+		putDNSCache(g.cache)
+	}
+
+	sort.Stable(byDomain(groups))
+	r.extDNS = groups
+}
+
+// servers returns the external servers of the group. Must be called with
+// extDNSLock held.
+func (g *extDNSGroup) servers() []string {
+	var servers []string
+	for i := 0; i < maxExtDNS; i++ {
+		if g.list[i].ipStr == "" {
+			break
+		}
+		servers = append(servers, g.list[i].ipStr)
+	}
+	return servers
+}
+
+// extDNSGroup returns the group of external servers the queries for the
+// name are forwarded to.
+func (r *resolver) extDNSGroup(name string) *extDNSGroup {
+	name = strings.ToLower(name)
+
+	r.extDNSLock.Lock()
+	defer r.extDNSLock.Unlock()
+
+	for _, g := range r.extDNS {
+		if g.domain == "" || dns.IsSubDomain(g.domain, name) {
+			return g
+		}
+	}
+	return nil
 }
 
-func (r *resolver) SetExtServers(dns []string) {
-	l := len(dns)
-	if l > maxExtDNS {
-		l = maxExtDNS
+func (r *resolver) CacheStatistics() []*types.DNSCacheStatistics {
+	r.extDNSLock.Lock()
+	defer r.extDNSLock.Unlock()
+
+	var stats []*types.DNSCacheStatistics
+	for _, g := range r.extDNS {
+		if st := g.cache.statistics(); st != nil {
+			stats = append(stats, st)
+		}
 	}
-	for i := 0; i < l; i++ {
-		r.extDNSList[i].ipStr = dns[i]
+	return stats
+}
+
+func (r *resolver) Statistics() []*types.ExtDNSStatistics {
+	r.extDNSLock.Lock()
+	defer r.extDNSLock.Unlock()
+
+	var stats []*types.ExtDNSStatistics
+	now := time.Now()
+	for _, g := range r.extDNS {
+		for i := 0; i < maxExtDNS; i++ {
+			extDNS := &g.list[i]
+			if extDNS.ipStr == "" {
+				break
+			}
+			st := &types.ExtDNSStatistics{
+				Domain:       g.domain,
+				Server:       extDNS.ipStr,
+				Healthy:      !now.Before(extDNS.retryAt),
+				Queries:      extDNS.queries,
+				Failures:     extDNS.errors,
+				TCPFallbacks: extDNS.tcpFallbacks,
+				Latency:      extDNS.latency,
+			}
+			if !st.Healthy {
+				st.RetryAt = extDNS.retryAt
+			}
+			stats = append(stats, st)
+		}
 	}
+	return stats
+}
+
+func (r *resolver) SetQueryLog(enable bool) {
+	r.statsLock.Lock()
+	r.queryLog = enable
+	r.statsLock.Unlock()
+}
+
+func (r *resolver) QueryStatistics() *types.DNSQueryStatistics {
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+
+	stats := r.stats
+	return &stats
+}
+
+// recordQuery accounts the query in the statistics of the resolver and
+// logs it when the query log is enabled. A nil resp means the query was
+// not answered.
+func (r *resolver) recordQuery(w dns.ResponseWriter, query *dns.Msg, resp *dns.Msg, source, upstream string, latency time.Duration) {
+	r.statsLock.Lock()
+	r.stats.Queries++
+	r.stats.Latency += latency
+	switch source {
+	case queryLocal:
+		r.stats.Local++
+	case queryCached:
+		r.stats.Cached++
+	case queryForwarded:
+		r.stats.Forwarded++
+	case queryDropped:
+		r.stats.Dropped++
+	default:
+		r.stats.Failed++
+	}
+	if resp != nil {
+		switch resp.Rcode {
+		case dns.RcodeNameError:
+			r.stats.NXDomain++
+		case dns.RcodeServerFailure:
+			r.stats.ServFail++
+		}
+	}
+	queryLog := r.queryLog
+	r.statsLock.Unlock()
+
+	if !queryLog {
+		return
+	}
+
+	q := query.Question[0]
+	fields := log.Fields{
+		"sandbox": filepath.Base(r.resolverKey),
+		"client":  w.RemoteAddr().String(),
+		"name":    q.Name,
+		"type":    dns.TypeToString[q.Qtype],
+		"source":  source,
+		"latency": latency,
+	}
+	if upstream != "" {
+		fields["upstream"] = upstream
+	}
+	if resp != nil {
+		fields["rcode"] = dns.RcodeToString[resp.Rcode]
+	}
+	log.WithFields(fields).Info("DNS query")
+}
+
+// parseExtDNSRules parses the rules of the form
+// "corp.example=10.1.1.53,10.1.1.54;lab.example=10.2.2.53"
+func parseExtDNSRules(s string) ([]ExtDNSRule, error) {
+	var rules []ExtDNSRule
+	for _, r := range strings.Split(s, ";") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		kv := strings.SplitN(r, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, types.BadRequestErrorf("invalid DNS forwarding rule %q, expected domain=server[,server]", r)
+		}
+		rule := ExtDNSRule{Domain: strings.TrimSpace(kv[0])}
+		if _, ok := dns.IsDomainName(strings.TrimPrefix(rule.Domain, "*.")); !ok {
+			return nil, types.BadRequestErrorf("invalid domain %q in DNS forwarding rule", rule.Domain)
+		}
+		for _, server := range strings.Split(kv[1], ",") {
+			server = strings.TrimSpace(server)
+			if net.ParseIP(server) == nil {
+				return nil, types.BadRequestErrorf("invalid server %q in DNS forwarding rule for %s", server, rule.Domain)
+			}
+			rule.Servers = append(rule.Servers, server)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
 }
 
 func (r *resolver) NameServer() string {
@@ -224,7 +538,7 @@ func (r *resolver) handleIPQuery(name string, query *dns.Msg, ipType int) (*dns.
 		return resp, nil
 	}
 	if addr == nil {
-		return nil, nil
+		return r.handleCNAMEQuery(name, query, ipType)
 	}
 
 	log.Debugf("Lookup for %s: IP %v", name, addr)
@@ -251,6 +565,61 @@ func (r *resolver) handleIPQuery(name string, query *dns.Msg, ipType int) (*dns.
 	return resp, nil
 }
 
+// handleCNAMEQuery answers the user-defined CNAME record of the name, along
+// with the addresses of the canonical name when it is also resolved by the
+// backend. The address query of other canonical names is left to the
+// client.
+func (r *resolver) handleCNAMEQuery(name string, query *dns.Msg, ipType int) (*dns.Msg, error) {
+	target := r.backend.ResolveCNAME(name)
+	if target == "" {
+		return nil, nil
+	}
+
+	log.Debugf("Lookup for %s: CNAME %s", name, target)
+
+	resp := createRespMsg(query)
+	rr := new(dns.CNAME)
+	rr.Hdr = dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: respTTL}
+	rr.Target = dns.Fqdn(target)
+	resp.Answer = append(resp.Answer, rr)
+
+	if ipType == 0 {
+		return resp, nil
+	}
+	addr, _ := r.backend.ResolveName(rr.Target, ipType)
+	for _, ip := range shuffleAddr(addr) {
+		var rr dns.RR
+		hdr := dns.RR_Header{Name: dns.Fqdn(target), Class: dns.ClassINET, Ttl: respTTL}
+		if ipType == types.IPv4 {
+			hdr.Rrtype = dns.TypeA
+			rr = &dns.A{Hdr: hdr, A: ip}
+		} else {
+			hdr.Rrtype = dns.TypeAAAA
+			rr = &dns.AAAA{Hdr: hdr, AAAA: ip}
+		}
+		resp.Answer = append(resp.Answer, rr)
+	}
+	return resp, nil
+}
+
+func (r *resolver) handleTXTQuery(name string, query *dns.Msg) (*dns.Msg, error) {
+	txt := r.backend.ResolveTXT(name)
+	if len(txt) == 0 {
+		return nil, nil
+	}
+
+	log.Debugf("Lookup for %s: TXT %v", name, txt)
+
+	resp := createRespMsg(query)
+	for _, t := range txt {
+		rr := new(dns.TXT)
+		rr.Hdr = dns.RR_Header{Name: name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: respTTL}
+		rr.Txt = []string{t}
+		resp.Answer = append(resp.Answer, rr)
+	}
+	return resp, nil
+}
+
 func (r *resolver) handlePTRQuery(ptr string, query *dns.Msg) (*dns.Msg, error) {
 	parts := []string{}
 
@@ -298,10 +667,17 @@ func (r *resolver) handleSRVQuery(svc string, query *dns.Msg) (*dns.Msg, error)
 	for i, r := range srv {
 		rr := new(dns.SRV)
 		rr.Hdr = dns.RR_Header{Name: svc, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: respTTL}
+		rr.Priority = r.Priority
+		rr.Weight = r.Weight
 		rr.Port = r.Port
 		rr.Target = r.Target
 		resp.Answer = append(resp.Answer, rr)
 
+		// The address of the targets of the user-defined records may not
+		// be known
+		if ip[i] == nil {
+			continue
+		}
 		rr1 := new(dns.A)
 		rr1.Hdr = dns.RR_Header{Name: r.Target, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: respTTL}
 		rr1.A = ip[i]
@@ -330,9 +706,11 @@ func truncateResp(resp *dns.Msg, maxSize int, isTCP bool) {
 
 func (r *resolver) ServeDNS(w dns.ResponseWriter, query *dns.Msg) {
 	var (
-		extConn net.Conn
-		resp    *dns.Msg
-		err     error
+		resp     *dns.Msg
+		err      error
+		source   = queryLocal
+		upstream string
+		start    = time.Now()
 	)
 
 	if query == nil || len(query.Question) == 0 {
@@ -340,6 +718,10 @@ func (r *resolver) ServeDNS(w dns.ResponseWriter, query *dns.Msg) {
 	}
 	name := query.Question[0].Name
 
+	defer func() {
+		r.recordQuery(w, query, resp, source, upstream, time.Since(start))
+	}()
+
 	switch query.Question[0].Qtype {
 	case dns.TypeA:
 		resp, err = r.handleIPQuery(name, query, types.IPv4)
@@ -349,10 +731,16 @@ func (r *resolver) ServeDNS(w dns.ResponseWriter, query *dns.Msg) {
 		resp, err = r.handlePTRQuery(name, query)
 	case dns.TypeSRV:
 		resp, err = r.handleSRVQuery(name, query)
+	case dns.TypeCNAME:
+		resp, err = r.handleCNAMEQuery(name, query, 0)
+	case dns.TypeTXT:
+		resp, err = r.handleTXTQuery(name, query)
 	}
 
 	if err != nil {
 		log.Error(err)
+		resp = nil
+		source = queryFailed
 		return
 	}
 
@@ -362,6 +750,7 @@ func (r *resolver) ServeDNS(w dns.ResponseWriter, query *dns.Msg) {
 		if !r.proxyDNS {
 			resp = new(dns.Msg)
 			resp.SetRcode(query, dns.RcodeServerFailure)
+			source = queryFailed
 			w.WriteMsg(resp)
 			return
 		}
@@ -394,71 +783,49 @@ func (r *resolver) ServeDNS(w dns.ResponseWriter, query *dns.Msg) {
 		}
 	}
 
-	if resp != nil {
-		if resp.Len() > maxSize {
-			truncateResp(resp, maxSize, proto == "tcp")
+	var (
+		g     *extDNSGroup
+		cache *dnsCache
+	)
+	if resp == nil {
+		source = queryFailed
+		if g = r.extDNSGroup(name); g == nil {
+			return
 		}
-	} else {
-		for i := 0; i < maxExtDNS; i++ {
-			extDNS := &r.extDNSList[i]
-			if extDNS.ipStr == "" {
-				break
-			}
-			extConnect := func() {
-				addr := fmt.Sprintf("%s:%d", extDNS.ipStr, 53)
-				extConn, err = net.DialTimeout(proto, addr, extIOTimeout)
-			}
-
-			execErr := r.backend.ExecFunc(extConnect)
-			if execErr != nil || err != nil {
-				log.Debugf("Connect failed, %s", err)
-				continue
-			}
-			log.Debugf("Query %s[%d] from %s, forwarding to %s:%s", name, query.Question[0].Qtype,
-				extConn.LocalAddr().String(), proto, extDNS.ipStr)
-
-			// Timeout has to be set for every IO operation.
-			extConn.SetDeadline(time.Now().Add(extIOTimeout))
-			co := &dns.Conn{
-				Conn:    extConn,
-				UDPSize: uint16(maxSize),
-			}
-			defer co.Close()
-
-			// limits the number of outstanding concurrent queries.
-			if r.forwardQueryStart() == false {
-				old := r.tStamp
-				r.tStamp = time.Now()
-				if r.tStamp.Sub(old) > logInterval {
-					log.Errorf("More than %v concurrent queries from %s", maxConcurrent, extConn.LocalAddr().String())
-				}
-				continue
-			}
-
-			err = co.WriteMsg(query)
-			if err != nil {
-				r.forwardQueryEnd()
-				log.Debugf("Send to DNS server failed, %s", err)
-				continue
-			}
+		r.extDNSLock.Lock()
+		cache = g.cache
+		r.extDNSLock.Unlock()
+		if resp = cache.get(query); resp != nil {
+			source = queryCached
+		}
+	}
 
-			resp, err = co.ReadMsg()
-			// Truncated DNS replies should be sent to the client so that the
-			// client can retry over TCP
-			if err != nil && err != dns.ErrTruncated {
-				r.forwardQueryEnd()
-				log.Debugf("Read from DNS server failed, %s", err)
-				continue
+	if resp == nil {
+		// limits the number of outstanding concurrent queries.
+		if r.forwardQueryStart() == false {
+			old := r.tStamp
+			r.tStamp = time.Now()
+			if r.tStamp.Sub(old) > logInterval {
+				log.Errorf("More than %v concurrent queries from %s", maxConcurrent, w.RemoteAddr().String())
 			}
-
-			r.forwardQueryEnd()
-
-			resp.Compress = true
-			break
+			source = queryDropped
+			return
 		}
+
+		resp, upstream = r.forwardExtDNS(g, query, proto, maxSize)
+		r.forwardQueryEnd()
 		if resp == nil {
 			return
 		}
+		source = queryForwarded
+		resp.Compress = true
+		cache.add(query, resp)
+	}
+
+	// The answers received over TCP from the external servers may be
+	// too large for the client
+	if resp.Len() > maxSize {
+		truncateResp(resp, maxSize, proto == "tcp")
 	}
 
 	if err = w.WriteMsg(resp); err != nil {
@@ -488,3 +855,177 @@ func (r *resolver) forwardQueryEnd() {
 		r.count--
 	}
 }
+
+// extServers returns the indexes of the external servers in the order
+// they are tried: the healthy ones first, then the ones which failed,
+// the ones which may be tried again the soonest first.
+func (r *resolver) extServers(g *extDNSGroup) []int {
+	r.extDNSLock.Lock()
+	defer r.extDNSLock.Unlock()
+
+	var healthy, failed []int
+	now := time.Now()
+	for i := 0; i < maxExtDNS; i++ {
+		extDNS := &g.list[i]
+		if extDNS.ipStr == "" {
+			break
+		}
+		if now.Before(extDNS.retryAt) {
+			j := len(failed)
+			for j > 0 && g.list[failed[j-1]].retryAt.After(extDNS.retryAt) {
+				j--
+			}
+			failed = append(failed, 0)
+			copy(failed[j+1:], failed[j:])
+			failed[j] = i
+			continue
+		}
+		healthy = append(healthy, i)
+	}
+
+	return append(healthy, failed...)
+}
+
+// forwardExtDNS forwards the query to the external servers of the group
+// and returns the first answer along with the server which sent it. The
+// first two servers are queried in parallel, so that a slow or dead server
+// does not stall the lookup, then the others in turn. When no server
+// answers, the last server tried is returned.
+func (r *resolver) forwardExtDNS(g *extDNSGroup, query *dns.Msg, proto string, maxSize int) (*dns.Msg, string) {
+	type answer struct {
+		resp  *dns.Msg
+		ipStr string
+	}
+
+	servers := r.extServers(g)
+	race := raceExtDNS
+	if len(servers) < race {
+		race = len(servers)
+	}
+
+	var last string
+	answers := make(chan answer, race)
+	for _, i := range servers[:race] {
+		go func(i int) {
+			resp, ipStr := r.exchangeExtDNS(g, i, query.Copy(), proto, maxSize)
+			answers <- answer{resp, ipStr}
+		}(i)
+	}
+	for n := 0; n < race; n++ {
+		a := <-answers
+		if a.resp != nil {
+			return a.resp, a.ipStr
+		}
+		last = a.ipStr
+	}
+
+	for _, i := range servers[race:] {
+		resp, ipStr := r.exchangeExtDNS(g, i, query, proto, maxSize)
+		if resp != nil {
+			return resp, ipStr
+		}
+		last = ipStr
+	}
+
+	return nil, last
+}
+
+// exchangeExtDNS sends the query to the external server and records its
+// health. A truncated answer received over UDP is queried again over
+// TCP. The address of the server is returned along with its answer.
+func (r *resolver) exchangeExtDNS(g *extDNSGroup, i int, query *dns.Msg, proto string, maxSize int) (*dns.Msg, string) {
+	r.extDNSLock.Lock()
+	ipStr := g.list[i].ipStr
+	r.extDNSLock.Unlock()
+
+	log.Debugf("Query %s[%d], forwarding to %s:%s", query.Question[0].Name, query.Question[0].Qtype, proto, ipStr)
+
+	start := time.Now()
+	resp, err := r.exchange(ipStr, query, proto, maxSize)
+	tcpFallback := err == nil && proto == "udp" && resp.Truncated
+	if tcpFallback {
+		log.Debugf("Truncated answer from DNS server %s, retrying over TCP", ipStr)
+		tcpResp, tcpErr := r.exchange(ipStr, query, "tcp", dns.MaxMsgSize-1)
+		if tcpErr != nil {
+			// Fall back to the truncated answer, the client may
+			// retry over TCP itself
+			log.Debugf("TCP query to DNS server %s failed: %v", ipStr, tcpErr)
+		} else {
+			resp = tcpResp
+		}
+	}
+	latency := time.Since(start)
+
+	r.extDNSLock.Lock()
+	defer r.extDNSLock.Unlock()
+
+	extDNS := &g.list[i]
+	if extDNS.ipStr != ipStr {
+		// The servers were changed meanwhile
+		return resp, ipStr
+	}
+	extDNS.queries++
+	if tcpFallback {
+		extDNS.tcpFallbacks++
+	}
+	if err != nil {
+		extDNS.errors++
+		extDNS.failures++
+		backoff := extDNSMinBackoff << uint(extDNS.failures-1)
+		if backoff > extDNSMaxBackoff || backoff <= 0 {
+			backoff = extDNSMaxBackoff
+		}
+		extDNS.retryAt = time.Now().Add(backoff)
+		log.Debugf("DNS server %s failed %d times, backing off for %v: %v", ipStr, extDNS.failures, backoff, err)
+		return nil, ipStr
+	}
+	extDNS.failures = 0
+	extDNS.retryAt = time.Time{}
+	extDNS.latency += latency
+
+	return resp, ipStr
+}
+
+// exchange sends the query to the external server from the network
+// namespace of the backend and reads its answer.
+func (r *resolver) exchange(ipStr string, query *dns.Msg, proto string, maxSize int) (*dns.Msg, error) {
+	var (
+		extConn net.Conn
+		err     error
+	)
+
+	extConnect := func() {
+		addr := net.JoinHostPort(ipStr, extDNSPort)
+		extConn, err = net.DialTimeout(proto, addr, extIOTimeout)
+	}
+	if execErr := r.backend.ExecFunc(extConnect); execErr != nil {
+		return nil, execErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Timeout has to be set for every IO operation.
+	extConn.SetDeadline(time.Now().Add(extIOTimeout))
+	co := &dns.Conn{
+		Conn:    extConn,
+		UDPSize: uint16(maxSize),
+	}
+	defer co.Close()
+
+	if err := co.WriteMsg(query); err != nil {
+		return nil, err
+	}
+
+	resp, err := co.ReadMsg()
+	// The truncated answers are usable, the caller retries them
+	// over TCP
+	if err != nil && err != dns.ErrTruncated {
+		return nil, err
+	}
+	if resp.Id != query.Id {
+		return nil, fmt.Errorf("DNS answer id %d does not match query id %d", resp.Id, query.Id)
+	}
+
+	return resp, nil
+}
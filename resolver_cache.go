@@ -0,0 +1,256 @@
+package libnetwork
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libnetwork/types"
+	"github.com/miekg/dns"
+)
+
+const (
+	maxCacheEntries = 4096
+	// Upper bounds of the time the answers are cached, RFC 2308
+	// recommends 1 to 3 hours for the negative answers
+	maxCacheTTL         = time.Hour
+	maxNegativeCacheTTL = time.Hour
+)
+
+// dnsCache caches the answers of the external DNS servers. It is shared
+// by all the resolvers forwarding to the same list of servers.
+type dnsCache struct {
+	servers   []string
+	refCnt    int
+	entries   map[dnsCacheKey]*list.Element
+	lru       *list.List
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	sync.Mutex
+}
+
+type dnsCacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+
+	// The DNSSEC OK bit and the checking disabled flag of the query
+	// change the records and the validation of the answer
+	do bool
+	cd bool
+}
+
+type dnsCacheEntry struct {
+	key     dnsCacheKey
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+var (
+	dnsCaches     = make(map[string]*dnsCache)
+	dnsCachesLock sync.Mutex
+)
+
+// getDNSCache returns the cache of the answers of the passed servers,
+// creating it if no other resolver uses it.
+func getDNSCache(servers []string) *dnsCache {
+	if len(servers) == 0 {
+		return nil
+	}
+	k := strings.Join(servers, ",")
+
+	dnsCachesLock.Lock()
+	defer dnsCachesLock.Unlock()
+
+	c, ok := dnsCaches[k]
+	if !ok {
+		c = &dnsCache{
+			servers: append([]string(nil), servers...),
+			entries: make(map[dnsCacheKey]*list.Element),
+			lru:     list.New(),
+		}
+		dnsCaches[k] = c
+	}
+	c.refCnt++
+
+	return c
+}
+
+// putDNSCache releases the cache, dropping it along with its answers
+// when no other resolver uses it.
+func putDNSCache(c *dnsCache) {
+	if c == nil {
+		return
+	}
+	k := strings.Join(c.servers, ",")
+
+	dnsCachesLock.Lock()
+	defer dnsCachesLock.Unlock()
+
+	c.refCnt--
+	if c.refCnt <= 0 {
+		delete(dnsCaches, k)
+	}
+}
+
+func cacheKey(query *dns.Msg) (dnsCacheKey, bool) {
+	if len(query.Question) != 1 {
+		return dnsCacheKey{}, false
+	}
+	q := query.Question[0]
+	k := dnsCacheKey{
+		name:   strings.ToLower(q.Name),
+		qtype:  q.Qtype,
+		qclass: q.Qclass,
+		cd:     query.CheckingDisabled,
+	}
+	if opt := query.IsEdns0(); opt != nil {
+		k.do = opt.Do()
+	}
+	return k, true
+}
+
+// get returns the cached answer to the query, with the TTLs lowered by
+// the time spent in the cache.
+func (c *dnsCache) get(query *dns.Msg) *dns.Msg {
+	if c == nil {
+		return nil
+	}
+	k, ok := cacheKey(query)
+	if !ok {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	el, ok := c.entries[k]
+	if !ok {
+		c.misses++
+		return nil
+	}
+	e := el.Value.(*dnsCacheEntry)
+	now := time.Now()
+	if !now.Before(e.expires) {
+		c.remove(el)
+		c.misses++
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	c.hits++
+
+	resp := e.msg.Copy()
+	resp.Id = query.Id
+	resp.Question = query.Question
+	elapsed := uint32(now.Sub(e.stored) / time.Second)
+	for _, rrs := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			if rr.Header().Ttl > elapsed {
+				rr.Header().Ttl -= elapsed
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+
+	return resp
+}
+
+// add caches the answer for the lowest TTL of its records. The negative
+// answers are cached per RFC 2308, for the TTL of the SOA record of the
+// authority section bounded by its minimum field, and are not cached
+// without SOA record.
+func (c *dnsCache) add(query *dns.Msg, resp *dns.Msg) {
+	if c == nil || resp.Truncated {
+		return
+	}
+	k, ok := cacheKey(query)
+	if !ok {
+		return
+	}
+
+	var ttl time.Duration
+	switch {
+	case resp.Rcode == dns.RcodeSuccess && len(resp.Answer) > 0:
+		ttl = maxCacheTTL
+		for _, rrs := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+			for _, rr := range rrs {
+				if rr.Header().Rrtype == dns.TypeOPT {
+					continue
+				}
+				if t := time.Duration(rr.Header().Ttl) * time.Second; t < ttl {
+					ttl = t
+				}
+			}
+		}
+	case resp.Rcode == dns.RcodeSuccess || resp.Rcode == dns.RcodeNameError:
+		for _, rr := range resp.Ns {
+			soa, ok := rr.(*dns.SOA)
+			if !ok {
+				continue
+			}
+			ttl = time.Duration(soa.Hdr.Ttl) * time.Second
+			if t := time.Duration(soa.Minttl) * time.Second; t < ttl {
+				ttl = t
+			}
+			if ttl > maxNegativeCacheTTL {
+				ttl = maxNegativeCacheTTL
+			}
+			break
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	e := &dnsCacheEntry{
+		key:     k,
+		msg:     resp.Copy(),
+		stored:  now,
+		expires: now.Add(ttl),
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if el, ok := c.entries[k]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(e)
+	for c.lru.Len() > maxCacheEntries {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+}
+
+func (c *dnsCache) remove(el *list.Element) {
+	delete(c.entries, el.Value.(*dnsCacheEntry).key)
+	c.lru.Remove(el)
+}
+
+func (c *dnsCache) statistics() *types.DNSCacheStatistics {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	return &types.DNSCacheStatistics{
+		Servers:   append([]string(nil), c.servers...),
+		Entries:   c.lru.Len(),
+		Capacity:  maxCacheEntries,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
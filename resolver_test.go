@@ -0,0 +1,413 @@
+package libnetwork
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/libnetwork/types"
+	"github.com/miekg/dns"
+)
+
+// tstDNSBackend answers the resolver from static maps, keyed by the
+// lower case name without the trailing dot
+type tstDNSBackend struct {
+	ips    map[string][]net.IP
+	cnames map[string]string
+	txts   map[string][]string
+	srvs   map[string][]*net.SRV
+}
+
+func (b *tstDNSBackend) ResolveName(name string, iplen int) ([]net.IP, bool) {
+	var ips []net.IP
+	for _, ip := range b.ips[strings.ToLower(name)] {
+		if (iplen == net.IPv4len) == (ip.To4() != nil) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, len(ips) == 0 && len(b.ips[strings.ToLower(name)]) > 0
+}
+
+func (b *tstDNSBackend) ResolveIP(name string) string {
+	return ""
+}
+
+func (b *tstDNSBackend) ResolveService(name string) ([]*net.SRV, []net.IP) {
+	srv := b.srvs[strings.ToLower(name)]
+	ips := make([]net.IP, 0, len(srv))
+	for _, s := range srv {
+		ips = append(ips, b.ips[strings.TrimSuffix(s.Target, ".")]...)
+	}
+	return srv, ips
+}
+
+func (b *tstDNSBackend) ResolveCNAME(name string) string {
+	return b.cnames[strings.ToLower(name)]
+}
+
+func (b *tstDNSBackend) ResolveTXT(name string) []string {
+	return b.txts[strings.ToLower(name)]
+}
+
+func (b *tstDNSBackend) ExecFunc(f func()) error {
+	f()
+	return nil
+}
+
+func (b *tstDNSBackend) NdotsSet() bool {
+	return false
+}
+
+// tstRespWriter keeps the response written by the resolver
+type tstRespWriter struct {
+	tstDNSWriter
+	msg *dns.Msg
+}
+
+func (w *tstRespWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
+
+func serveQuery(r *resolver, name string, qtype uint16) *dns.Msg {
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(name), qtype)
+	w := &tstRespWriter{}
+	r.ServeDNS(w, query)
+	return w.msg
+}
+
+func TestResolverNoProxyFailure(t *testing.T) {
+	r := NewResolver(resolverIPSandbox, false, "", &tstDNSBackend{}).(*resolver)
+
+	resp := serveQuery(r, "www.example.com", dns.TypeA)
+	if resp == nil || resp.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("Expected a SERVFAIL response, got %v", resp)
+	}
+
+	st := r.QueryStatistics()
+	if st.Queries != 1 || st.Failed != 1 || st.Local != 0 || st.ServFail != 1 {
+		t.Fatalf("Unexpected query statistics: %s", st)
+	}
+}
+
+// Behaviours of the fake external servers
+const (
+	upstreamAnswer   = "answer"
+	upstreamTruncate = "truncate" // truncated answers over UDP
+	upstreamTimeout  = "timeout"  // never answers
+	upstreamRefuse   = "refuse"   // not listening
+)
+
+// upstreamReply returns the answer of a fake external server to the query
+// received over proto, nil if it does not answer
+func upstreamReply(mode, proto string, query *dns.Msg) *dns.Msg {
+	if mode == upstreamTimeout {
+		return nil
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(query)
+	if mode == upstreamTruncate && proto == "udp" {
+		resp.Truncated = true
+		return resp
+	}
+	rr, err := dns.NewRR(query.Question[0].Name + " 60 IN A 192.0.2.1")
+	if err != nil {
+		panic(err)
+	}
+	resp.Answer = append(resp.Answer, rr)
+	return resp
+}
+
+// serveUpstreamUDP answers the queries received on the connection until
+// it is closed. The UDP server of the dns package is not used as its
+// connection cannot be closed while it is reading.
+func serveUpstreamUDP(mode string, pc *net.UDPConn) {
+	b := make([]byte, dns.MaxMsgSize)
+	for {
+		n, addr, err := pc.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+		query := new(dns.Msg)
+		if err := query.Unpack(b[:n]); err != nil {
+			continue
+		}
+		if resp := upstreamReply(mode, "udp", query); resp != nil {
+			if out, err := resp.Pack(); err == nil {
+				pc.WriteToUDP(out, addr)
+			}
+		}
+	}
+}
+
+// startUpstreams starts a fake external server per mode, on its own
+// loopback address and on the same port, which the resolver is pointed
+// to. It returns the addresses of the servers and a function restoring
+// the resolver settings and stopping the servers.
+func startUpstreams(t *testing.T, modes ...string) ([]string, func()) {
+	var (
+		ips   []string
+		conns []io.Closer
+		port  = 0
+	)
+	stop := func() {
+		for _, c := range conns {
+			c.Close()
+		}
+		extDNSPort = dnsPort
+		extIOTimeout = 4 * time.Second
+	}
+
+	for i, mode := range modes {
+		ip := fmt.Sprintf("127.0.10.%d", i+1)
+		ips = append(ips, ip)
+		if mode == upstreamRefuse {
+			continue
+		}
+
+		l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP(ip), Port: port})
+		if err != nil {
+			stop()
+			t.Fatal(err)
+		}
+		port = l.Addr().(*net.TCPAddr).Port
+		pc, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(ip), Port: port})
+		if err != nil {
+			l.Close()
+			stop()
+			t.Fatal(err)
+		}
+
+		conns = append(conns, l, pc)
+
+		mode := mode
+		started := make(chan struct{})
+		s := &dns.Server{
+			Listener: l,
+			Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+				if resp := upstreamReply(mode, "tcp", query); resp != nil {
+					w.WriteMsg(resp)
+				}
+			}),
+			NotifyStartedFunc: func() { close(started) },
+		}
+		go s.ActivateAndServe()
+		<-started
+		go serveUpstreamUDP(mode, pc)
+	}
+	if port == 0 {
+		l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.10.1")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		port = l.Addr().(*net.TCPAddr).Port
+		l.Close()
+	}
+
+	extDNSPort = strconv.Itoa(port)
+	extIOTimeout = 200 * time.Millisecond
+	return ips, stop
+}
+
+func newForwardingResolver(servers []string) *resolver {
+	r := NewResolver(resolverIPSandbox, true, "", &tstDNSBackend{}).(*resolver)
+	r.SetExtServers([]ExtDNSRule{{Servers: servers}})
+	return r
+}
+
+func extDNSStatistics(r *resolver, server string) *types.ExtDNSStatistics {
+	for _, st := range r.Statistics() {
+		if st.Server == server {
+			return st
+		}
+	}
+	return nil
+}
+
+func TestResolverForwardTruncated(t *testing.T) {
+	servers, stop := startUpstreams(t, upstreamTruncate)
+	defer stop()
+	r := newForwardingResolver(servers)
+
+	resp := serveQuery(r, "www.example.com", dns.TypeA)
+	if resp == nil || resp.Truncated || len(resp.Answer) != 1 {
+		t.Fatalf("Expected the answer to be queried again over TCP, got %v", resp)
+	}
+
+	st := extDNSStatistics(r, servers[0])
+	if st == nil || st.Queries != 1 || st.TCPFallbacks != 1 || st.Failures != 0 || !st.Healthy {
+		t.Fatalf("Unexpected statistics of the server: %#v", st)
+	}
+	if qs := r.QueryStatistics(); qs.Forwarded != 1 {
+		t.Fatalf("Expected the query to be forwarded: %s", qs)
+	}
+}
+
+func TestResolverForwardTimeout(t *testing.T) {
+	servers, stop := startUpstreams(t, upstreamTimeout, upstreamAnswer)
+	defer stop()
+	r := newForwardingResolver(servers)
+	g := r.extDNSGroup("www.example.com.")
+
+	query := new(dns.Msg)
+	query.SetQuestion("www.example.com.", dns.TypeA)
+
+	// Both servers are raced, the dead one does not delay the answer
+	start := time.Now()
+	resp, upstream := r.forwardExtDNS(g, query, "udp", defaultRespSize)
+	if resp == nil || upstream != servers[1] {
+		t.Fatalf("Expected an answer from %s, got %v from %s", servers[1], resp, upstream)
+	}
+	if d := time.Since(start); d >= extIOTimeout {
+		t.Fatalf("Expected the answer before the timeout of the dead server, got it after %v", d)
+	}
+
+	// The answer is returned before the dead server times out
+	time.Sleep(2 * extIOTimeout)
+	st := extDNSStatistics(r, servers[0])
+	if st == nil || st.Healthy || st.Failures != 1 || st.RetryAt.IsZero() {
+		t.Fatalf("Expected the dead server to back off: %#v", st)
+	}
+	if order := r.extServers(g); len(order) != 2 || order[0] != 1 || order[1] != 0 {
+		t.Fatalf("Expected the dead server to be tried last, got %v", order)
+	}
+}
+
+func TestResolverForwardFailures(t *testing.T) {
+	servers, stop := startUpstreams(t, upstreamRefuse, upstreamTimeout, upstreamAnswer)
+	defer stop()
+	r := newForwardingResolver(servers)
+	g := r.extDNSGroup("www.example.com.")
+
+	query := new(dns.Msg)
+	query.SetQuestion("www.example.com.", dns.TypeA)
+
+	// The third server is only tried once the raced ones failed
+	resp, upstream := r.forwardExtDNS(g, query, "udp", defaultRespSize)
+	if resp == nil || upstream != servers[2] {
+		t.Fatalf("Expected an answer from %s, got %v from %s", servers[2], resp, upstream)
+	}
+	for _, server := range servers[:2] {
+		if st := extDNSStatistics(r, server); st == nil || st.Healthy || st.Failures != 1 {
+			t.Fatalf("Expected server %s to back off: %#v", server, st)
+		}
+	}
+
+	// The backoff doubles with the consecutive failures
+	r = newForwardingResolver(servers[:1])
+	g = r.extDNSGroup("www.example.com.")
+	for failures := 1; failures <= 2; failures++ {
+		before := time.Now()
+		resp, upstream = r.forwardExtDNS(g, query, "udp", defaultRespSize)
+		if resp != nil || upstream != servers[0] {
+			t.Fatalf("Expected no answer and %s to be named, got %v from %s", servers[0], resp, upstream)
+		}
+		st := extDNSStatistics(r, servers[0])
+		backoff := extDNSMinBackoff << uint(failures-1)
+		if st.Failures != uint64(failures) || st.RetryAt.Before(before.Add(backoff)) || st.RetryAt.After(time.Now().Add(backoff)) {
+			t.Fatalf("Unexpected backoff after %d failures: %#v", failures, st)
+		}
+	}
+
+	// No answer is sent to the client when all the servers failed
+	if resp := serveQuery(r, "www.example.com", dns.TypeA); resp != nil {
+		t.Fatalf("Unexpected answer %v", resp)
+	}
+	if qs := r.QueryStatistics(); qs.Failed != 1 || qs.Forwarded != 0 {
+		t.Fatalf("Expected the query to fail: %s", qs)
+	}
+}
+
+// TestResolverStaticRecords answers the user-defined records of a network,
+// behind the names and the services of its containers
+func TestResolverStaticRecords(t *testing.T) {
+	c := &controller{
+		svcRecords: make(map[string]svcInfo),
+		dnsRecords: make(map[string]map[string]*DNSRecord),
+	}
+	n := &network{id: "n1", name: "net1", ctrlr: c, dnsRecords: make(map[string]*DNSRecord)}
+
+	if _, err := (DNSRecord{Name: "_pg.internal", Type: DNSRecordSRV, Values: []string{"10 5 5432 db.internal"}}).normalize(); err == nil {
+		t.Fatal("Expected a SRV record without protocol to be rejected")
+	}
+	for _, rec := range []DNSRecord{
+		{Name: "db.internal", Type: DNSRecordA, Values: []string{"10.1.0.5"}},
+		{Name: "db.internal", Type: DNSRecordAAAA, Values: []string{"fd00::5"}},
+		{Name: "db.internal", Type: DNSRecordTXT, Values: []string{"v=1", "primary"}},
+		{Name: "cache.internal", Type: DNSRecordCNAME, Values: []string{"db.internal"}},
+		{Name: "_pg._tcp.internal", Type: DNSRecordSRV, Values: []string{"10 5 5432 db.internal"}},
+		{Name: "web", Type: DNSRecordA, Values: []string{"10.1.0.9"}},
+		{Name: "_http._tcp.web", Type: DNSRecordSRV, Values: []string{"0 0 8080 static.internal"}},
+	} {
+		rec, err := rec.normalize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.dnsRecords[rec.key()] = &rec
+	}
+
+	n.addSvcRecords("web", net.ParseIP("10.0.0.2"), nil, true)
+	sr := c.svcRecords[n.ID()]
+	sr.service = map[string][]servicePorts{"web": {{
+		portName: "_http",
+		proto:    "_tcp",
+		target:   []serviceTarget{{name: "web.", ip: net.ParseIP("10.0.0.2"), port: 80}},
+	}}}
+	c.svcRecords[n.ID()] = sr
+
+	r := NewResolver(resolverIPSandbox, false, "", n).(*resolver)
+
+	resp := serveQuery(r, "db.internal", dns.TypeA)
+	if resp == nil || len(resp.Answer) != 1 || !resp.Answer[0].(*dns.A).A.Equal(net.ParseIP("10.1.0.5")) {
+		t.Fatalf("Unexpected A response %v", resp)
+	}
+
+	resp = serveQuery(r, "DB.internal", dns.TypeAAAA)
+	if resp == nil || len(resp.Answer) != 1 || !resp.Answer[0].(*dns.AAAA).AAAA.Equal(net.ParseIP("fd00::5")) {
+		t.Fatalf("Unexpected AAAA response %v", resp)
+	}
+
+	resp = serveQuery(r, "db.internal", dns.TypeTXT)
+	if resp == nil || len(resp.Answer) != 2 || resp.Answer[0].(*dns.TXT).Txt[0] != "v=1" || resp.Answer[1].(*dns.TXT).Txt[0] != "primary" {
+		t.Fatalf("Unexpected TXT response %v", resp)
+	}
+
+	// The address of the canonical name follows the CNAME record
+	resp = serveQuery(r, "cache.internal", dns.TypeA)
+	if resp == nil || len(resp.Answer) != 2 || resp.Answer[0].(*dns.CNAME).Target != "db.internal." ||
+		!resp.Answer[1].(*dns.A).A.Equal(net.ParseIP("10.1.0.5")) {
+		t.Fatalf("Unexpected CNAME response %v", resp)
+	}
+
+	resp = serveQuery(r, "cache.internal", dns.TypeCNAME)
+	if resp == nil || len(resp.Answer) != 1 || resp.Answer[0].(*dns.CNAME).Target != "db.internal." {
+		t.Fatalf("Unexpected CNAME response %v", resp)
+	}
+
+	resp = serveQuery(r, "_pg._tcp.internal", dns.TypeSRV)
+	if resp == nil || len(resp.Answer) != 1 || len(resp.Extra) != 1 {
+		t.Fatalf("Unexpected SRV response %v", resp)
+	}
+	if srv := resp.Answer[0].(*dns.SRV); srv.Priority != 10 || srv.Weight != 5 || srv.Port != 5432 || srv.Target != "db.internal." {
+		t.Fatalf("Unexpected SRV record %v", srv)
+	}
+	if !resp.Extra[0].(*dns.A).A.Equal(net.ParseIP("10.1.0.5")) {
+		t.Fatalf("Unexpected SRV target address %v", resp.Extra[0])
+	}
+
+	// The containers take precedence over the user-defined records
+	resp = serveQuery(r, "web", dns.TypeA)
+	if resp == nil || len(resp.Answer) != 1 || !resp.Answer[0].(*dns.A).A.Equal(net.ParseIP("10.0.0.2")) {
+		t.Fatalf("Unexpected A response for the container %v", resp)
+	}
+
+	resp = serveQuery(r, "_http._tcp.web", dns.TypeSRV)
+	if resp == nil || len(resp.Answer) != 1 || resp.Answer[0].(*dns.SRV).Target != "web." || resp.Answer[0].(*dns.SRV).Port != 80 {
+		t.Fatalf("Unexpected SRV response for the container service %v", resp)
+	}
+}
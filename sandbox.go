@@ -26,8 +26,19 @@ type Sandbox interface {
 	ContainerID() string
 	// Labels returns the sandbox's labels
 	Labels() map[string]interface{}
-	// Statistics retrieves the interfaces' statistics for the sandbox
+	// Statistics retrieves the interfaces' statistics for the sandbox,
+	// along with those of the service load balancers reached through
+	// each interface
 	Statistics() (map[string]*types.InterfaceStatistics, error)
+	// ResolverStatistics retrieves the health and the statistics of the
+	// external DNS servers used by the embedded resolver of the sandbox
+	ResolverStatistics() []*types.ExtDNSStatistics
+	// ResolverCacheStatistics retrieves the statistics of the cache of
+	// the answers of the external DNS servers used by the sandbox
+	ResolverCacheStatistics() []*types.DNSCacheStatistics
+	// ResolverQueryStatistics retrieves the counters of the queries
+	// answered by the embedded resolver of the sandbox
+	ResolverQueryStatistics() *types.DNSQueryStatistics
 	// Refresh leaves all the endpoints, resets and re-applies the options,
 	// re-joins all the endpoints without destroying the osl sandbox
 	Refresh(options ...SandboxOption) error
@@ -117,6 +128,7 @@ type resolvConfPathConfig struct {
 	dnsList              []string
 	dnsSearchList        []string
 	dnsOptionsList       []string
+	dnsForwardRules      []ExtDNSRule
 }
 
 type containerConfig struct {
@@ -175,9 +187,66 @@ func (sb *sandbox) Statistics() (map[string]*types.InterfaceStatistics, error) {
 		}
 	}
 
+	lbStats, err := sb.lbStatistics()
+	if err != nil || len(lbStats) == 0 {
+		return m, err
+	}
+
+	// The load balancers of a network are reached through the
+	// interface of the sandbox endpoint on that network.
+	ifaces := make(map[string]string)
+	for _, ep := range sb.getConnectedEndpoints() {
+		if ep.Iface() == nil {
+			continue
+		}
+		for _, i := range osb.Info().Interfaces() {
+			if types.CompareIPNet(i.Address(), ep.Iface().Address()) {
+				ifaces[ep.getNetwork().ID()] = i.DstName()
+			}
+		}
+	}
+	for _, st := range lbStats {
+		if is, ok := m[ifaces[st.NetworkID]]; ok {
+			is.LoadBalancers = append(is.LoadBalancers, st)
+		}
+	}
+
 	return m, nil
 }
 
+func (sb *sandbox) ResolverStatistics() []*types.ExtDNSStatistics {
+	sb.Lock()
+	r := sb.resolver
+	sb.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	return r.Statistics()
+}
+
+func (sb *sandbox) ResolverCacheStatistics() []*types.DNSCacheStatistics {
+	sb.Lock()
+	r := sb.resolver
+	sb.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	return r.CacheStatistics()
+}
+
+func (sb *sandbox) ResolverQueryStatistics() *types.DNSQueryStatistics {
+	sb.Lock()
+	r := sb.resolver
+	sb.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	return r.QueryStatistics()
+}
+
 func (sb *sandbox) Delete() error {
 	return sb.delete(false)
 }
@@ -257,6 +326,8 @@ func (sb *sandbox) delete(force bool) error {
 	delete(c.sandboxes, sb.ID())
 	c.Unlock()
 
+	c.publishSandboxEvent(EventSandboxDestroy, sb)
+
 	return nil
 }
 
@@ -455,6 +526,24 @@ func (sb *sandbox) ResolveService(name string) ([]*net.SRV, []net.IP) {
 	return srv, ip
 }
 
+func (sb *sandbox) ResolveCNAME(name string) string {
+	for _, ep := range sb.getConnectedEndpoints() {
+		if target := ep.getNetwork().ResolveCNAME(name); target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
+func (sb *sandbox) ResolveTXT(name string) []string {
+	for _, ep := range sb.getConnectedEndpoints() {
+		if txt := ep.getNetwork().ResolveTXT(name); len(txt) > 0 {
+			return txt
+		}
+	}
+	return nil
+}
+
 func getDynamicNwEndpoints(epList []*endpoint) []*endpoint {
 	eps := []*endpoint{}
 	for _, ep := range epList {
@@ -755,6 +844,9 @@ func (sb *sandbox) restoreOslSandbox() error {
 		if len(i.llAddrs) != 0 {
 			ifaceOptions = append(ifaceOptions, sb.osSbox.InterfaceOptions().LinkLocalAddresses(i.llAddrs))
 		}
+		if i.qos != nil {
+			ifaceOptions = append(ifaceOptions, sb.osSbox.InterfaceOptions().QosPolicy(i.qos))
+		}
 		Ifaces[fmt.Sprintf("%s+%s", i.srcName, i.dstPrefix)] = ifaceOptions
 		if joinInfo != nil {
 			for _, r := range joinInfo.StaticRoutes {
@@ -811,6 +903,9 @@ func (sb *sandbox) populateNetworkResources(ep *endpoint) error {
 		if i.mac != nil {
 			ifaceOptions = append(ifaceOptions, sb.osSbox.InterfaceOptions().MacAddress(i.mac))
 		}
+		if i.qos != nil {
+			ifaceOptions = append(ifaceOptions, sb.osSbox.InterfaceOptions().QosPolicy(i.qos))
+		}
 
 		if err := sb.osSbox.AddInterface(i.srcName, i.dstPrefix, ifaceOptions...); err != nil {
 			return fmt.Errorf("failed to add interface %s to sandbox: %v", i.srcName, err)
@@ -838,6 +933,9 @@ func (sb *sandbox) populateNetworkResources(ep *endpoint) error {
 	sb.populatedEndpoints[ep.ID()] = struct{}{}
 	sb.Unlock()
 
+	// The network may have DNS forwarding rules
+	sb.updateExtDNS()
+
 	// Populate load balancer only after updating all the other
 	// information including gateway and other routes so that
 	// loadbalancers are populated all the network state is in
@@ -910,6 +1008,8 @@ func (sb *sandbox) clearNetworkResources(origEp *endpoint) error {
 		sb.updateGateway(gwepAfter)
 	}
 
+	sb.updateExtDNS()
+
 	// Only update the store if we did not come here as part of
 	// sandbox delete. If we came here as part of delete then do
 	// not bother updating the store. The sandbox object will be
@@ -1055,6 +1155,16 @@ func OptionDNSOptions(options string) SandboxOption {
 	}
 }
 
+// OptionDNSForward function returns an option setter for a conditional
+// forwarding rule of the embedded DNS server, forwarding the queries for
+// the names in the domain to the servers, to be passed to container
+// Create method.
+func OptionDNSForward(domain string, servers ...string) SandboxOption {
+	return func(sb *sandbox) {
+		sb.config.dnsForwardRules = append(sb.config.dnsForwardRules, ExtDNSRule{Domain: domain, Servers: servers})
+	}
+}
+
 // OptionUseDefaultSandbox function returns an option setter for using default sandbox to
 // be passed to container Create method.
 func OptionUseDefaultSandbox() SandboxOption {
@@ -13,6 +13,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/libnetwork/etchosts"
+	"github.com/docker/libnetwork/netlabel"
 	"github.com/docker/libnetwork/resolvconf"
 	"github.com/docker/libnetwork/types"
 )
@@ -44,7 +45,8 @@ func (sb *sandbox) startResolver(restore bool) {
 				return
 			}
 		}
-		sb.resolver.SetExtServers(sb.extDNS)
+		sb.resolver.SetExtServers(sb.extDNSRules())
+		sb.resolver.SetQueryLog(sb.controller.Config().Daemon.DNSQueryLog)
 
 		if err = sb.osSbox.InvokeFunc(sb.resolver.SetupFunc(0)); err != nil {
 			log.Errorf("Resolver Setup function failed for container %s, %q", sb.ContainerID(), err)
@@ -57,6 +59,45 @@ func (sb *sandbox) startResolver(restore bool) {
 	})
 }
 
+// extDNSRules returns the rules selecting the external servers the
+// embedded DNS server forwards the queries to: the rules of the sandbox,
+// then the rules of the connected networks, then the servers from
+// resolv.conf for all the other names.
+func (sb *sandbox) extDNSRules() []ExtDNSRule {
+	rules := append([]ExtDNSRule(nil), sb.config.dnsForwardRules...)
+	for _, ep := range sb.getConnectedEndpoints() {
+		n := ep.getNetwork()
+		if n == nil {
+			continue
+		}
+		label, ok := n.Labels()[netlabel.DNSForward]
+		if !ok {
+			continue
+		}
+		nRules, err := parseExtDNSRules(label)
+		if err != nil {
+			log.Warnf("Ignoring DNS forwarding rules of network %s: %v", n.Name(), err)
+			continue
+		}
+		rules = append(rules, nRules...)
+	}
+
+	return append(rules, ExtDNSRule{Servers: sb.extDNS})
+}
+
+// updateExtDNS updates the forwarding rules of the embedded DNS server
+// after the networks of the sandbox changed.
+func (sb *sandbox) updateExtDNS() {
+	sb.Lock()
+	r := sb.resolver
+	sb.Unlock()
+	if r == nil {
+		return
+	}
+
+	r.SetExtServers(sb.extDNSRules())
+}
+
 func (sb *sandbox) setupResolutionFiles() error {
 	if err := sb.buildHostsFile(); err != nil {
 		return err
@@ -11,6 +11,9 @@ import (
 func (sb *sandbox) startResolver(bool) {
 }
 
+func (sb *sandbox) updateExtDNS() {
+}
+
 func (sb *sandbox) setupResolutionFiles() error {
 	return nil
 }
@@ -193,6 +193,7 @@ func (c *controller) sandboxCleanup(activeSandboxes map[string]interface{}) {
 		return
 	}
 
+	var restored []*sandbox
 	for _, kvo := range kvol {
 		sbs := kvo.(*sbState)
 
@@ -277,6 +278,18 @@ func (c *controller) sandboxCleanup(activeSandboxes map[string]interface{}) {
 			if !c.isAgent() {
 				c.watchSvcRecord(ep)
 			}
+
+			// The endpoint is plumbed already, the loadbalancers
+			// get programmed as the service bindings are added,
+			// taking over the ipvs services of the previous run.
+			if ep.Iface() != nil {
+				sb.populatedEndpoints[ep.ID()] = struct{}{}
+			}
 		}
+		restored = append(restored, sb)
 	}
+
+	// Remove the ipvs services of the previous run which were not
+	// taken over once the service bindings are replayed.
+	c.sweepLoadBalancers(restored)
 }
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
+
+	"github.com/docker/libnetwork/types"
 )
 
 var (
@@ -48,17 +51,147 @@ type service struct {
 	// Service aliases
 	aliases []string
 
+	// Load balancing options of the service
+	lbConfig lbConfig
+
 	sync.Mutex
 }
 
+// lbConfig specifies how the connections to the virtual IP of a
+// service are balanced across its backends.
+type lbConfig struct {
+	// IPVS scheduler, round robin when empty
+	scheduler string
+
+	// Timeout in seconds of the affinity of a client to a backend,
+	// no affinity when zero
+	persistence uint32
+
+	// Probe of the backends, taken out of the service while failing
+	healthCheck lbHealthCheck
+}
+
+// lbHealthCheck specifies how the backends of a service are probed,
+// no probe when the protocol is empty.
+type lbHealthCheck struct {
+	// tcp, the connection to the port must succeed, or http, the
+	// response to a GET of the path must be a 2xx or 3xx
+	protocol string
+	port     uint32
+	path     string
+
+	interval time.Duration
+	timeout  time.Duration
+}
+
+var lbSchedulers = map[string]bool{
+	"":    true,
+	"rr":  true,
+	"wrr": true,
+	"lc":  true,
+	"sh":  true,
+	"mh":  true,
+}
+
+func (cfg lbConfig) validate() error {
+	if !lbSchedulers[cfg.scheduler] {
+		return types.BadRequestErrorf("unsupported load balancing scheduler %q", cfg.scheduler)
+	}
+	return cfg.healthCheck.validate()
+}
+
+func (hc lbHealthCheck) validate() error {
+	switch hc.protocol {
+	case "":
+		return nil
+	case "tcp", "http":
+	default:
+		return types.BadRequestErrorf("unsupported health check protocol %q", hc.protocol)
+	}
+	if hc.port == 0 || hc.port > 65535 {
+		return types.BadRequestErrorf("invalid health check port %d", hc.port)
+	}
+	if hc.path != "" && hc.path[0] != '/' {
+		return types.BadRequestErrorf("invalid health check path %q", hc.path)
+	}
+	if hc.interval < 0 || hc.timeout < 0 {
+		return types.BadRequestErrorf("invalid health check interval %v or timeout %v", hc.interval, hc.timeout)
+	}
+	return nil
+}
+
+type lbBackend struct {
+	ip net.IP
+
+	// Relative share of the connections the backend receives with
+	// the weighted schedulers
+	weight uint32
+
+	// Set while the health check of the backend fails, it then
+	// receives no new connections
+	unhealthy bool
+}
+
+// ipvsWeight returns the weight of the backend in the ipvs service
+func (be lbBackend) ipvsWeight() int {
+	if be.unhealthy {
+		return 0
+	}
+	if be.weight == 0 {
+		return 1
+	}
+	return int(be.weight)
+}
+
+// BackendHealth is the state of the health check of a load balancer
+// backend.
+type BackendHealth struct {
+	Healthy       bool
+	FailingStreak int
+	LastCheck     time.Time
+	LastError     string
+}
+
 type loadBalancer struct {
 	vip    net.IP
 	fwMark uint32
 
-	// Map of backend IPs backing this loadbalancer on this
+	// Map of backends backing this loadbalancer on this
 	// network. It is keyed with endpoint ID.
-	backEnds map[string]net.IP
+	backEnds map[string]lbBackend
 
 	// Back pointer to service to which the loadbalancer belongs.
 	service *service
+
+	// State of the health checks of the backends, keyed with
+	// endpoint ID, and the channel stopping them.
+	health     map[string]*BackendHealth
+	stopHealth chan struct{}
+}
+
+// backendHealth returns the state of the health check of the endpoint
+// among the backends of the service on the network, nil when the
+// service is not health checked.
+func (c *controller) backendHealth(sid, nid, eid string) *BackendHealth {
+	c.Lock()
+	var services []*service
+	for skey, s := range c.serviceBindings {
+		if skey.id == sid {
+			services = append(services, s)
+		}
+	}
+	c.Unlock()
+
+	for _, s := range services {
+		s.Lock()
+		if lb, ok := s.loadBalancers[nid]; ok {
+			if h, ok := lb.health[eid]; ok {
+				hc := *h
+				s.Unlock()
+				return &hc
+			}
+		}
+		s.Unlock()
+	}
+	return nil
 }
@@ -8,13 +8,14 @@ import (
 	"github.com/Sirupsen/logrus"
 )
 
-func newService(name string, id string, ingressPorts []*PortConfig, aliases []string) *service {
+func newService(name string, id string, ingressPorts []*PortConfig, aliases []string, lbCfg lbConfig) *service {
 	return &service{
 		name:          name,
 		id:            id,
 		ingressPorts:  ingressPorts,
 		loadBalancers: make(map[string]*loadBalancer),
 		aliases:       aliases,
+		lbConfig:      lbCfg,
 	}
 }
 
@@ -35,12 +36,12 @@ func (c *controller) cleanupServiceBindings(cleanupNID string) {
 				continue
 			}
 
-			for eid, ip := range lb.backEnds {
+			for eid, be := range lb.backEnds {
 				service := s
 				loadBalancer := lb
 				networkID := nid
 				epID := eid
-				epIP := ip
+				epIP := be.ip
 
 				cleanupFuncs = append(cleanupFuncs, func() {
 					if err := c.rmServiceBinding(service.name, service.id, networkID, epID, loadBalancer.vip,
@@ -60,12 +61,16 @@ func (c *controller) cleanupServiceBindings(cleanupNID string) {
 
 }
 
-func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, ingressPorts []*PortConfig, aliases []string, ip net.IP) error {
+func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, ingressPorts []*PortConfig, aliases []string, ip net.IP, lbCfg lbConfig, weight uint32) error {
 	var (
 		s          *service
 		addService bool
 	)
 
+	if err := lbCfg.validate(); err != nil {
+		return err
+	}
+
 	n, err := c.NetworkByID(nid)
 	if err != nil {
 		return err
@@ -81,11 +86,17 @@ func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, i
 	if !ok {
 		// Create a new service if we are seeing this service
 		// for the first time.
-		s = newService(name, sid, ingressPorts, aliases)
+		s = newService(name, sid, ingressPorts, aliases, lbCfg)
 		c.serviceBindings[skey] = s
 	}
 	c.Unlock()
 
+	// The load balancing options are those of the service, a backend
+	// cannot change them.
+	if s.lbConfig != lbCfg {
+		logrus.Warnf("Ignoring the load balancing options of endpoint %s which differ from those of service %s", eid, name)
+	}
+
 	// Add endpoint IP to special "tasks.svc_name" so that the
 	// applications have access to DNS RR.
 	n.(*network).addSvcRecords("tasks."+name, ip, nil, false)
@@ -104,8 +115,6 @@ func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, i
 	}
 
 	s.Lock()
-	defer s.Unlock()
-
 	lb, ok := s.loadBalancers[nid]
 	if !ok {
 		// Create a new load balancer if we are seeing this
@@ -114,7 +123,7 @@ func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, i
 		lb = &loadBalancer{
 			vip:      vip,
 			fwMark:   fwMarkCtr,
-			backEnds: make(map[string]net.IP),
+			backEnds: make(map[string]lbBackend),
 			service:  s,
 		}
 
@@ -128,15 +137,25 @@ func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, i
 		// we add a new service service in IPVS rules.
 		addService = true
 
+		lb.startHealthCheck(n.(*network))
 	}
 
-	lb.backEnds[eid] = ip
+	// A backend added again is healthy until its health check
+	// fails again.
+	be := lbBackend{ip: ip, weight: weight}
+	lb.backEnds[eid] = be
+	if lb.health != nil {
+		lb.health[eid] = &BackendHealth{Healthy: true}
+	}
 
 	// Add loadbalancer service and backend in all sandboxes in
 	// the network only if vip is valid.
 	if len(vip) != 0 {
-		n.(*network).addLBBackend(ip, vip, lb.fwMark, ingressPorts, addService)
+		n.(*network).addLBBackend(ip, be.ipvsWeight(), vip, lb.fwMark, s.lbConfig, ingressPorts, addService)
 	}
+	s.Unlock()
+
+	c.publishServiceBindingEvent(EventServiceBindingAdd, name, sid, n, eid, ip)
 
 	return nil
 }
@@ -176,12 +195,14 @@ func (c *controller) rmServiceBinding(name, sid, nid, eid string, vip net.IP, in
 	}
 
 	delete(lb.backEnds, eid)
+	delete(lb.health, eid)
 	if len(lb.backEnds) == 0 {
 		// All the backends for this service have been
 		// removed. Time to remove the load balancer and also
 		// remove the service entry in IPVS.
 		rmService = true
 
+		lb.stopHealthCheck(n.(*network))
 		delete(s.loadBalancers, nid)
 	}
 
@@ -221,5 +242,7 @@ func (c *controller) rmServiceBinding(name, sid, nid, eid string, vip net.IP, in
 		}
 	}
 
+	c.publishServiceBindingEvent(EventServiceBindingRemove, name, sid, n, eid, ip)
+
 	return nil
 }
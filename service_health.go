@@ -0,0 +1,184 @@
+// +build linux windows
+
+package libnetwork
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultHealthCheckInterval = 5 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+
+	// Number of consecutive failed checks after which a backend is
+	// taken out of the service
+	healthCheckRetries = 3
+)
+
+// startHealthCheck starts the periodic check of the backends of the
+// load balancer, if the service has a health check. The backends of a
+// DNS round robin service are checked as well, the unhealthy ones are
+// only taken out of the DNS records. It must be called with the service
+// locked.
+func (lb *loadBalancer) startHealthCheck(n *network) {
+	hc := lb.service.lbConfig.healthCheck
+	if hc.protocol == "" {
+		return
+	}
+	if hc.interval == 0 {
+		hc.interval = defaultHealthCheckInterval
+	}
+	if hc.timeout == 0 {
+		hc.timeout = defaultHealthCheckTimeout
+	}
+	if hc.path == "" {
+		hc.path = "/"
+	}
+
+	lb.health = make(map[string]*BackendHealth, len(lb.backEnds))
+	for eid := range lb.backEnds {
+		lb.health[eid] = &BackendHealth{Healthy: true}
+	}
+	lb.stopHealth = make(chan struct{})
+
+	go lb.monitorHealth(n, hc, lb.stopHealth)
+}
+
+// stopHealthCheck stops the check of the backends of the load balancer
+// and puts the unhealthy ones back in the service. It must be called
+// with the service locked.
+func (lb *loadBalancer) stopHealthCheck(n *network) {
+	if lb.stopHealth == nil {
+		return
+	}
+	close(lb.stopHealth)
+	lb.stopHealth = nil
+	lb.health = nil
+
+	for eid, be := range lb.backEnds {
+		if !be.unhealthy {
+			continue
+		}
+		be.unhealthy = false
+		lb.backEnds[eid] = be
+		lb.updateBackend(n, be)
+	}
+}
+
+// updateBackend applies the health of the backend to its weight in the
+// ipvs service and to its DNS records. Without a vip the service name
+// resolves to the backends as well. It must be called with the service
+// locked.
+func (lb *loadBalancer) updateBackend(n *network, be lbBackend) {
+	s := lb.service
+	if len(lb.vip) != 0 {
+		n.addLBBackend(be.ip, be.ipvsWeight(), lb.vip, lb.fwMark, s.lbConfig, s.ingressPorts, false)
+	}
+
+	for _, name := range append([]string{s.name}, s.aliases...) {
+		records := []string{"tasks." + name}
+		if len(lb.vip) == 0 {
+			records = append(records, name)
+		}
+		for _, r := range records {
+			if be.unhealthy {
+				n.deleteSvcRecords(r, be.ip, nil, false)
+			} else {
+				n.addSvcRecords(r, be.ip, nil, false)
+			}
+		}
+	}
+}
+
+func (lb *loadBalancer) monitorHealth(n *network, hc lbHealthCheck, stop chan struct{}) {
+	ticker := time.NewTicker(hc.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		lb.checkBackends(n, hc, stop)
+	}
+}
+
+// checkBackends probes all the backends of the load balancer from a
+// sandbox of this node, and updates the weight and the DNS records of
+// the backends whose health changed.
+func (lb *loadBalancer) checkBackends(n *network, hc lbHealthCheck, stop chan struct{}) {
+	sb := n.lbSandbox()
+	if sb == nil {
+		// No sandbox on this node to probe from
+		return
+	}
+
+	s := lb.service
+	s.Lock()
+	backends := make(map[string]net.IP, len(lb.backEnds))
+	for eid, be := range lb.backEnds {
+		backends[eid] = be.ip
+	}
+	s.Unlock()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error, len(backends))
+	)
+	for eid, ip := range backends {
+		wg.Add(1)
+		go func(eid string, ip net.IP) {
+			defer wg.Done()
+			err := sb.probeLBBackend(ip, hc)
+			mu.Lock()
+			results[eid] = err
+			mu.Unlock()
+		}(eid, ip)
+	}
+	wg.Wait()
+
+	s.Lock()
+	defer s.Unlock()
+	if lb.stopHealth != stop {
+		// The health check was stopped or changed meanwhile
+		return
+	}
+	now := time.Now()
+	for eid, err := range results {
+		be, ok := lb.backEnds[eid]
+		h, hok := lb.health[eid]
+		if !ok || !hok || !be.ip.Equal(backends[eid]) {
+			continue
+		}
+
+		h.LastCheck = now
+		if err != nil {
+			h.FailingStreak++
+			h.LastError = err.Error()
+		} else {
+			h.FailingStreak = 0
+			h.LastError = ""
+		}
+
+		healthy := h.FailingStreak < healthCheckRetries
+		if healthy == h.Healthy {
+			continue
+		}
+		h.Healthy = healthy
+		if healthy {
+			logrus.Infof("Backend %s of service %s is healthy again", be.ip, s.name)
+		} else {
+			logrus.Warnf("Backend %s of service %s is unhealthy: %s", be.ip, s.name, h.LastError)
+		}
+
+		be.unhealthy = !healthy
+		lb.backEnds[eid] = be
+		lb.updateBackend(n, be)
+	}
+}
@@ -1,10 +1,12 @@
 package libnetwork
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,12 +15,14 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/reexec"
 	"github.com/docker/libnetwork/iptables"
 	"github.com/docker/libnetwork/ipvs"
 	"github.com/docker/libnetwork/ns"
+	"github.com/docker/libnetwork/types"
 	"github.com/gogo/protobuf/proto"
 	"github.com/vishvananda/netlink/nl"
 	"github.com/vishvananda/netns"
@@ -53,6 +57,37 @@ func (n *network) connectedLoadbalancers() []*loadBalancer {
 	return lbs
 }
 
+// A loadbalancer programmed in a sandbox, along with its network.
+type sandboxLB struct {
+	lb *loadBalancer
+	n  *network
+}
+
+// Get all loadbalancers programmed in this sandbox, those with a vip of
+// the networks of its populated endpoints, keyed with their firewall
+// mark. The loadbalancers of the ingress network are only programmed in
+// the ingress sandbox.
+func (sb *sandbox) connectedLoadbalancers() map[uint32]sandboxLB {
+	lbs := make(map[uint32]sandboxLB)
+	for _, ep := range sb.getConnectedEndpoints() {
+		if ep.Iface() == nil || !sb.isEndpointPopulated(ep) {
+			continue
+		}
+
+		n := ep.getNetwork()
+		if n.ingress && !sb.ingress {
+			continue
+		}
+		for _, lb := range n.connectedLoadbalancers() {
+			if len(lb.vip) != 0 {
+				lbs[lb.fwMark] = sandboxLB{lb: lb, n: n}
+			}
+		}
+	}
+
+	return lbs
+}
+
 // Populate all loadbalancers on the network that the passed endpoint
 // belongs to, into this sandbox.
 func (sb *sandbox) populateLoadbalancers(ep *endpoint) {
@@ -98,8 +133,8 @@ func (sb *sandbox) populateLoadbalancers(ep *endpoint) {
 
 		lb.service.Lock()
 		addService := true
-		for _, ip := range lb.backEnds {
-			sb.addLBBackend(ip, lb.vip, lb.fwMark, lb.service.ingressPorts,
+		for _, be := range lb.backEnds {
+			sb.addLBBackend(be.ip, be.ipvsWeight(), lb.vip, lb.fwMark, lb.service.lbConfig, lb.service.ingressPorts,
 				eIP, gwIP, addService, n.ingress)
 			// For a new service program the vip as an alias on the task's sandbox interface
 			// connected to this network.
@@ -118,7 +153,7 @@ func (sb *sandbox) populateLoadbalancers(ep *endpoint) {
 // Add loadbalancer backend to all sandboxes which has a connection to
 // this network. If needed add the service as well, as specified by
 // the addService bool.
-func (n *network) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*PortConfig, addService bool) {
+func (n *network) addLBBackend(ip net.IP, weight int, vip net.IP, fwMark uint32, cfg lbConfig, ingressPorts []*PortConfig, addService bool) {
 	n.WalkEndpoints(func(e Endpoint) bool {
 		ep := e.(*endpoint)
 		if sb, ok := ep.getSandbox(); ok {
@@ -131,7 +166,7 @@ func (n *network) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*Po
 				gwIP = ep.Iface().Address().IP
 			}
 
-			sb.addLBBackend(ip, vip, fwMark, ingressPorts, ep.Iface().Address(), gwIP, addService, n.ingress)
+			sb.addLBBackend(ip, weight, vip, fwMark, cfg, ingressPorts, ep.Iface().Address(), gwIP, addService, n.ingress)
 
 			// For a new service program the vip as an alias on the task's sandbox interface
 			// connected to this network.
@@ -146,6 +181,26 @@ func (n *network) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*Po
 	})
 }
 
+// Get a sandbox of this node connected to the network from which the
+// backends of the loadbalancers can be health checked. For the ingress
+// network, it is the ingress sandbox.
+func (n *network) lbSandbox() *sandbox {
+	var lbSb *sandbox
+	n.WalkEndpoints(func(e Endpoint) bool {
+		ep := e.(*endpoint)
+		sb, ok := ep.getSandbox()
+		if !ok || sb.osSbox == nil || !sb.isEndpointPopulated(ep) {
+			return false
+		}
+		if n.ingress && !sb.ingress {
+			return false
+		}
+		lbSb = sb
+		return true
+	})
+	return lbSb
+}
+
 // Remove loadbalancer backend from all sandboxes which has a
 // connection to this network. If needed remove the service entry as
 // well, as specified by the rmService bool.
@@ -177,8 +232,28 @@ func (n *network) rmLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*Por
 	})
 }
 
+// lbService returns the ipvs service balancing the connections marked
+// with fwMark as specified by the load balancing options.
+func lbService(fwMark uint32, cfg lbConfig) *ipvs.Service {
+	s := &ipvs.Service{
+		AddressFamily: nl.FAMILY_V4,
+		FWMark:        fwMark,
+		SchedName:     cfg.scheduler,
+	}
+	if s.SchedName == "" {
+		s.SchedName = ipvs.RoundRobin
+	}
+	if cfg.persistence != 0 {
+		// The affinity is per client address
+		s.Flags = ipvs.SvcFlagPersistent
+		s.Timeout = cfg.persistence
+		s.Netmask = 0xFFFFFFFF
+	}
+	return s
+}
+
 // Add loadbalancer backend into one connected sandbox.
-func (sb *sandbox) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*PortConfig, eIP *net.IPNet, gwIP net.IP, addService bool, isIngressNetwork bool) {
+func (sb *sandbox) addLBBackend(ip net.IP, weight int, vip net.IP, fwMark uint32, cfg lbConfig, ingressPorts []*PortConfig, eIP *net.IPNet, gwIP net.IP, addService bool, isIngressNetwork bool) {
 	if sb.osSbox == nil {
 		return
 	}
@@ -194,11 +269,7 @@ func (sb *sandbox) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*P
 	}
 	defer i.Close()
 
-	s := &ipvs.Service{
-		AddressFamily: nl.FAMILY_V4,
-		FWMark:        fwMark,
-		SchedName:     ipvs.RoundRobin,
-	}
+	s := lbService(fwMark, cfg)
 
 	if addService {
 		var filteredPorts []*PortConfig
@@ -216,7 +287,12 @@ func (sb *sandbox) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*P
 			return
 		}
 
-		if err := i.NewService(s); err != nil {
+		err = i.NewService(s)
+		if err == syscall.EEXIST {
+			logrus.Debugf("Reclaiming the service for vip %s fwmark %d in sbox %s", vip, fwMark, sb.Key())
+			err = sb.reclaimLBService(i, s, ip)
+		}
+		if err != nil {
 			logrus.Errorf("Failed to create a new service for vip %s fwmark %d: %v", vip, fwMark, err)
 			return
 		}
@@ -225,17 +301,243 @@ func (sb *sandbox) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*P
 	d := &ipvs.Destination{
 		AddressFamily: nl.FAMILY_V4,
 		Address:       ip,
-		Weight:        1,
+		Weight:        weight,
 	}
 
 	// Remove the sched name before using the service to add
 	// destination.
 	s.SchedName = ""
-	if err := i.NewDestination(s, d); err != nil && err != syscall.EEXIST {
+	err = i.NewDestination(s, d)
+	if err == syscall.EEXIST {
+		// The backend is already known, its weight may have
+		// changed.
+		err = i.UpdateDestination(s, d)
+	}
+	if err != nil {
 		logrus.Errorf("Failed to create real server %s for vip %s fwmark %d in sb %s: %v", ip, vip, fwMark, sb.containerID, err)
 	}
 }
 
+// Probe the loadbalancer backend from the network namespace of the
+// sandbox as specified by the health check.
+func (sb *sandbox) probeLBBackend(ip net.IP, hc lbHealthCheck) error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(int(hc.port)))
+	if ierr := sb.osSbox.InvokeFunc(func() {
+		conn, err = net.DialTimeout("tcp", addr, hc.timeout)
+	}); ierr != nil {
+		return fmt.Errorf("failed to enter sandbox %s: %v", sb.Key(), ierr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if hc.protocol != "http" {
+		return nil
+	}
+
+	// The connection lives in the namespace of the sandbox, the
+	// request is sent over it directly.
+	conn.SetDeadline(time.Now().Add(hc.timeout))
+	req, err := http.NewRequest("GET", "http://"+addr+hc.path, nil)
+	if err != nil {
+		return err
+	}
+	req.Close = true
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Take over the ipvs service with the firewall mark of a new
+// loadbalancer, programmed in the sandbox by a previous run of the
+// daemon. Its options are updated and its real servers other than the
+// first backend of the loadbalancer are removed, the other backends are
+// added again as their bindings are replayed.
+func (sb *sandbox) reclaimLBService(i *ipvs.Handle, s *ipvs.Service, ip net.IP) error {
+	if err := i.UpdateService(s); err != nil {
+		return err
+	}
+
+	dests, err := i.GetDestinations(s)
+	if err != nil {
+		return err
+	}
+	for _, d := range dests {
+		if d.Address.Equal(ip) {
+			continue
+		}
+		if err := i.DelDestination(s, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Time given to the service bindings to be replayed by the gossip once
+// the agent is initialized, before the ipvs services of the restored
+// sandboxes which were not taken over are removed.
+const lbSweepDelay = time.Minute
+
+// Remove, once the service bindings are replayed, the ipvs services of
+// the sandboxes restored from a previous run of the daemon whose firewall
+// mark matches no loadbalancer. Only the sandboxes which have such
+// services are waited for.
+func (c *controller) sweepLoadBalancers(sbs []*sandbox) {
+	var stale []*sandbox
+	for _, sb := range sbs {
+		if len(sb.staleLBServices()) != 0 {
+			stale = append(stale, sb)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	go func() {
+		c.AgentInitWait()
+		time.Sleep(lbSweepDelay)
+
+		for _, sb := range stale {
+			c.Lock()
+			current := c.sandboxes[sb.ID()] == sb
+			c.Unlock()
+			if current {
+				sb.removeStaleLBServices()
+			}
+		}
+	}()
+}
+
+// Get the ipvs services of the sandbox whose firewall mark matches no
+// loadbalancer connected to the sandbox. The services are all marked,
+// the others were not programmed by us.
+func (sb *sandbox) staleLBServices() []*ipvs.Service {
+	if sb.osSbox == nil {
+		return nil
+	}
+
+	i, err := ipvs.New(sb.Key())
+	if err != nil {
+		logrus.Errorf("Failed to create an ipvs handle for sbox %s: %v", sb.Key(), err)
+		return nil
+	}
+	defer i.Close()
+
+	services, err := i.GetServices()
+	if err != nil {
+		logrus.Errorf("Failed to get the ipvs services of sbox %s: %v", sb.Key(), err)
+		return nil
+	}
+
+	lbs := sb.connectedLoadbalancers()
+	var stale []*ipvs.Service
+	for _, svc := range services {
+		if _, ok := lbs[svc.FWMark]; svc.FWMark != 0 && !ok {
+			stale = append(stale, svc)
+		}
+	}
+	return stale
+}
+
+func (sb *sandbox) removeStaleLBServices() {
+	stale := sb.staleLBServices()
+	if len(stale) == 0 {
+		return
+	}
+
+	i, err := ipvs.New(sb.Key())
+	if err != nil {
+		logrus.Errorf("Failed to create an ipvs handle for sbox %s: %v", sb.Key(), err)
+		return
+	}
+	defer i.Close()
+
+	for _, svc := range stale {
+		logrus.Debugf("Removing stale service fwmark %d from sbox %s", svc.FWMark, sb.Key())
+		if err := i.DelService(svc); err != nil {
+			logrus.Errorf("Failed to remove stale service fwmark %d from sbox %s: %v", svc.FWMark, sb.Key(), err)
+		}
+	}
+}
+
+// Get the statistics of the loadbalancers programmed in this sandbox.
+func (sb *sandbox) lbStatistics() ([]*types.LoadBalancerStatistics, error) {
+	lbs := sb.connectedLoadbalancers()
+	if len(lbs) == 0 {
+		return nil, nil
+	}
+
+	i, err := ipvs.New(sb.Key())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an ipvs handle for sbox %s: %v", sb.Key(), err)
+	}
+	defer i.Close()
+
+	services, err := i.GetServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the ipvs services of sbox %s: %v", sb.Key(), err)
+	}
+
+	var stats []*types.LoadBalancerStatistics
+	for _, svc := range services {
+		slb, ok := lbs[svc.FWMark]
+		if svc.FWMark == 0 || !ok {
+			continue
+		}
+
+		dests, err := i.GetDestinations(svc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the real servers for fwmark %d in sbox %s: %v", svc.FWMark, sb.Key(), err)
+		}
+
+		slb.lb.service.Lock()
+		st := &types.LoadBalancerStatistics{
+			ServiceName: slb.lb.service.name,
+			ServiceID:   slb.lb.service.id,
+			NetworkID:   slb.n.ID(),
+			VIP:         types.GetIPCopy(slb.lb.vip),
+			Connections: svc.Stats.Connections,
+			PacketsIn:   svc.Stats.PacketsIn,
+			PacketsOut:  svc.Stats.PacketsOut,
+			BytesIn:     svc.Stats.BytesIn,
+			BytesOut:    svc.Stats.BytesOut,
+		}
+		slb.lb.service.Unlock()
+
+		for _, d := range dests {
+			st.Backends = append(st.Backends, &types.BackendStatistics{
+				IP:                  d.Address,
+				Weight:              d.Weight,
+				ActiveConnections:   d.ActiveConnections,
+				InactiveConnections: d.InactiveConnections,
+				Connections:         d.Stats.Connections,
+				PacketsIn:           d.Stats.PacketsIn,
+				PacketsOut:          d.Stats.PacketsOut,
+				BytesIn:             d.Stats.BytesIn,
+				BytesOut:            d.Stats.BytesOut,
+			})
+		}
+		stats = append(stats, st)
+	}
+
+	return stats, nil
+}
+
 // Remove loadbalancer backend from one connected sandbox.
 func (sb *sandbox) rmLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*PortConfig, eIP *net.IPNet, gwIP net.IP, rmService bool, isIngressNetwork bool) {
 	if sb.osSbox == nil {
@@ -336,9 +638,9 @@ func filterPortConfigs(ingressPorts []*PortConfig, isDelete bool) []*PortConfig
 }
 
 func programIngress(gwIP net.IP, ingressPorts []*PortConfig, isDelete bool) error {
-	addDelOpt := "-I"
+	action := iptables.Insert
 	if isDelete {
-		addDelOpt = "-D"
+		action = iptables.Delete
 	}
 
 	chainExists := iptables.ExistChain(ingressChain, iptables.Nat)
@@ -364,6 +666,7 @@ func programIngress(gwIP net.IP, ingressPorts []*PortConfig, isDelete bool) erro
 			if err := iptables.RawCombinedOutput("-t", "nat", "-N", ingressChain); err != nil {
 				return fmt.Errorf("failed to create ingress chain: %v", err)
 			}
+			chainExists = true
 		}
 		if !filterChainExists {
 			if err := iptables.RawCombinedOutput("-N", ingressChain); err != nil {
@@ -371,32 +674,6 @@ func programIngress(gwIP net.IP, ingressPorts []*PortConfig, isDelete bool) erro
 			}
 		}
 
-		if !iptables.Exists(iptables.Nat, ingressChain, "-j", "RETURN") {
-			if err := iptables.RawCombinedOutput("-t", "nat", "-A", ingressChain, "-j", "RETURN"); err != nil {
-				return fmt.Errorf("failed to add return rule in nat table ingress chain: %v", err)
-			}
-		}
-
-		if !iptables.Exists(iptables.Filter, ingressChain, "-j", "RETURN") {
-			if err := iptables.RawCombinedOutput("-A", ingressChain, "-j", "RETURN"); err != nil {
-				return fmt.Errorf("failed to add return rule to filter table ingress chain: %v", err)
-			}
-		}
-
-		for _, chain := range []string{"OUTPUT", "PREROUTING"} {
-			if !iptables.Exists(iptables.Nat, chain, "-m", "addrtype", "--dst-type", "LOCAL", "-j", ingressChain) {
-				if err := iptables.RawCombinedOutput("-t", "nat", "-I", chain, "-m", "addrtype", "--dst-type", "LOCAL", "-j", ingressChain); err != nil {
-					return fmt.Errorf("failed to add jump rule in %s to ingress chain: %v", chain, err)
-				}
-			}
-		}
-
-		if !iptables.Exists(iptables.Filter, "FORWARD", "-j", ingressChain) {
-			if err := iptables.RawCombinedOutput("-I", "FORWARD", "-j", ingressChain); err != nil {
-				return fmt.Errorf("failed to add jump rule to %s in filter table forward chain: %v", ingressChain, err)
-			}
-		}
-
 		oifName, err := findOIFName(gwIP)
 		if err != nil {
 			return fmt.Errorf("failed to find gateway bridge interface name for %s: %v", gwIP, err)
@@ -407,52 +684,46 @@ func programIngress(gwIP net.IP, ingressPorts []*PortConfig, isDelete bool) erro
 			return fmt.Errorf("could not write to %s: %v", path, err)
 		}
 
-		ruleArgs := strings.Fields(fmt.Sprintf("-m addrtype --src-type LOCAL -o %s -j MASQUERADE", oifName))
-		if !iptables.Exists(iptables.Nat, "POSTROUTING", ruleArgs...) {
-			if err := iptables.RawCombinedOutput(append([]string{"-t", "nat", "-I", "POSTROUTING"}, ruleArgs...)...); err != nil {
-				return fmt.Errorf("failed to add ingress localhost POSTROUTING rule for %s: %v", oifName, err)
-			}
+		t := iptables.NewTransaction()
+		t.ProgramRule(iptables.Nat, ingressChain, iptables.Append, []string{"-j", "RETURN"})
+		t.ProgramRule(iptables.Filter, ingressChain, iptables.Append, []string{"-j", "RETURN"})
+		for _, chain := range []string{"OUTPUT", "PREROUTING"} {
+			t.ProgramRule(iptables.Nat, chain, iptables.Insert, []string{"-m", "addrtype", "--dst-type", "LOCAL", "-j", ingressChain})
+		}
+		t.ProgramRule(iptables.Filter, "FORWARD", iptables.Insert, []string{"-j", ingressChain})
+		t.ProgramRule(iptables.Nat, "POSTROUTING", iptables.Insert,
+			strings.Fields(fmt.Sprintf("-m addrtype --src-type LOCAL -o %s -j MASQUERADE", oifName)))
+		if err := t.Commit(); err != nil {
+			return fmt.Errorf("failed to set up ingress chains for %s: %v", oifName, err)
 		}
 	}
 
+	// The rules of all the ports are programmed in a single transaction
+	t := iptables.NewTransaction()
 	for _, iPort := range ingressPorts {
-		if iptables.ExistChain(ingressChain, iptables.Nat) {
-			rule := strings.Fields(fmt.Sprintf("-t nat %s %s -p %s --dport %d -j DNAT --to-destination %s:%d",
-				addDelOpt, ingressChain, strings.ToLower(PortConfig_Protocol_name[int32(iPort.Protocol)]), iPort.PublishedPort, gwIP, iPort.PublishedPort))
-			if err := iptables.RawCombinedOutput(rule...); err != nil {
-				errStr := fmt.Sprintf("setting up rule failed, %v: %v", rule, err)
-				if !isDelete {
-					return fmt.Errorf("%s", errStr)
-				}
-
-				logrus.Infof("%s", errStr)
-			}
+		proto := strings.ToLower(PortConfig_Protocol_name[int32(iPort.Protocol)])
+		if chainExists {
+			t.ProgramRule(iptables.Nat, ingressChain, action, strings.Fields(fmt.Sprintf("-p %s --dport %d -j DNAT --to-destination %s:%d",
+				proto, iPort.PublishedPort, gwIP, iPort.PublishedPort)))
 		}
 
 		// Filter table rules to allow a published service to be accessible in the local node from..
 		// 1) service tasks attached to other networks
 		// 2) unmanaged containers on bridge networks
-		rule := strings.Fields(fmt.Sprintf("%s %s -m state -p %s --sport %d --state ESTABLISHED,RELATED -j ACCEPT",
-			addDelOpt, ingressChain, strings.ToLower(PortConfig_Protocol_name[int32(iPort.Protocol)]), iPort.PublishedPort))
-		if err := iptables.RawCombinedOutput(rule...); err != nil {
-			errStr := fmt.Sprintf("setting up rule failed, %v: %v", rule, err)
-			if !isDelete {
-				return fmt.Errorf("%s", errStr)
-			}
-			logrus.Warnf("%s", errStr)
-		}
-
-		rule = strings.Fields(fmt.Sprintf("%s %s -p %s --dport %d -j ACCEPT",
-			addDelOpt, ingressChain, strings.ToLower(PortConfig_Protocol_name[int32(iPort.Protocol)]), iPort.PublishedPort))
-		if err := iptables.RawCombinedOutput(rule...); err != nil {
-			errStr := fmt.Sprintf("setting up rule failed, %v: %v", rule, err)
-			if !isDelete {
-				return fmt.Errorf("%s", errStr)
-			}
-
-			logrus.Warnf("%s", errStr)
+		t.ProgramRule(iptables.Filter, ingressChain, action, strings.Fields(fmt.Sprintf("-m state -p %s --sport %d --state ESTABLISHED,RELATED -j ACCEPT",
+			proto, iPort.PublishedPort)))
+		t.ProgramRule(iptables.Filter, ingressChain, action, strings.Fields(fmt.Sprintf("-p %s --dport %d -j ACCEPT",
+			proto, iPort.PublishedPort)))
+	}
+	if err := t.Commit(); err != nil {
+		errStr := fmt.Sprintf("setting up ingress port rules failed: %v", err)
+		if !isDelete {
+			return fmt.Errorf("%s", errStr)
 		}
+		logrus.Warnf("%s", errStr)
+	}
 
+	for _, iPort := range ingressPorts {
 		if err := plumbProxy(iPort, isDelete); err != nil {
 			logrus.Warnf("failed to create proxy for port %d: %v", iPort.PublishedPort, err)
 		}
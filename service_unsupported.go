@@ -5,12 +5,14 @@ package libnetwork
 import (
 	"fmt"
 	"net"
+
+	"github.com/docker/libnetwork/types"
 )
 
 func (c *controller) cleanupServiceBindings(nid string) {
 }
 
-func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, ingressPorts []*PortConfig, aliases []string, ip net.IP) error {
+func (c *controller) addServiceBinding(name, sid, nid, eid string, vip net.IP, ingressPorts []*PortConfig, aliases []string, ip net.IP, lbCfg lbConfig, weight uint32) error {
 	return fmt.Errorf("not supported")
 }
 
@@ -21,5 +23,12 @@ func (c *controller) rmServiceBinding(name, sid, nid, eid string, vip net.IP, in
 func (sb *sandbox) populateLoadbalancers(ep *endpoint) {
 }
 
+func (c *controller) sweepLoadBalancers(sbs []*sandbox) {
+}
+
+func (sb *sandbox) lbStatistics() ([]*types.LoadBalancerStatistics, error) {
+	return nil, nil
+}
+
 func arrangeIngressFilterRule() {
 }
@@ -1,8 +1,20 @@
 package libnetwork
 
-import "net"
+import (
+	"net"
 
-func (n *network) addLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*PortConfig, addService bool) {
+	"github.com/docker/libnetwork/types"
+)
+
+func (n *network) addLBBackend(ip net.IP, weight int, vip net.IP, fwMark uint32, cfg lbConfig, ingressPorts []*PortConfig, addService bool) {
+}
+
+func (n *network) lbSandbox() *sandbox {
+	return nil
+}
+
+func (sb *sandbox) probeLBBackend(ip net.IP, hc lbHealthCheck) error {
+	return nil
 }
 
 func (n *network) rmLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*PortConfig, rmService bool) {
@@ -11,5 +23,12 @@ func (n *network) rmLBBackend(ip, vip net.IP, fwMark uint32, ingressPorts []*Por
 func (sb *sandbox) populateLoadbalancers(ep *endpoint) {
 }
 
+func (c *controller) sweepLoadBalancers(sbs []*sandbox) {
+}
+
+func (sb *sandbox) lbStatistics() ([]*types.LoadBalancerStatistics, error) {
+	return nil, nil
+}
+
 func arrangeIngressFilterRule() {
 }
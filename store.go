@@ -9,6 +9,7 @@ import (
 	"github.com/docker/libkv/store/etcd"
 	"github.com/docker/libkv/store/zookeeper"
 	"github.com/docker/libnetwork/datastore"
+	"github.com/docker/libnetwork/types"
 )
 
 func registerKVStores() {
@@ -345,6 +346,22 @@ func (c *controller) networkWatchLoop(nw *netWatch, ep *endpoint, ecCh <-chan da
 	}
 }
 
+// networkDefWatchLoop applies the policies and the DNS records of a network
+// in the global store whenever another host updates them
+func (c *controller) networkDefWatchLoop(nw *netWatch, n *network, ch <-chan datastore.KVObject) {
+	var enforced []types.NetworkPolicy
+	for {
+		select {
+		case <-nw.stopCh:
+			return
+		case o := <-ch:
+			latest := o.(*network)
+			c.refreshDNSRecords(latest)
+			enforced = n.enforceWatchedPolicies(enforced, latest.NetworkPolicies())
+		}
+	}
+}
+
 func (c *controller) processEndpointCreate(nmap map[string]*netWatch, ep *endpoint) {
 	if !c.isDistributedControl() && ep.getNetwork().driverScope() == datastore.GlobalScope {
 		return
@@ -401,6 +418,15 @@ func (c *controller) processEndpointCreate(nmap map[string]*netWatch, ep *endpoi
 	}
 
 	go c.networkWatchLoop(nw, ep, ch)
+
+	// The policies and the DNS records of the network are updated by any host
+	nch, err := store.Watch(ep.getNetwork(), nw.stopCh)
+	if err != nil {
+		log.Warnf("Error creating definition watch for network: %v", err)
+		return
+	}
+
+	go c.networkDefWatchLoop(nw, ep.getNetwork(), nch)
 }
 
 func (c *controller) processEndpointDelete(nmap map[string]*netWatch, ep *endpoint) {
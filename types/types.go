@@ -7,6 +7,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // constants for the IP address type
@@ -28,9 +29,11 @@ type EncryptionKey struct {
 // UUID represents a globally unique ID of various resources like network and endpoint
 type UUID string
 
-// QosPolicy represents a quality of service policy on an endpoint
+// QosPolicy represents a quality of service policy on an endpoint.
+// Bandwidths are expressed in bytes per second, zero means unlimited.
 type QosPolicy struct {
-	MaxEgressBandwidth uint64
+	MaxEgressBandwidth  uint64
+	MaxIngressBandwidth uint64
 }
 
 // TransportPort represents a local Layer 4 endpoint
@@ -79,6 +82,125 @@ func (t *TransportPort) FromString(s string) error {
 	return BadRequestErrorf("invalid format for transport port: %s", s)
 }
 
+// ConnectivityRule allows the traffic to the endpoints of a network from
+// the endpoints of a peer network, through the isolation of the networks.
+// Empty selectors and an empty port list match all.
+type ConnectivityRule struct {
+	// Network is the ID of the peer network, the network of the rule
+	// itself when empty
+	Network string
+	// From selects by label the endpoints of the peer network
+	From map[string]string
+	// To selects by label the endpoints of the network
+	To map[string]string
+	// Ports are the destination ports, a zero port matching all the
+	// ports of its protocol
+	Ports []TransportPort
+}
+
+// GetCopy returns a copy of this ConnectivityRule structure instance
+func (r *ConnectivityRule) GetCopy() ConnectivityRule {
+	cr := ConnectivityRule{Network: r.Network, From: copyLabels(r.From), To: copyLabels(r.To)}
+	for _, p := range r.Ports {
+		cr.Ports = append(cr.Ports, p.GetCopy())
+	}
+	return cr
+}
+
+// Directions of the traffic a NetworkPolicy isolates the endpoints in
+const (
+	PolicyIngress = "ingress"
+	PolicyEgress  = "egress"
+)
+
+// NetworkPolicy restricts the traffic of the endpoints of a network its
+// selector matches to the traffic its rules allow.
+type NetworkPolicy struct {
+	// Name identifies the policy in the network
+	Name string
+	// Selector selects by label the endpoints the policy applies to, an
+	// empty selector selecting all the endpoints of the network
+	Selector map[string]string
+	// Ingress is the traffic allowed to the selected endpoints
+	Ingress []PolicyRule
+	// Egress is the traffic allowed from the selected endpoints
+	Egress []PolicyRule
+	// Isolation lists the directions, PolicyIngress and PolicyEgress, the
+	// selected endpoints are isolated in. When empty the endpoints are
+	// isolated in ingress, and in egress as well if the policy has egress
+	// rules.
+	Isolation []string
+}
+
+// PolicyRule allows the traffic with its peers on its ports. Empty peer and
+// port lists match all.
+type PolicyRule struct {
+	Peers []PolicyPeer
+	// Ports are the destination ports, a zero port matching all the
+	// ports of its protocol
+	Ports []TransportPort
+}
+
+// PolicyPeer selects either endpoints of the network by label, or
+// addresses by range
+type PolicyPeer struct {
+	Labels map[string]string
+	CIDR   string
+}
+
+// Isolates returns whether the policy isolates the selected endpoints in the
+// direction
+func (p *NetworkPolicy) Isolates(direction string) bool {
+	if len(p.Isolation) == 0 {
+		return direction == PolicyIngress || (direction == PolicyEgress && len(p.Egress) > 0)
+	}
+	for _, d := range p.Isolation {
+		if d == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCopy returns a copy of this NetworkPolicy structure instance
+func (p *NetworkPolicy) GetCopy() NetworkPolicy {
+	np := NetworkPolicy{
+		Name:      p.Name,
+		Selector:  copyLabels(p.Selector),
+		Isolation: append([]string(nil), p.Isolation...),
+	}
+	for _, r := range p.Ingress {
+		np.Ingress = append(np.Ingress, r.GetCopy())
+	}
+	for _, r := range p.Egress {
+		np.Egress = append(np.Egress, r.GetCopy())
+	}
+	return np
+}
+
+// GetCopy returns a copy of this PolicyRule structure instance
+func (r *PolicyRule) GetCopy() PolicyRule {
+	var pr PolicyRule
+	for _, p := range r.Peers {
+		pr.Peers = append(pr.Peers, PolicyPeer{Labels: copyLabels(p.Labels), CIDR: p.CIDR})
+	}
+	for _, p := range r.Ports {
+		pr.Ports = append(pr.Ports, p.GetCopy())
+	}
+	return pr
+}
+
+func copyLabels(from map[string]string) map[string]string {
+	if from == nil {
+		return nil
+	}
+	to := make(map[string]string, len(from))
+	for k, v := range from {
+		to[k] = v
+	}
+	return to
+}
+
 // PortBinding represents a port binding between the container and the host
 type PortBinding struct {
 	Proto       Protocol
@@ -450,11 +572,118 @@ type InterfaceStatistics struct {
 	TxPackets uint64
 	TxErrors  uint64
 	TxDropped uint64
+
+	// Statistics of the service load balancers reached through the
+	// interface
+	LoadBalancers []*LoadBalancerStatistics
 }
 
 func (is *InterfaceStatistics) String() string {
-	return fmt.Sprintf("\nRxBytes: %d, RxPackets: %d, RxErrors: %d, RxDropped: %d, TxBytes: %d, TxPackets: %d, TxErrors: %d, TxDropped: %d",
+	s := fmt.Sprintf("\nRxBytes: %d, RxPackets: %d, RxErrors: %d, RxDropped: %d, TxBytes: %d, TxPackets: %d, TxErrors: %d, TxDropped: %d",
 		is.RxBytes, is.RxPackets, is.RxErrors, is.RxDropped, is.TxBytes, is.TxPackets, is.TxErrors, is.TxDropped)
+	for _, ls := range is.LoadBalancers {
+		s += ls.String()
+	}
+	return s
+}
+
+// LoadBalancerStatistics represents the statistics of the load balancer
+// of a service in a sandbox
+type LoadBalancerStatistics struct {
+	ServiceName string
+	ServiceID   string
+	NetworkID   string
+	VIP         net.IP
+	Connections uint64
+	PacketsIn   uint64
+	PacketsOut  uint64
+	BytesIn     uint64
+	BytesOut    uint64
+	Backends    []*BackendStatistics
+}
+
+// BackendStatistics represents the statistics of a backend of a load
+// balancer
+type BackendStatistics struct {
+	IP                  net.IP
+	Weight              int
+	ActiveConnections   uint32
+	InactiveConnections uint32
+	Connections         uint64
+	PacketsIn           uint64
+	PacketsOut          uint64
+	BytesIn             uint64
+	BytesOut            uint64
+}
+
+func (ls *LoadBalancerStatistics) String() string {
+	return fmt.Sprintf("\nService: %s, VIP: %s, Connections: %d, PacketsIn: %d, PacketsOut: %d, BytesIn: %d, BytesOut: %d, Backends: %d",
+		ls.ServiceName, ls.VIP, ls.Connections, ls.PacketsIn, ls.PacketsOut, ls.BytesIn, ls.BytesOut, len(ls.Backends))
+}
+
+// ExtDNSStatistics represents the health and the statistics of an
+// external DNS server the embedded resolver forwards queries to
+type ExtDNSStatistics struct {
+	Domain       string
+	Server       string
+	Healthy      bool
+	RetryAt      time.Time
+	Queries      uint64
+	Failures     uint64
+	TCPFallbacks uint64
+	Latency      time.Duration
+}
+
+func (es *ExtDNSStatistics) String() string {
+	avg := time.Duration(0)
+	if ok := es.Queries - es.Failures; ok > 0 {
+		avg = es.Latency / time.Duration(ok)
+	}
+	domain := es.Domain
+	if domain == "" {
+		domain = "."
+	}
+	return fmt.Sprintf("\nDomain: %s, Server: %s, Healthy: %t, Queries: %d, Failures: %d, TCPFallbacks: %d, AvgLatency: %v",
+		domain, es.Server, es.Healthy, es.Queries, es.Failures, es.TCPFallbacks, avg)
+}
+
+// DNSCacheStatistics represents the statistics of the cache of the
+// answers of a list of external DNS servers
+type DNSCacheStatistics struct {
+	Servers   []string
+	Entries   int
+	Capacity  int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+func (cs *DNSCacheStatistics) String() string {
+	return fmt.Sprintf("\nServers: %s, Entries: %d/%d, Hits: %d, Misses: %d, Evictions: %d",
+		strings.Join(cs.Servers, ","), cs.Entries, cs.Capacity, cs.Hits, cs.Misses, cs.Evictions)
+}
+
+// DNSQueryStatistics represents the counters of the queries answered
+// by the embedded DNS server of a sandbox
+type DNSQueryStatistics struct {
+	Queries   uint64
+	Local     uint64
+	Cached    uint64
+	Forwarded uint64
+	Failed    uint64
+	Dropped   uint64
+	NXDomain  uint64
+	ServFail  uint64
+	Latency   time.Duration
+}
+
+func (qs *DNSQueryStatistics) String() string {
+	avg := time.Duration(0)
+	if qs.Queries > 0 {
+		avg = qs.Latency / time.Duration(qs.Queries)
+	}
+	return fmt.Sprintf("\nQueries: %d, Local: %d, Cached: %d, Forwarded: %d, Failed: %d, Dropped: %d, NXDomain: %d, ServFail: %d, AvgLatency: %v",
+		qs.Queries, qs.Local, qs.Cached, qs.Forwarded, qs.Failed, qs.Dropped, qs.NXDomain, qs.ServFail, avg)
 }
 
 /******************************